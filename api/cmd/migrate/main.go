@@ -0,0 +1,120 @@
+// Command migrate applies, rolls back and creates schema migrations.
+//
+//	migrate up              apply all pending migrations
+//	migrate down [n]        roll back the last n migrations (default 1)
+//	migrate version         print the current schema version
+//	migrate force <version> mark version as applied and clean
+//	migrate create <name>   add an empty NNN_name.up/down.sql pair
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"memwright/api/internal/config"
+	"memwright/api/internal/database"
+	"memwright/api/internal/migrate"
+	"memwright/api/migrations"
+	"memwright/api/pkg/logger"
+)
+
+// migrationsDir is where create writes new files, relative to the api
+// module root the Makefile runs from.
+const migrationsDir = "migrations"
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage()
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			usage()
+		}
+		up, down, err := migrate.Create(migrationsDir, args[1])
+		if err != nil {
+			log.Fatalf("failed to create migration: %v", err)
+		}
+		fmt.Printf("created %s\ncreated %s\n", up, down)
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	appLogger := logger.New(nil, logger.LevelInfo)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.Open(ctx, database.OptionsFromConfig(cfg), appLogger)
+	if err != nil {
+		appLogger.Fatal("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	runner, err := migrate.NewRunner(db, migrations.FS, appLogger)
+	if err != nil {
+		appLogger.Fatal("failed to load migrations: %v", err)
+	}
+
+	if err := run(ctx, runner, args); err != nil {
+		_ = db.Close()
+		appLogger.Fatal("%s failed: %v", args[0], err)
+	}
+}
+
+func run(ctx context.Context, runner *migrate.Runner, args []string) error {
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s), schema at version %d\n", applied, runner.Latest())
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := runner.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+	case "version":
+		version, dirty, err := runner.Version(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version %d (latest %d, dirty %t)\n", version, runner.Latest(), dirty)
+	case "force":
+		if len(args) != 2 {
+			usage()
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return runner.Force(ctx, version)
+	default:
+		usage()
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: migrate up | down [n] | version | force <version> | create <name>")
+	os.Exit(2)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+
+	"memwright/api/internal/config"
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+const (
+	demoEmail    = "demo@memwright.dev"
+	demoPassword = "memwright-demo"
+)
+
+// newDemoDependencies runs the API on in-memory repositories seeded with a
+// demo account, so it can be tried without PostgreSQL.
+func newDemoDependencies(ctx context.Context, cfg *config.Config, log logger.Logger) (handler.Dependencies, error) {
+	store := memory.NewStore()
+	deps, err := newServices(cfg, memory.NewRepositories(store), memory.NewUnitOfWork(store), log)
+	if err != nil {
+		return handler.Dependencies{}, err
+	}
+	if err := seedDemo(ctx, deps); err != nil {
+		return handler.Dependencies{}, err
+	}
+	log.Info("demo account email=%s password=%s", demoEmail, demoPassword)
+	return deps, nil
+}
+
+// seedDemo creates the demo account with a small deck tree.
+func seedDemo(ctx context.Context, deps handler.Dependencies) error {
+	account, err := deps.Auth.Register(ctx, service.RegisterInput{Email: demoEmail, Password: demoPassword, DisplayName: "Demo"})
+	if err != nil {
+		return err
+	}
+	userID := account.User.ID
+
+	spanish, err := deps.Decks.Create(ctx, userID, service.DeckInput{Name: "Spanish", Description: "Everyday vocabulary", Language: "spanish"})
+	if err != nil {
+		return err
+	}
+	verbs, err := deps.Decks.Create(ctx, userID, service.DeckInput{ParentID: &spanish.ID, Name: "Verbs", Language: "spanish"})
+	if err != nil {
+		return err
+	}
+
+	cards := []struct {
+		deckID int64
+		input  service.CardInput
+	}{
+		{spanish.ID, service.CardInput{Front: "hola", Back: "hello", Tags: []string{"spanish::greetings"}}},
+		{spanish.ID, service.CardInput{Front: "gracias", Back: "thank you", Tags: []string{"spanish::greetings"}}},
+		{spanish.ID, service.CardInput{Type: model.CardTypeReverse, Front: "el perro", Back: "the dog", Tags: []string{"spanish::nouns::animals"}}},
+		{verbs.ID, service.CardInput{Front: "hablar", Back: "to speak", Tags: []string{"spanish::verbs::ar"}}},
+		{verbs.ID, service.CardInput{Type: model.CardTypeCloze, Front: "Yo {{c1::como}} pan.", Back: "I eat bread.", Tags: []string{"spanish::verbs::er"}}},
+	}
+	for i, card := range cards {
+		card.input.Position = i
+		if _, err := deps.Cards.Create(ctx, userID, card.deckID, card.input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
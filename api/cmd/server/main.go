@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,12 +14,24 @@ import (
 	"syscall"
 	"time"
 
+	"memwright/api/internal/auth"
 	"memwright/api/internal/config"
+	"memwright/api/internal/database"
 	"memwright/api/internal/handler"
+	"memwright/api/internal/health"
+	"memwright/api/internal/migrate"
+	"memwright/api/internal/ratelimit"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+	"memwright/api/migrations"
 	"memwright/api/pkg/logger"
 )
 
 func main() {
+	runMigrations := flag.Bool("migrate", false, "apply pending database migrations before serving")
+	demo := flag.Bool("demo", false, "run without a database on in-memory data seeded with a demo account")
+	flag.Parse()
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load configuration: %v", err)
@@ -29,20 +45,57 @@ func main() {
 
 	appLogger.Info("starting memwright API server environment=%s port=%d", cfg.Environment, cfg.Port)
 
+	// Let SIGINT/SIGTERM interrupt the connection retries during startup
+	startupCtx, stopStartup := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	var db *sql.DB
+	var deps handler.Dependencies
+	if *demo {
+		appLogger.Warn("running in demo mode: data is kept in memory and lost on exit")
+		deps, err = newDemoDependencies(startupCtx, cfg, appLogger)
+	} else {
+		db, err = openDatabase(startupCtx, cfg, *runMigrations, appLogger)
+		if err == nil {
+			deps, err = newDependencies(cfg, db, appLogger)
+		}
+	}
+	stopStartup()
+	if err != nil {
+		closeDatabase(db, appLogger)
+		appLogger.Fatal("failed to initialize: %v", err)
+	}
+	if cfg.RateLimitEnabled {
+		store := ratelimit.NewMemoryStore()
+		deps.AuthLimiter = ratelimit.NewLimiter("auth", store, ratelimit.PerMinute(cfg.RateLimitAuthPerMin, cfg.RateLimitAuthBurst))
+		deps.APILimiter = ratelimit.NewLimiter("api", store, ratelimit.PerMinute(cfg.RateLimitAPIPerMin, cfg.RateLimitAPIBurst))
+	}
+
 	mux := http.NewServeMux()
-	handler.RegisterRoutes(mux, handler.Dependencies{
-		Logger:      appLogger,
-		Environment: cfg.Environment,
-	})
+	handler.RegisterRoutes(mux, deps)
 
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%d", cfg.Port),
-		Handler:      mux,
+		Addr: fmt.Sprintf(":%d", cfg.Port),
+		Handler: handler.Wrap(mux, handler.MiddlewareOptions{
+			Logger:    appLogger,
+			AccessLog: cfg.AccessLogEnabled,
+			CORS: handler.CORSOptions{
+				AllowedOrigins: cfg.CORSAllowedOrigins,
+				MaxAge:         time.Duration(cfg.CORSMaxAgeSeconds) * time.Second,
+			},
+			SecurityHeaders: cfg.SecurityHeadersEnabled,
+			HSTSMaxAge:      time.Duration(cfg.HSTSMaxAgeSeconds) * time.Second,
+		}),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	purged := make(chan struct{})
+	go func() {
+		defer close(purged)
+		purgeTrash(purgeCtx, deps.Trash, time.Duration(cfg.TrashPurgeIntervalMinutes)*time.Minute, appLogger)
+	}()
+
 	go func() {
 		appLogger.Info("server listening addr=%s", server.Addr)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -54,6 +107,9 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	// The purge must be done with the database before it is closed.
+	stopPurge()
+	<-purged
 
 	appLogger.Info("shutting down server...")
 
@@ -62,8 +118,141 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
+		closeDatabase(db, appLogger)
 		appLogger.Fatal("server forced to shutdown: %v", err)
 	}
+	closeDatabase(db, appLogger)
 
 	appLogger.Info("server exited gracefully")
 }
+
+// openDatabase connects the pool and, if asked to, migrates the schema.
+func openDatabase(ctx context.Context, cfg *config.Config, runMigrations bool, log logger.Logger) (*sql.DB, error) {
+	db, err := database.Open(ctx, database.OptionsFromConfig(cfg), log)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	log.Info("database connected host=%s db=%s max_open_conns=%d", cfg.DatabaseHost, cfg.DatabaseName, cfg.DatabaseMaxOpenConns)
+
+	if runMigrations {
+		if err := migrateUp(ctx, db, log); err != nil {
+			return db, fmt.Errorf("migrate database: %w", err)
+		}
+	}
+	return db, nil
+}
+
+// closeDatabase closes the pool; db is nil in demo mode.
+func closeDatabase(db *sql.DB, log logger.Logger) {
+	if db == nil {
+		return
+	}
+	if err := db.Close(); err != nil {
+		log.Error("failed to close database: %v", err)
+	}
+}
+
+// newDependencies builds the repositories and services on top of the pool.
+func newDependencies(cfg *config.Config, db *sql.DB, log logger.Logger) (handler.Dependencies, error) {
+	expectedVersion, err := migrations.LatestVersion()
+	if err != nil {
+		return handler.Dependencies{}, err
+	}
+
+	return newServices(cfg, repository.NewRepositories(db), repository.NewUnitOfWork(db), log,
+		health.Database(db),
+		health.Migrations(repository.NewSchemaRepository(db), expectedVersion),
+	)
+}
+
+// newServices wires the services on top of repos; uow must run units on
+// the same backend.
+func newServices(cfg *config.Config, repos repository.Repositories, uow repository.UnitOfWork, log logger.Logger, checks ...health.Check) (handler.Dependencies, error) {
+	secret, err := jwtSecret(cfg, log)
+	if err != nil {
+		return handler.Dependencies{}, err
+	}
+	tokens, err := auth.NewTokenManager(secret, time.Duration(cfg.JWTExpirationHours)*time.Hour)
+	if err != nil {
+		return handler.Dependencies{}, err
+	}
+
+	authorizer := service.NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+
+	return handler.Dependencies{
+		Logger:      log,
+		Environment: cfg.Environment,
+		TrustProxy:  cfg.TrustProxyHeaders,
+		Readiness:   health.NewChecker(time.Duration(cfg.HealthCheckTimeoutSeconds)*time.Second, checks...),
+
+		Tokens: tokens,
+		Auth:   service.NewAuthService(repos.Users, repos.RefreshTokens, uow, tokens, time.Duration(cfg.JWTRefreshExpirationHours)*time.Hour),
+		Decks:  service.NewDeckService(authorizer, repos.Decks, uow),
+		Cards:  service.NewCardService(authorizer, repos.Cards, uow),
+		Tags:   service.NewTagService(repos.Tags),
+		Search: service.NewSearchService(authorizer, repos.Search),
+		Trash:  service.NewTrashService(repos.Decks, repos.Cards, uow, time.Duration(cfg.TrashRetentionDays)*24*time.Hour),
+
+		Reviews: service.NewReviewService(repos.ReviewLogs),
+
+		Backlog: service.NewBacklogService(authorizer, repos.Schedules, repos.Users, uow),
+
+		Transfer: service.NewTransferService(authorizer, repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs, uow),
+	}, nil
+}
+
+// purgeTrash deletes expired trash at startup and then every interval until
+// ctx is done. A non-positive interval disables it.
+func purgeTrash(ctx context.Context, trash *service.TrashService, interval time.Duration, log logger.Logger) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := trash.Purge(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Error("failed to purge trash: %v", err)
+		case result.Decks > 0 || result.Cards > 0:
+			log.Info("trash purged decks=%d cards=%d", result.Decks, result.Cards)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// migrateUp applies the embedded migrations. The advisory lock taken by the
+// runner lets several instances start with -migrate at once.
+func migrateUp(ctx context.Context, db *sql.DB, log logger.Logger) error {
+	runner, err := migrate.NewRunner(db, migrations.FS, log)
+	if err != nil {
+		return err
+	}
+	applied, err := runner.Up(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info("database migrated applied=%d version=%d", applied, runner.Latest())
+	return nil
+}
+
+// jwtSecret returns the configured signing secret. Development may run
+// without one: a random secret is generated, so tokens do not survive a
+// restart.
+func jwtSecret(cfg *config.Config, log logger.Logger) (string, error) {
+	if cfg.JWTSecret != "" || !cfg.IsDevelopment() {
+		return cfg.JWTSecret, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	log.Warn("JWT_SECRET is not set, using a random secret; tokens are invalidated on restart")
+	return hex.EncodeToString(buf), nil
+}
@@ -1,3 +1,8 @@
 module memwright/api
 
 go 1.22
+
+require (
+	github.com/lib/pq v1.10.9
+	golang.org/x/crypto v0.28.0
+)
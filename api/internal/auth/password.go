@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"memwright/api/internal/model"
+)
+
+const MinPasswordLength = 8
+
+// bcrypt ignores everything past 72 bytes, so longer passwords are rejected
+// instead of being silently truncated.
+const maxPasswordLength = 72
+
+func HashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// dummyHash is the bcrypt hash, at the default cost, of a random password
+// that was thrown away.
+const dummyHash = "$2a$10$VC/P5GRd3tetJoVHBpnLuOXCmvaRQCfYmUkEqPSlZF/YQhtDnoM4y"
+
+// RejectPassword takes as long as CheckPassword and always fails. Logins
+// for unknown emails use it so response times don't reveal which emails
+// are registered.
+func RejectPassword(password string) error {
+	_ = bcrypt.CompareHashAndPassword([]byte(dummyHash), []byte(password))
+	return model.ErrUnauthorized
+}
+
+// CheckPassword returns model.ErrUnauthorized when password does not match hash.
+func CheckPassword(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return model.ErrUnauthorized
+	}
+	return err
+}
+
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return model.NewValidationError("password", fmt.Sprintf("must be at least %d characters", MinPasswordLength))
+	}
+	if len(password) > maxPasswordLength {
+		return model.NewValidationError("password", fmt.Sprintf("must be at most %d bytes", maxPasswordLength))
+	}
+	return nil
+}
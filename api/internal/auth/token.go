@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"memwright/api/internal/model"
+)
+
+const MinSecretLength = 32
+
+var (
+	ErrInvalidToken = fmt.Errorf("%w: invalid token", model.ErrUnauthorized)
+	ErrExpiredToken = fmt.Errorf("%w: token expired", model.ErrUnauthorized)
+)
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims is the payload of an access token.
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// UserID parses the subject claim.
+func (c *Claims) UserID() (int64, error) {
+	userID, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// TokenManager issues and verifies HS256 access tokens.
+type TokenManager struct {
+	secret    []byte
+	accessTTL time.Duration
+	now       func() time.Time
+}
+
+func NewTokenManager(secret string, accessTTL time.Duration) (*TokenManager, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("JWT secret must be at least %d characters", MinSecretLength)
+	}
+	if accessTTL <= 0 {
+		return nil, errors.New("access token TTL must be positive")
+	}
+	return &TokenManager{
+		secret:    []byte(secret),
+		accessTTL: accessTTL,
+		now:       time.Now,
+	}, nil
+}
+
+// WithClock replaces the time source used for issuing and validating tokens.
+func (m *TokenManager) WithClock(now func() time.Time) *TokenManager {
+	m.now = now
+	return m
+}
+
+func (m *TokenManager) IssueAccessToken(userID int64) (string, time.Time, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := m.now()
+	expiresAt := now.Add(m.accessTTL)
+	claims := Claims{
+		Subject:   strconv.FormatInt(userID, 10),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        jti,
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + m.sign(signingInput), expiresAt, nil
+}
+
+func (m *TokenManager) ParseAccessToken(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	if parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(m.sign(signingInput))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if m.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return claims, nil
+}
+
+func (m *TokenManager) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewRefreshToken returns an opaque refresh token for the client and the hash
+// under which it is stored server-side.
+func NewRefreshToken() (token string, hash string, err error) {
+	token, err = randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
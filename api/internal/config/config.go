@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"memwright/api/pkg/env"
 )
@@ -20,8 +21,34 @@ type Config struct {
 	DatabaseName     string
 	DatabaseSSLMode  string
 
-	JWTSecret          string
-	JWTExpirationHours int
+	DatabaseMaxOpenConns           int
+	DatabaseMaxIdleConns           int
+	DatabaseConnMaxLifetimeMinutes int
+	DatabaseConnMaxIdleTimeMinutes int
+	DatabaseStatementTimeoutMs     int
+	DatabaseConnectAttempts        int
+
+	JWTSecret                 string
+	JWTExpirationHours        int
+	JWTRefreshExpirationHours int
+
+	AccessLogEnabled       bool
+	CORSAllowedOrigins     []string
+	CORSMaxAgeSeconds      int
+	SecurityHeadersEnabled bool
+	HSTSMaxAgeSeconds      int
+
+	RateLimitEnabled    bool
+	RateLimitAuthPerMin int
+	RateLimitAuthBurst  int
+	RateLimitAPIPerMin  int
+	RateLimitAPIBurst   int
+	TrustProxyHeaders   bool
+
+	HealthCheckTimeoutSeconds int
+
+	TrashRetentionDays        int
+	TrashPurgeIntervalMinutes int
 }
 
 func Load() (*Config, error) {
@@ -41,8 +68,34 @@ func Load() (*Config, error) {
 		DatabaseName:     getEnv("POSTGRES_DB", "memwright"),
 		DatabaseSSLMode:  getEnv("POSTGRES_SSLMODE", "disable"),
 
-		JWTSecret:          getEnv("JWT_SECRET", ""),
-		JWTExpirationHours: getEnvInt("JWT_EXPIRATION_HOURS", 24),
+		DatabaseMaxOpenConns:           getEnvInt("DATABASE_MAX_OPEN_CONNS", 25),
+		DatabaseMaxIdleConns:           getEnvInt("DATABASE_MAX_IDLE_CONNS", 5),
+		DatabaseConnMaxLifetimeMinutes: getEnvInt("DATABASE_CONN_MAX_LIFETIME_MINUTES", 30),
+		DatabaseConnMaxIdleTimeMinutes: getEnvInt("DATABASE_CONN_MAX_IDLE_TIME_MINUTES", 5),
+		DatabaseStatementTimeoutMs:     getEnvInt("DATABASE_STATEMENT_TIMEOUT_MS", 30000),
+		DatabaseConnectAttempts:        getEnvInt("DATABASE_CONNECT_ATTEMPTS", 10),
+
+		JWTSecret:                 getEnv("JWT_SECRET", ""),
+		JWTExpirationHours:        getEnvInt("JWT_EXPIRATION_HOURS", 24),
+		JWTRefreshExpirationHours: getEnvInt("JWT_REFRESH_EXPIRATION_HOURS", 720),
+
+		AccessLogEnabled:       getEnvBool("ACCESS_LOG_ENABLED", true),
+		CORSAllowedOrigins:     getEnvList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173"}),
+		CORSMaxAgeSeconds:      getEnvInt("CORS_MAX_AGE_SECONDS", 600),
+		SecurityHeadersEnabled: getEnvBool("SECURITY_HEADERS_ENABLED", true),
+		HSTSMaxAgeSeconds:      getEnvInt("HSTS_MAX_AGE_SECONDS", 0),
+
+		RateLimitEnabled:    getEnvBool("RATE_LIMIT_ENABLED", true),
+		RateLimitAuthPerMin: getEnvInt("RATE_LIMIT_AUTH_PER_MINUTE", 10),
+		RateLimitAuthBurst:  getEnvInt("RATE_LIMIT_AUTH_BURST", 5),
+		RateLimitAPIPerMin:  getEnvInt("RATE_LIMIT_API_PER_MINUTE", 300),
+		RateLimitAPIBurst:   getEnvInt("RATE_LIMIT_API_BURST", 60),
+		TrustProxyHeaders:   getEnvBool("TRUST_PROXY_HEADERS", false),
+
+		HealthCheckTimeoutSeconds: getEnvInt("HEALTH_CHECK_TIMEOUT_SECONDS", 2),
+
+		TrashRetentionDays:        getEnvInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeIntervalMinutes: getEnvInt("TRASH_PURGE_INTERVAL_MINUTES", 60),
 	}, nil
 }
 
@@ -81,3 +134,27 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList reads a comma-separated list, dropping empty entries.
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,129 @@
+// Package database opens the PostgreSQL connection pool used by the
+// repositories.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"memwright/api/internal/config"
+	"memwright/api/pkg/logger"
+)
+
+const pingTimeout = 5 * time.Second
+
+type Options struct {
+	URL              string
+	MaxOpenConns     int
+	MaxIdleConns     int
+	ConnMaxLifetime  time.Duration
+	ConnMaxIdleTime  time.Duration
+	StatementTimeout time.Duration
+
+	// ConnectAttempts bounds how often Open pings the database before
+	// giving up. Waits between attempts double from InitialBackoff up to
+	// MaxBackoff.
+	ConnectAttempts int
+	InitialBackoff  time.Duration
+	MaxBackoff      time.Duration
+}
+
+func OptionsFromConfig(cfg *config.Config) Options {
+	return Options{
+		URL:              cfg.DatabaseURL(),
+		MaxOpenConns:     cfg.DatabaseMaxOpenConns,
+		MaxIdleConns:     cfg.DatabaseMaxIdleConns,
+		ConnMaxLifetime:  time.Duration(cfg.DatabaseConnMaxLifetimeMinutes) * time.Minute,
+		ConnMaxIdleTime:  time.Duration(cfg.DatabaseConnMaxIdleTimeMinutes) * time.Minute,
+		StatementTimeout: time.Duration(cfg.DatabaseStatementTimeoutMs) * time.Millisecond,
+		ConnectAttempts:  cfg.DatabaseConnectAttempts,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+	}
+}
+
+// Open configures the pool and waits until the database answers. The
+// caller owns the returned pool and must close it on shutdown.
+func Open(ctx context.Context, options Options, log logger.Logger) (*sql.DB, error) {
+	dsn, err := options.DSN()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	db.SetMaxIdleConns(options.MaxIdleConns)
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+
+	if err := WaitForConnection(ctx, db, options, log); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// WaitForConnection pings until the database answers, backing off
+// exponentially between attempts. It gives up after ConnectAttempts pings or
+// when ctx is done.
+func WaitForConnection(ctx context.Context, db Pinger, options Options, log logger.Logger) error {
+	attempts := options.ConnectAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := options.InitialBackoff
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err = db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		log.Warn("database not ready attempt=%d/%d retry_in=%s: %v", attempt, attempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("connect to database: %w", ctx.Err())
+		}
+		backoff *= 2
+		if options.MaxBackoff > 0 && backoff > options.MaxBackoff {
+			backoff = options.MaxBackoff
+		}
+	}
+	return fmt.Errorf("connect to database after %d attempts: %w", attempts, err)
+}
+
+// DSN returns the connection URL with the statement_timeout run-time
+// parameter, which lib/pq sends to the server for every pooled connection.
+func (o Options) DSN() (string, error) {
+	if o.StatementTimeout <= 0 {
+		return o.URL, nil
+	}
+	parsed, err := url.Parse(o.URL)
+	if err != nil {
+		return "", fmt.Errorf("parse database URL: %w", err)
+	}
+	query := parsed.Query()
+	query.Set("statement_timeout", strconv.FormatInt(o.StatementTimeout.Milliseconds(), 10))
+	parsed.RawQuery = query.Encode()
+	return parsed.String(), nil
+}
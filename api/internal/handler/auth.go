@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AuthHandler struct {
+	auth   *service.AuthService
+	logger logger.Logger
+}
+
+func NewAuthHandler(auth *service.AuthService, log logger.Logger) *AuthHandler {
+	return &AuthHandler{
+		auth:   auth,
+		logger: log,
+	}
+}
+
+func (handler *AuthHandler) Register(writer http.ResponseWriter, request *http.Request) {
+	var input service.RegisterInput
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.auth.Register(request.Context(), input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusCreated, result)
+}
+
+func (handler *AuthHandler) Login(writer http.ResponseWriter, request *http.Request) {
+	var input LoginRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.auth.Login(request.Context(), input.Email, input.Password)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+func (handler *AuthHandler) Refresh(writer http.ResponseWriter, request *http.Request) {
+	var input RefreshRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.auth.Refresh(request.Context(), input.RefreshToken)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+func (handler *AuthHandler) Logout(writer http.ResponseWriter, request *http.Request) {
+	var input RefreshRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	if err := handler.auth.Logout(request.Context(), input.RefreshToken); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
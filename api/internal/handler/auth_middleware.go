@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"memwright/api/internal/auth"
+)
+
+// RequireAuth rejects requests without a valid bearer access token and stores
+// the authenticated user ID in the request context.
+func RequireAuth(tokens *auth.TokenManager) func(http.Handler) http.Handler {
+	return requireAuth(tokens, nil)
+}
+
+// requireAuth is RequireAuth with rejections passed through reject, such as
+// a rate limit keyed by client IP, which may answer them itself. Requests
+// that authenticate skip it. A nil reject writes the 401 directly.
+func requireAuth(tokens *auth.TokenManager, reject Middleware) func(http.Handler) http.Handler {
+	unauthorized := func(writer http.ResponseWriter, request *http.Request, challenge, detail string) {
+		var rejected http.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			if challenge != "" {
+				writer.Header().Set("WWW-Authenticate", challenge)
+			}
+			writeStatusError(writer, request, http.StatusUnauthorized, "unauthorized", detail)
+		})
+		if reject != nil {
+			rejected = reject(rejected)
+		}
+		rejected.ServeHTTP(writer, request)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			header := request.Header.Get("Authorization")
+			scheme, token, found := strings.Cut(header, " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthorized(writer, request, `Bearer realm="memwright"`, "missing bearer token")
+				return
+			}
+
+			claims, err := tokens.ParseAccessToken(token)
+			if err != nil {
+				unauthorized(writer, request, `Bearer realm="memwright", error="invalid_token"`, "invalid or expired token")
+				return
+			}
+
+			userID, err := claims.UserID()
+			if err != nil {
+				unauthorized(writer, request, "", "invalid or expired token")
+				return
+			}
+
+			next.ServeHTTP(writer, request.WithContext(auth.WithUserID(request.Context(), userID)))
+		})
+	}
+}
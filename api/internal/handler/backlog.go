@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type BacklogHandler struct {
+	backlog *service.BacklogService
+	logger  logger.Logger
+}
+
+func NewBacklogHandler(backlog *service.BacklogService, log logger.Logger) *BacklogHandler {
+	return &BacklogHandler{
+		backlog: backlog,
+		logger:  log,
+	}
+}
+
+// Preview shows how the overdue reviews would be spread without moving any.
+func (handler *BacklogHandler) Preview(writer http.ResponseWriter, request *http.Request) {
+	handler.run(writer, request, handler.backlog.Preview)
+}
+
+// Apply spreads the overdue reviews and returns the plan it applied.
+func (handler *BacklogHandler) Apply(writer http.ResponseWriter, request *http.Request) {
+	handler.run(writer, request, handler.backlog.Apply)
+}
+
+func (handler *BacklogHandler) run(writer http.ResponseWriter, request *http.Request, plan func(ctx context.Context, userID int64, opts service.BacklogOptions) (*service.BacklogPlan, error)) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var opts service.BacklogOptions
+	if err := decodeJSON(writer, request, &opts); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := plan(request.Context(), userID, opts)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type CardHandler struct {
+	cards  *service.CardService
+	logger logger.Logger
+}
+
+func NewCardHandler(cards *service.CardService, log logger.Logger) *CardHandler {
+	return &CardHandler{
+		cards:  cards,
+		logger: log,
+	}
+}
+
+func (handler *CardHandler) List(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "deckId")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	cards, err := handler.cards.List(request.Context(), userID, deckID, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, cards, page.Limit)
+}
+
+func (handler *CardHandler) Create(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "deckId")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.CardInput
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	card, err := handler.cards.Create(request.Context(), userID, deckID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, card.Version)
+	writeJSON(writer, http.StatusCreated, card)
+}
+
+func (handler *CardHandler) Get(writer http.ResponseWriter, request *http.Request) {
+	userID, cardID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	card, err := handler.cards.Get(request.Context(), userID, cardID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, card.Version)
+	writeJSON(writer, http.StatusOK, card)
+}
+
+func (handler *CardHandler) Update(writer http.ResponseWriter, request *http.Request) {
+	userID, cardID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.CardInput
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	if err := applyIfMatch(request, &input.Version); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	card, err := handler.cards.Update(request.Context(), userID, cardID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, card.Version)
+	writeJSON(writer, http.StatusOK, card)
+}
+
+func (handler *CardHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	userID, cardID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	if err := handler.cards.Delete(request.Context(), userID, cardID); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// Bulk responds 200 when every card was changed and 422 with the per-item
+// results when the transaction was rolled back.
+func (handler *CardHandler) Bulk(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.BulkCardRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.cards.Bulk(request.Context(), userID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(writer, status, result)
+}
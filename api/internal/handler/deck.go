@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type DeckHandler struct {
+	decks  *service.DeckService
+	logger logger.Logger
+}
+
+func NewDeckHandler(decks *service.DeckService, log logger.Logger) *DeckHandler {
+	return &DeckHandler{
+		decks:  decks,
+		logger: log,
+	}
+}
+
+func (handler *DeckHandler) List(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	tree, err := handler.decks.Tree(request.Context(), userID, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, tree, page.Limit)
+}
+
+func (handler *DeckHandler) Create(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.DeckInput
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	deck, err := handler.decks.Create(request.Context(), userID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, deck.Version)
+	writeJSON(writer, http.StatusCreated, deck)
+}
+
+func (handler *DeckHandler) Get(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	node, err := handler.decks.Get(request.Context(), userID, deckID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, node.Version)
+	writeJSON(writer, http.StatusOK, node)
+}
+
+func (handler *DeckHandler) Update(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.DeckInput
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	if err := applyIfMatch(request, &input.Version); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	deck, err := handler.decks.Update(request.Context(), userID, deckID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, deck.Version)
+	writeJSON(writer, http.StatusOK, deck)
+}
+
+func (handler *DeckHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	if err := handler.decks.Delete(request.Context(), userID, deckID); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *DeckHandler) Subdecks(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	subdecks, err := handler.decks.Subdecks(request.Context(), userID, deckID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, subdecks)
+}
+
+func (handler *DeckHandler) Move(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.MoveDeckRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	if err := applyIfMatch(request, &input.Version); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	deck, err := handler.decks.Move(request.Context(), userID, deckID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, deck.Version)
+	writeJSON(writer, http.StatusOK, deck)
+}
+
+// Archive and Unarchive take the expected version from If-Match only.
+func (handler *DeckHandler) Archive(writer http.ResponseWriter, request *http.Request) {
+	handler.setArchived(writer, request, handler.decks.Archive)
+}
+
+func (handler *DeckHandler) Unarchive(writer http.ResponseWriter, request *http.Request) {
+	handler.setArchived(writer, request, handler.decks.Unarchive)
+}
+
+func (handler *DeckHandler) setArchived(writer http.ResponseWriter, request *http.Request, set func(ctx context.Context, userID, deckID int64, version int) (*model.Deck, error)) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var version int
+	if err := applyIfMatch(request, &version); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	deck, err := set(request.Context(), userID, deckID, version)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, deck.Version)
+	writeJSON(writer, http.StatusOK, deck)
+}
+
+func (handler *DeckHandler) Reorder(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.ReorderDecksRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	decks, err := handler.decks.Reorder(request.Context(), userID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, decks)
+}
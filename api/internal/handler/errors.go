@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"memwright/api/internal/model"
+	"memwright/api/pkg/logger"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body. Code is a stable,
+// machine-readable identifier of the error; Errors lists field-level
+// validation problems.
+type Problem struct {
+	Type      string             `json:"type"`
+	Title     string             `json:"title"`
+	Status    int                `json:"status"`
+	Detail    string             `json:"detail,omitempty"`
+	Instance  string             `json:"instance,omitempty"`
+	Code      string             `json:"code"`
+	RequestID string             `json:"request_id"`
+	Errors    []model.FieldError `json:"errors,omitempty"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+	// expose reports whether the error message is safe to show as detail.
+	expose bool
+}
+
+// errorMappings is the single place where model errors become HTTP statuses.
+// The first matching entry wins, so more specific errors come first.
+var errorMappings = []errorMapping{
+	{err: model.ErrInvalidInput, status: http.StatusBadRequest, code: "invalid_input", expose: true},
+	{err: model.ErrUnauthorized, status: http.StatusUnauthorized, code: "unauthorized"},
+	{err: model.ErrForbidden, status: http.StatusForbidden, code: "forbidden"},
+	{err: model.ErrNotFound, status: http.StatusNotFound, code: "not_found"},
+	{err: model.ErrConflict, status: http.StatusPreconditionFailed, code: "version_conflict", expose: true},
+	{err: model.ErrDuplicateEmail, status: http.StatusConflict, code: "duplicate_email", expose: true},
+	{err: model.ErrDuplicateName, status: http.StatusConflict, code: "duplicate_name", expose: true},
+	{err: model.ErrDuplicateKey, status: http.StatusConflict, code: "duplicate_key", expose: true},
+	{err: model.ErrDeckCycle, status: http.StatusConflict, code: "deck_cycle", expose: true},
+}
+
+// StatusForError returns the HTTP status a model error maps to, or 500 for
+// errors that are not part of the model.
+func StatusForError(err error) int {
+	if mapping, ok := lookupErrorMapping(err); ok {
+		return mapping.status
+	}
+	return http.StatusInternalServerError
+}
+
+func lookupErrorMapping(err error) (errorMapping, bool) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping, true
+		}
+	}
+	return errorMapping{}, false
+}
+
+// writeError renders err as problem+json. Errors outside the model are
+// logged and reported as a generic 500 without leaking their message.
+func writeError(writer http.ResponseWriter, request *http.Request, log logger.Logger, err error) {
+	problem := Problem{
+		Type:      "about:blank",
+		Status:    http.StatusInternalServerError,
+		Code:      "internal_error",
+		Instance:  request.URL.Path,
+		RequestID: requestID(request),
+	}
+
+	if mapping, ok := lookupErrorMapping(err); ok {
+		problem.Status = mapping.status
+		problem.Code = mapping.code
+		if mapping.expose {
+			problem.Detail = err.Error()
+		}
+	} else if log != nil {
+		log.Error("request failed request_id=%s method=%s path=%s: %v", problem.RequestID, request.Method, request.URL.Path, err)
+	}
+
+	var validationErr *model.ValidationError
+	if errors.As(err, &validationErr) {
+		problem.Code = "validation_failed"
+		problem.Detail = "one or more fields are invalid"
+		problem.Errors = validationErr.Fields
+	}
+
+	writeProblem(writer, problem)
+}
+
+// writeStatusError renders a problem for a status that has no model error,
+// such as 405 or 429.
+func writeStatusError(writer http.ResponseWriter, request *http.Request, status int, code, detail string) {
+	writeProblem(writer, Problem{
+		Type:      "about:blank",
+		Status:    status,
+		Code:      code,
+		Detail:    detail,
+		Instance:  request.URL.Path,
+		RequestID: requestID(request),
+	})
+}
+
+func writeProblem(writer http.ResponseWriter, problem Problem) {
+	problem.Title = http.StatusText(problem.Status)
+	writer.Header().Set("Content-Type", problemContentType)
+	writer.Header().Set(RequestIDHeader, problem.RequestID)
+	writer.WriteHeader(problem.Status)
+	_ = json.NewEncoder(writer).Encode(problem)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"memwright/api/internal/model"
+)
+
+// Decks and cards carry their version as a strong ETag such as "3". The
+// tag identifies the stored row; derived fields like deck counts are not
+// part of it.
+func setETag(writer http.ResponseWriter, version int) {
+	writer.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// applyIfMatch turns an If-Match header into the version an update expects.
+// Without the header, or with "*", the version from the body (if any) is
+// kept. Weak tags never match, as If-Match uses strong comparison.
+func applyIfMatch(request *http.Request, version *int) error {
+	header := strings.TrimSpace(request.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return model.ErrConflict
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return model.NewValidationError("If-Match", `must be a single entity tag such as "3"`)
+	}
+	parsed, err := strconv.Atoi(tag)
+	if err != nil || parsed <= 0 {
+		// A tag this API never issued cannot match the current version.
+		return model.ErrConflict
+	}
+	*version = parsed
+	return nil
+}
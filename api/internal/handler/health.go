@@ -3,6 +3,8 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+
+	"memwright/api/internal/health"
 )
 
 type HealthResponse struct {
@@ -22,7 +24,8 @@ func NewHealthHandler(environment string) *HealthHandler {
 
 func (handler *HealthHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	if request.Method != http.MethodGet {
-		http.Error(writer, "Method not allowed", http.StatusMethodNotAllowed)
+		writer.Header().Set("Allow", http.MethodGet)
+		writeStatusError(writer, request, http.StatusMethodNotAllowed, "method_not_allowed", "only GET is supported")
 		return
 	}
 
@@ -35,3 +38,35 @@ func (handler *HealthHandler) ServeHTTP(writer http.ResponseWriter, request *htt
 	writer.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(writer).Encode(response)
 }
+
+// ReadinessHandler reports whether the API can serve traffic. It answers 503
+// when any dependency check fails so load balancers stop routing to it.
+type ReadinessHandler struct {
+	checker *health.Checker
+}
+
+func NewReadinessHandler(checker *health.Checker) *ReadinessHandler {
+	if checker == nil {
+		checker = health.NewChecker(0)
+	}
+	return &ReadinessHandler{
+		checker: checker,
+	}
+}
+
+func (handler *ReadinessHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writer.Header().Set("Allow", http.MethodGet)
+		writeStatusError(writer, request, http.StatusMethodNotAllowed, "method_not_allowed", "only GET is supported")
+		return
+	}
+
+	report := handler.checker.Run(request.Context())
+
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writer.Header().Set("Cache-Control", "no-store")
+	writeJSON(writer, status, report)
+}
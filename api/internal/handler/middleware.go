@@ -0,0 +1,240 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"memwright/api/pkg/logger"
+)
+
+// Middleware wraps an http.Handler with additional behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler with the given middlewares. The first middleware is
+// the outermost, so it sees the request first and the response last.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+type MiddlewareOptions struct {
+	Logger          logger.Logger
+	AccessLog       bool
+	CORS            CORSOptions
+	SecurityHeaders bool
+	HSTSMaxAge      time.Duration
+}
+
+// Wrap applies the standard middleware stack to the API handler.
+func Wrap(handler http.Handler, options MiddlewareOptions) http.Handler {
+	middlewares := []Middleware{RequestID()}
+	if options.AccessLog {
+		middlewares = append(middlewares, AccessLog(options.Logger))
+	}
+	middlewares = append(middlewares, Recover(options.Logger))
+	if options.SecurityHeaders {
+		middlewares = append(middlewares, SecurityHeaders(options.HSTSMaxAge))
+	}
+	middlewares = append(middlewares, CORS(options.CORS))
+	return Chain(handler, middlewares...)
+}
+
+// RequestID assigns every request an ID, reusing a valid X-Request-ID
+// header from the client, and returns it in the response.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			id := requestID(request)
+			writer.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(writer, request.WithContext(withRequestID(request.Context(), id)))
+		})
+	}
+}
+
+// AccessLog logs one line per request with its status, size and latency.
+func AccessLog(log logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			start := time.Now()
+			recorder := recordResponse(writer)
+			next.ServeHTTP(recorder, request)
+
+			log.Info("request method=%s path=%s status=%d bytes=%d duration=%s remote=%s request_id=%s",
+				request.Method,
+				request.URL.Path,
+				recorder.Status(),
+				recorder.bytes,
+				time.Since(start),
+				request.RemoteAddr,
+				RequestIDFromContext(request.Context()),
+			)
+		})
+	}
+}
+
+// Recover turns a panic in a handler into a logged 500 response instead of
+// dropping the connection.
+func Recover(log logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			recorder := recordResponse(writer)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				log.Error("panic serving request request_id=%s method=%s path=%s: %v\n%s",
+					requestID(request), request.Method, request.URL.Path, recovered, debug.Stack())
+				if !recorder.wroteHeader {
+					writeError(recorder, request, nil, fmt.Errorf("panic: %v", recovered))
+				}
+			}()
+			next.ServeHTTP(recorder, request)
+		})
+	}
+}
+
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to call the API. "*" allows
+	// any origin. An empty list disables CORS handling.
+	AllowedOrigins []string
+	MaxAge         time.Duration
+}
+
+var (
+	corsAllowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	corsAllowedHeaders = "Authorization, Content-Type, If-Match, " + RequestIDHeader
+	corsExposedHeaders = "ETag, Location, Retry-After, " + RequestIDHeader
+)
+
+// CORS answers preflight requests and adds CORS headers for allowed origins.
+// Credentials are never allowed because the API uses bearer tokens.
+func CORS(options CORSOptions) Middleware {
+	allowAny := false
+	allowed := make(map[string]bool, len(options.AllowedOrigins))
+	for _, origin := range options.AllowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			origin := request.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			header := writer.Header()
+			header.Add("Vary", "Origin")
+			if !allowAny && !allowed[origin] {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			if allowAny {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+				header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+				header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				if options.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(int(options.MaxAge.Seconds())))
+				}
+				writer.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			header.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// SecurityHeaders sets conservative browser security headers suitable for a
+// JSON API. HSTS is only sent when hstsMaxAge is positive, which should be
+// limited to deployments served over HTTPS.
+func SecurityHeaders(hstsMaxAge time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			header := writer.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", "DENY")
+			header.Set("Referrer-Policy", "no-referrer")
+			header.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+			header.Set("Cross-Origin-Resource-Policy", "same-site")
+			if hstsMaxAge > 0 {
+				header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(hstsMaxAge.Seconds())))
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// responseRecorder captures the status and size of a response.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// recordResponse wraps writer, reusing an existing recorder so nested
+// middlewares observe the same response.
+func recordResponse(writer http.ResponseWriter) *responseRecorder {
+	if recorder, ok := writer.(*responseRecorder); ok {
+		return recorder
+	}
+	return &responseRecorder{ResponseWriter: writer}
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+// Status returns the response status, defaulting to 200 when the handler
+// never wrote anything.
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
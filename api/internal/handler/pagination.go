@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"memwright/api/internal/model"
+)
+
+// PageResponse is the envelope of paginated list endpoints. Next is the URL
+// of the following page and is omitted on the last page.
+type PageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Next       string `json:"next,omitempty"`
+}
+
+// pageRequest reads the cursor and limit query parameters. The limit
+// defaults to model.DefaultPageLimit.
+func pageRequest(request *http.Request) (model.PageRequest, error) {
+	query := request.URL.Query()
+	page := model.PageRequest{
+		Cursor: query.Get("cursor"),
+		Limit:  model.DefaultPageLimit,
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > model.MaxPageLimit {
+			return page, model.NewValidationError("limit", fmt.Sprintf("must be between 1 and %d", model.MaxPageLimit))
+		}
+		page.Limit = limit
+	}
+	if _, err := model.DecodeCursor(page.Cursor); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+// writePage writes a page and advertises the next page both in the body and
+// in a Link header.
+func writePage[T any](writer http.ResponseWriter, request *http.Request, page model.Page[T], limit int) {
+	response := PageResponse[T]{Items: page.Items, NextCursor: page.NextCursor}
+	if page.NextCursor != "" {
+		query := url.Values{}
+		for key, values := range request.URL.Query() {
+			query[key] = values
+		}
+		query.Set("cursor", page.NextCursor)
+		query.Set("limit", strconv.Itoa(limit))
+		next := url.URL{Path: request.URL.Path, RawQuery: query.Encode()}
+		response.Next = next.String()
+		writer.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, response.Next))
+	}
+	writeJSON(writer, http.StatusOK, response)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"memwright/api/internal/auth"
+	"memwright/api/internal/ratelimit"
+	"memwright/api/pkg/logger"
+)
+
+// RateLimitKey derives the bucket key for a request.
+type RateLimitKey func(request *http.Request) string
+
+// ClientIPKey keys requests by client IP. With trustProxy set, the first
+// address of X-Forwarded-For is used; only enable it behind a proxy that
+// overwrites the header.
+func ClientIPKey(trustProxy bool) RateLimitKey {
+	return func(request *http.Request) string {
+		return "ip:" + clientIP(request, trustProxy)
+	}
+}
+
+// UserOrIPKey keys authenticated requests by user and falls back to the
+// client IP otherwise. It must run after RequireAuth to see the user.
+func UserOrIPKey(trustProxy bool) RateLimitKey {
+	byIP := ClientIPKey(trustProxy)
+	return func(request *http.Request) string {
+		if userID, ok := auth.UserIDFromContext(request.Context()); ok {
+			return "user:" + strconv.FormatInt(userID, 10)
+		}
+		return byIP(request)
+	}
+}
+
+// RateLimit rejects requests over the limiter's policy with 429 and a
+// Retry-After header. Store failures are logged and the request is let
+// through, so an unavailable store never takes the API down.
+func RateLimit(limiter *ratelimit.Limiter, key RateLimitKey, log logger.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			decision, err := limiter.Allow(request.Context(), key(request))
+			if err != nil {
+				log.Error("rate limit store failed request_id=%s: %v", requestID(request), err)
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			header := writer.Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			if !decision.Allowed {
+				retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				header.Set("Retry-After", strconv.Itoa(retryAfter))
+				writeStatusError(writer, request, http.StatusTooManyRequests, "rate_limited", "too many requests, retry after "+strconv.Itoa(retryAfter)+"s")
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+func clientIP(request *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := request.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID assigned to the current request.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// requestID returns the request's ID from the context, falling back to the
+// incoming header and finally to a freshly generated ID.
+func requestID(request *http.Request) string {
+	if id := RequestIDFromContext(request.Context()); id != "" {
+		return id
+	}
+	if id := request.Header.Get(RequestIDHeader); isValidRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+// isValidRequestID accepts client-supplied IDs only if they are short and
+// printable, so they are safe to echo back and write to logs.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
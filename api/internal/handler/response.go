@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"memwright/api/internal/auth"
+	"memwright/api/internal/model"
+)
+
+const maxRequestBodyBytes = 1 << 20
+
+// maxImportBodyBytes bounds the documents accepted by the import endpoints.
+const maxImportBodyBytes = 64 << 20
+
+func writeJSON(writer http.ResponseWriter, status int, body interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(body)
+}
+
+func decodeJSON(writer http.ResponseWriter, request *http.Request, dst interface{}) error {
+	return decodeJSONBody(writer, request.Body, dst, maxRequestBodyBytes)
+}
+
+// decodeJSONLimit decodes an upload larger than the usual request body.
+// Every read extends the connection's deadlines, so an upload that keeps
+// arriving outlives the server's ReadTimeout.
+func decodeJSONLimit(writer http.ResponseWriter, request *http.Request, dst interface{}, limit int64) error {
+	return decodeJSONBody(writer, newDeadlineReader(writer, request.Body), dst, limit)
+}
+
+func decodeJSONBody(writer http.ResponseWriter, body io.ReadCloser, dst interface{}, limit int64) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, body, limit))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: request body is empty", model.ErrInvalidInput)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return model.NewValidationError(typeErr.Field, "must be of type "+typeErr.Type.String())
+	case errors.As(err, &maxBytesErr):
+		return fmt.Errorf("%w: request body exceeds %d bytes", model.ErrInvalidInput, maxBytesErr.Limit)
+	default:
+		return fmt.Errorf("%w: malformed JSON: %v", model.ErrInvalidInput, err)
+	}
+}
+
+// readBodyLimit reads a request body that is not JSON, such as an uploaded
+// file. Like decodeJSONLimit, it extends the deadlines as the file arrives.
+func readBodyLimit(writer http.ResponseWriter, request *http.Request, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(writer, newDeadlineReader(writer, request.Body), limit))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return nil, fmt.Errorf("%w: request body exceeds %d bytes", model.ErrInvalidInput, maxBytesErr.Limit)
+	case err != nil:
+		return nil, fmt.Errorf("%w: reading request body: %v", model.ErrInvalidInput, err)
+	case len(body) == 0:
+		return nil, fmt.Errorf("%w: request body is empty", model.ErrInvalidInput)
+	}
+	return body, nil
+}
+
+func pathID(request *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(request.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, model.NewValidationError(name, "must be a positive integer")
+	}
+	return id, nil
+}
+
+// currentUserID returns the user stored by RequireAuth.
+func currentUserID(request *http.Request) (int64, error) {
+	userID, ok := auth.UserIDFromContext(request.Context())
+	if !ok {
+		return 0, model.ErrUnauthorized
+	}
+	return userID, nil
+}
+
+func userAndPathID(request *http.Request, name string) (int64, int64, error) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := pathID(request, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return userID, id, nil
+}
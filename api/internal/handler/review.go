@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type ReviewHandler struct {
+	reviews *service.ReviewService
+	logger  logger.Logger
+}
+
+func NewReviewHandler(reviews *service.ReviewService, log logger.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		reviews: reviews,
+		logger:  log,
+	}
+}
+
+func (handler *ReviewHandler) List(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	reviews, err := handler.reviews.List(request.Context(), userID, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, reviews, page.Limit)
+}
@@ -2,7 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"strings"
 
+	"memwright/api/internal/auth"
+	"memwright/api/internal/health"
+	"memwright/api/internal/ratelimit"
+	"memwright/api/internal/service"
 	"memwright/api/pkg/logger"
 )
 
@@ -10,11 +15,165 @@ import (
 type Dependencies struct {
 	Logger      logger.Logger
 	Environment string
+	Readiness   *health.Checker
+
+	Tokens *auth.TokenManager
+	Auth   *service.AuthService
+	Decks  *service.DeckService
+	Cards  *service.CardService
+	Tags   *service.TagService
+	Search *service.SearchService
+	Trash  *service.TrashService
+
+	Reviews *service.ReviewService
+
+	Backlog *service.BacklogService
+
+	Transfer *service.TransferService
+
+	// AuthLimiter throttles register and login per client IP; APILimiter
+	// throttles all other API calls per user. Nil disables a limiter.
+	AuthLimiter *ratelimit.Limiter
+	APILimiter  *ratelimit.Limiter
+	// TrustProxy makes client IPs come from X-Forwarded-For.
+	TrustProxy bool
 }
 
 // RegisterRoutes registers all API routes on the given mux.
 func RegisterRoutes(mux *http.ServeMux, deps Dependencies) {
 	healthHandler := NewHealthHandler(deps.Environment)
 
+	mux.Handle("/", notFound(mux))
 	mux.Handle("/health", healthHandler)
+	mux.Handle("/health/live", healthHandler)
+	mux.Handle("/health/ready", NewReadinessHandler(deps.Readiness))
+
+	public := RateLimit(deps.APILimiter, ClientIPKey(deps.TrustProxy), deps.Logger)
+
+	if deps.Auth != nil {
+		authHandler := NewAuthHandler(deps.Auth, deps.Logger)
+		bruteForce := RateLimit(deps.AuthLimiter, ClientIPKey(deps.TrustProxy), deps.Logger)
+
+		mux.Handle("POST /api/v1/auth/register", bruteForce(http.HandlerFunc(authHandler.Register)))
+		mux.Handle("POST /api/v1/auth/login", bruteForce(http.HandlerFunc(authHandler.Login)))
+		mux.Handle("POST /api/v1/auth/refresh", public(http.HandlerFunc(authHandler.Refresh)))
+		mux.Handle("POST /api/v1/auth/logout", public(http.HandlerFunc(authHandler.Logout)))
+	}
+
+	if deps.Tokens == nil {
+		return
+	}
+	// Authenticated requests are limited per user. Requests that fail
+	// authentication are limited per IP, so floods with missing or invalid
+	// tokens are throttled without users behind one IP sharing a quota.
+	authenticate := requireAuth(deps.Tokens, public)
+	limit := RateLimit(deps.APILimiter, UserOrIPKey(deps.TrustProxy), deps.Logger)
+	protect := func(next http.Handler) http.Handler {
+		return authenticate(limit(next))
+	}
+
+	if deps.Decks != nil {
+		deckHandler := NewDeckHandler(deps.Decks, deps.Logger)
+
+		mux.Handle("GET /api/v1/decks", protect(http.HandlerFunc(deckHandler.List)))
+		mux.Handle("POST /api/v1/decks", protect(http.HandlerFunc(deckHandler.Create)))
+		mux.Handle("GET /api/v1/decks/{id}", protect(http.HandlerFunc(deckHandler.Get)))
+		mux.Handle("PUT /api/v1/decks/{id}", protect(http.HandlerFunc(deckHandler.Update)))
+		mux.Handle("DELETE /api/v1/decks/{id}", protect(http.HandlerFunc(deckHandler.Delete)))
+		mux.Handle("GET /api/v1/decks/{id}/subdecks", protect(http.HandlerFunc(deckHandler.Subdecks)))
+		mux.Handle("POST /api/v1/decks/{id}/move", protect(http.HandlerFunc(deckHandler.Move)))
+		mux.Handle("POST /api/v1/decks/reorder", protect(http.HandlerFunc(deckHandler.Reorder)))
+		mux.Handle("POST /api/v1/decks/{id}/archive", protect(http.HandlerFunc(deckHandler.Archive)))
+		mux.Handle("POST /api/v1/decks/{id}/unarchive", protect(http.HandlerFunc(deckHandler.Unarchive)))
+	}
+
+	if deps.Cards != nil {
+		cardHandler := NewCardHandler(deps.Cards, deps.Logger)
+
+		mux.Handle("GET /api/v1/decks/{deckId}/cards", protect(http.HandlerFunc(cardHandler.List)))
+		mux.Handle("POST /api/v1/decks/{deckId}/cards", protect(http.HandlerFunc(cardHandler.Create)))
+		mux.Handle("GET /api/v1/cards/{id}", protect(http.HandlerFunc(cardHandler.Get)))
+		mux.Handle("PUT /api/v1/cards/{id}", protect(http.HandlerFunc(cardHandler.Update)))
+		mux.Handle("DELETE /api/v1/cards/{id}", protect(http.HandlerFunc(cardHandler.Delete)))
+		mux.Handle("POST /api/v1/cards/bulk", protect(http.HandlerFunc(cardHandler.Bulk)))
+	}
+
+	if deps.Search != nil {
+		searchHandler := NewSearchHandler(deps.Search, deps.Logger)
+
+		mux.Handle("GET /api/v1/cards/search", protect(http.HandlerFunc(searchHandler.Cards)))
+	}
+
+	if deps.Tags != nil {
+		tagHandler := NewTagHandler(deps.Tags, deps.Logger)
+
+		mux.Handle("GET /api/v1/tags", protect(http.HandlerFunc(tagHandler.List)))
+		mux.Handle("GET /api/v1/tags/cards", protect(http.HandlerFunc(tagHandler.Cards)))
+		mux.Handle("POST /api/v1/tags/rename", protect(http.HandlerFunc(tagHandler.Rename)))
+		mux.Handle("POST /api/v1/tags/merge", protect(http.HandlerFunc(tagHandler.Merge)))
+	}
+
+	if deps.Transfer != nil {
+		transferHandler := NewTransferHandler(deps.Transfer, deps.Logger)
+
+		mux.Handle("POST /api/v1/decks/{id}/export", protect(http.HandlerFunc(transferHandler.Export)))
+		mux.Handle("POST /api/v1/decks/import", protect(http.HandlerFunc(transferHandler.Import)))
+		mux.Handle("POST /api/v1/decks/{id}/import/csv", protect(http.HandlerFunc(transferHandler.ImportCSV)))
+		mux.Handle("POST /api/v1/decks/{id}/export/anki", protect(http.HandlerFunc(transferHandler.ExportAnki)))
+		mux.Handle("POST /api/v1/decks/import/anki", protect(http.HandlerFunc(transferHandler.ImportAnki)))
+		mux.Handle("POST /api/v1/decks/{id}/export/markdown", protect(http.HandlerFunc(transferHandler.ExportMarkdown)))
+		mux.Handle("POST /api/v1/decks/import/markdown", protect(http.HandlerFunc(transferHandler.ImportMarkdown)))
+	}
+
+	if deps.Backlog != nil {
+		backlogHandler := NewBacklogHandler(deps.Backlog, deps.Logger)
+
+		mux.Handle("POST /api/v1/backlog/preview", protect(http.HandlerFunc(backlogHandler.Preview)))
+		mux.Handle("POST /api/v1/backlog/apply", protect(http.HandlerFunc(backlogHandler.Apply)))
+	}
+
+	if deps.Reviews != nil {
+		reviewHandler := NewReviewHandler(deps.Reviews, deps.Logger)
+
+		mux.Handle("GET /api/v1/reviews", protect(http.HandlerFunc(reviewHandler.List)))
+	}
+
+	if deps.Trash != nil {
+		trashHandler := NewTrashHandler(deps.Trash, deps.Logger)
+
+		mux.Handle("GET /api/v1/trash/decks", protect(http.HandlerFunc(trashHandler.Decks)))
+		mux.Handle("GET /api/v1/trash/cards", protect(http.HandlerFunc(trashHandler.Cards)))
+		mux.Handle("POST /api/v1/trash/decks/{id}/restore", protect(http.HandlerFunc(trashHandler.RestoreDeck)))
+		mux.Handle("POST /api/v1/trash/cards/{id}/restore", protect(http.HandlerFunc(trashHandler.RestoreCard)))
+	}
+}
+
+// routeMethods lists the methods notFound tries when looking for a route
+// that serves the path with another method.
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// notFound answers requests that no other route of mux matches with a
+// problem document in place of ServeMux's plain-text errors: 405 with an
+// Allow header when the path has routes for other methods, 404 otherwise.
+func notFound(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			probe := request.Clone(request.Context())
+			probe.Method = method
+			if _, pattern := mux.Handler(probe); pattern != "" && pattern != "/" {
+				allowed = append(allowed, method)
+			}
+		}
+
+		if len(allowed) == 0 {
+			writeStatusError(writer, request, http.StatusNotFound, "not_found", "no route matches "+request.URL.Path)
+			return
+		}
+		writer.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeStatusError(writer, request, http.StatusMethodNotAllowed, "method_not_allowed", request.Method+" is not allowed on "+request.URL.Path)
+	})
 }
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type SearchHandler struct {
+	search *service.SearchService
+	logger logger.Logger
+}
+
+func NewSearchHandler(search *service.SearchService, log logger.Logger) *SearchHandler {
+	return &SearchHandler{
+		search: search,
+		logger: log,
+	}
+}
+
+// Cards searches with the q, deck_id, tag, type and state query parameters.
+// q is in the query language; tag, type and state may be repeated.
+func (handler *SearchHandler) Cards(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	search, err := cardSearch(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	hits, err := handler.search.Search(request.Context(), userID, search, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, hits, page.Limit)
+}
+
+func cardSearch(request *http.Request) (model.CardSearch, error) {
+	query := request.URL.Query()
+	search := model.CardSearch{
+		Query: query.Get("q"),
+		Tags:  query["tag"],
+	}
+	if raw := query.Get("deck_id"); raw != "" {
+		deckID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || deckID <= 0 {
+			return search, model.NewValidationError("deck_id", "must be a positive integer")
+		}
+		search.DeckID = &deckID
+	}
+	for _, cardType := range query["type"] {
+		search.Types = append(search.Types, model.CardType(cardType))
+	}
+	for _, state := range query["state"] {
+		search.States = append(search.States, model.ScheduleState(state))
+	}
+	return search, nil
+}
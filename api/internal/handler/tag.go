@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type TagHandler struct {
+	tags   *service.TagService
+	logger logger.Logger
+}
+
+func NewTagHandler(tags *service.TagService, log logger.Logger) *TagHandler {
+	return &TagHandler{
+		tags:   tags,
+		logger: log,
+	}
+}
+
+func (handler *TagHandler) List(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	tags, err := handler.tags.List(request.Context(), userID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, tags)
+}
+
+// Cards lists the cards in the subtree of the tag query parameter.
+func (handler *TagHandler) Cards(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	cards, err := handler.tags.Cards(request.Context(), userID, request.URL.Query().Get("tag"), page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, cards, page.Limit)
+}
+
+func (handler *TagHandler) Rename(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.RenameTagRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.tags.Rename(request.Context(), userID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+func (handler *TagHandler) Merge(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var input service.MergeTagsRequest
+	if err := decodeJSON(writer, request, &input); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.tags.Merge(request.Context(), userID, input)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
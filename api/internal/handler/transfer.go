@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+// exportWriteTimeout bounds the wait for each write of an export. Every
+// write pushes the connection's deadline forward by it, so an export that
+// keeps making progress outlives the server's WriteTimeout.
+const exportWriteTimeout = 30 * time.Second
+
+// importReadTimeout bounds the wait for each read of an uploaded import in
+// the same way, so a large file on a slow uplink outlives ReadTimeout.
+const importReadTimeout = 30 * time.Second
+
+type TransferHandler struct {
+	transfer *service.TransferService
+	logger   logger.Logger
+}
+
+func NewTransferHandler(transfer *service.TransferService, log logger.Logger) *TransferHandler {
+	return &TransferHandler{
+		transfer: transfer,
+		logger:   log,
+	}
+}
+
+// Export streams a deck subtree as a native JSON document. The body with
+// the export options may be left out.
+func (handler *TransferHandler) Export(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	var options service.ExportOptions
+	if request.ContentLength != 0 {
+		if err := decodeJSON(writer, request, &options); err != nil {
+			writeError(writer, request, handler.logger, err)
+			return
+		}
+	}
+
+	export, err := handler.transfer.Export(request.Context(), userID, deckID, options)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Content-Disposition", `attachment; filename="deck-`+strconv.FormatInt(deckID, 10)+`.json"`)
+	writer.WriteHeader(http.StatusOK)
+	if err := export.Encode(request.Context(), newDeadlineWriter(writer)); err != nil {
+		// The status is out already; the client sees a truncated document.
+		handler.logger.Error("export failed request_id=%s deck_id=%d: %v", requestID(request), deckID, err)
+	}
+}
+
+// Import reads a native JSON document. The query parameters parent_id and
+// on_conflict set the import options.
+func (handler *TransferHandler) Import(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	options, err := importOptions(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	var document model.ExportDocument
+	if err := decodeJSONLimit(writer, request, &document, maxImportBodyBytes); err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.transfer.Import(request.Context(), userID, &document, options)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+// ExportAnki writes a deck subtree as Anki "Notes in Plain Text".
+func (handler *TransferHandler) ExportAnki(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	export, err := handler.transfer.Export(request.Context(), userID, deckID, service.ExportOptions{})
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="deck-`+strconv.FormatInt(deckID, 10)+`.txt"`)
+	writer.WriteHeader(http.StatusOK)
+	if err := export.EncodeAnki(request.Context(), newDeadlineWriter(writer)); err != nil {
+		handler.logger.Error("anki export failed request_id=%s deck_id=%d: %v", requestID(request), deckID, err)
+	}
+}
+
+// ImportAnki reads Anki "Notes in Plain Text". The query parameters
+// parent_id, on_conflict and deck set the import options.
+func (handler *TransferHandler) ImportAnki(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	options, err := importOptions(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	data, err := readBodyLimit(writer, request, maxImportBodyBytes)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.transfer.ImportAnki(request.Context(), userID, data, service.AnkiImportOptions{ImportOptions: options, Deck: request.URL.Query().Get("deck")})
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+// ExportMarkdown writes a deck subtree as a Markdown deck file.
+func (handler *TransferHandler) ExportMarkdown(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	export, err := handler.transfer.Export(request.Context(), userID, deckID, service.ExportOptions{})
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="deck-`+strconv.FormatInt(deckID, 10)+`.md"`)
+	writer.WriteHeader(http.StatusOK)
+	if err := export.EncodeMarkdown(request.Context(), newDeadlineWriter(writer)); err != nil {
+		handler.logger.Error("markdown export failed request_id=%s deck_id=%d: %v", requestID(request), deckID, err)
+	}
+}
+
+// ImportMarkdown reads a Markdown deck file. The query parameter parent_id
+// places its top-level decks below a deck.
+func (handler *TransferHandler) ImportMarkdown(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	parentID, err := parentIDQuery(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	data, err := readBodyLimit(writer, request, maxImportBodyBytes)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.transfer.ImportMarkdown(request.Context(), userID, data, service.MarkdownImportOptions{ParentID: parentID})
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+// ImportCSV reads a CSV or TSV file into a deck. The query parameters
+// delimiter, header, columns, key and preview set the import options.
+func (handler *TransferHandler) ImportCSV(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	options, err := csvImportOptions(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	data, err := readBodyLimit(writer, request, maxImportBodyBytes)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	result, err := handler.transfer.ImportCSV(request.Context(), userID, deckID, data, options)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+func importOptions(request *http.Request) (service.ImportOptions, error) {
+	parentID, err := parentIDQuery(request)
+	return service.ImportOptions{ParentID: parentID, OnConflict: service.ConflictMode(request.URL.Query().Get("on_conflict"))}, err
+}
+
+func parentIDQuery(request *http.Request) (*int64, error) {
+	raw := request.URL.Query().Get("parent_id")
+	if raw == "" {
+		return nil, nil
+	}
+	parentID, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || parentID <= 0 {
+		return nil, model.NewValidationError("parent_id", "must be a positive integer")
+	}
+	return &parentID, nil
+}
+
+func csvImportOptions(request *http.Request) (service.CSVImportOptions, error) {
+	query := request.URL.Query()
+	options := service.CSVImportOptions{Key: strings.ToLower(strings.TrimSpace(query.Get("key")))}
+	switch raw := query.Get("delimiter"); raw {
+	case "":
+	case "tab", `\t`:
+		options.Delimiter = '\t'
+	default:
+		delimiter, size := utf8.DecodeRuneInString(raw)
+		if size != len(raw) || delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+			return options, model.NewValidationError("delimiter", "must be a single character or tab")
+		}
+		options.Delimiter = delimiter
+	}
+	if raw := query.Get("header"); raw != "" {
+		header, err := strconv.ParseBool(raw)
+		if err != nil {
+			return options, model.NewValidationError("header", "must be true or false")
+		}
+		options.Header = &header
+	}
+	if query.Has("columns") {
+		options.Columns = strings.Split(query.Get("columns"), ",")
+	}
+	if raw := query.Get("preview"); raw != "" {
+		preview, err := strconv.Atoi(raw)
+		if err != nil || preview <= 0 {
+			return options, model.NewValidationError("preview", "must be a positive integer")
+		}
+		options.Preview = preview
+	}
+	return options, nil
+}
+
+// deadlineWriter extends the write deadline of a response before each write.
+type deadlineWriter struct {
+	writer     http.ResponseWriter
+	controller *http.ResponseController
+}
+
+func newDeadlineWriter(writer http.ResponseWriter) *deadlineWriter {
+	return &deadlineWriter{writer: writer, controller: http.NewResponseController(writer)}
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	// Writers without a connection, such as test recorders, have no
+	// deadline to extend.
+	_ = w.controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	return w.writer.Write(p)
+}
+
+// deadlineReader extends the read deadline of a request before each read of
+// its body. The write deadline, which the server starts counting when the
+// request arrives, moves along so the response can still be sent.
+type deadlineReader struct {
+	body       io.ReadCloser
+	controller *http.ResponseController
+}
+
+func newDeadlineReader(writer http.ResponseWriter, body io.ReadCloser) *deadlineReader {
+	return &deadlineReader{body: body, controller: http.NewResponseController(writer)}
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	// As with deadlineWriter, test recorders have no deadline to extend.
+	deadline := time.Now().Add(importReadTimeout)
+	_ = r.controller.SetReadDeadline(deadline)
+	_ = r.controller.SetWriteDeadline(deadline)
+	return r.body.Read(p)
+}
+
+func (r *deadlineReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type TrashHandler struct {
+	trash  *service.TrashService
+	logger logger.Logger
+}
+
+func NewTrashHandler(trash *service.TrashService, log logger.Logger) *TrashHandler {
+	return &TrashHandler{
+		trash:  trash,
+		logger: log,
+	}
+}
+
+func (handler *TrashHandler) Decks(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	decks, err := handler.trash.Decks(request.Context(), userID, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, decks, page.Limit)
+}
+
+func (handler *TrashHandler) Cards(writer http.ResponseWriter, request *http.Request) {
+	userID, err := currentUserID(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	page, err := pageRequest(request)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	cards, err := handler.trash.Cards(request.Context(), userID, page)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	writePage(writer, request, cards, page.Limit)
+}
+
+func (handler *TrashHandler) RestoreDeck(writer http.ResponseWriter, request *http.Request) {
+	userID, deckID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	deck, err := handler.trash.RestoreDeck(request.Context(), userID, deckID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, deck.Version)
+	writeJSON(writer, http.StatusOK, deck)
+}
+
+func (handler *TrashHandler) RestoreCard(writer http.ResponseWriter, request *http.Request) {
+	userID, cardID, err := userAndPathID(request, "id")
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+
+	card, err := handler.trash.RestoreCard(request.Context(), userID, cardID)
+	if err != nil {
+		writeError(writer, request, handler.logger, err)
+		return
+	}
+	setETag(writer, card.Version)
+	writeJSON(writer, http.StatusOK, card)
+}
@@ -0,0 +1,122 @@
+// Package health runs readiness checks against the API's dependencies.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"memwright/api/internal/repository"
+)
+
+type Status string
+
+const (
+	StatusOK          Status = "ok"
+	StatusUnavailable Status = "unavailable"
+)
+
+// Check probes one dependency. Run must honour the context deadline.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Result struct {
+	Status    Status  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs all checks concurrently, each bounded by the timeout.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run executes the checks. The report is ok only if every check passed.
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := Result{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Database checks that the database accepts connections.
+func Database(db Pinger) Check {
+	return Check{
+		Name: "database",
+		Run:  db.PingContext,
+	}
+}
+
+// Migrations checks that the schema is at the version the binary was built
+// for and that no migration was left half-applied.
+func Migrations(schema repository.SchemaRepository, expected int64) Check {
+	return Check{
+		Name: "migrations",
+		Run: func(ctx context.Context) error {
+			version, dirty, err := schema.Version(ctx)
+			if err != nil {
+				return err
+			}
+			if dirty {
+				return fmt.Errorf("migration %d is dirty", version)
+			}
+			if version != expected {
+				return fmt.Errorf("schema version %d, expected %d", version, expected)
+			}
+			return nil
+		},
+	}
+}
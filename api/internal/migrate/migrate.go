@@ -0,0 +1,309 @@
+// Package migrate applies the SQL schema migrations in order and records
+// the current version in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"memwright/api/pkg/logger"
+)
+
+// lockID identifies the advisory lock held while migrating, so concurrent
+// instances started at the same time apply each migration only once.
+const lockID int64 = 7_271_956_245
+
+var (
+	ErrDirty = errors.New("database is dirty")
+
+	fileNameExpr = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	nameExpr     = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads NNN_name.up.sql / NNN_name.down.sql pairs from fsys and
+// returns them ordered by version. Every version needs both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNameExpr.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %q: expected NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %q: invalid version %q", entry.Name(), match[1])
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, migration.Name, match[2])
+		}
+
+		target := &migration.Up
+		if match[3] == "down" {
+			target = &migration.Down
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("migration %q: duplicate %s file", entry.Name(), match[3])
+		}
+		*target = string(body)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("migration %d_%s: needs non-empty up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Create writes an empty up/down pair numbered after the highest version
+// in dir and returns the paths of the new files.
+func Create(dir, name string) (string, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !nameExpr.MatchString(name) {
+		return "", "", fmt.Errorf("migration name %q: use lower-case letters, digits and underscores", name)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", err
+	}
+	var latest int64
+	for _, entry := range entries {
+		if match := fileNameExpr.FindStringSubmatch(entry.Name()); match != nil {
+			if version, _ := strconv.ParseInt(match[1], 10, 64); version > latest {
+				latest = version
+			}
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", latest+1, name))
+	up, down := base+".up.sql", base+".down.sql"
+	for _, path := range []string{up, down} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		if err := file.Close(); err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
+
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+	log        logger.Logger
+}
+
+func NewRunner(db *sql.DB, fsys fs.FS, log logger.Logger) (*Runner, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations, log: log}, nil
+}
+
+// Latest returns the highest known migration version.
+func (r *Runner) Latest() int64 {
+	if len(r.migrations) == 0 {
+		return 0
+	}
+	return r.migrations[len(r.migrations)-1].Version
+}
+
+// Up applies all pending migrations and returns how many ran. Each
+// migration and its version bump commit in a single transaction.
+func (r *Runner) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := r.locked(ctx, func(conn *sql.Conn, current int64) error {
+		for _, migration := range r.migrations {
+			if migration.Version <= current {
+				continue
+			}
+			if err := r.apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			r.log.Info("applied migration version=%d name=%s", migration.Version, migration.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the given number of applied migrations, newest first.
+func (r *Runner) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	reverted := 0
+	err := r.locked(ctx, func(conn *sql.Conn, current int64) error {
+		for i := len(r.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := r.migrations[i]
+			if migration.Version > current {
+				continue
+			}
+			var previous int64
+			if i > 0 {
+				previous = r.migrations[i-1].Version
+			}
+			if err := r.apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			r.log.Info("reverted migration version=%d name=%s", migration.Version, migration.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Version reports the recorded schema version; 0 means nothing is applied.
+func (r *Runner) Version(ctx context.Context) (int64, bool, error) {
+	if err := r.ensureTable(ctx, r.db); err != nil {
+		return 0, false, err
+	}
+	return readVersion(ctx, r.db)
+}
+
+// Force records version as applied and clean without running any SQL, for
+// a schema repaired or migrated by hand. The runner itself never leaves a
+// version dirty, since a failed migration rolls back with its transaction;
+// a dirty flag comes from another tool that shares schema_migrations.
+func (r *Runner) Force(ctx context.Context, version int64) error {
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		if err := r.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		return r.apply(ctx, conn, "", version)
+	})
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *Runner) ensureTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)`)
+	return err
+}
+
+func readVersion(ctx context.Context, db queryer) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations ORDER BY version DESC LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// locked runs fn under the advisory lock with the current, clean version.
+// It refuses to run on a version another tool left dirty.
+func (r *Runner) locked(ctx context.Context, fn func(conn *sql.Conn, current int64) error) error {
+	return r.withLock(ctx, func(conn *sql.Conn) error {
+		if err := r.ensureTable(ctx, conn); err != nil {
+			return err
+		}
+		current, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w at version %d: fix the schema by hand, then run force", ErrDirty, current)
+		}
+		return fn(conn, current)
+	})
+}
+
+// withLock holds a session-level advisory lock on a dedicated connection,
+// since the pool could otherwise hand the unlock to a different session.
+func (r *Runner) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			r.log.Error("failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn(conn)
+}
+
+// apply runs statements and records version, always clean, in one
+// transaction. Version 0 clears the table.
+func (r *Runner) apply(ctx context.Context, conn *sql.Conn, statements string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if strings.TrimSpace(statements) != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
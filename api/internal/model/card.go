@@ -13,6 +13,14 @@ const (
 	CardTypeReverse        CardType = "reverse"
 )
 
+func (t CardType) IsValid() bool {
+	switch t {
+	case CardTypeBasic, CardTypeCloze, CardTypeMCQ, CardTypeImageOcclusion, CardTypeAudio, CardTypeReverse:
+		return true
+	}
+	return false
+}
+
 type Card struct {
 	ID        int64     `json:"id" db:"id"`
 	DeckID    int64     `json:"deck_id" db:"deck_id"`
@@ -22,6 +30,47 @@ type Card struct {
 	Extra     string    `json:"extra,omitempty" db:"extra"`
 	Tags      []string  `json:"tags,omitempty" db:"tags"`
 	Position  int       `json:"position" db:"position"`
+	Suspended bool      `json:"suspended" db:"suspended"`
+	Version   int       `json:"version" db:"version"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// DeletedAt is set while the card is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// AddTags appends the tags the card does not have yet.
+func (c *Card) AddTags(tags ...string) {
+	c.Tags = NormalizeTags(append(c.Tags, tags...))
+}
+
+// RemoveTags drops the given tags from the card.
+func (c *Card) RemoveTags(tags ...string) {
+	remove := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		remove[NormalizeTag(tag)] = true
+	}
+
+	kept := c.Tags[:0]
+	for _, tag := range c.Tags {
+		if !remove[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	c.Tags = NormalizeTags(kept)
+}
+
+// NormalizeTags normalizes tags and removes blanks and duplicates, keeping
+// the original order.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var normalized []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
 }
@@ -12,6 +12,14 @@ const (
 	ScheduleStateMastered   ScheduleState = "mastered"
 )
 
+func (s ScheduleState) IsValid() bool {
+	switch s {
+	case ScheduleStateNew, ScheduleStateLearning, ScheduleStateReview, ScheduleStateRelearning, ScheduleStateMastered:
+		return true
+	}
+	return false
+}
+
 type CardSchedule struct {
 	ID             int64         `json:"id" db:"id"`
 	CardID         int64         `json:"card_id" db:"card_id"`
@@ -28,3 +36,11 @@ type CardSchedule struct {
 }
 
 const DefaultEaseFactor = 2.5
+
+// DueDateMove moves a schedule from the due date it was planned from to a
+// new one.
+type DueDateMove struct {
+	ScheduleID int64
+	From       time.Time
+	To         time.Time
+}
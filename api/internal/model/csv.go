@@ -0,0 +1,52 @@
+package model
+
+// CSV columns map to these card fields.
+const (
+	CSVColumnFront = "front"
+	CSVColumnBack  = "back"
+	CSVColumnExtra = "extra"
+	CSVColumnTags  = "tags"
+	CSVColumnType  = "type"
+)
+
+type CSVRowStatus string
+
+const (
+	CSVRowCreated   CSVRowStatus = "created"
+	CSVRowUpdated   CSVRowStatus = "updated"
+	CSVRowUnchanged CSVRowStatus = "unchanged"
+	CSVRowFailed    CSVRowStatus = "failed"
+)
+
+// CSVImportResult reports a spreadsheet import, or what it would do when
+// Preview is set. Delimiter, Header and Columns tell how the file was read.
+type CSVImportResult struct {
+	Preview   bool     `json:"preview"`
+	Delimiter string   `json:"delimiter"`
+	Header    []string `json:"header,omitempty"`
+	// Columns holds the card field of each column, "" for ignored ones.
+	Columns   []string `json:"columns"`
+	Created   int      `json:"created"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Failed    int      `json:"failed"`
+	// Rows lists every row read by a preview, and only the failed rows
+	// otherwise.
+	Rows []CSVRow `json:"rows"`
+}
+
+// CSVRow is the outcome of one row. Line is where the row starts in the
+// file, counting from 1; a quoted field can make a row span several lines.
+type CSVRow struct {
+	Line   int          `json:"line"`
+	Status CSVRowStatus `json:"status"`
+	// CardID is the card the row updated, or created outside a preview.
+	CardID int64        `json:"card_id,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+	// The card as the row leaves it, in previews only.
+	Type  CardType `json:"type,omitempty"`
+	Front string   `json:"front,omitempty"`
+	Back  string   `json:"back,omitempty"`
+	Extra string   `json:"extra,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"memwright/api/internal/srs"
@@ -14,10 +15,17 @@ type Deck struct {
 	Name        string     `json:"name" db:"name"`
 	Description string     `json:"description" db:"description"`
 	Algorithm   string     `json:"algorithm" db:"algorithm"`
+	Language    string     `json:"language" db:"language"`
 	SRSConfig   *SRSConfig `json:"srs_config" db:"srs_config"`
 	Position    int        `json:"position" db:"position"`
+	Version     int        `json:"version" db:"version"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	// ArchivedAt is set on archived decks, which are left out of study
+	// queues with their subdecks.
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	// DeletedAt is set while the deck is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 type SRSConfig struct {
@@ -53,3 +61,102 @@ const (
 	AlgorithmSM2  = "sm2"
 	AlgorithmFSRS = "fsrs"
 )
+
+const MaxDeckNameLength = 255
+
+// DefaultLanguage indexes words as they are, without stemming or stop words.
+const DefaultLanguage = "simple"
+
+// Languages are the text search configurations PostgreSQL ships with. A
+// deck's language decides how its cards are stemmed for search.
+var Languages = []string{
+	DefaultLanguage, "arabic", "armenian", "basque", "catalan", "danish", "dutch", "english",
+	"finnish", "french", "german", "greek", "hindi", "hungarian", "indonesian", "irish",
+	"italian", "lithuanian", "nepali", "norwegian", "portuguese", "romanian", "russian",
+	"serbian", "spanish", "swedish", "tamil", "turkish", "yiddish",
+}
+
+func ValidLanguage(language string) bool {
+	for _, known := range Languages {
+		if language == known {
+			return true
+		}
+	}
+	return false
+}
+
+// DeckCounts summarizes the study queue of a single deck.
+type DeckCounts struct {
+	New      int `json:"new"`
+	Learning int `json:"learning"`
+	Due      int `json:"due"`
+}
+
+// DeckNode is a deck with its queue counts and subdecks, as rendered in the
+// deck tree. Counts covers the deck's own cards, Totals its whole subtree.
+type DeckNode struct {
+	*Deck
+	Counts   DeckCounts  `json:"counts"`
+	Totals   DeckCounts  `json:"totals"`
+	Children []*DeckNode `json:"children"`
+}
+
+// DefaultSRSConfig mirrors the column default of decks.srs_config.
+func DefaultSRSConfig() *SRSConfig {
+	return &SRSConfig{
+		SM2: &srs.SM2Config{
+			InitialEaseFactor:  DefaultEaseFactor,
+			MinEaseFactor:      1.3,
+			MaxEaseFactor:      3.0,
+			EaseDecrement:      0.2,
+			EaseIncrement:      0.15,
+			EasyBonusMultipler: 1.3,
+			GraduatingInterval: 1,
+			MasteredThreshold:  21,
+		},
+	}
+}
+
+// Validate checks the configuration of the given algorithm.
+func (c *SRSConfig) Validate(algorithm string) error {
+	switch algorithm {
+	case AlgorithmSM2:
+		if c == nil || c.SM2 == nil {
+			return NewValidationError("srs_config.sm2", "is required for the sm2 algorithm")
+		}
+		return validateSM2Config(c.SM2)
+	case AlgorithmFSRS:
+		return nil
+	default:
+		return NewValidationError("algorithm", fmt.Sprintf("must be %q or %q", AlgorithmSM2, AlgorithmFSRS))
+	}
+}
+
+func validateSM2Config(cfg *srs.SM2Config) error {
+	errs := &ValidationError{}
+	if cfg.MinEaseFactor < 1.0 {
+		errs.Add("srs_config.sm2.min_ease_factor", "must be at least 1.0")
+	}
+	if cfg.MaxEaseFactor < cfg.MinEaseFactor {
+		errs.Add("srs_config.sm2.max_ease_factor", "must not be below min_ease_factor")
+	}
+	if cfg.InitialEaseFactor < cfg.MinEaseFactor || cfg.InitialEaseFactor > cfg.MaxEaseFactor {
+		errs.Add("srs_config.sm2.initial_ease_factor", "must be between min_ease_factor and max_ease_factor")
+	}
+	if cfg.EaseDecrement < 0 {
+		errs.Add("srs_config.sm2.ease_decrement", "must not be negative")
+	}
+	if cfg.EaseIncrement < 0 {
+		errs.Add("srs_config.sm2.ease_increment", "must not be negative")
+	}
+	if cfg.EasyBonusMultipler < 1.0 {
+		errs.Add("srs_config.sm2.easy_bonus_multiplier", "must be at least 1.0")
+	}
+	if cfg.GraduatingInterval < 1 {
+		errs.Add("srs_config.sm2.graduating_interval", "must be at least 1 day")
+	}
+	if cfg.MasteredThreshold < cfg.GraduatingInterval {
+		errs.Add("srs_config.sm2.mastered_threshold", "must not be below graduating_interval")
+	}
+	return errs.OrNil()
+}
@@ -3,11 +3,13 @@ package model
 import "errors"
 
 var (
-	ErrNotFound     = errors.New("resource not found")
-	ErrDuplicateKey = errors.New("duplicate key")
+	ErrNotFound       = errors.New("resource not found")
+	ErrDuplicateKey   = errors.New("duplicate key")
 	ErrDuplicateEmail = errors.New("email already exists")
 	ErrDuplicateName  = errors.New("name already exists")
 	ErrInvalidInput   = errors.New("invalid input")
 	ErrUnauthorized   = errors.New("unauthorized")
 	ErrForbidden      = errors.New("forbidden")
+	ErrConflict       = errors.New("resource was modified by another request")
+	ErrDeckCycle      = errors.New("a deck cannot be moved into itself or one of its subdecks")
 )
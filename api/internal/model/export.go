@@ -0,0 +1,99 @@
+package model
+
+import "time"
+
+// ExportFormat and ExportVersion identify the native JSON export. The
+// version changes whenever a document of the old version would no longer
+// import as it did.
+const (
+	ExportFormat  = "memwright"
+	ExportVersion = 1
+)
+
+// ExportDocument is a deck subtree in the native format. IDs are those of
+// the exporting database and only serve to link the items of one document;
+// an import assigns new ones. Parents come before their subdecks.
+type ExportDocument struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Decks      []ExportedDeck `json:"decks"`
+	Cards      []ExportedCard `json:"cards"`
+}
+
+type ExportedDeck struct {
+	ID int64 `json:"id"`
+	// ParentID is nil for the exported deck itself.
+	ParentID    *int64     `json:"parent_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Algorithm   string     `json:"algorithm"`
+	Language    string     `json:"language,omitempty"`
+	SRSConfig   *SRSConfig `json:"srs_config,omitempty"`
+	Position    int        `json:"position"`
+	Archived    bool       `json:"archived,omitempty"`
+}
+
+type ExportedCard struct {
+	ID        int64    `json:"id"`
+	DeckID    int64    `json:"deck_id"`
+	Type      CardType `json:"type"`
+	Front     string   `json:"front"`
+	Back      string   `json:"back"`
+	Extra     string   `json:"extra,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Position  int      `json:"position"`
+	Suspended bool     `json:"suspended,omitempty"`
+	// Schedule is the exporting user's progress on the card, when asked for.
+	Schedule *ExportedSchedule `json:"schedule,omitempty"`
+}
+
+type ExportedSchedule struct {
+	State          ScheduleState    `json:"state"`
+	DueAt          time.Time        `json:"due_at"`
+	Interval       int              `json:"interval"`
+	EaseFactor     float64          `json:"ease_factor"`
+	ReviewCount    int              `json:"review_count"`
+	LapseCount     int              `json:"lapse_count"`
+	LastReviewedAt *time.Time       `json:"last_reviewed_at,omitempty"`
+	Reviews        []ExportedReview `json:"reviews,omitempty"`
+}
+
+type ExportedReview struct {
+	Rating           ReviewRating  `json:"rating"`
+	PreviousState    ScheduleState `json:"previous_state"`
+	NewState         ScheduleState `json:"new_state"`
+	PreviousEase     float64       `json:"previous_ease"`
+	NewEase          float64       `json:"new_ease"`
+	PreviousInterval int           `json:"previous_interval"`
+	NewInterval      int           `json:"new_interval"`
+	ReviewDuration   int           `json:"review_duration"`
+	ReviewedAt       time.Time     `json:"reviewed_at"`
+}
+
+// ImportResult reports what an import changed. DeckIDs maps each deck ID of
+// the document to the deck it was imported into.
+type ImportResult struct {
+	DecksCreated int              `json:"decks_created"`
+	DecksMerged  int              `json:"decks_merged"`
+	CardsCreated int              `json:"cards_created"`
+	CardsUpdated int              `json:"cards_updated"`
+	CardsSkipped int              `json:"cards_skipped"`
+	Schedules    int              `json:"schedules"`
+	Reviews      int              `json:"reviews"`
+	DeckIDs      map[int64]int64  `json:"deck_ids"`
+	Conflicts    []ImportConflict `json:"conflicts"`
+}
+
+// ImportConflict is an item of the document that matched an existing deck
+// or card but differs from it in Fields.
+type ImportConflict struct {
+	// Kind is "deck" or "card".
+	Kind       string   `json:"kind"`
+	SourceID   int64    `json:"source_id"`
+	ExistingID int64    `json:"existing_id"`
+	Fields     []string `json:"fields"`
+	// Resolution is "kept" when the existing item was left as it was and
+	// "overwritten" when the document's version replaced it.
+	Resolution string `json:"resolution"`
+}
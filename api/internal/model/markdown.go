@@ -0,0 +1,30 @@
+package model
+
+type MarkdownStatus string
+
+const (
+	MarkdownCreated   MarkdownStatus = "created"
+	MarkdownUpdated   MarkdownStatus = "updated"
+	MarkdownUnchanged MarkdownStatus = "unchanged"
+)
+
+// MarkdownImportResult reports a Markdown import with the outcome of every
+// heading and card of the file.
+type MarkdownImportResult struct {
+	DecksCreated   int            `json:"decks_created"`
+	DecksUpdated   int            `json:"decks_updated"`
+	DecksUnchanged int            `json:"decks_unchanged"`
+	CardsCreated   int            `json:"cards_created"`
+	CardsUpdated   int            `json:"cards_updated"`
+	CardsUnchanged int            `json:"cards_unchanged"`
+	Decks          []MarkdownItem `json:"decks"`
+	Cards          []MarkdownItem `json:"cards"`
+}
+
+// MarkdownItem is the deck or card that the heading or card starting at
+// Line became.
+type MarkdownItem struct {
+	Line   int            `json:"line"`
+	ID     int64          `json:"id"`
+	Status MarkdownStatus `json:"status"`
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// PageRequest asks for the items after Cursor. A zero Limit returns all
+// remaining items.
+type PageRequest struct {
+	Cursor string
+	Limit  int
+}
+
+// Page is one slice of a keyset-paginated list. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Cursor is the keyset position of the last item of a page. Only the fields
+// of the list's sort key are set. Clients see it as an opaque string.
+type Cursor struct {
+	Position   int        `json:"p,omitempty"`
+	Name       string     `json:"n,omitempty"`
+	ReviewedAt *time.Time `json:"t,omitempty"`
+	DeletedAt  *time.Time `json:"d,omitempty"`
+	Rank       *float64   `json:"r,omitempty"`
+	ID         int64      `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor from PageRequest.Cursor. An empty string
+// yields nil, meaning the first page.
+func DecodeCursor(encoded string) (*Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, NewValidationError("cursor", "is malformed")
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, NewValidationError("cursor", "is malformed")
+	}
+	return &cursor, nil
+}
+
+// NewPage builds a page from up to limit+1 items, the extra item signalling
+// that another page follows.
+func NewPage[T any](items []T, limit int, cursor func(T) Cursor) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if limit <= 0 || len(items) <= limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:limit]
+	return Page[T]{Items: items, NextCursor: cursor(items[limit-1]).Encode()}
+}
+
+func CardCursor(card *Card) Cursor {
+	return Cursor{Position: card.Position, ID: card.ID}
+}
+
+func DeckCursor(deck *Deck) Cursor {
+	return Cursor{Position: deck.Position, Name: deck.Name, ID: deck.ID}
+}
+
+func ReviewLogCursor(log *ReviewLog) Cursor {
+	reviewedAt := log.ReviewedAt
+	return Cursor{ReviewedAt: &reviewedAt, ID: log.ID}
+}
+
+// CardIDCursor positions lists of cards from several decks, which are
+// ordered by ID alone.
+func CardIDCursor(card *Card) Cursor {
+	return Cursor{ID: card.ID}
+}
+
+func SearchHitCursor(hit *CardSearchHit) Cursor {
+	rank := hit.Rank
+	return Cursor{Rank: &rank, ID: hit.Card.ID}
+}
+
+// DeletedDeckCursor and DeletedCardCursor position the trash, which is
+// ordered by deletion time and ID, newest first.
+func DeletedDeckCursor(deck *Deck) Cursor {
+	return Cursor{DeletedAt: deck.DeletedAt, ID: deck.ID}
+}
+
+func DeletedCardCursor(card *Card) Cursor {
+	return Cursor{DeletedAt: card.DeletedAt, ID: card.ID}
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"user_id" db:"user_id"`
+	TokenHash    string     `json:"-" db:"token_hash"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedByID *int64     `json:"replaced_by_id,omitempty" db:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
+
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
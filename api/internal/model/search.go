@@ -0,0 +1,31 @@
+package model
+
+// CardSearch selects the cards of one user. Query is in the search
+// language of package query: words, "quoted phrases", fields such as
+// deck:, tag: and is:, -negation, or and (groups). The filters narrow the
+// result; empty filters match everything.
+type CardSearch struct {
+	Query string
+	// DeckID includes the deck's subdecks.
+	DeckID *int64
+	// Tags must all match; each covers its subtree.
+	Tags  []string
+	Types []CardType
+	// States match the user's schedule; cards never studied are new.
+	States []ScheduleState
+}
+
+// CardSearchHit is one search result, ordered by descending rank. The
+// snippets show front and back with matched words wrapped in <mark> tags;
+// they are empty when the search has no query.
+type CardSearchHit struct {
+	Card         *Card   `json:"card"`
+	Rank         float64 `json:"rank"`
+	FrontSnippet string  `json:"front_snippet,omitempty"`
+	BackSnippet  string  `json:"back_snippet,omitempty"`
+}
+
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
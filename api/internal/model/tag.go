@@ -0,0 +1,85 @@
+package model
+
+import "strings"
+
+// TagSeparator splits hierarchical tags: "lang::spanish::verbs" lies below
+// "lang::spanish", which lies below "lang".
+const TagSeparator = "::"
+
+// TagCount reports how many cards carry a tag. Cards counts the tag itself,
+// Total also counts cards tagged anywhere below it. Ancestors that no card
+// carries directly are listed with Cards = 0.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Cards int    `json:"cards"`
+	Total int    `json:"total"`
+}
+
+// NormalizeTag trims the tag and each of its segments, so " lang :: es "
+// becomes "lang::es".
+func NormalizeTag(tag string) string {
+	segments := strings.Split(strings.TrimSpace(tag), TagSeparator)
+	for i, segment := range segments {
+		segments[i] = strings.TrimSpace(segment)
+	}
+	return strings.Join(segments, TagSeparator)
+}
+
+// ValidTag reports whether a normalized tag is non-empty and has no empty
+// segments, as in "lang::" or "a::::b".
+func ValidTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, segment := range strings.Split(tag, TagSeparator) {
+		if segment == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// TagInSubtree reports whether tag is root or lies below it.
+func TagInSubtree(tag, root string) bool {
+	return tag == root || strings.HasPrefix(tag, root+TagSeparator)
+}
+
+// TagPaths returns every tag together with all of its ancestors, without
+// duplicates, in order of first appearance.
+func TagPaths(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var paths []string
+	for _, tag := range tags {
+		for end := 0; end < len(tag); {
+			next := strings.Index(tag[end:], TagSeparator)
+			if next < 0 {
+				end = len(tag)
+			} else {
+				end += next
+			}
+			if path := tag[:end]; !seen[path] {
+				seen[path] = true
+				paths = append(paths, path)
+			}
+			end += len(TagSeparator)
+		}
+	}
+	return paths
+}
+
+// ReplaceTagPrefix moves tag from below the longest matching source to the
+// same place below target, so replacing "lang" by "languages" turns
+// "lang::es" into "languages::es". Tags outside every source are returned
+// unchanged with false.
+func ReplaceTagPrefix(tag string, sources []string, target string) (string, bool) {
+	match := ""
+	for _, source := range sources {
+		if TagInSubtree(tag, source) && len(source) > len(match) {
+			match = source
+		}
+	}
+	if match == "" {
+		return tag, false
+	}
+	return target + tag[len(match):], true
+}
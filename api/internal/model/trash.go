@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// TrashedDeck is a deck in the trash. Restoring it also brings back the
+// subdecks and cards deleted along with it.
+type TrashedDeck struct {
+	*Deck
+	// PurgeAt is when the deck will be deleted for good.
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashedCard is a card deleted on its own whose deck is still there.
+type TrashedCard struct {
+	*Card
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// PurgeResult counts the decks and cards a purge deleted for good. Decks
+// count once however many subdecks and cards went with them.
+type PurgeResult struct {
+	Decks int64
+	Cards int64
+}
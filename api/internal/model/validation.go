@@ -0,0 +1,50 @@
+package model
+
+import "strings"
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	// Position locates the problem within the field's value, in characters
+	// from 0, when the value has a syntax of its own.
+	Position *int `json:"position,omitempty"`
+}
+
+// ValidationError collects field-level problems with an input. It matches
+// ErrInvalidInput with errors.Is.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+func (e *ValidationError) AddAt(field string, position int, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message, Position: &position})
+}
+
+// OrNil returns nil when no field error was added, so callers can collect
+// problems and return the result unconditionally.
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return ErrInvalidInput.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
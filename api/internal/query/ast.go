@@ -0,0 +1,281 @@
+// Package query implements the card search language: Anki-like queries
+// such as `deck:Spanish tag:verbs is:due -is:suspended prop:lapses>3
+// added:7 "exact phrase"` are parsed into an AST, which is compiled to SQL
+// for Postgres or matched directly against cards in memory.
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"memwright/api/internal/model"
+)
+
+// Node is an expression of the AST. A nil Node matches every card.
+type Node interface {
+	String() string
+	node()
+}
+
+// And matches cards matching all of its nodes.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches cards matching any of its nodes.
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches cards not matching its node.
+type Not struct {
+	Node Node
+}
+
+// Text is a full-text word or, when Phrase is set, a sequence of words
+// that must occur together.
+type Text struct {
+	Text   string
+	Phrase bool
+}
+
+// Deck matches the deck's full name, "Parent::Child", and its subdecks.
+// Pattern is case-insensitive and * matches any run of characters.
+type Deck struct {
+	Pattern string
+}
+
+// DeckID matches the deck and its subdecks.
+type DeckID struct {
+	ID int64
+}
+
+// Tag matches a tag and its subtags, like Deck.
+type Tag struct {
+	Pattern string
+}
+
+// Untagged matches cards without tags.
+type Untagged struct{}
+
+// State matches the user's schedule state; cards never studied are new.
+type State struct {
+	State model.ScheduleState
+}
+
+// Due matches studied cards due now.
+type Due struct{}
+
+// Suspended matches suspended cards.
+type Suspended struct{}
+
+// Type matches the card type.
+type Type struct {
+	Type model.CardType
+}
+
+// Prop compares a schedule property with a number. Cards never studied
+// have no properties and match no comparison.
+type Prop struct {
+	Name  string
+	Op    string
+	Value float64
+}
+
+// Properties, named as in Anki.
+const (
+	PropInterval = "ivl"
+	PropEase     = "ease"
+	PropLapses   = "lapses"
+	PropReviews  = "reps"
+	// PropDue is the number of days until the card is due, negative when
+	// overdue.
+	PropDue = "due"
+)
+
+// Recent matches cards added, edited or rated within the last Days days.
+type Recent struct {
+	Field string
+	Days  int
+}
+
+const (
+	RecentAdded  = "added"
+	RecentEdited = "edited"
+	RecentRated  = "rated"
+)
+
+// Field matches the whole content of the front, back or extra field
+// case-insensitively; * matches any run of characters.
+type Field struct {
+	Name    string
+	Pattern string
+}
+
+func (And) node()       {}
+func (Or) node()        {}
+func (Not) node()       {}
+func (Text) node()      {}
+func (Deck) node()      {}
+func (DeckID) node()    {}
+func (Tag) node()       {}
+func (Untagged) node()  {}
+func (State) node()     {}
+func (Due) node()       {}
+func (Suspended) node() {}
+func (Type) node()      {}
+func (Prop) node()      {}
+func (Recent) node()    {}
+func (Field) node()     {}
+
+// String returns the canonical query for the node; parsing it yields the
+// same AST.
+func (n And) String() string {
+	parts := make([]string, len(n.Nodes))
+	for i, node := range n.Nodes {
+		parts[i] = node.String()
+		if _, ok := node.(Or); ok {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (n Or) String() string {
+	parts := make([]string, len(n.Nodes))
+	for i, node := range n.Nodes {
+		parts[i] = node.String()
+	}
+	return strings.Join(parts, " or ")
+}
+
+func (n Not) String() string {
+	switch n.Node.(type) {
+	case And, Or:
+		return "-(" + n.Node.String() + ")"
+	}
+	return "-" + n.Node.String()
+}
+
+func (n Text) String() string {
+	if n.Phrase || needsQuotes(n.Text) || isKeyword(n.Text) || strings.Contains(n.Text, ":") || strings.HasPrefix(n.Text, "-") {
+		return quote(n.Text)
+	}
+	return n.Text
+}
+
+func (n Deck) String() string    { return "deck:" + value(n.Pattern) }
+func (n DeckID) String() string  { return "did:" + strconv.FormatInt(n.ID, 10) }
+func (n Tag) String() string     { return "tag:" + value(n.Pattern) }
+func (Untagged) String() string  { return "tag:none" }
+func (n State) String() string   { return "is:" + string(n.State) }
+func (Due) String() string       { return "is:due" }
+func (Suspended) String() string { return "is:suspended" }
+func (n Type) String() string    { return "type:" + string(n.Type) }
+func (n Recent) String() string  { return n.Field + ":" + strconv.Itoa(n.Days) }
+func (n Field) String() string   { return n.Name + ":" + value(n.Pattern) }
+func (n Prop) String() string {
+	return "prop:" + n.Name + n.Op + strconv.FormatFloat(n.Value, 'f', -1, 64)
+}
+
+// Conjoin joins nodes with And, dropping nil nodes and flattening nested
+// conjunctions. It returns nil when no node is left.
+func Conjoin(nodes ...Node) Node {
+	var all []Node
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case nil:
+		case And:
+			all = append(all, n.Nodes...)
+		default:
+			all = append(all, n)
+		}
+	}
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	}
+	return And{Nodes: all}
+}
+
+// Disjoin joins nodes with Or like Conjoin.
+func Disjoin(nodes ...Node) Node {
+	var all []Node
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case nil:
+		case Or:
+			all = append(all, n.Nodes...)
+		default:
+			all = append(all, n)
+		}
+	}
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	}
+	return Or{Nodes: all}
+}
+
+// Terms returns the text a card is searched for: the words and phrases not
+// under a negation, in query order. They rank hits and are highlighted.
+func Terms(node Node) []Text {
+	var terms []Text
+	var walk func(Node)
+	walk = func(node Node) {
+		switch n := node.(type) {
+		case And:
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case Or:
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case Text:
+			terms = append(terms, n)
+		}
+	}
+	walk(node)
+	return terms
+}
+
+// RequiredTerms returns the text every match must contain: the words and
+// phrases that are direct conjuncts of the query.
+func RequiredTerms(node Node) []Text {
+	var terms []Text
+	nodes := []Node{node}
+	if and, ok := node.(And); ok {
+		nodes = and.Nodes
+	}
+	for _, node := range nodes {
+		if text, ok := node.(Text); ok {
+			terms = append(terms, text)
+		}
+	}
+	return terms
+}
+
+func value(s string) string {
+	if s == "" || needsQuotes(s) {
+		return quote(s)
+	}
+	return s
+}
+
+func needsQuotes(s string) bool {
+	return strings.ContainsAny(s, `"()\`) || strings.IndexFunc(s, unicode.IsSpace) >= 0
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func isKeyword(s string) bool {
+	return strings.EqualFold(s, "or") || strings.EqualFold(s, "and")
+}
@@ -0,0 +1,156 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"memwright/api/internal/model"
+)
+
+// Candidate is a card with the context Match needs to evaluate a query
+// without a database.
+type Candidate struct {
+	Card *model.Card
+	// DeckPath is the full name of the card's deck, "Parent::Child".
+	DeckPath string
+	// DeckIDs holds the card's deck and its ancestors.
+	DeckIDs []int64
+	// Schedule is nil when the user never studied the card.
+	Schedule   *model.CardSchedule
+	ReviewedAt []time.Time
+	// Text reports whether the card contains a word or phrase.
+	Text func(Text) bool
+}
+
+// Match evaluates the expression against a candidate as SQL does.
+func Match(node Node, candidate Candidate, now time.Time) bool {
+	card, schedule := candidate.Card, candidate.Schedule
+	switch n := node.(type) {
+	case nil:
+		return true
+	case And:
+		for _, child := range n.Nodes {
+			if !Match(child, candidate, now) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, child := range n.Nodes {
+			if Match(child, candidate, now) {
+				return true
+			}
+		}
+		return false
+	case Not:
+		return !Match(n.Node, candidate, now)
+	case Text:
+		return candidate.Text(n)
+	case Deck:
+		return glob(n.Pattern, candidate.DeckPath) || glob(n.Pattern+"::*", candidate.DeckPath)
+	case DeckID:
+		for _, id := range candidate.DeckIDs {
+			if id == n.ID {
+				return true
+			}
+		}
+		return false
+	case Tag:
+		for _, tag := range card.Tags {
+			if glob(n.Pattern, tag) || glob(n.Pattern+"::*", tag) {
+				return true
+			}
+		}
+		return false
+	case Untagged:
+		return len(card.Tags) == 0
+	case State:
+		if schedule == nil {
+			return n.State == model.ScheduleStateNew
+		}
+		return schedule.State == n.State
+	case Due:
+		if schedule == nil {
+			return false
+		}
+		switch schedule.State {
+		case model.ScheduleStateLearning, model.ScheduleStateReview, model.ScheduleStateRelearning:
+			return !schedule.DueAt.After(now)
+		}
+		return false
+	case Suspended:
+		return card.Suspended
+	case Type:
+		return card.Type == n.Type
+	case Prop:
+		if schedule == nil {
+			return false
+		}
+		return compare(propValue(n.Name, schedule, now), n.Op, n.Value)
+	case Recent:
+		since := now.AddDate(0, 0, -n.Days)
+		switch n.Field {
+		case RecentAdded:
+			return !card.CreatedAt.Before(since)
+		case RecentEdited:
+			return !card.UpdatedAt.Before(since)
+		}
+		for _, reviewedAt := range candidate.ReviewedAt {
+			if !reviewedAt.Before(since) {
+				return true
+			}
+		}
+		return false
+	case Field:
+		switch n.Name {
+		case "front":
+			return glob(n.Pattern, card.Front)
+		case "back":
+			return glob(n.Pattern, card.Back)
+		}
+		return glob(n.Pattern, card.Extra)
+	}
+	panic(fmt.Sprintf("query: unknown node %T", node))
+}
+
+func propValue(name string, schedule *model.CardSchedule, now time.Time) float64 {
+	switch name {
+	case PropInterval:
+		return float64(schedule.Interval)
+	case PropEase:
+		return schedule.EaseFactor
+	case PropLapses:
+		return float64(schedule.LapseCount)
+	case PropReviews:
+		return float64(schedule.ReviewCount)
+	}
+	return schedule.DueAt.Sub(now).Hours() / 24
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "!=":
+		return a != b
+	}
+	return a == b
+}
+
+// glob matches the whole of s case-insensitively, * matching any run of
+// characters, like the LIKE patterns SQL builds.
+func glob(pattern, s string) bool {
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("(?s)^" + strings.Join(parts, ".*") + "$").MatchString(strings.ToLower(s))
+}
@@ -0,0 +1,389 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"memwright/api/internal/model"
+)
+
+// MaxRecentDays bounds added:, edited: and rated:.
+const MaxRecentDays = 36500
+
+// SyntaxError reports an invalid query. Pos is the offset of the problem
+// in characters, counted from 0.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses a query. Terms separated by spaces must all match; or
+// separates alternatives and binds looser than the implicit and, a
+// leading - negates a term or a (group), and "quotes" make a phrase or
+// allow spaces in a field value. A blank query parses to nil.
+func Parse(input string) (Node, error) {
+	p := &parser{lexer: lexer{input: []rune(input)}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenEOF {
+		return nil, nil
+	}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: p.tok.pos, Msg: "unexpected " + p.tok.describe()}
+	}
+	return node, nil
+}
+
+type parser struct {
+	lexer lexer
+	tok   token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) or() (Node, error) {
+	node, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []Node{node}
+	for p.tok.kind == tokenOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		node, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return Disjoin(nodes...), nil
+}
+
+func (p *parser) and() (Node, error) {
+	var nodes []Node
+	for {
+		if p.tok.kind == tokenAnd && len(nodes) > 0 {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		} else if len(nodes) > 0 && !p.tok.startsTerm() {
+			return Conjoin(nodes...), nil
+		}
+		node, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+func (p *parser) unary() (Node, error) {
+	if p.tok.kind != tokenNot {
+		return p.primary()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	node, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if not, ok := node.(Not); ok {
+		return not.Node, nil
+	}
+	return Not{Node: node}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokenLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenRParen {
+			return nil, &SyntaxError{Pos: p.tok.pos, Msg: "empty group"}
+		}
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: `missing ")" to close this group`}
+		}
+		return node, p.advance()
+	case tokenTerm:
+		node, err := term(tok)
+		if err != nil {
+			return nil, err
+		}
+		return node, p.advance()
+	}
+	return nil, &SyntaxError{Pos: tok.pos, Msg: "expected a search term, found " + tok.describe()}
+}
+
+// term validates a term's value and builds its node.
+func term(tok token) (Node, error) {
+	fail := func(offset int, format string, args ...interface{}) error {
+		return &SyntaxError{Pos: tok.valuePos + offset, Msg: fmt.Sprintf(format, args...)}
+	}
+	value := tok.value
+
+	switch tok.field {
+	case "":
+		if value == "" {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "empty phrase"}
+		}
+		return Text{Text: value, Phrase: tok.quoted}, nil
+	case "deck":
+		if value == "" {
+			return nil, fail(0, "deck: needs a deck name")
+		}
+		return Deck{Pattern: value}, nil
+	case "did":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fail(0, "did: needs a deck id, got %q", value)
+		}
+		return DeckID{ID: id}, nil
+	case "tag":
+		if value == "" {
+			return nil, fail(0, "tag: needs a tag")
+		}
+		if strings.EqualFold(value, "none") {
+			return Untagged{}, nil
+		}
+		return Tag{Pattern: value}, nil
+	case "is":
+		switch strings.ToLower(value) {
+		case "due":
+			return Due{}, nil
+		case "suspended":
+			return Suspended{}, nil
+		}
+		if state := model.ScheduleState(strings.ToLower(value)); state.IsValid() {
+			return State{State: state}, nil
+		}
+		return nil, fail(0, "unknown is:%s, expected one of due, suspended, new, learning, review, relearning, mastered", value)
+	case "type":
+		if cardType := model.CardType(strings.ToLower(value)); cardType.IsValid() {
+			return Type{Type: cardType}, nil
+		}
+		return nil, fail(0, "unknown card type %q", value)
+	case "prop":
+		return prop(value, fail)
+	case RecentAdded, RecentEdited, RecentRated:
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 || days > MaxRecentDays {
+			return nil, fail(0, "%s: needs a number of days between 1 and %d, got %q", tok.field, MaxRecentDays, value)
+		}
+		return Recent{Field: tok.field, Days: days}, nil
+	}
+	return Field{Name: tok.field, Pattern: value}, nil
+}
+
+var propOperators = []string{"<=", ">=", "!=", "<", ">", "="}
+
+func prop(value string, fail func(int, string, ...interface{}) error) (Node, error) {
+	runes := []rune(value)
+	nameEnd := 0
+	for nameEnd < len(runes) && unicode.IsLetter(runes[nameEnd]) {
+		nameEnd++
+	}
+	name := strings.ToLower(string(runes[:nameEnd]))
+	switch name {
+	case PropInterval, PropEase, PropLapses, PropReviews, PropDue:
+	case "":
+		return nil, fail(0, "prop: needs a property, one of ivl, ease, lapses, reps, due")
+	default:
+		return nil, fail(0, "unknown property %q, expected one of ivl, ease, lapses, reps, due", name)
+	}
+
+	rest := string(runes[nameEnd:])
+	for _, op := range propOperators {
+		if !strings.HasPrefix(rest, op) {
+			continue
+		}
+		number := rest[len(op):]
+		n, err := strconv.ParseFloat(number, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fail(nameEnd+len(op), "prop:%s needs a number, got %q", name, number)
+		}
+		return Prop{Name: name, Op: op, Value: n}, nil
+	}
+	return nil, fail(nameEnd, "prop:%s needs a comparison, one of < <= = != >= >", name)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenTerm
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	// field is the lower-case field of a term, empty for text.
+	field    string
+	value    string
+	valuePos int
+	quoted   bool
+}
+
+func (t token) startsTerm() bool {
+	return t.kind == tokenLParen || t.kind == tokenNot || t.kind == tokenTerm
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenLParen:
+		return `"("`
+	case tokenRParen:
+		return `")"`
+	case tokenNot:
+		return `"-"`
+	case tokenAnd:
+		return `"and"`
+	case tokenOr:
+		return `"or"`
+	}
+	return "term"
+}
+
+var fields = map[string]bool{
+	"deck": true, "did": true, "tag": true, "is": true, "type": true, "prop": true,
+	RecentAdded: true, RecentEdited: true, RecentRated: true,
+	"front": true, "back": true, "extra": true,
+}
+
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if start == len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	switch r := l.input[start]; {
+	case r == '(':
+		l.pos++
+		return token{kind: tokenLParen, pos: start}, nil
+	case r == ')':
+		l.pos++
+		return token{kind: tokenRParen, pos: start}, nil
+	case r == '-':
+		l.pos++
+		return token{kind: tokenNot, pos: start}, nil
+	case r == '"':
+		text, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenTerm, pos: start, value: text, valuePos: start + 1, quoted: true}, nil
+	}
+
+	word := l.word()
+	if colon := strings.IndexRune(word, ':'); colon > 0 {
+		name := strings.ToLower(word[:colon])
+		valuePos := start + len([]rune(word[:colon])) + 1
+		if fields[name] {
+			tok := token{kind: tokenTerm, pos: start, field: name, value: word[colon+1:], valuePos: valuePos}
+			if tok.value == "" && l.pos < len(l.input) && l.input[l.pos] == '"' {
+				value, err := l.quoted()
+				if err != nil {
+					return token{}, err
+				}
+				tok.value, tok.valuePos, tok.quoted = value, valuePos+1, true
+			}
+			return tok, nil
+		}
+		if isLetters(name) {
+			return token{}, &SyntaxError{Pos: start, Msg: fmt.Sprintf("unknown field %q", name)}
+		}
+	}
+	switch {
+	case strings.EqualFold(word, "or"):
+		return token{kind: tokenOr, pos: start}, nil
+	case strings.EqualFold(word, "and"):
+		return token{kind: tokenAnd, pos: start}, nil
+	}
+	return token{kind: tokenTerm, pos: start, value: word, valuePos: start}, nil
+}
+
+// word reads up to a space, a parenthesis or a quote.
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.input) {
+		r := l.input[l.pos]
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+			break
+		}
+		l.pos++
+	}
+	return string(l.input[start:l.pos])
+}
+
+// quoted reads a quoted string; a backslash escapes the next character.
+func (l *lexer) quoted() (string, error) {
+	start := l.pos
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.input); l.pos++ {
+		switch r := l.input[l.pos]; r {
+		case '"':
+			l.pos++
+			return b.String(), nil
+		case '\\':
+			if l.pos+1 < len(l.input) {
+				l.pos++
+				r = l.input[l.pos]
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", &SyntaxError{Pos: start, Msg: "unterminated quote"}
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,115 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SQL compiles the expression into a condition over the cards c, their
+// decks d, the decks' full names dp.path and the user's schedules cs,
+// which may be missing for cards never studied. arg binds a parameter and
+// returns its placeholder. Every condition is true or false, never NULL,
+// so negations select exactly the remaining cards.
+func SQL(node Node, arg func(interface{}) string) string {
+	switch n := node.(type) {
+	case nil:
+		return "TRUE"
+	case And:
+		return join(n.Nodes, " AND ", arg)
+	case Or:
+		return join(n.Nodes, " OR ", arg)
+	case Not:
+		return "NOT (" + SQL(n.Node, arg) + ")"
+	case Text:
+		return "c.search_vector @@ " + TSQuery(n, "d.language", arg)
+	case Deck:
+		pattern := arg(likePattern(n.Pattern))
+		return fmt.Sprintf(`(lower(dp.path) LIKE %s OR lower(dp.path) LIKE (%s || '::%%'))`, pattern, pattern)
+	case DeckID:
+		return `c.deck_id IN (
+					WITH RECURSIVE subtree(id) AS (
+						SELECT ` + arg(n.ID) + `::bigint
+						UNION ALL
+						SELECT child.id FROM decks child INNER JOIN subtree ON child.parent_id = subtree.id
+					)
+					SELECT id FROM subtree
+				)`
+	case Tag:
+		pattern := arg(likePattern(n.Pattern))
+		return fmt.Sprintf(`EXISTS (SELECT 1 FROM unnest(c.tags) AS t(tag) WHERE lower(t.tag) LIKE %s OR lower(t.tag) LIKE (%s || '::%%'))`, pattern, pattern)
+	case Untagged:
+		return "COALESCE(cardinality(c.tags), 0) = 0"
+	case State:
+		return "COALESCE(cs.state, 'new') = " + arg(string(n.State))
+	case Due:
+		return "COALESCE(cs.state IN ('learning', 'review', 'relearning') AND cs.due_at <= NOW(), FALSE)"
+	case Suspended:
+		return "c.suspended"
+	case Type:
+		return "c.type = " + arg(string(n.Type))
+	case Prop:
+		return fmt.Sprintf("COALESCE(%s %s %s::float8, FALSE)", propColumns[n.Name], n.Op, arg(n.Value))
+	case Recent:
+		since := "NOW() - make_interval(days => " + arg(n.Days) + "::int)"
+		switch n.Field {
+		case RecentAdded:
+			return "c.created_at >= " + since
+		case RecentEdited:
+			return "c.updated_at >= " + since
+		}
+		return "EXISTS (SELECT 1 FROM review_logs rl WHERE rl.card_schedule_id = cs.id AND rl.reviewed_at >= " + since + ")"
+	case Field:
+		return fmt.Sprintf("lower(c.%s) LIKE %s", fieldColumns[n.Name], arg(likePattern(n.Pattern)))
+	}
+	panic(fmt.Sprintf("query: unknown node %T", node))
+}
+
+// TSQuery returns the tsquery of a word or phrase in the given text search
+// configuration.
+func TSQuery(text Text, config string, arg func(interface{}) string) string {
+	function := "plainto_tsquery"
+	if text.Phrase {
+		function = "phraseto_tsquery"
+	}
+	return fmt.Sprintf("%s(%s, %s)", function, config, arg(text.Text))
+}
+
+var propColumns = map[string]string{
+	PropInterval: "cs.interval",
+	PropEase:     "cs.ease_factor",
+	PropLapses:   "cs.lapse_count",
+	PropReviews:  "cs.review_count",
+	PropDue:      "EXTRACT(EPOCH FROM cs.due_at - NOW()) / 86400",
+}
+
+var fieldColumns = map[string]string{
+	"front": "front",
+	"back":  "back",
+	"extra": "extra",
+}
+
+func join(nodes []Node, operator string, arg func(interface{}) string) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = SQL(node, arg)
+	}
+	return "(" + strings.Join(parts, operator) + ")"
+}
+
+// likePattern lower-cases a pattern and turns it into a LIKE pattern with
+// * as the only wildcard.
+func likePattern(pattern string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(pattern) {
+		switch r {
+		case '\\', '%', '_':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '*':
+			b.WriteRune('%')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	updated  time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	capacity := float64(policy.Capacity())
+	rate := policy.Rate()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	} else if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.updated = now
+	}
+	b.capacity = capacity
+	b.rate = rate
+
+	decision := Decision{Limit: policy.Capacity()}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+		decision.Remaining = int(b.tokens)
+		return decision, nil
+	}
+
+	if rate > 0 {
+		seconds := (1 - b.tokens) / rate
+		decision.RetryAfter = time.Duration(seconds * float64(time.Second))
+	} else {
+		decision.RetryAfter = time.Duration(math.MaxInt64)
+	}
+	return decision, nil
+}
+
+// sweep drops buckets that have refilled completely, since they behave
+// exactly like a missing bucket. It runs at most once per sweepInterval.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if b.rate <= 0 {
+			continue
+		}
+		elapsed := now.Sub(b.updated).Seconds()
+		if b.tokens+elapsed*b.rate >= b.capacity {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of tracked buckets.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
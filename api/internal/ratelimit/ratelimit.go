@@ -0,0 +1,80 @@
+// Package ratelimit implements token-bucket rate limiting with a pluggable
+// bucket store.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy allows Limit requests per Period on average, with bursts of up to
+// Burst requests.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// PerMinute returns a policy refilling limit tokens per minute.
+func PerMinute(limit, burst int) Policy {
+	return Policy{Limit: limit, Period: time.Minute, Burst: burst}
+}
+
+// Rate returns the number of tokens refilled per second.
+func (p Policy) Rate() float64 {
+	if p.Period <= 0 {
+		return 0
+	}
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Capacity returns the bucket size, which is Burst or Limit when no burst
+// is configured.
+func (p Policy) Capacity() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+	return p.Limit
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets. Implementations must be safe for concurrent
+// use; a shared store lets several API instances enforce one limit.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Decision, error)
+}
+
+// Limiter applies one policy to keys in a namespace of a store, so several
+// limiters can share a store without their buckets colliding.
+type Limiter struct {
+	name   string
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewLimiter(name string, store Store, policy Policy) *Limiter {
+	return &Limiter{
+		name:   name,
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// WithClock replaces the limiter's time source. It is intended for tests.
+func (l *Limiter) WithClock(now func() time.Time) *Limiter {
+	l.now = now
+	return l
+}
+
+func (l *Limiter) Allow(ctx context.Context, key string) (Decision, error) {
+	return l.store.Take(ctx, l.name+":"+key, l.policy, l.now())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"memwright/api/internal/model"
 )
@@ -11,9 +12,19 @@ import (
 type CardRepository interface {
 	Create(ctx context.Context, card *model.Card) error
 	GetByID(ctx context.Context, id int64) (*model.Card, error)
-	GetByDeckID(ctx context.Context, deckID int64) ([]*model.Card, error)
+	GetByDeckID(ctx context.Context, deckID int64, page model.PageRequest) (model.Page[*model.Card], error)
 	Update(ctx context.Context, card *model.Card) error
+	// Delete moves the card to the trash.
 	Delete(ctx context.Context, id int64) error
+	// GetDeleted returns a card in the trash.
+	GetDeleted(ctx context.Context, id int64) (*model.Card, error)
+	// Trash lists the cards of the user deleted after deletedAfter whose
+	// deck is not in the trash, most recently deleted first.
+	Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Card], error)
+	Restore(ctx context.Context, id int64) error
+	// Purge deletes the cards deleted before cutoff for good, with their
+	// schedules and review logs.
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
 }
 
 type cardRepository struct {
@@ -26,9 +37,9 @@ func NewCardRepository(db DB) CardRepository {
 
 func (r *cardRepository) Create(ctx context.Context, card *model.Card) error {
 	query := `
-		INSERT INTO cards (deck_id, type, front, back, extra, tags, position, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		RETURNING id, created_at, updated_at`
+		INSERT INTO cards (deck_id, type, front, back, extra, tags, position, suspended, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING id, version, created_at, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
 		card.DeckID,
@@ -38,7 +49,8 @@ func (r *cardRepository) Create(ctx context.Context, card *model.Card) error {
 		card.Extra,
 		tagsToArray(card.Tags),
 		card.Position,
-	).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+		card.Suspended,
+	).Scan(&card.ID, &card.Version, &card.CreatedAt, &card.UpdatedAt)
 
 	if err != nil {
 		return err
@@ -47,14 +59,23 @@ func (r *cardRepository) Create(ctx context.Context, card *model.Card) error {
 }
 
 func (r *cardRepository) GetByID(ctx context.Context, id int64) (*model.Card, error) {
+	return r.get(ctx, id, false)
+}
+
+func (r *cardRepository) GetDeleted(ctx context.Context, id int64) (*model.Card, error) {
+	return r.get(ctx, id, true)
+}
+
+// get reads a live card, or one in the trash when deleted is set.
+func (r *cardRepository) get(ctx context.Context, id int64, deleted bool) (*model.Card, error) {
 	query := `
-		SELECT id, deck_id, type, front, back, extra, tags, position, created_at, updated_at
+		SELECT id, deck_id, type, front, back, extra, tags, position, suspended, version, created_at, updated_at, deleted_at
 		FROM cards
-		WHERE id = $1`
+		WHERE id = $1 AND (deleted_at IS NOT NULL) = $2`
 
 	card := &model.Card{}
-	var tags sql.NullString
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	var tags TextArray
+	err := r.db.QueryRowContext(ctx, query, id, deleted).Scan(
 		&card.ID,
 		&card.DeckID,
 		&card.Type,
@@ -63,8 +84,11 @@ func (r *cardRepository) GetByID(ctx context.Context, id int64) (*model.Card, er
 		&card.Extra,
 		&tags,
 		&card.Position,
+		&card.Suspended,
+		&card.Version,
 		&card.CreatedAt,
 		&card.UpdatedAt,
+		&card.DeletedAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -73,27 +97,38 @@ func (r *cardRepository) GetByID(ctx context.Context, id int64) (*model.Card, er
 	if err != nil {
 		return nil, err
 	}
-	card.Tags = arrayToTags(tags)
+	card.Tags = tagsFromArray(tags)
 	return card, nil
 }
 
-func (r *cardRepository) GetByDeckID(ctx context.Context, deckID int64) ([]*model.Card, error) {
+// GetByDeckID lists a deck's cards ordered by position and ID.
+func (r *cardRepository) GetByDeckID(ctx context.Context, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
 	query := `
-		SELECT id, deck_id, type, front, back, extra, tags, position, created_at, updated_at
+		SELECT id, deck_id, type, front, back, extra, tags, position, suspended, version, created_at, updated_at
 		FROM cards
-		WHERE deck_id = $1
-		ORDER BY position, id`
+		WHERE deck_id = $1 AND deleted_at IS NULL`
+	args := []interface{}{deckID}
+	if cursor != nil {
+		query += ` AND (position, id) > ($2, $3)`
+		args = append(args, cursor.Position, cursor.ID)
+	}
+	query += ` ORDER BY position, id` + limitClause(page.Limit)
 
-	rows, err := r.db.QueryContext(ctx, query, deckID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return model.Page[*model.Card]{}, err
 	}
 	defer rows.Close()
 
 	var cards []*model.Card
 	for rows.Next() {
 		card := &model.Card{}
-		var tags sql.NullString
+		var tags TextArray
 		err := rows.Scan(
 			&card.ID,
 			&card.DeckID,
@@ -103,24 +138,32 @@ func (r *cardRepository) GetByDeckID(ctx context.Context, deckID int64) ([]*mode
 			&card.Extra,
 			&tags,
 			&card.Position,
+			&card.Suspended,
+			&card.Version,
 			&card.CreatedAt,
 			&card.UpdatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return model.Page[*model.Card]{}, err
 		}
-		card.Tags = arrayToTags(tags)
+		card.Tags = tagsFromArray(tags)
 		cards = append(cards, card)
 	}
-	return cards, rows.Err()
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	return model.NewPage(cards, page.Limit, model.CardCursor), nil
 }
 
+// Update writes the card if it is still at card.Version and returns
+// ErrConflict if another update came first.
 func (r *cardRepository) Update(ctx context.Context, card *model.Card) error {
 	query := `
 		UPDATE cards
-		SET deck_id = $2, type = $3, front = $4, back = $5, extra = $6, tags = $7, position = $8, updated_at = NOW()
-		WHERE id = $1
-		RETURNING updated_at`
+		SET deck_id = $2, type = $3, front = $4, back = $5, extra = $6, tags = $7, position = $8, suspended = $9,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $10 AND deleted_at IS NULL
+		RETURNING version, updated_at`
 
 	err := r.db.QueryRowContext(ctx, query,
 		card.ID,
@@ -131,10 +174,12 @@ func (r *cardRepository) Update(ctx context.Context, card *model.Card) error {
 		card.Extra,
 		tagsToArray(card.Tags),
 		card.Position,
-	).Scan(&card.UpdatedAt)
+		card.Suspended,
+		card.Version,
+	).Scan(&card.Version, &card.UpdatedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return model.ErrNotFound
+		return missingOrConflict(ctx, r.db, "cards", card.ID)
 	}
 	if err != nil {
 		return err
@@ -143,7 +188,7 @@ func (r *cardRepository) Update(ctx context.Context, card *model.Card) error {
 }
 
 func (r *cardRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM cards WHERE id = $1`
+	query := `UPDATE cards SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
@@ -160,46 +205,102 @@ func (r *cardRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-func tagsToArray(tags []string) interface{} {
-	if len(tags) == 0 {
-		return nil
+func (r *cardRepository) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
 	}
-	return tags
-}
 
-func arrayToTags(tags sql.NullString) []string {
-	if !tags.Valid || tags.String == "" || tags.String == "{}" {
-		return nil
+	query := `
+		SELECT c.id, c.deck_id, c.type, c.front, c.back, c.extra, c.tags, c.position, c.suspended, c.version, c.created_at, c.updated_at, c.deleted_at
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL AND c.deleted_at > $2`
+	args := []interface{}{userID, deletedAfter}
+	if cursor != nil && cursor.DeletedAt != nil {
+		query += ` AND (c.deleted_at, c.id) < ($3, $4)`
+		args = append(args, *cursor.DeletedAt, cursor.ID)
 	}
-	return parsePostgresArray(tags.String)
+	query += ` ORDER BY c.deleted_at DESC, c.id DESC` + limitClause(page.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	defer rows.Close()
+
+	var cards []*model.Card
+	for rows.Next() {
+		card := &model.Card{}
+		var tags TextArray
+		err := rows.Scan(
+			&card.ID,
+			&card.DeckID,
+			&card.Type,
+			&card.Front,
+			&card.Back,
+			&card.Extra,
+			&tags,
+			&card.Position,
+			&card.Suspended,
+			&card.Version,
+			&card.CreatedAt,
+			&card.UpdatedAt,
+			&card.DeletedAt,
+		)
+		if err != nil {
+			return model.Page[*model.Card]{}, err
+		}
+		card.Tags = tagsFromArray(tags)
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	return model.NewPage(cards, page.Limit, model.DeletedCardCursor), nil
 }
 
-func parsePostgresArray(s string) []string {
-	if len(s) < 2 {
-		return nil
+func (r *cardRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		UPDATE cards
+		SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
 	}
-	s = s[1 : len(s)-1]
-	if s == "" {
-		return nil
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
 
-	var result []string
-	var current string
-	inQuotes := false
+func (r *cardRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM cards WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
 
-	for i := 0; i < len(s); i++ {
-		ch := s[i]
-		if ch == '"' {
-			inQuotes = !inQuotes
-		} else if ch == ',' && !inQuotes {
-			result = append(result, current)
-			current = ""
-		} else {
-			current += string(ch)
-		}
+// tagsToArray stores cards without tags as NULL, matching how they read
+// back.
+func tagsToArray(tags []string) TextArray {
+	if len(tags) == 0 {
+		return nil
 	}
-	if current != "" {
-		result = append(result, current)
+	return TextArray(tags)
+}
+
+func tagsFromArray(tags TextArray) []string {
+	if len(tags) == 0 {
+		return nil
 	}
-	return result
+	return tags
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	"memwright/api/internal/model"
 )
 
@@ -13,12 +16,22 @@ type CardScheduleRepository interface {
 	Create(ctx context.Context, schedule *model.CardSchedule) error
 	GetByID(ctx context.Context, id int64) (*model.CardSchedule, error)
 	GetByCardAndUser(ctx context.Context, cardID, userID int64) (*model.CardSchedule, error)
+	// GetByCards returns the user's schedules of the given cards, ordered
+	// by card.
+	GetByCards(ctx context.Context, userID int64, cardIDs []int64) ([]*model.CardSchedule, error)
+	// The study queues leave out suspended cards, cards in the trash and
+	// cards below an archived deck.
 	GetDueCards(ctx context.Context, userID int64, deckID int64, dueBy time.Time, limit int) ([]*model.CardSchedule, error)
 	GetNewCards(ctx context.Context, userID int64, deckID int64, limit int) ([]*model.CardSchedule, error)
+	GetOverdue(ctx context.Context, userID int64, deckID int64, dueBefore time.Time) ([]*model.CardSchedule, error)
+	CountDueByDay(ctx context.Context, userID int64, deckID int64, from time.Time) (map[int]int, error)
+	UpdateDueDates(ctx context.Context, userID int64, moves []model.DueDateMove) error
 	Update(ctx context.Context, schedule *model.CardSchedule) error
 	Delete(ctx context.Context, id int64) error
 }
 
+const cardScheduleConstraint = "card_schedules_card_id_user_id_key"
+
 type cardScheduleRepository struct {
 	db DB
 }
@@ -45,10 +58,10 @@ func (r *cardScheduleRepository) Create(ctx context.Context, schedule *model.Car
 		schedule.LastReviewedAt,
 	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.UpdatedAt)
 
-	if err != nil {
-		return err
+	if isDuplicateKeyError(err, cardScheduleConstraint) {
+		return model.ErrDuplicateKey
 	}
-	return nil
+	return err
 }
 
 func (r *cardScheduleRepository) GetByID(ctx context.Context, id int64) (*model.CardSchedule, error) {
@@ -113,8 +126,34 @@ func (r *cardScheduleRepository) GetByCardAndUser(ctx context.Context, cardID, u
 	return schedule, nil
 }
 
+func (r *cardScheduleRepository) GetByCards(ctx context.Context, userID int64, cardIDs []int64) ([]*model.CardSchedule, error) {
+	if len(cardIDs) == 0 {
+		return nil, nil
+	}
+	q := &sqlBuilder{}
+	userArg := q.arg(userID)
+	ids := make([]string, len(cardIDs))
+	for i, id := range cardIDs {
+		ids[i] = q.arg(id)
+	}
+	query := `
+		SELECT id, card_id, user_id, state, due_at, interval, ease_factor, review_count, lapse_count, last_reviewed_at, created_at, updated_at
+		FROM card_schedules
+		WHERE user_id = ` + userArg + ` AND card_id IN (` + strings.Join(ids, ", ") + `)
+		ORDER BY card_id`
+
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCardSchedules(rows)
+}
+
 func (r *cardScheduleRepository) GetDueCards(ctx context.Context, userID int64, deckID int64, dueBy time.Time, limit int) ([]*model.CardSchedule, error) {
 	query := `
+		WITH RECURSIVE ` + archivedDecks + `
 		SELECT cs.id, cs.card_id, cs.user_id, cs.state, cs.due_at, cs.interval, cs.ease_factor, cs.review_count, cs.lapse_count, cs.last_reviewed_at, cs.created_at, cs.updated_at
 		FROM card_schedules cs
 		INNER JOIN cards c ON cs.card_id = c.id
@@ -122,7 +161,10 @@ func (r *cardScheduleRepository) GetDueCards(ctx context.Context, userID int64,
 			AND c.deck_id = $2
 			AND cs.due_at <= $3
 			AND cs.state IN ('learning', 'review', 'relearning')
-		ORDER BY cs.due_at ASC
+			AND NOT c.suspended
+			AND c.deleted_at IS NULL
+			AND c.deck_id NOT IN (SELECT id FROM archived)
+		ORDER BY cs.due_at ASC, cs.id ASC
 		LIMIT $4`
 
 	rows, err := r.db.QueryContext(ctx, query, userID, deckID, dueBy, limit)
@@ -136,12 +178,16 @@ func (r *cardScheduleRepository) GetDueCards(ctx context.Context, userID int64,
 
 func (r *cardScheduleRepository) GetNewCards(ctx context.Context, userID int64, deckID int64, limit int) ([]*model.CardSchedule, error) {
 	query := `
+		WITH RECURSIVE ` + archivedDecks + `
 		SELECT cs.id, cs.card_id, cs.user_id, cs.state, cs.due_at, cs.interval, cs.ease_factor, cs.review_count, cs.lapse_count, cs.last_reviewed_at, cs.created_at, cs.updated_at
 		FROM card_schedules cs
 		INNER JOIN cards c ON cs.card_id = c.id
 		WHERE cs.user_id = $1
 			AND c.deck_id = $2
 			AND cs.state = 'new'
+			AND NOT c.suspended
+			AND c.deleted_at IS NULL
+			AND c.deck_id NOT IN (SELECT id FROM archived)
 		ORDER BY c.position, cs.id
 		LIMIT $3`
 
@@ -154,6 +200,97 @@ func (r *cardScheduleRepository) GetNewCards(ctx context.Context, userID int64,
 	return scanCardSchedules(rows)
 }
 
+// GetOverdue returns every reviewable schedule of the user due before dueBefore.
+// A deckID of 0 covers all of the user's decks, any other the deck and its
+// subdecks.
+func (r *cardScheduleRepository) GetOverdue(ctx context.Context, userID int64, deckID int64, dueBefore time.Time) ([]*model.CardSchedule, error) {
+	query := `
+		WITH RECURSIVE ` + archivedDecks + `, ` + deckSubtree + `
+		SELECT cs.id, cs.card_id, cs.user_id, cs.state, cs.due_at, cs.interval, cs.ease_factor, cs.review_count, cs.lapse_count, cs.last_reviewed_at, cs.created_at, cs.updated_at
+		FROM card_schedules cs
+		INNER JOIN cards c ON cs.card_id = c.id
+		WHERE cs.user_id = $1
+			AND ($2::bigint = 0 OR c.deck_id IN (SELECT id FROM subtree))
+			AND cs.due_at < $3
+			AND cs.state IN ('learning', 'review', 'relearning', 'mastered')
+			AND NOT c.suspended
+			AND c.deleted_at IS NULL
+			AND c.deck_id NOT IN (SELECT id FROM archived)
+		ORDER BY cs.due_at ASC, cs.id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, deckID, dueBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCardSchedules(rows)
+}
+
+// CountDueByDay counts the schedules GetOverdue would return that fall due
+// at or after from, keyed by the number of whole days since from. Days
+// without reviews are left out.
+func (r *cardScheduleRepository) CountDueByDay(ctx context.Context, userID int64, deckID int64, from time.Time) (map[int]int, error) {
+	query := `
+		WITH RECURSIVE ` + archivedDecks + `, ` + deckSubtree + `
+		SELECT floor(extract(epoch FROM cs.due_at - $3::timestamptz) / 86400)::int AS day, COUNT(*)
+		FROM card_schedules cs
+		INNER JOIN cards c ON cs.card_id = c.id
+		WHERE cs.user_id = $1
+			AND ($2::bigint = 0 OR c.deck_id IN (SELECT id FROM subtree))
+			AND cs.due_at >= $3
+			AND cs.state IN ('learning', 'review', 'relearning', 'mastered')
+			AND NOT c.suspended
+			AND c.deleted_at IS NULL
+			AND c.deck_id NOT IN (SELECT id FROM archived)
+		GROUP BY day`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, deckID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var day, count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, err
+		}
+		counts[day] = count
+	}
+	return counts, rows.Err()
+}
+
+// UpdateDueDates moves the due date of several schedules in one statement.
+// Schedules that do not belong to userID, or whose due date is no longer
+// the one a move was planned from, are left untouched.
+func (r *cardScheduleRepository) UpdateDueDates(ctx context.Context, userID int64, moves []model.DueDateMove) error {
+	if len(moves) == 0 {
+		return nil
+	}
+
+	// Arrays keep the statement at four parameters however many schedules
+	// move.
+	ids := make([]int64, len(moves))
+	from := make([]string, len(moves))
+	to := make([]string, len(moves))
+	for i, move := range moves {
+		ids[i] = move.ScheduleID
+		from[i] = move.From.Format(time.RFC3339Nano)
+		to[i] = move.To.Format(time.RFC3339Nano)
+	}
+
+	query := `
+		UPDATE card_schedules cs
+		SET due_at = v.due_at, updated_at = NOW()
+		FROM unnest($2::bigint[], $3::timestamptz[], $4::timestamptz[]) AS v(id, previous_due_at, due_at)
+		WHERE cs.id = v.id AND cs.user_id = $1 AND cs.due_at = v.previous_due_at`
+
+	_, err := r.db.ExecContext(ctx, query, userID, pq.Array(ids), pq.Array(from), pq.Array(to))
+	return err
+}
+
 func (r *cardScheduleRepository) Update(ctx context.Context, schedule *model.CardSchedule) error {
 	query := `
 		UPDATE card_schedules
@@ -5,6 +5,11 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 
 	"memwright/api/internal/model"
 )
@@ -12,12 +17,41 @@ import (
 type DeckRepository interface {
 	Create(ctx context.Context, deck *model.Deck) error
 	GetByID(ctx context.Context, id int64) (*model.Deck, error)
-	GetByUserID(ctx context.Context, userID int64) ([]*model.Deck, error)
+	// GetByUserID lists a page of the user's top-level decks ordered by
+	// position, name and ID.
+	GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.Deck], error)
 	Update(ctx context.Context, deck *model.Deck) error
 	UpdateSRSConfig(ctx context.Context, id int64, config *model.SRSConfig) error
 	Delete(ctx context.Context, id int64) error
+	GetCardCounts(ctx context.Context, userID int64, now time.Time) (map[int64]model.DeckCounts, error)
+	// Children lists the user's decks below parentID, or the top-level
+	// decks when it is nil, ordered by position, name and ID.
+	Children(ctx context.Context, userID int64, parentID *int64) ([]*model.Deck, error)
+	// Renumber gives the decks positions 0..n-1 in the order given.
+	Renumber(ctx context.Context, ids []int64) error
+	// Tree returns the decks in the subtrees of rootIDs, or every deck of
+	// the user when rootIDs is nil, with their own counts and the totals of
+	// their subtrees, without linking the children.
+	Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error)
+	// GetDeleted returns a deck in the trash.
+	GetDeleted(ctx context.Context, id int64) (*model.Deck, error)
+	// Trash lists the user's decks deleted after deletedAfter whose parent
+	// is not in the trash too, most recently deleted first.
+	Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Deck], error)
+	// Restore takes a deck out of the trash together with the subdecks and
+	// cards deleted along with it.
+	Restore(ctx context.Context, id int64) error
+	// Purge deletes the decks deleted before cutoff for good, with
+	// everything below them. Subdecks that expired with their parent are
+	// not counted.
+	Purge(ctx context.Context, cutoff time.Time) (int64, error)
 }
 
+const (
+	deckNameConstraint  = "decks_user_id_parent_id_name_key"
+	deckCycleConstraint = "decks_no_cycles"
+)
+
 type deckRepository struct {
 	db DB
 }
@@ -37,41 +71,61 @@ func (r *deckRepository) Create(ctx context.Context, deck *model.Deck) error {
 	}
 
 	query := `
-		INSERT INTO decks (user_id, parent_id, name, description, algorithm, srs_config, position, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		RETURNING id, created_at, updated_at`
+		INSERT INTO decks (user_id, parent_id, name, description, algorithm, language, srs_config, position, archived_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, '')::regconfig, 'simple'), $7, $8, $9, NOW(), NOW())
+		RETURNING id, language::text, version, created_at, updated_at`
 
-	return r.db.QueryRowContext(ctx, query,
+	err = r.db.QueryRowContext(ctx, query,
 		deck.UserID,
 		deck.ParentID,
 		deck.Name,
 		deck.Description,
 		deck.Algorithm,
+		deck.Language,
 		configJSON,
 		deck.Position,
-	).Scan(&deck.ID, &deck.CreatedAt, &deck.UpdatedAt)
+		deck.ArchivedAt,
+	).Scan(&deck.ID, &deck.Language, &deck.Version, &deck.CreatedAt, &deck.UpdatedAt)
+
+	if isDuplicateKeyError(err, deckNameConstraint) {
+		return model.ErrDuplicateName
+	}
+	return err
 }
 
 func (r *deckRepository) GetByID(ctx context.Context, id int64) (*model.Deck, error) {
+	return r.get(ctx, id, false)
+}
+
+func (r *deckRepository) GetDeleted(ctx context.Context, id int64) (*model.Deck, error) {
+	return r.get(ctx, id, true)
+}
+
+// get reads a live deck, or one in the trash when deleted is set.
+func (r *deckRepository) get(ctx context.Context, id int64, deleted bool) (*model.Deck, error) {
 	query := `
-		SELECT id, user_id, parent_id, name, description, algorithm, srs_config, position, created_at, updated_at
+		SELECT id, user_id, parent_id, name, description, algorithm, language::text, srs_config, position, version, created_at, updated_at, archived_at, deleted_at
 		FROM decks
-		WHERE id = $1`
+		WHERE id = $1 AND (deleted_at IS NOT NULL) = $2`
 
 	deck := &model.Deck{}
 	var configJSON sql.NullString
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id, deleted).Scan(
 		&deck.ID,
 		&deck.UserID,
 		&deck.ParentID,
 		&deck.Name,
 		&deck.Description,
 		&deck.Algorithm,
+		&deck.Language,
 		&configJSON,
 		&deck.Position,
+		&deck.Version,
 		&deck.CreatedAt,
 		&deck.UpdatedAt,
+		&deck.ArchivedAt,
+		&deck.DeletedAt,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -91,53 +145,48 @@ func (r *deckRepository) GetByID(ctx context.Context, id int64) (*model.Deck, er
 	return deck, nil
 }
 
-func (r *deckRepository) GetByUserID(ctx context.Context, userID int64) ([]*model.Deck, error) {
+// GetByUserID lists a user's top-level decks ordered by position, name and
+// ID. Names compare bytewise so the order matches the deck tree built in Go.
+func (r *deckRepository) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.Deck], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+
 	query := `
-		SELECT id, user_id, parent_id, name, description, algorithm, srs_config, position, created_at, updated_at
+		SELECT id, user_id, parent_id, name, description, algorithm, language::text, srs_config, position, version, created_at, updated_at, archived_at, deleted_at
 		FROM decks
-		WHERE user_id = $1
-		ORDER BY position, name`
+		WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL`
+	args := []interface{}{userID}
+	if cursor != nil {
+		query += ` AND (position, name COLLATE "C", id) > ($2, $3, $4)`
+		args = append(args, cursor.Position, cursor.Name, cursor.ID)
+	}
+	query += ` ORDER BY position, name COLLATE "C", id` + limitClause(page.Limit)
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return model.Page[*model.Deck]{}, err
 	}
 	defer rows.Close()
 
 	var decks []*model.Deck
 	for rows.Next() {
-		deck := &model.Deck{}
-		var configJSON sql.NullString
-
-		err := rows.Scan(
-			&deck.ID,
-			&deck.UserID,
-			&deck.ParentID,
-			&deck.Name,
-			&deck.Description,
-			&deck.Algorithm,
-			&configJSON,
-			&deck.Position,
-			&deck.CreatedAt,
-			&deck.UpdatedAt,
-		)
+		deck, err := scanDeck(rows)
 		if err != nil {
-			return nil, err
+			return model.Page[*model.Deck]{}, err
 		}
-
-		if configJSON.Valid && configJSON.String != "" {
-			deck.SRSConfig = &model.SRSConfig{}
-			if err := json.Unmarshal([]byte(configJSON.String), deck.SRSConfig); err != nil {
-				return nil, err
-			}
-		}
-
 		decks = append(decks, deck)
 	}
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
 
-	return decks, rows.Err()
+	return model.NewPage(decks, page.Limit, model.DeckCursor), nil
 }
 
+// Update writes the deck if it is still at deck.Version and returns
+// ErrConflict if another update came first.
 func (r *deckRepository) Update(ctx context.Context, deck *model.Deck) error {
 	var configJSON []byte
 	var err error
@@ -150,9 +199,10 @@ func (r *deckRepository) Update(ctx context.Context, deck *model.Deck) error {
 
 	query := `
 		UPDATE decks
-		SET parent_id = $2, name = $3, description = $4, algorithm = $5, srs_config = $6, position = $7, updated_at = NOW()
-		WHERE id = $1
-		RETURNING updated_at`
+		SET parent_id = $2, name = $3, description = $4, algorithm = $5, srs_config = $6, position = $7,
+			language = COALESCE(NULLIF($9, '')::regconfig, language), archived_at = $10, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $8 AND deleted_at IS NULL
+		RETURNING language::text, version, updated_at`
 
 	err = r.db.QueryRowContext(ctx, query,
 		deck.ID,
@@ -162,10 +212,19 @@ func (r *deckRepository) Update(ctx context.Context, deck *model.Deck) error {
 		deck.Algorithm,
 		configJSON,
 		deck.Position,
-	).Scan(&deck.UpdatedAt)
+		deck.Version,
+		deck.Language,
+		deck.ArchivedAt,
+	).Scan(&deck.Language, &deck.Version, &deck.UpdatedAt)
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return model.ErrNotFound
+		return missingOrConflict(ctx, r.db, "decks", deck.ID)
+	}
+	if isDuplicateKeyError(err, deckNameConstraint) {
+		return model.ErrDuplicateName
+	}
+	if err != nil && strings.Contains(err.Error(), deckCycleConstraint) {
+		return model.ErrDeckCycle
 	}
 	return err
 }
@@ -182,8 +241,8 @@ func (r *deckRepository) UpdateSRSConfig(ctx context.Context, id int64, config *
 
 	query := `
 		UPDATE decks
-		SET srs_config = $2, updated_at = NOW()
-		WHERE id = $1`
+		SET srs_config = $2, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	result, err := r.db.ExecContext(ctx, query, id, configJSON)
 	if err != nil {
@@ -201,8 +260,22 @@ func (r *deckRepository) UpdateSRSConfig(ctx context.Context, id int64, config *
 	return nil
 }
 
+// Delete moves the deck to the trash with its subdecks and their cards.
+// NOW() is fixed within a transaction, so they all share one deleted_at,
+// which Restore relies on to tell them from items deleted earlier.
 func (r *deckRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM decks WHERE id = $1`
+	query := `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM decks WHERE id = $1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT child.id FROM decks child INNER JOIN subtree ON child.parent_id = subtree.id
+			WHERE child.deleted_at IS NULL
+		), deleted_cards AS (
+			UPDATE cards SET deleted_at = NOW()
+			WHERE deck_id IN (SELECT id FROM subtree) AND deleted_at IS NULL
+		)
+		UPDATE decks SET deleted_at = NOW()
+		WHERE id IN (SELECT id FROM subtree)`
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
@@ -219,3 +292,272 @@ func (r *deckRepository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// GetCardCounts returns the new, learning and due counts of every deck of the
+// user that contains cards. Cards without a schedule yet count as new;
+// archived decks have none.
+func (r *deckRepository) GetCardCounts(ctx context.Context, userID int64, now time.Time) (map[int64]model.DeckCounts, error) {
+	query := `
+		WITH RECURSIVE ` + archivedDecks + `
+		SELECT c.deck_id,
+			COUNT(*) FILTER (WHERE cs.id IS NULL OR cs.state = 'new'),
+			COUNT(*) FILTER (WHERE cs.state IN ('learning', 'relearning')),
+			COUNT(*) FILTER (WHERE cs.state IN ('learning', 'review', 'relearning', 'mastered') AND cs.due_at <= $2)
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		LEFT JOIN card_schedules cs ON cs.card_id = c.id AND cs.user_id = d.user_id
+		WHERE d.user_id = $1 AND c.deleted_at IS NULL AND c.deck_id NOT IN (SELECT id FROM archived)
+		GROUP BY c.deck_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]model.DeckCounts)
+	for rows.Next() {
+		var deckID int64
+		var deckCounts model.DeckCounts
+		if err := rows.Scan(&deckID, &deckCounts.New, &deckCounts.Learning, &deckCounts.Due); err != nil {
+			return nil, err
+		}
+		counts[deckID] = deckCounts
+	}
+	return counts, rows.Err()
+}
+
+func (r *deckRepository) Children(ctx context.Context, userID int64, parentID *int64) ([]*model.Deck, error) {
+	query := `
+		SELECT id, user_id, parent_id, name, description, algorithm, language::text, srs_config, position, version, created_at, updated_at, archived_at, deleted_at
+		FROM decks
+		WHERE user_id = $1 AND parent_id IS NOT DISTINCT FROM $2 AND deleted_at IS NULL
+		ORDER BY position, name COLLATE "C", id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decks []*model.Deck
+	for rows.Next() {
+		deck, err := scanDeck(rows)
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, deck)
+	}
+	return decks, rows.Err()
+}
+
+// Renumber only touches decks whose position changes, so their versions
+// stay put otherwise.
+func (r *deckRepository) Renumber(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	q := &sqlBuilder{}
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = fmt.Sprintf("(%s::bigint, %s::int)", q.arg(id), q.arg(i))
+	}
+	query := `
+		UPDATE decks d
+		SET position = o.position, version = d.version + 1, updated_at = NOW()
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS o(id, position)
+		WHERE d.id = o.id AND d.position <> o.position`
+
+	_, err := r.db.ExecContext(ctx, query, q.args...)
+	return err
+}
+
+// Tree collects the decks below rootIDs, then walks each one's subtree
+// with a recursive CTE to add up the counts of the decks below it. Parents
+// come before their children.
+func (r *deckRepository) Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error) {
+	query := `
+		WITH RECURSIVE ` + archivedDecks + `, scope(id) AS (
+			SELECT id FROM decks
+			WHERE user_id = $1 AND deleted_at IS NULL AND ($3::bigint[] IS NULL OR id = ANY($3::bigint[]))
+			UNION ALL
+			SELECT child.id
+			FROM decks child INNER JOIN scope ON child.parent_id = scope.id
+			WHERE child.deleted_at IS NULL AND $3::bigint[] IS NOT NULL
+		), counts AS (
+			SELECT c.deck_id,
+				COUNT(*) FILTER (WHERE cs.id IS NULL OR cs.state = 'new') AS new,
+				COUNT(*) FILTER (WHERE cs.state IN ('learning', 'relearning')) AS learning,
+				COUNT(*) FILTER (WHERE cs.state IN ('learning', 'review', 'relearning', 'mastered') AND cs.due_at <= $2) AS due
+			FROM cards c
+			INNER JOIN decks d ON c.deck_id = d.id
+			LEFT JOIN card_schedules cs ON cs.card_id = c.id AND cs.user_id = d.user_id
+			WHERE d.user_id = $1 AND c.deleted_at IS NULL AND c.deck_id IN (SELECT id FROM scope) AND c.deck_id NOT IN (SELECT id FROM archived)
+			GROUP BY c.deck_id
+		), subtree(root_id, deck_id, depth) AS (
+			SELECT id, id, 0 FROM scope
+			UNION ALL
+			SELECT subtree.root_id, child.id, subtree.depth + 1
+			FROM decks child INNER JOIN subtree ON child.parent_id = subtree.deck_id
+			WHERE child.deleted_at IS NULL
+		), totals AS (
+			SELECT subtree.root_id,
+				SUM(counts.new)::int AS new, SUM(counts.learning)::int AS learning, SUM(counts.due)::int AS due
+			FROM subtree INNER JOIN counts ON counts.deck_id = subtree.deck_id
+			GROUP BY subtree.root_id
+		), depths AS (
+			SELECT deck_id, MAX(depth) AS depth FROM subtree GROUP BY deck_id
+		)
+		SELECT d.id, d.user_id, d.parent_id, d.name, d.description, d.algorithm, d.language::text, d.srs_config, d.position, d.version, d.created_at, d.updated_at, d.archived_at, d.deleted_at,
+			COALESCE(counts.new, 0), COALESCE(counts.learning, 0), COALESCE(counts.due, 0),
+			COALESCE(totals.new, 0), COALESCE(totals.learning, 0), COALESCE(totals.due, 0)
+		FROM decks d
+		INNER JOIN depths ON depths.deck_id = d.id
+		LEFT JOIN counts ON counts.deck_id = d.id
+		LEFT JOIN totals ON totals.root_id = d.id
+		WHERE d.user_id = $1 AND d.deleted_at IS NULL
+		ORDER BY depths.depth, d.position, d.name COLLATE "C", d.id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, now, pq.Array(rootIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*model.DeckNode
+	for rows.Next() {
+		node := &model.DeckNode{Children: []*model.DeckNode{}}
+		deck, err := scanDeck(rows,
+			&node.Counts.New, &node.Counts.Learning, &node.Counts.Due,
+			&node.Totals.New, &node.Totals.Learning, &node.Totals.Due,
+		)
+		if err != nil {
+			return nil, err
+		}
+		node.Deck = deck
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+func (r *deckRepository) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Deck], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+
+	query := `
+		SELECT d.id, d.user_id, d.parent_id, d.name, d.description, d.algorithm, d.language::text, d.srs_config, d.position, d.version, d.created_at, d.updated_at, d.archived_at, d.deleted_at
+		FROM decks d
+		LEFT JOIN decks parent ON parent.id = d.parent_id
+		WHERE d.user_id = $1 AND d.deleted_at > $2 AND parent.deleted_at IS NULL`
+	args := []interface{}{userID, deletedAfter}
+	if cursor != nil && cursor.DeletedAt != nil {
+		query += ` AND (d.deleted_at, d.id) < ($3, $4)`
+		args = append(args, *cursor.DeletedAt, cursor.ID)
+	}
+	query += ` ORDER BY d.deleted_at DESC, d.id DESC` + limitClause(page.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+	defer rows.Close()
+
+	var decks []*model.Deck
+	for rows.Next() {
+		deck, err := scanDeck(rows)
+		if err != nil {
+			return model.Page[*model.Deck]{}, err
+		}
+		decks = append(decks, deck)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+	return model.NewPage(decks, page.Limit, model.DeletedDeckCursor), nil
+}
+
+// Restore walks down from the deck through the decks that share its
+// deleted_at. Subdecks and cards deleted earlier on their own stay in the
+// trash.
+func (r *deckRepository) Restore(ctx context.Context, id int64) error {
+	query := `
+		WITH RECURSIVE batch(id, deleted_at) AS (
+			SELECT id, deleted_at FROM decks WHERE id = $1 AND deleted_at IS NOT NULL
+			UNION ALL
+			SELECT child.id, child.deleted_at FROM decks child INNER JOIN batch ON child.parent_id = batch.id
+			WHERE child.deleted_at = batch.deleted_at
+		), restored_cards AS (
+			UPDATE cards c SET deleted_at = NULL, version = c.version + 1, updated_at = NOW()
+			FROM batch
+			WHERE c.deck_id = batch.id AND c.deleted_at = batch.deleted_at
+		)
+		UPDATE decks d SET deleted_at = NULL, version = d.version + 1, updated_at = NOW()
+		FROM batch
+		WHERE d.id = batch.id`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if isDuplicateKeyError(err, deckNameConstraint) {
+		return model.ErrDuplicateName
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+// Purge deletes the topmost expired decks and relies on ON DELETE CASCADE
+// to take the subdecks, cards, schedules and review logs along.
+func (r *deckRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `
+		DELETE FROM decks d
+		WHERE d.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM decks parent WHERE parent.id = d.parent_id AND parent.deleted_at < $1)`
+
+	result, err := r.db.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanDeck reads the deck columns in table order followed by extra.
+func scanDeck(rows *sql.Rows, extra ...interface{}) (*model.Deck, error) {
+	deck := &model.Deck{}
+	var configJSON sql.NullString
+	dest := append([]interface{}{
+		&deck.ID,
+		&deck.UserID,
+		&deck.ParentID,
+		&deck.Name,
+		&deck.Description,
+		&deck.Algorithm,
+		&deck.Language,
+		&configJSON,
+		&deck.Position,
+		&deck.Version,
+		&deck.CreatedAt,
+		&deck.UpdatedAt,
+		&deck.ArchivedAt,
+		&deck.DeletedAt,
+	}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if configJSON.Valid && configJSON.String != "" {
+		deck.SRSConfig = &model.SRSConfig{}
+		if err := json.Unmarshal([]byte(configJSON.String), deck.SRSConfig); err != nil {
+			return nil, err
+		}
+	}
+	return deck, nil
+}
@@ -1,6 +1,11 @@
 package repository
 
-import "strings"
+import (
+	"context"
+	"strings"
+
+	"memwright/api/internal/model"
+)
 
 func isDuplicateKeyError(err error, constraintName string) bool {
 	if err == nil {
@@ -9,3 +14,34 @@ func isDuplicateKeyError(err error, constraintName string) bool {
 	errStr := err.Error()
 	return strings.Contains(errStr, "duplicate key") && strings.Contains(errStr, constraintName)
 }
+
+// missingOrConflict explains why a versioned update matched no row: the row
+// is gone or in the trash, or another update bumped its version first.
+func missingOrConflict(ctx context.Context, db DB, table string, id int64) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+` WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return model.ErrNotFound
+	}
+	return model.ErrConflict
+}
+
+// archivedDecks defines the CTE archived(id): the archived decks of the
+// user in $1 and every deck below them, which study queues leave out. It
+// goes after WITH RECURSIVE.
+const archivedDecks = `archived(id) AS (
+			SELECT id FROM decks WHERE user_id = $1 AND archived_at IS NOT NULL
+			UNION
+			SELECT child.id FROM decks child INNER JOIN archived ON child.parent_id = archived.id
+		)`
+
+// deckSubtree defines the CTE subtree(id): the deck in $2 and every deck
+// below it. It goes after WITH RECURSIVE.
+const deckSubtree = `subtree(id) AS (
+			SELECT $2::bigint
+			UNION ALL
+			SELECT child.id FROM decks child INNER JOIN subtree ON child.parent_id = subtree.id
+		)`
@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type cardRepository struct {
+	store *Store
+}
+
+func NewCardRepository(store *Store) repository.CardRepository {
+	return &cardRepository{store: store}
+}
+
+func (r *cardRepository) Create(ctx context.Context, card *model.Card) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.decks[card.DeckID]; !ok {
+		return foreignKeyError("cards", "deck_id", card.DeckID)
+	}
+
+	stored := copyCard(card)
+	stored.ID = s.nextID("cards")
+	stored.DeletedAt = nil
+	stored.Version = 1
+	stored.CreatedAt = s.timestamp()
+	stored.UpdatedAt = stored.CreatedAt
+	s.cards[stored.ID] = stored
+
+	card.ID, card.Version, card.CreatedAt, card.UpdatedAt = stored.ID, stored.Version, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (r *cardRepository) GetByID(ctx context.Context, id int64) (*model.Card, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	card, ok := s.cards[id]
+	if !ok || card.DeletedAt != nil {
+		return nil, model.ErrNotFound
+	}
+	return copyCard(card), nil
+}
+
+func (r *cardRepository) GetDeleted(ctx context.Context, id int64) (*model.Card, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	card, ok := s.cards[id]
+	if !ok || card.DeletedAt == nil {
+		return nil, model.ErrNotFound
+	}
+	return copyCard(card), nil
+}
+
+// GetByDeckID lists a deck's cards ordered by position and ID.
+func (r *cardRepository) GetByDeckID(ctx context.Context, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cards []*model.Card
+	for _, card := range s.cards {
+		if card.DeckID != deckID || card.DeletedAt != nil {
+			continue
+		}
+		if cursor != nil && !cardAfter(card, cursor.Position, cursor.ID) {
+			continue
+		}
+		cards = append(cards, copyCard(card))
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cardAfter(cards[j], cards[i].Position, cards[i].ID)
+	})
+	return model.NewPage(limit(cards, page.Limit), page.Limit, model.CardCursor), nil
+}
+
+func (r *cardRepository) Update(ctx context.Context, card *model.Card) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.cards[card.ID]
+	if !ok || existing.DeletedAt != nil {
+		return model.ErrNotFound
+	}
+	if existing.Version != card.Version {
+		return model.ErrConflict
+	}
+	if _, ok := s.decks[card.DeckID]; !ok {
+		return foreignKeyError("cards", "deck_id", card.DeckID)
+	}
+
+	stored := copyCard(card)
+	stored.DeletedAt = nil
+	stored.Version = existing.Version + 1
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = s.timestamp()
+	s.cards[stored.ID] = stored
+
+	card.Version, card.UpdatedAt = stored.Version, stored.UpdatedAt
+	return nil
+}
+
+func (r *cardRepository) Delete(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card, ok := s.cards[id]
+	if !ok || card.DeletedAt != nil {
+		return model.ErrNotFound
+	}
+	card.DeletedAt = timePtr(s.deletionStamp())
+	return nil
+}
+
+// Trash orders the cards by deleted_at and ID, newest first.
+func (r *cardRepository) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cards []*model.Card
+	for _, card := range s.cards {
+		deck := s.decks[card.DeckID]
+		if deck == nil || deck.UserID != userID || deck.DeletedAt != nil {
+			continue
+		}
+		if card.DeletedAt == nil || !card.DeletedAt.After(deletedAfter) {
+			continue
+		}
+		if cursor != nil && cursor.DeletedAt != nil && !deletedBefore(*card.DeletedAt, card.ID, cursor) {
+			continue
+		}
+		cards = append(cards, copyCard(card))
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return deletedBefore(*cards[j].DeletedAt, cards[j].ID, &model.Cursor{DeletedAt: cards[i].DeletedAt, ID: cards[i].ID})
+	})
+	return model.NewPage(limit(cards, page.Limit), page.Limit, model.DeletedCardCursor), nil
+}
+
+func (r *cardRepository) Restore(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	card, ok := s.cards[id]
+	if !ok || card.DeletedAt == nil {
+		return model.ErrNotFound
+	}
+	card.DeletedAt = nil
+	card.Version++
+	card.UpdatedAt = s.timestamp()
+	return nil
+}
+
+func (r *cardRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, card := range s.cards {
+		if isExpired(card.DeletedAt, cutoff) {
+			s.deleteCard(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// cardAfter reports whether card sorts after (position, id).
+func cardAfter(card *model.Card, position int, id int64) bool {
+	if card.Position != position {
+		return card.Position > position
+	}
+	return card.ID > id
+}
+
+// copyCard returns a deep copy. Empty tags read back as nil, as the
+// PostgreSQL repository stores them as NULL.
+func copyCard(card *model.Card) *model.Card {
+	copied := *card
+	copied.DeletedAt = copyTime(card.DeletedAt)
+	copied.Tags = nil
+	if len(card.Tags) > 0 {
+		copied.Tags = append([]string(nil), card.Tags...)
+	}
+	return &copied
+}
@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type cardScheduleRepository struct {
+	store *Store
+}
+
+func NewCardScheduleRepository(store *Store) repository.CardScheduleRepository {
+	return &cardScheduleRepository{store: store}
+}
+
+func (r *cardScheduleRepository) Create(ctx context.Context, schedule *model.CardSchedule) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cards[schedule.CardID]; !ok {
+		return foreignKeyError("card_schedules", "card_id", schedule.CardID)
+	}
+	if _, ok := s.users[schedule.UserID]; !ok {
+		return foreignKeyError("card_schedules", "user_id", schedule.UserID)
+	}
+	for _, other := range s.schedules {
+		if other.CardID == schedule.CardID && other.UserID == schedule.UserID {
+			return model.ErrDuplicateKey
+		}
+	}
+
+	stored := copySchedule(schedule)
+	stored.ID = s.nextID("card_schedules")
+	stored.CreatedAt = s.timestamp()
+	stored.UpdatedAt = stored.CreatedAt
+	s.schedules[stored.ID] = stored
+
+	schedule.ID, schedule.CreatedAt, schedule.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (r *cardScheduleRepository) GetByID(ctx context.Context, id int64) (*model.CardSchedule, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return copySchedule(schedule), nil
+}
+
+func (r *cardScheduleRepository) GetByCardAndUser(ctx context.Context, cardID, userID int64) (*model.CardSchedule, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, schedule := range s.schedules {
+		if schedule.CardID == cardID && schedule.UserID == userID {
+			return copySchedule(schedule), nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+func (r *cardScheduleRepository) GetByCards(ctx context.Context, userID int64, cardIDs []int64) ([]*model.CardSchedule, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[int64]bool, len(cardIDs))
+	for _, id := range cardIDs {
+		wanted[id] = true
+	}
+	var schedules []*model.CardSchedule
+	for _, schedule := range s.schedules {
+		if schedule.UserID == userID && wanted[schedule.CardID] {
+			schedules = append(schedules, copySchedule(schedule))
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CardID < schedules[j].CardID
+	})
+	return schedules, nil
+}
+
+func (r *cardScheduleRepository) GetDueCards(ctx context.Context, userID int64, deckID int64, dueBy time.Time, limit int) ([]*model.CardSchedule, error) {
+	schedules := r.store.selectSchedules(userID, func(schedule *model.CardSchedule, card *model.Card) bool {
+		return card.DeckID == deckID && !schedule.DueAt.After(dueBy) && isReviewable(schedule.State)
+	})
+	sortByDue(schedules)
+	return truncate(schedules, limit), nil
+}
+
+func (r *cardScheduleRepository) GetNewCards(ctx context.Context, userID int64, deckID int64, limit int) ([]*model.CardSchedule, error) {
+	positions := map[int64]int{}
+	schedules := r.store.selectSchedules(userID, func(schedule *model.CardSchedule, card *model.Card) bool {
+		positions[schedule.ID] = card.Position
+		return card.DeckID == deckID && schedule.State == model.ScheduleStateNew
+	})
+
+	sort.Slice(schedules, func(i, j int) bool {
+		a, b := schedules[i], schedules[j]
+		if positions[a.ID] != positions[b.ID] {
+			return positions[a.ID] < positions[b.ID]
+		}
+		return a.ID < b.ID
+	})
+	return truncate(schedules, limit), nil
+}
+
+// GetOverdue returns every reviewable schedule of the user due before dueBefore.
+// A deckID of 0 covers all of the user's decks, any other the deck and its
+// subdecks.
+func (r *cardScheduleRepository) GetOverdue(ctx context.Context, userID int64, deckID int64, dueBefore time.Time) ([]*model.CardSchedule, error) {
+	schedules := r.store.selectSchedules(userID, func(schedule *model.CardSchedule, card *model.Card) bool {
+		return r.store.inDeck(card, deckID) && schedule.DueAt.Before(dueBefore) && isBacklog(schedule.State)
+	})
+	sortByDue(schedules)
+	return schedules, nil
+}
+
+// CountDueByDay counts the schedules GetOverdue would return that fall due
+// at or after from, keyed by the number of whole days since from.
+func (r *cardScheduleRepository) CountDueByDay(ctx context.Context, userID int64, deckID int64, from time.Time) (map[int]int, error) {
+	schedules := r.store.selectSchedules(userID, func(schedule *model.CardSchedule, card *model.Card) bool {
+		return r.store.inDeck(card, deckID) && !schedule.DueAt.Before(from) && isBacklog(schedule.State)
+	})
+	counts := map[int]int{}
+	for _, schedule := range schedules {
+		counts[int(schedule.DueAt.Sub(from)/(24*time.Hour))]++
+	}
+	return counts, nil
+}
+
+// UpdateDueDates moves the due date of several schedules at once.
+// Schedules that do not belong to userID, or whose due date is no longer
+// the one a move was planned from, are left untouched.
+func (r *cardScheduleRepository) UpdateDueDates(ctx context.Context, userID int64, moves []model.DueDateMove) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for _, move := range moves {
+		if schedule, ok := s.schedules[move.ScheduleID]; ok && schedule.UserID == userID && schedule.DueAt.Equal(move.From) {
+			schedule.DueAt = move.To
+			schedule.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (r *cardScheduleRepository) Update(ctx context.Context, schedule *model.CardSchedule) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.schedules[schedule.ID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	stored := copySchedule(schedule)
+	stored.CardID = existing.CardID
+	stored.UserID = existing.UserID
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = s.timestamp()
+	s.schedules[stored.ID] = stored
+
+	schedule.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r *cardScheduleRepository) Delete(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return model.ErrNotFound
+	}
+	s.deleteSchedule(id)
+	return nil
+}
+
+// selectSchedules returns copies of the user's schedules that match keep,
+// leaving out suspended cards, cards in the trash and cards below an
+// archived deck.
+func (s *Store) selectSchedules(userID int64, keep func(*model.CardSchedule, *model.Card) bool) []*model.CardSchedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var schedules []*model.CardSchedule
+	for _, schedule := range s.schedules {
+		card, ok := s.cards[schedule.CardID]
+		if !ok || schedule.UserID != userID || card.Suspended || card.DeletedAt != nil || s.isArchived(s.decks[card.DeckID]) {
+			continue
+		}
+		if keep(schedule, card) {
+			schedules = append(schedules, copySchedule(schedule))
+		}
+	}
+	return schedules
+}
+
+func isReviewable(state model.ScheduleState) bool {
+	return state == model.ScheduleStateLearning || state == model.ScheduleStateReview || state == model.ScheduleStateRelearning
+}
+
+// isBacklog reports whether a schedule in state counts towards a backlog:
+// reviewable or mastered.
+func isBacklog(state model.ScheduleState) bool {
+	return isReviewable(state) || state == model.ScheduleStateMastered
+}
+
+// inDeck reports whether the card is in the deck or one of its subdecks; a
+// deckID of 0 matches every card. The caller holds the lock.
+func (s *Store) inDeck(card *model.Card, deckID int64) bool {
+	return deckID == 0 || s.isAncestor(deckID, &card.DeckID)
+}
+
+func sortByDue(schedules []*model.CardSchedule) {
+	sort.Slice(schedules, func(i, j int) bool {
+		if !schedules[i].DueAt.Equal(schedules[j].DueAt) {
+			return schedules[i].DueAt.Before(schedules[j].DueAt)
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+}
+
+func truncate[T any](items []T, n int) []T {
+	if n >= 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+func copySchedule(schedule *model.CardSchedule) *model.CardSchedule {
+	copied := *schedule
+	if schedule.LastReviewedAt != nil {
+		lastReviewedAt := *schedule.LastReviewedAt
+		copied.LastReviewedAt = &lastReviewedAt
+	}
+	return &copied
+}
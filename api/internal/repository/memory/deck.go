@@ -0,0 +1,542 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type deckRepository struct {
+	store *Store
+}
+
+func NewDeckRepository(store *Store) repository.DeckRepository {
+	return &deckRepository{store: store}
+}
+
+func (r *deckRepository) Create(ctx context.Context, deck *model.Deck) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := copyDeck(deck)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDeck(stored); err != nil {
+		return err
+	}
+
+	stored.ID = s.nextID("decks")
+	stored.DeletedAt = nil
+	if stored.Language == "" {
+		stored.Language = model.DefaultLanguage
+	}
+	stored.Version = 1
+	stored.CreatedAt = s.timestamp()
+	stored.UpdatedAt = stored.CreatedAt
+	s.decks[stored.ID] = stored
+
+	deck.ID, deck.Language, deck.Version = stored.ID, stored.Language, stored.Version
+	deck.CreatedAt, deck.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (r *deckRepository) GetByID(ctx context.Context, id int64) (*model.Deck, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deck, ok := s.decks[id]
+	if !ok || deck.DeletedAt != nil {
+		return nil, model.ErrNotFound
+	}
+	return copyDeck(deck)
+}
+
+func (r *deckRepository) GetDeleted(ctx context.Context, id int64) (*model.Deck, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deck, ok := s.decks[id]
+	if !ok || deck.DeletedAt == nil {
+		return nil, model.ErrNotFound
+	}
+	return copyDeck(deck)
+}
+
+// GetByUserID lists a user's top-level decks ordered by position, name and
+// ID.
+func (r *deckRepository) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.Deck], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var decks []*model.Deck
+	for _, deck := range s.decks {
+		if deck.UserID != userID || deck.ParentID != nil || deck.DeletedAt != nil {
+			continue
+		}
+		if cursor != nil && !deckAfter(deck, cursor) {
+			continue
+		}
+		copied, err := copyDeck(deck)
+		if err != nil {
+			return model.Page[*model.Deck]{}, err
+		}
+		decks = append(decks, copied)
+	}
+	sort.Slice(decks, func(i, j int) bool {
+		return deckAfter(decks[j], &model.Cursor{Position: decks[i].Position, Name: decks[i].Name, ID: decks[i].ID})
+	})
+	return model.NewPage(limit(decks, page.Limit), page.Limit, model.DeckCursor), nil
+}
+
+func (r *deckRepository) Update(ctx context.Context, deck *model.Deck) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.decks[deck.ID]
+	if !ok || existing.DeletedAt != nil {
+		return model.ErrNotFound
+	}
+	if existing.Version != deck.Version {
+		return model.ErrConflict
+	}
+	stored, err := copyDeck(deck)
+	if err != nil {
+		return err
+	}
+	stored.UserID = existing.UserID
+	stored.DeletedAt = nil
+	if err := s.checkDeck(stored); err != nil {
+		return err
+	}
+	if s.isAncestor(stored.ID, stored.ParentID) {
+		return model.ErrDeckCycle
+	}
+
+	if stored.Language == "" {
+		stored.Language = existing.Language
+	}
+	stored.Version = existing.Version + 1
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = s.timestamp()
+	s.decks[stored.ID] = stored
+
+	deck.Language, deck.Version, deck.UpdatedAt = stored.Language, stored.Version, stored.UpdatedAt
+	return nil
+}
+
+func (r *deckRepository) UpdateSRSConfig(ctx context.Context, id int64, config *model.SRSConfig) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, ok := s.decks[id]
+	if !ok || deck.DeletedAt != nil {
+		return model.ErrNotFound
+	}
+	copied, err := copySRSConfig(config)
+	if err != nil {
+		return err
+	}
+	deck.SRSConfig = copied
+	deck.Version++
+	deck.UpdatedAt = s.timestamp()
+	return nil
+}
+
+// Delete moves the deck to the trash with its subdecks and their cards,
+// all stamped with the same deleted_at.
+func (r *deckRepository) Delete(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, ok := s.decks[id]
+	if !ok || deck.DeletedAt != nil {
+		return model.ErrNotFound
+	}
+	s.trashDeck(deck, s.deletionStamp())
+	return nil
+}
+
+// GetCardCounts returns the new, learning and due counts of every deck of the
+// user that contains cards. Cards without a schedule yet count as new.
+func (r *deckRepository) GetCardCounts(ctx context.Context, userID int64, now time.Time) (map[int64]model.DeckCounts, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cardCounts(userID, now), nil
+}
+
+func (r *deckRepository) Children(ctx context.Context, userID int64, parentID *int64) ([]*model.Deck, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var decks []*model.Deck
+	for _, deck := range s.children(userID, parentID) {
+		copied, err := copyDeck(deck)
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, copied)
+	}
+	return decks, nil
+}
+
+func (r *deckRepository) Renumber(ctx context.Context, ids []int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for position, id := range ids {
+		if deck, ok := s.decks[id]; ok && deck.Position != position {
+			deck.Position = position
+			deck.Version++
+			deck.UpdatedAt = s.timestamp()
+		}
+	}
+	return nil
+}
+
+// Trash orders the decks by deleted_at and ID, newest first.
+func (r *deckRepository) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Deck], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var decks []*model.Deck
+	for _, deck := range s.decks {
+		if deck.UserID != userID || deck.DeletedAt == nil || !deck.DeletedAt.After(deletedAfter) {
+			continue
+		}
+		if parent := s.parent(deck); parent != nil && parent.DeletedAt != nil {
+			continue
+		}
+		if cursor != nil && cursor.DeletedAt != nil && !deletedBefore(*deck.DeletedAt, deck.ID, cursor) {
+			continue
+		}
+		copied, err := copyDeck(deck)
+		if err != nil {
+			return model.Page[*model.Deck]{}, err
+		}
+		decks = append(decks, copied)
+	}
+	sort.Slice(decks, func(i, j int) bool {
+		return deletedBefore(*decks[j].DeletedAt, decks[j].ID, &model.Cursor{DeletedAt: decks[i].DeletedAt, ID: decks[i].ID})
+	})
+	return model.NewPage(limit(decks, page.Limit), page.Limit, model.DeletedDeckCursor), nil
+}
+
+func (r *deckRepository) Restore(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deck, ok := s.decks[id]
+	if !ok || deck.DeletedAt == nil {
+		return model.ErrNotFound
+	}
+	for _, other := range s.children(deck.UserID, deck.ParentID) {
+		if other.Name == deck.Name {
+			return model.ErrDuplicateName
+		}
+	}
+	s.restoreDeck(deck, *deck.DeletedAt, s.timestamp())
+	return nil
+}
+
+func (r *deckRepository) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []int64
+	for id, deck := range s.decks {
+		if !isExpired(deck.DeletedAt, cutoff) {
+			continue
+		}
+		if parent := s.parent(deck); parent != nil && isExpired(parent.DeletedAt, cutoff) {
+			continue
+		}
+		expired = append(expired, id)
+	}
+	for _, id := range expired {
+		s.deleteDeck(id)
+	}
+	return int64(len(expired)), nil
+}
+
+// Tree lists parents before their children, each level below the roots
+// ordered like Children.
+func (r *deckRepository) Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roots := s.children(userID, nil)
+	if rootIDs != nil {
+		roots = nil
+		for _, id := range rootIDs {
+			if deck, ok := s.decks[id]; ok && deck.UserID == userID && deck.DeletedAt == nil {
+				roots = append(roots, deck)
+			}
+		}
+	}
+
+	counts := s.cardCounts(userID, now)
+	var nodes []*model.DeckNode
+	var walk func(decks []*model.Deck) (model.DeckCounts, error)
+	walk = func(decks []*model.Deck) (model.DeckCounts, error) {
+		var sum model.DeckCounts
+		for _, deck := range decks {
+			copied, err := copyDeck(deck)
+			if err != nil {
+				return sum, err
+			}
+			node := &model.DeckNode{Deck: copied, Counts: counts[deck.ID], Children: []*model.DeckNode{}}
+			nodes = append(nodes, node)
+			below, err := walk(s.children(userID, &deck.ID))
+			if err != nil {
+				return sum, err
+			}
+			node.Totals = model.DeckCounts{
+				New:      node.Counts.New + below.New,
+				Learning: node.Counts.Learning + below.Learning,
+				Due:      node.Counts.Due + below.Due,
+			}
+			sum.New += node.Totals.New
+			sum.Learning += node.Totals.Learning
+			sum.Due += node.Totals.Due
+		}
+		return sum, nil
+	}
+	if _, err := walk(roots); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return s.deckDepth(nodes[i].Deck) < s.deckDepth(nodes[j].Deck)
+	})
+	return nodes, nil
+}
+
+func (s *Store) cardCounts(userID int64, now time.Time) map[int64]model.DeckCounts {
+	schedules := make(map[[2]int64]*model.CardSchedule, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules[[2]int64{schedule.CardID, schedule.UserID}] = schedule
+	}
+
+	counts := make(map[int64]model.DeckCounts)
+	for _, card := range s.cards {
+		deck := s.decks[card.DeckID]
+		if deck == nil || deck.UserID != userID || card.DeletedAt != nil || s.isArchived(deck) {
+			continue
+		}
+
+		deckCounts := counts[card.DeckID]
+		schedule := schedules[[2]int64{card.ID, userID}]
+		switch {
+		case schedule == nil || schedule.State == model.ScheduleStateNew:
+			deckCounts.New++
+		default:
+			if schedule.State == model.ScheduleStateLearning || schedule.State == model.ScheduleStateRelearning {
+				deckCounts.Learning++
+			}
+			if !schedule.DueAt.After(now) {
+				deckCounts.Due++
+			}
+		}
+		counts[card.DeckID] = deckCounts
+	}
+	return counts
+}
+
+// children returns the user's live decks below parentID ordered by
+// position, name and ID.
+func (s *Store) children(userID int64, parentID *int64) []*model.Deck {
+	var decks []*model.Deck
+	for _, deck := range s.decks {
+		if deck.UserID == userID && deck.DeletedAt == nil && sameParent(deck.ParentID, parentID) {
+			decks = append(decks, deck)
+		}
+	}
+	sort.Slice(decks, func(i, j int) bool {
+		return deckAfter(decks[j], &model.Cursor{Position: decks[i].Position, Name: decks[i].Name, ID: decks[i].ID})
+	})
+	return decks
+}
+
+// isAncestor reports whether deckID is parentID or one of its ancestors,
+// the cycle the decks_no_cycles trigger rejects.
+func (s *Store) isAncestor(deckID int64, parentID *int64) bool {
+	for id := parentID; id != nil; {
+		if *id == deckID {
+			return true
+		}
+		parent, ok := s.decks[*id]
+		if !ok {
+			return false
+		}
+		id = parent.ParentID
+	}
+	return false
+}
+
+func (s *Store) parent(deck *model.Deck) *model.Deck {
+	if deck.ParentID == nil {
+		return nil
+	}
+	return s.decks[*deck.ParentID]
+}
+
+// isArchived reports whether the deck or one of its ancestors is archived.
+func (s *Store) isArchived(deck *model.Deck) bool {
+	for ; deck != nil; deck = s.parent(deck) {
+		if deck.ArchivedAt != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// trashDeck stamps the deck, its live subdecks and their live cards with
+// deletedAt. The caller holds the write lock.
+func (s *Store) trashDeck(deck *model.Deck, deletedAt time.Time) {
+	for _, child := range s.children(deck.UserID, &deck.ID) {
+		s.trashDeck(child, deletedAt)
+	}
+	for _, card := range s.cards {
+		if card.DeckID == deck.ID && card.DeletedAt == nil {
+			card.DeletedAt = timePtr(deletedAt)
+		}
+	}
+	deck.DeletedAt = timePtr(deletedAt)
+}
+
+// restoreDeck undoes trashDeck for the items stamped with deletedAt.
+func (s *Store) restoreDeck(deck *model.Deck, deletedAt, now time.Time) {
+	for _, child := range s.decks {
+		if child.ParentID != nil && *child.ParentID == deck.ID && child.DeletedAt != nil && child.DeletedAt.Equal(deletedAt) {
+			s.restoreDeck(child, deletedAt, now)
+		}
+	}
+	for _, card := range s.cards {
+		if card.DeckID == deck.ID && card.DeletedAt != nil && card.DeletedAt.Equal(deletedAt) {
+			card.DeletedAt = nil
+			card.Version++
+			card.UpdatedAt = now
+		}
+	}
+	deck.DeletedAt = nil
+	deck.Version++
+	deck.UpdatedAt = now
+}
+
+func (s *Store) deckDepth(deck *model.Deck) int {
+	depth := 0
+	for id := deck.ParentID; id != nil; depth++ {
+		parent, ok := s.decks[*id]
+		if !ok {
+			break
+		}
+		id = parent.ParentID
+	}
+	return depth
+}
+
+func sameParent(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// checkDeck enforces the foreign keys and the unique index on user_id,
+// parent_id and name of the decks outside the trash. Top-level decks are
+// siblings too, so they may not share a name either.
+func (s *Store) checkDeck(deck *model.Deck) error {
+	if _, ok := s.users[deck.UserID]; !ok {
+		return foreignKeyError("decks", "user_id", deck.UserID)
+	}
+	if deck.ParentID != nil {
+		if _, ok := s.decks[*deck.ParentID]; !ok {
+			return foreignKeyError("decks", "parent_id", *deck.ParentID)
+		}
+	}
+	for _, other := range s.decks {
+		if other.ID != deck.ID && other.UserID == deck.UserID && other.DeletedAt == nil &&
+			sameParent(other.ParentID, deck.ParentID) && other.Name == deck.Name {
+			return model.ErrDuplicateName
+		}
+	}
+	return nil
+}
+
+// deckAfter reports whether deck sorts after the cursor position.
+func deckAfter(deck *model.Deck, cursor *model.Cursor) bool {
+	if deck.Position != cursor.Position {
+		return deck.Position > cursor.Position
+	}
+	if deck.Name != cursor.Name {
+		return deck.Name > cursor.Name
+	}
+	return deck.ID > cursor.ID
+}
+
+// copyDeck returns a deep copy. The SRS config goes through JSON, as it
+// does when stored in the JSONB column.
+func copyDeck(deck *model.Deck) (*model.Deck, error) {
+	copied := *deck
+	if deck.ParentID != nil {
+		parentID := *deck.ParentID
+		copied.ParentID = &parentID
+	}
+	copied.ArchivedAt = copyTime(deck.ArchivedAt)
+	copied.DeletedAt = copyTime(deck.DeletedAt)
+	config, err := copySRSConfig(deck.SRSConfig)
+	if err != nil {
+		return nil, err
+	}
+	copied.SRSConfig = config
+	return &copied, nil
+}
+
+func copySRSConfig(config *model.SRSConfig) (*model.SRSConfig, error) {
+	if config == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	copied := &model.SRSConfig{}
+	if err := json.Unmarshal(data, copied); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// limit keeps the limit+1 rows the repositories fetch so NewPage can tell
+// whether another page follows. A limit of 0 keeps everything.
+func limit[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit+1 {
+		return items[:limit+1]
+	}
+	return items
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type refreshTokenRepository struct {
+	store *Store
+}
+
+func NewRefreshTokenRepository(store *Store) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{store: store}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[token.UserID]; !ok {
+		return foreignKeyError("refresh_tokens", "user_id", token.UserID)
+	}
+	for _, other := range s.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			return model.ErrDuplicateKey
+		}
+	}
+
+	stored := copyRefreshToken(token)
+	stored.ID = s.nextID("refresh_tokens")
+	stored.RevokedAt = nil
+	stored.ReplacedByID = nil
+	stored.CreatedAt = s.timestamp()
+	s.refreshTokens[stored.ID] = stored
+
+	token.ID, token.CreatedAt = stored.ID, stored.CreatedAt
+	return nil
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, token := range s.refreshTokens {
+		if token.TokenHash == tokenHash {
+			return copyRefreshToken(token), nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+// Revoke marks a token as revoked. Revoking an already revoked token keeps
+// its original revocation time.
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id int64, replacedByID *int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	if token.RevokedAt == nil {
+		now := s.timestamp()
+		token.RevokedAt = &now
+	}
+	if replacedByID != nil {
+		replacement := *replacedByID
+		token.ReplacedByID = &replacement
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.timestamp()
+	for _, token := range s.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func copyRefreshToken(token *model.RefreshToken) *model.RefreshToken {
+	copied := *token
+	if token.RevokedAt != nil {
+		revokedAt := *token.RevokedAt
+		copied.RevokedAt = &revokedAt
+	}
+	if token.ReplacedByID != nil {
+		replacedByID := *token.ReplacedByID
+		copied.ReplacedByID = &replacedByID
+	}
+	return &copied
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type reviewLogRepository struct {
+	store *Store
+}
+
+func NewReviewLogRepository(store *Store) repository.ReviewLogRepository {
+	return &reviewLogRepository{store: store}
+}
+
+func (r *reviewLogRepository) Create(ctx context.Context, log *model.ReviewLog) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[log.CardScheduleID]; !ok {
+		return foreignKeyError("review_logs", "card_schedule_id", log.CardScheduleID)
+	}
+	if _, ok := s.users[log.UserID]; !ok {
+		return foreignKeyError("review_logs", "user_id", log.UserID)
+	}
+
+	stored := *log
+	stored.ID = s.nextID("review_logs")
+	s.reviewLogs[stored.ID] = &stored
+
+	log.ID = stored.ID
+	return nil
+}
+
+func (r *reviewLogRepository) GetByID(ctx context.Context, id int64) (*model.ReviewLog, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log, ok := s.reviewLogs[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	copied := *log
+	return &copied, nil
+}
+
+// GetByUserID lists a user's reviews, most recent first.
+func (r *reviewLogRepository) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.ReviewLog], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.ReviewLog]{}, err
+	}
+	if cursor != nil && cursor.ReviewedAt == nil {
+		return model.Page[*model.ReviewLog]{}, model.NewValidationError("cursor", "is malformed")
+	}
+
+	logs := r.store.selectReviewLogs(func(log *model.ReviewLog) bool {
+		return log.UserID == userID && (cursor == nil || reviewedBefore(log, *cursor.ReviewedAt, cursor.ID))
+	})
+	sort.Slice(logs, func(i, j int) bool {
+		return reviewedBefore(logs[j], logs[i].ReviewedAt, logs[i].ID)
+	})
+	return model.NewPage(limit(logs, page.Limit), page.Limit, model.ReviewLogCursor), nil
+}
+
+func (r *reviewLogRepository) GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*model.ReviewLog, error) {
+	logs := r.store.selectReviewLogs(func(log *model.ReviewLog) bool {
+		return log.UserID == userID && !log.ReviewedAt.Before(start) && log.ReviewedAt.Before(end)
+	})
+	sort.Slice(logs, func(i, j int) bool {
+		return reviewedBefore(logs[i], logs[j].ReviewedAt, logs[j].ID)
+	})
+	return logs, nil
+}
+
+func (r *reviewLogRepository) GetBySchedules(ctx context.Context, scheduleIDs []int64) ([]*model.ReviewLog, error) {
+	wanted := make(map[int64]bool, len(scheduleIDs))
+	for _, id := range scheduleIDs {
+		wanted[id] = true
+	}
+	logs := r.store.selectReviewLogs(func(log *model.ReviewLog) bool {
+		return wanted[log.CardScheduleID]
+	})
+	sort.Slice(logs, func(i, j int) bool {
+		return reviewedBefore(logs[i], logs[j].ReviewedAt, logs[j].ID)
+	})
+	return logs, nil
+}
+
+func (s *Store) selectReviewLogs(keep func(*model.ReviewLog) bool) []*model.ReviewLog {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var logs []*model.ReviewLog
+	for _, log := range s.reviewLogs {
+		if keep(log) {
+			copied := *log
+			logs = append(logs, &copied)
+		}
+	}
+	return logs
+}
+
+// reviewedBefore reports whether log sorts before (reviewedAt, id).
+func reviewedBefore(log *model.ReviewLog, reviewedAt time.Time, id int64) bool {
+	if !log.ReviewedAt.Equal(reviewedAt) {
+		return log.ReviewedAt.Before(reviewedAt)
+	}
+	return log.ID < id
+}
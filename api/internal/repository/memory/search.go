@@ -0,0 +1,202 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/query"
+	"memwright/api/internal/repository"
+)
+
+type searchRepository struct {
+	store *Store
+}
+
+// NewSearchRepository searches like the "simple" text search configuration
+// whatever the deck's language: words match exactly, case-insensitively,
+// without stemming or stop words.
+func NewSearchRepository(store *Store) repository.SearchRepository {
+	return &searchRepository{store: store}
+}
+
+// Weights of front, back and extra, the ts_rank defaults for A, B and C.
+var fieldWeights = [3]float64{1.0, 0.4, 0.2}
+
+func (r *searchRepository) Search(ctx context.Context, userID int64, expr query.Node, page model.PageRequest) (model.Page[*model.CardSearchHit], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.CardSearchHit]{}, err
+	}
+	var terms []textTerm
+	for _, text := range query.Terms(expr) {
+		terms = append(terms, newTextTerm(text))
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+
+	var hits []*model.CardSearchHit
+	for _, card := range s.userCards(userID) {
+		fields := [3][]string{searchWords(card.Front), searchWords(card.Back), searchWords(card.Extra)}
+		if !query.Match(expr, s.searchCandidate(card, userID, fields), now) {
+			continue
+		}
+		hit := &model.CardSearchHit{Card: copyCard(card)}
+		if len(terms) > 0 {
+			hit.Rank = rank(terms, fields)
+			hit.FrontSnippet = highlight(terms, card.Front)
+			hit.BackSnippet = highlight(terms, card.Back)
+		}
+		if cursor != nil && cursor.Rank != nil &&
+			!(hit.Rank < *cursor.Rank || (hit.Rank == *cursor.Rank && card.ID > cursor.ID)) {
+			continue
+		}
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Card.ID < hits[j].Card.ID
+	})
+	return model.NewPage(limit(hits, page.Limit), page.Limit, model.SearchHitCursor), nil
+}
+
+// searchCandidate gathers what a query may ask about a card.
+func (s *Store) searchCandidate(card *model.Card, userID int64, fields [3][]string) query.Candidate {
+	all := append(append(append([]string(nil), fields[0]...), fields[1]...), fields[2]...)
+	candidate := query.Candidate{
+		Card: card,
+		Text: func(text query.Text) bool {
+			return newTextTerm(text).occurrencesIn(all) > 0
+		},
+	}
+	var names []string
+	for deck := s.decks[card.DeckID]; deck != nil; {
+		names = append([]string{deck.Name}, names...)
+		candidate.DeckIDs = append(candidate.DeckIDs, deck.ID)
+		if deck.ParentID == nil {
+			break
+		}
+		deck = s.decks[*deck.ParentID]
+	}
+	candidate.DeckPath = strings.Join(names, "::")
+
+	for _, schedule := range s.schedules {
+		if schedule.CardID == card.ID && schedule.UserID == userID {
+			candidate.Schedule = schedule
+		}
+	}
+	if candidate.Schedule != nil {
+		for _, log := range s.reviewLogs {
+			if log.CardScheduleID == candidate.Schedule.ID {
+				candidate.ReviewedAt = append(candidate.ReviewedAt, log.ReviewedAt)
+			}
+		}
+	}
+	return candidate
+}
+
+// textTerm is a word or a phrase. Like plainto_tsquery, the words of an
+// unquoted term may occur anywhere; like phraseto_tsquery, those of a
+// phrase must be consecutive.
+type textTerm struct {
+	words  []string
+	phrase bool
+}
+
+func newTextTerm(text query.Text) textTerm {
+	return textTerm{words: searchWords(text.Text), phrase: text.Phrase}
+}
+
+// rank weighs every occurrence of a term by the field it is in.
+func rank(terms []textTerm, fields [3][]string) float64 {
+	var rank float64
+	for _, term := range terms {
+		for i, field := range fields {
+			rank += fieldWeights[i] * float64(term.occurrencesIn(field))
+		}
+	}
+	return rank
+}
+
+// highlight wraps every word of a term in <mark> tags.
+func highlight(terms []textTerm, text string) string {
+	wanted := map[string]bool{}
+	for _, term := range terms {
+		for _, word := range term.words {
+			wanted[word] = true
+		}
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isSearchRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isSearchRune(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		if wanted[strings.ToLower(word)] {
+			word = model.HighlightStart + word + model.HighlightStop
+		}
+		b.WriteString(word)
+		i = end
+	}
+	return b.String()
+}
+
+func (t textTerm) occurrencesIn(words []string) int {
+	if len(t.words) == 0 {
+		return 0
+	}
+	if !t.phrase {
+		count := 0
+		for _, word := range t.words {
+			n := 0
+			for _, candidate := range words {
+				if candidate == word {
+					n++
+				}
+			}
+			if n == 0 {
+				return 0
+			}
+			count += n
+		}
+		return count
+	}
+	count := 0
+	for i := 0; i+len(t.words) <= len(words); i++ {
+		matched := true
+		for j, word := range t.words {
+			if words[i+j] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+		}
+	}
+	return count
+}
+
+// searchWords splits text into lower-case runs of letters and digits.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isSearchRune(r) })
+}
+
+func isSearchRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,186 @@
+// Package memory implements the repositories on top of in-process maps. It
+// mirrors the PostgreSQL schema: unique constraints, foreign keys with
+// ON DELETE CASCADE and the ordering of every query. It backs the tests and
+// the server's demo mode.
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// Store holds the tables shared by the repositories built on it. All
+// repositories of one Store see each other's writes, like tables of a
+// single database.
+type Store struct {
+	mu sync.RWMutex
+	// txMu serializes units of work; reads share it.
+	txMu sync.RWMutex
+
+	users         map[int64]*model.User
+	decks         map[int64]*model.Deck
+	cards         map[int64]*model.Card
+	schedules     map[int64]*model.CardSchedule
+	reviewLogs    map[int64]*model.ReviewLog
+	refreshTokens map[int64]*model.RefreshToken
+
+	// sequences play the role of the BIGSERIAL columns, one per table.
+	sequences map[string]int64
+	// lastDeletion is the latest deleted_at handed out.
+	lastDeletion time.Time
+
+	now func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:         map[int64]*model.User{},
+		decks:         map[int64]*model.Deck{},
+		cards:         map[int64]*model.Card{},
+		schedules:     map[int64]*model.CardSchedule{},
+		reviewLogs:    map[int64]*model.ReviewLog{},
+		refreshTokens: map[int64]*model.RefreshToken{},
+		sequences:     map[string]int64{},
+		now:           time.Now,
+	}
+}
+
+// WithClock replaces the clock used for created_at and updated_at.
+func (s *Store) WithClock(now func() time.Time) *Store {
+	s.now = now
+	return s
+}
+
+// NewRepositories returns every repository backed by store.
+func NewRepositories(store *Store) repository.Repositories {
+	return repository.Repositories{
+		Decks:         NewDeckRepository(store),
+		Cards:         NewCardRepository(store),
+		Tags:          NewTagRepository(store),
+		Search:        NewSearchRepository(store),
+		Schedules:     NewCardScheduleRepository(store),
+		ReviewLogs:    NewReviewLogRepository(store),
+		Users:         NewUserRepository(store),
+		RefreshTokens: NewRefreshTokenRepository(store),
+	}
+}
+
+func (s *Store) nextID(table string) int64 {
+	s.sequences[table]++
+	return s.sequences[table]
+}
+
+// timestamp matches the microsecond precision of TIMESTAMP WITH TIME ZONE.
+func (s *Store) timestamp() time.Time {
+	return s.now().UTC().Truncate(time.Microsecond)
+}
+
+// deletionStamp is a timestamp later than any earlier one, so that items
+// deleted together, and only those, share a deleted_at as they do when
+// each deletion runs in its own transaction.
+func (s *Store) deletionStamp() time.Time {
+	stamp := s.timestamp()
+	if !stamp.After(s.lastDeletion) {
+		stamp = s.lastDeletion.Add(time.Microsecond)
+	}
+	s.lastDeletion = stamp
+	return stamp
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return timePtr(*t)
+}
+
+// isExpired reports whether an item deleted at deletedAt is due for a
+// purge with the given cutoff.
+func isExpired(deletedAt *time.Time, cutoff time.Time) bool {
+	return deletedAt != nil && deletedAt.Before(cutoff)
+}
+
+// deletedBefore reports whether an item sorts after the cursor in the
+// trash, which lists the most recently deleted first.
+func deletedBefore(deletedAt time.Time, id int64, cursor *model.Cursor) bool {
+	if !deletedAt.Equal(*cursor.DeletedAt) {
+		return deletedAt.Before(*cursor.DeletedAt)
+	}
+	return id < cursor.ID
+}
+
+// foreignKeyError mirrors the error PostgreSQL raises when a referenced row
+// is missing.
+func foreignKeyError(table, column string, id int64) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint: %s %d does not exist", table, column, id)
+}
+
+// The delete helpers cascade like the ON DELETE CASCADE foreign keys. The
+// caller holds the write lock.
+
+func (s *Store) deleteUser(id int64) {
+	delete(s.users, id)
+	for deckID, deck := range s.decks {
+		if deck.UserID == id {
+			s.deleteDeck(deckID)
+		}
+	}
+	for scheduleID, schedule := range s.schedules {
+		if schedule.UserID == id {
+			s.deleteSchedule(scheduleID)
+		}
+	}
+	for logID, log := range s.reviewLogs {
+		if log.UserID == id {
+			delete(s.reviewLogs, logID)
+		}
+	}
+	for tokenID, token := range s.refreshTokens {
+		if token.UserID == id {
+			delete(s.refreshTokens, tokenID)
+		}
+	}
+}
+
+func (s *Store) deleteDeck(id int64) {
+	if _, ok := s.decks[id]; !ok {
+		return
+	}
+	delete(s.decks, id)
+	for childID, child := range s.decks {
+		if child.ParentID != nil && *child.ParentID == id {
+			s.deleteDeck(childID)
+		}
+	}
+	for cardID, card := range s.cards {
+		if card.DeckID == id {
+			s.deleteCard(cardID)
+		}
+	}
+}
+
+func (s *Store) deleteCard(id int64) {
+	delete(s.cards, id)
+	for scheduleID, schedule := range s.schedules {
+		if schedule.CardID == id {
+			s.deleteSchedule(scheduleID)
+		}
+	}
+}
+
+func (s *Store) deleteSchedule(id int64) {
+	delete(s.schedules, id)
+	for logID, log := range s.reviewLogs {
+		if log.CardScheduleID == id {
+			delete(s.reviewLogs, logID)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type tagRepository struct {
+	store *Store
+}
+
+func NewTagRepository(store *Store) repository.TagRepository {
+	return &tagRepository{store: store}
+}
+
+func (r *tagRepository) List(ctx context.Context, userID int64) ([]model.TagCount, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := map[string]*model.TagCount{}
+	for _, card := range s.userCards(userID) {
+		own := make(map[string]bool, len(card.Tags))
+		for _, tag := range card.Tags {
+			own[tag] = true
+		}
+		for _, path := range model.TagPaths(card.Tags) {
+			count := counts[path]
+			if count == nil {
+				count = &model.TagCount{Tag: path}
+				counts[path] = count
+			}
+			count.Total++
+			if own[path] {
+				count.Cards++
+			}
+		}
+	}
+
+	tags := make([]model.TagCount, 0, len(counts))
+	for _, count := range counts {
+		tags = append(tags, *count)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags, nil
+}
+
+func (r *tagRepository) Cards(ctx context.Context, userID int64, tag string, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cards []*model.Card
+	for _, card := range s.userCards(userID) {
+		if cursor != nil && card.ID <= cursor.ID {
+			continue
+		}
+		for _, path := range model.TagPaths(card.Tags) {
+			if path == tag {
+				cards = append(cards, copyCard(card))
+				break
+			}
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return model.NewPage(limit(cards, page.Limit), page.Limit, model.CardIDCursor), nil
+}
+
+func (r *tagRepository) Replace(ctx context.Context, userID int64, sources []string, target string) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed int64
+	for _, card := range s.userCards(userID) {
+		matched := false
+		seen := make(map[string]bool, len(card.Tags))
+		tags := make([]string, 0, len(card.Tags))
+		for _, tag := range card.Tags {
+			tag, ok := model.ReplaceTagPrefix(tag, sources, target)
+			matched = matched || ok
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		if !matched {
+			continue
+		}
+		card.Tags = tags
+		card.Version++
+		card.UpdatedAt = s.timestamp()
+		changed++
+	}
+	return changed, nil
+}
+
+// userCards returns the stored cards in the user's decks, leaving out the
+// trash; callers hold mu.
+func (s *Store) userCards(userID int64) []*model.Card {
+	var cards []*model.Card
+	for _, card := range s.cards {
+		if deck := s.decks[card.DeckID]; deck != nil && deck.UserID == userID && card.DeletedAt == nil {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type unitOfWork struct {
+	store *Store
+}
+
+// NewUnitOfWork runs units against store one at a time. A unit that fails
+// is rolled back by restoring the tables as they were when it started, so
+// writes made outside a unit while it runs are lost with it.
+func NewUnitOfWork(store *Store) repository.UnitOfWork {
+	return &unitOfWork{store: store}
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	s := u.store
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	snapshot := s.snapshot()
+	if err := fn(ctx, NewRepositories(s)); err != nil {
+		s.restore(snapshot)
+		return err
+	}
+	return nil
+}
+
+// Read runs fn while no unit of work runs, so the data it reads does not
+// change under it unless written outside a unit.
+func (u *unitOfWork) Read(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	s := u.store
+	s.txMu.RLock()
+	defer s.txMu.RUnlock()
+
+	return fn(ctx, NewRepositories(s))
+}
+
+type tables struct {
+	users         map[int64]*model.User
+	decks         map[int64]*model.Deck
+	cards         map[int64]*model.Card
+	schedules     map[int64]*model.CardSchedule
+	reviewLogs    map[int64]*model.ReviewLog
+	refreshTokens map[int64]*model.RefreshToken
+}
+
+// snapshot deep-copies every table. Like PostgreSQL sequences, the ID
+// counters are not part of it and keep advancing after a rollback.
+func (s *Store) snapshot() tables {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := tables{
+		users:         make(map[int64]*model.User, len(s.users)),
+		decks:         make(map[int64]*model.Deck, len(s.decks)),
+		cards:         make(map[int64]*model.Card, len(s.cards)),
+		schedules:     make(map[int64]*model.CardSchedule, len(s.schedules)),
+		reviewLogs:    make(map[int64]*model.ReviewLog, len(s.reviewLogs)),
+		refreshTokens: make(map[int64]*model.RefreshToken, len(s.refreshTokens)),
+	}
+	for id, user := range s.users {
+		copied := *user
+		snapshot.users[id] = &copied
+	}
+	for id, deck := range s.decks {
+		copied := *deck
+		if deck.ParentID != nil {
+			parentID := *deck.ParentID
+			copied.ParentID = &parentID
+		}
+		// The stored config is never mutated in place, only replaced.
+		snapshot.decks[id] = &copied
+	}
+	for id, card := range s.cards {
+		snapshot.cards[id] = copyCard(card)
+	}
+	for id, schedule := range s.schedules {
+		snapshot.schedules[id] = copySchedule(schedule)
+	}
+	for id, log := range s.reviewLogs {
+		copied := *log
+		snapshot.reviewLogs[id] = &copied
+	}
+	for id, token := range s.refreshTokens {
+		snapshot.refreshTokens[id] = copyRefreshToken(token)
+	}
+	return snapshot
+}
+
+func (s *Store) restore(snapshot tables) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = snapshot.users
+	s.decks = snapshot.decks
+	s.cards = snapshot.cards
+	s.schedules = snapshot.schedules
+	s.reviewLogs = snapshot.reviewLogs
+	s.refreshTokens = snapshot.refreshTokens
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type userRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{store: store}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(user.Email, 0) {
+		return model.ErrDuplicateEmail
+	}
+
+	stored := *user
+	stored.ID = s.nextID("users")
+	stored.CreatedAt = s.timestamp()
+	stored.UpdatedAt = stored.CreatedAt
+	s.users[stored.ID] = &stored
+
+	user.ID, user.CreatedAt, user.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+
+// Update saves the profile fields. The password hash is not changed.
+func (r *userRepository) Update(ctx context.Context, user *model.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	if s.emailTaken(user.Email, user.ID) {
+		return model.ErrDuplicateEmail
+	}
+
+	stored := *user
+	stored.PasswordHash = existing.PasswordHash
+	stored.CreatedAt = existing.CreatedAt
+	stored.UpdatedAt = s.timestamp()
+	s.users[stored.ID] = &stored
+
+	user.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return model.ErrNotFound
+	}
+	s.deleteUser(id)
+	return nil
+}
+
+// emailTaken enforces users_email_key. Like the column, the comparison is
+// case-sensitive; the auth service lower-cases emails before storing them.
+func (s *Store) emailTaken(email string, exceptID int64) bool {
+	for _, user := range s.users {
+		if user.ID != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
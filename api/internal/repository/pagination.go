@@ -0,0 +1,12 @@
+package repository
+
+import "strconv"
+
+// limitClause fetches one row more than the page size so NewPage can tell
+// whether another page follows. A non-positive limit fetches everything.
+func limitClause(limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	return " LIMIT " + strconv.Itoa(limit+1)
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"memwright/api/internal/model"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *model.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	Revoke(ctx context.Context, id int64, replacedByID *int64) error
+	RevokeAllForUser(ctx context.Context, userID int64) error
+}
+
+type refreshTokenRepository struct {
+	db DB
+}
+
+func NewRefreshTokenRepository(db DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *model.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID,
+		token.TokenHash,
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if isDuplicateKeyError(err, "refresh_tokens_token_hash_key") {
+		return model.ErrDuplicateKey
+	}
+	return err
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, revoked_at, replaced_by_id, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1`
+
+	token := &model.RefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.ReplacedByID,
+		&token.CreatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, model.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Revoke marks a token as revoked. Revoking an already revoked token keeps
+// its original revocation time.
+func (r *refreshTokenRepository) Revoke(ctx context.Context, id int64, replacedByID *int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = COALESCE(revoked_at, NOW()), replaced_by_id = COALESCE($2, replaced_by_id)
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id, replacedByID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return model.ErrNotFound
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -14,3 +14,30 @@ type DB interface {
 type TxBeginner interface {
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
+
+// Repositories bundles one implementation of every repository, so callers
+// can wire services against PostgreSQL or another backend alike.
+type Repositories struct {
+	Decks         DeckRepository
+	Cards         CardRepository
+	Tags          TagRepository
+	Search        SearchRepository
+	Schedules     CardScheduleRepository
+	ReviewLogs    ReviewLogRepository
+	Users         UserRepository
+	RefreshTokens RefreshTokenRepository
+}
+
+// NewRepositories returns the PostgreSQL repositories running on db.
+func NewRepositories(db DB) Repositories {
+	return Repositories{
+		Decks:         NewDeckRepository(db),
+		Cards:         NewCardRepository(db),
+		Tags:          NewTagRepository(db),
+		Search:        NewSearchRepository(db),
+		Schedules:     NewCardScheduleRepository(db),
+		ReviewLogs:    NewReviewLogRepository(db),
+		Users:         NewUserRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+	}
+}
@@ -0,0 +1,1123 @@
+// Package repositorytest holds the contract every repository implementation
+// must satisfy. The same suite runs against the in-memory repositories in
+// the unit tests and against PostgreSQL in the integration tests.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/query"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/srs"
+)
+
+// Factory returns repositories over an empty database.
+type Factory func(t *testing.T) repository.Repositories
+
+// Run runs the contract suite; each subtest gets fresh repositories.
+func Run(t *testing.T, newRepos Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, f *fixture)
+	}{
+		{"users", testUsers},
+		{"decks", testDecks},
+		{"deck pagination", testDeckPagination},
+		{"deck delete and purge", testDeckDeleteAndPurge},
+		{"trash", testTrash},
+		{"archived decks", testArchivedDecks},
+		{"deck tree", testDeckTree},
+		{"cards", testCards},
+		{"card pagination", testCardPagination},
+		{"versions", testVersions},
+		{"tags", testTags},
+		{"search", testSearch},
+		{"schedules", testSchedules},
+		{"due and new queues", testQueues},
+		{"card counts", testCardCounts},
+		{"review logs", testReviewLogs},
+		{"refresh tokens", testRefreshTokens},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, &fixture{t: t, ctx: context.Background(), repos: newRepos(t)})
+		})
+	}
+}
+
+// base is a fixed reference time at microsecond precision, so values
+// survive a round trip through TIMESTAMP WITH TIME ZONE unchanged.
+var base = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+type fixture struct {
+	t     *testing.T
+	ctx   context.Context
+	repos repository.Repositories
+}
+
+func (f *fixture) user(email string) *model.User {
+	f.t.Helper()
+	user := &model.User{
+		Email:         email,
+		PasswordHash:  "hash",
+		DisplayName:   "Test",
+		SRSAlgorithm:  model.SRSAlgorithmSM2,
+		DailyNewCards: model.DefaultDailyNewCards,
+		DailyReviews:  model.DefaultDailyReviews,
+	}
+	if err := f.repos.Users.Create(f.ctx, user); err != nil {
+		f.t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func (f *fixture) deck(userID int64, parentID *int64, name string, position int) *model.Deck {
+	f.t.Helper()
+	deck := &model.Deck{UserID: userID, ParentID: parentID, Name: name, Algorithm: model.AlgorithmSM2, Position: position}
+	if err := f.repos.Decks.Create(f.ctx, deck); err != nil {
+		f.t.Fatalf("create deck %q: %v", name, err)
+	}
+	return deck
+}
+
+func (f *fixture) card(deckID int64, front string, position int) *model.Card {
+	f.t.Helper()
+	card := &model.Card{DeckID: deckID, Type: model.CardTypeBasic, Front: front, Position: position}
+	if err := f.repos.Cards.Create(f.ctx, card); err != nil {
+		f.t.Fatalf("create card %q: %v", front, err)
+	}
+	return card
+}
+
+func (f *fixture) schedule(cardID, userID int64, state model.ScheduleState, dueAt time.Time) *model.CardSchedule {
+	f.t.Helper()
+	schedule := &model.CardSchedule{CardID: cardID, UserID: userID, State: state, DueAt: dueAt, EaseFactor: model.DefaultEaseFactor}
+	if err := f.repos.Schedules.Create(f.ctx, schedule); err != nil {
+		f.t.Fatalf("create schedule: %v", err)
+	}
+	return schedule
+}
+
+func expectErr(t *testing.T, what string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("%s: error = %v, want %v", what, err, want)
+	}
+}
+
+func testUsers(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	if user.ID == 0 || user.CreatedAt.IsZero() {
+		t.Fatalf("expected ID and timestamps to be set, got %+v", user)
+	}
+
+	byEmail, err := f.repos.Users.GetByEmail(f.ctx, "ada@example.com")
+	if err != nil || byEmail.ID != user.ID || byEmail.PasswordHash != "hash" {
+		t.Errorf("GetByEmail() = %+v, %v", byEmail, err)
+	}
+	_, err = f.repos.Users.GetByEmail(f.ctx, "nobody@example.com")
+	expectErr(t, "GetByEmail(unknown)", err, model.ErrNotFound)
+
+	err = f.repos.Users.Create(f.ctx, &model.User{Email: "ada@example.com", PasswordHash: "x", SRSAlgorithm: model.SRSAlgorithmSM2})
+	expectErr(t, "Create(duplicate email)", err, model.ErrDuplicateEmail)
+
+	other := f.user("grace@example.com")
+	other.Email = "ada@example.com"
+	expectErr(t, "Update(duplicate email)", f.repos.Users.Update(f.ctx, other), model.ErrDuplicateEmail)
+
+	user.DisplayName = "Ada"
+	user.DailyNewCards = 5
+	if err := f.repos.Users.Update(f.ctx, user); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	stored, err := f.repos.Users.GetByID(f.ctx, user.ID)
+	if err != nil || stored.DisplayName != "Ada" || stored.DailyNewCards != 5 {
+		t.Errorf("GetByID() after update = %+v, %v", stored, err)
+	}
+
+	expectErr(t, "Update(missing)", f.repos.Users.Update(f.ctx, &model.User{ID: 999_999, Email: "x@example.com"}), model.ErrNotFound)
+	if err := f.repos.Users.Delete(f.ctx, user.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = f.repos.Users.GetByID(f.ctx, user.ID)
+	expectErr(t, "GetByID(deleted)", err, model.ErrNotFound)
+	expectErr(t, "Delete(deleted)", f.repos.Users.Delete(f.ctx, user.ID), model.ErrNotFound)
+}
+
+func testDecks(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	root := f.deck(user.ID, nil, "Spanish", 0)
+	f.deck(user.ID, &root.ID, "Verbs", 0)
+
+	err := f.repos.Decks.Create(f.ctx, &model.Deck{UserID: user.ID, ParentID: &root.ID, Name: "Verbs", Algorithm: model.AlgorithmSM2})
+	expectErr(t, "Create(duplicate sibling)", err, model.ErrDuplicateName)
+
+	err = f.repos.Decks.Create(f.ctx, &model.Deck{UserID: user.ID, Name: "Spanish", Algorithm: model.AlgorithmSM2, Position: 1})
+	expectErr(t, "Create(duplicate top-level deck)", err, model.ErrDuplicateName)
+	// Other users' decks don't conflict.
+	f.deck(f.user("grace@example.com").ID, nil, "Spanish", 0)
+
+	nouns := f.deck(user.ID, &root.ID, "Nouns", 1)
+	nouns.Name = "Verbs"
+	expectErr(t, "Update(duplicate sibling)", f.repos.Decks.Update(f.ctx, nouns), model.ErrDuplicateName)
+
+	nouns.Name = "Nouns and articles"
+	nouns.Description = "der, die, das"
+	if err := f.repos.Decks.Update(f.ctx, nouns); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	config := &model.SRSConfig{SM2: &srs.SM2Config{InitialEaseFactor: 2.3, MinEaseFactor: 1.3, MaxEaseFactor: 3, GraduatingInterval: 2}}
+	if err := f.repos.Decks.UpdateSRSConfig(f.ctx, nouns.ID, config); err != nil {
+		t.Fatalf("UpdateSRSConfig() error = %v", err)
+	}
+	config.SM2.InitialEaseFactor = 9 // the stored copy must not alias the caller's value
+
+	stored, err := f.repos.Decks.GetByID(f.ctx, nouns.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.Name != "Nouns and articles" || stored.Description != "der, die, das" || stored.ParentID == nil || *stored.ParentID != root.ID {
+		t.Errorf("unexpected deck %+v", stored)
+	}
+	if got := stored.GetSM2Config(); got.InitialEaseFactor != 2.3 || got.GraduatingInterval != 2 {
+		t.Errorf("unexpected SRS config %+v", got)
+	}
+
+	expectErr(t, "UpdateSRSConfig(missing)", f.repos.Decks.UpdateSRSConfig(f.ctx, 999_999, config), model.ErrNotFound)
+	expectErr(t, "Update(missing)", f.repos.Decks.Update(f.ctx, &model.Deck{ID: 999_999, UserID: user.ID, Name: "x", Algorithm: model.AlgorithmSM2}), model.ErrNotFound)
+	_, err = f.repos.Decks.GetByID(f.ctx, 999_999)
+	expectErr(t, "GetByID(missing)", err, model.ErrNotFound)
+}
+
+func testDeckPagination(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	f.deck(other.ID, nil, "Foreign", 0)
+	for _, deck := range []struct {
+		name     string
+		position int
+	}{{"b", 1}, {"B", 1}, {"a", 1}, {"z", 0}} {
+		parent := f.deck(user.ID, nil, deck.name, deck.position)
+		f.deck(user.ID, &parent.ID, "sub"+deck.name, 0)
+	}
+
+	var names []string
+	page := model.PageRequest{Limit: 3}
+	for i := 0; ; i++ {
+		if i > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		result, err := f.repos.Decks.GetByUserID(f.ctx, user.ID, page)
+		if err != nil {
+			t.Fatalf("GetByUserID() error = %v", err)
+		}
+		for _, deck := range result.Items {
+			names = append(names, deck.Name)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+
+	// Top-level decks only, position first, then names bytewise: upper case
+	// sorts before lower.
+	if got := strings.Join(names, ","); got != "z,B,a,b" {
+		t.Errorf("unexpected order %s", got)
+	}
+}
+
+func testDeckDeleteAndPurge(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	root := f.deck(user.ID, nil, "Spanish", 0)
+	child := f.deck(user.ID, &root.ID, "Verbs", 0)
+	card := f.card(child.ID, "hablar", 0)
+	schedule := f.schedule(card.ID, user.ID, model.ScheduleStateReview, base)
+	log := &model.ReviewLog{CardScheduleID: schedule.ID, UserID: user.ID, Rating: model.ReviewRatingCorrect, PreviousState: model.ScheduleStateReview, NewState: model.ScheduleStateReview, ReviewedAt: base}
+	if err := f.repos.ReviewLogs.Create(f.ctx, log); err != nil {
+		t.Fatalf("create review log: %v", err)
+	}
+
+	if err := f.repos.Decks.Delete(f.ctx, root.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err := f.repos.Decks.GetByID(f.ctx, child.ID)
+	expectErr(t, "GetByID(child deck)", err, model.ErrNotFound)
+	_, err = f.repos.Cards.GetByID(f.ctx, card.ID)
+	expectErr(t, "GetByID(card)", err, model.ErrNotFound)
+	expectErr(t, "Delete(deleted)", f.repos.Decks.Delete(f.ctx, root.ID), model.ErrNotFound)
+	f.deck(user.ID, nil, "Spanish", 0)
+
+	trashed, err := f.repos.Decks.GetDeleted(f.ctx, child.ID)
+	if err != nil || trashed.DeletedAt == nil {
+		t.Fatalf("GetDeleted(child deck) = %+v, %v", trashed, err)
+	}
+	_, err = f.repos.Decks.GetDeleted(f.ctx, f.deck(user.ID, nil, "French", 1).ID)
+	expectErr(t, "GetDeleted(live deck)", err, model.ErrNotFound)
+	if _, err := f.repos.Schedules.GetByID(f.ctx, schedule.ID); err != nil {
+		t.Errorf("expected the schedule to outlive a soft delete, got %v", err)
+	}
+
+	purged, err := f.repos.Decks.Purge(f.ctx, trashed.DeletedAt.Add(-time.Second))
+	if err != nil || purged != 0 {
+		t.Errorf("Purge(before deletion) = %d, %v", purged, err)
+	}
+	purged, err = f.repos.Decks.Purge(f.ctx, trashed.DeletedAt.Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Fatalf("Purge() = %d, %v", purged, err)
+	}
+	_, err = f.repos.Decks.GetDeleted(f.ctx, child.ID)
+	expectErr(t, "GetDeleted(purged deck)", err, model.ErrNotFound)
+	_, err = f.repos.Cards.GetDeleted(f.ctx, card.ID)
+	expectErr(t, "GetDeleted(purged card)", err, model.ErrNotFound)
+	_, err = f.repos.Schedules.GetByID(f.ctx, schedule.ID)
+	expectErr(t, "GetByID(schedule)", err, model.ErrNotFound)
+	_, err = f.repos.ReviewLogs.GetByID(f.ctx, log.ID)
+	expectErr(t, "GetByID(review log)", err, model.ErrNotFound)
+}
+
+func testTrash(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	spanish := f.deck(user.ID, nil, "Spanish", 0)
+	verbs := f.deck(user.ID, &spanish.ID, "Verbs", 0)
+	nouns := f.deck(user.ID, &spanish.ID, "Nouns", 1)
+	hola := f.card(spanish.ID, "hola", 0)
+	adios := f.card(spanish.ID, "adios", 1)
+	hablar := f.card(verbs.ID, "hablar", 0)
+	french := f.deck(user.ID, nil, "French", 1)
+	f.deck(other.ID, nil, "Foreign", 0)
+
+	if err := f.repos.Cards.Delete(f.ctx, adios.ID); err != nil {
+		t.Fatalf("Delete(card) error = %v", err)
+	}
+	expectErr(t, "Delete(deleted card)", f.repos.Cards.Delete(f.ctx, adios.ID), model.ErrNotFound)
+	cards, err := f.repos.Cards.Trash(f.ctx, user.ID, time.Time{}, model.PageRequest{})
+	if err != nil || len(cards.Items) != 1 || cards.Items[0].ID != adios.ID || cards.Items[0].DeletedAt == nil {
+		t.Fatalf("Cards.Trash() = %+v, %v", cards.Items, err)
+	}
+	for _, id := range []int64{nouns.ID, spanish.ID, french.ID} {
+		if err := f.repos.Decks.Delete(f.ctx, id); err != nil {
+			t.Fatalf("Delete(deck) error = %v", err)
+		}
+	}
+
+	first, err := f.repos.Decks.Trash(f.ctx, user.ID, time.Time{}, model.PageRequest{Limit: 1})
+	if err != nil || len(first.Items) != 1 || first.NextCursor == "" {
+		t.Fatalf("Decks.Trash(limit 1) = %+v, %v", first, err)
+	}
+	second, err := f.repos.Decks.Trash(f.ctx, user.ID, time.Time{}, model.PageRequest{Cursor: first.NextCursor, Limit: 1})
+	if err != nil || len(second.Items) != 1 || second.NextCursor != "" {
+		t.Fatalf("Decks.Trash(second page) = %+v, %v", second, err)
+	}
+	if ids := []int64{first.Items[0].ID, second.Items[0].ID}; !(ids[0] == french.ID && ids[1] == spanish.ID) && !(ids[0] == spanish.ID && ids[1] == french.ID) {
+		t.Errorf("expected only the deleted top-level decks, got %v", ids)
+	}
+	recent, err := f.repos.Decks.Trash(f.ctx, user.ID, first.Items[0].DeletedAt.Add(time.Second), model.PageRequest{})
+	if err != nil || len(recent.Items) != 0 {
+		t.Errorf("Decks.Trash(after every deletion) = %+v, %v", recent.Items, err)
+	}
+	cards, _ = f.repos.Cards.Trash(f.ctx, user.ID, time.Time{}, model.PageRequest{})
+	if len(cards.Items) != 0 {
+		t.Errorf("expected cards of trashed decks to stay with their deck, got %+v", cards.Items)
+	}
+
+	if err := f.repos.Decks.Restore(f.ctx, spanish.ID); err != nil {
+		t.Fatalf("Restore(deck) error = %v", err)
+	}
+	expectErr(t, "Restore(live deck)", f.repos.Decks.Restore(f.ctx, spanish.ID), model.ErrNotFound)
+	restored, err := f.repos.Decks.GetByID(f.ctx, verbs.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Version != verbs.Version+1 {
+		t.Errorf("GetByID(restored subdeck) = %+v, %v", restored, err)
+	}
+	for _, id := range []int64{hola.ID, hablar.ID} {
+		if _, err := f.repos.Cards.GetByID(f.ctx, id); err != nil {
+			t.Errorf("GetByID(restored card %d) error = %v", id, err)
+		}
+	}
+	_, err = f.repos.Cards.GetByID(f.ctx, adios.ID)
+	expectErr(t, "GetByID(card deleted on its own)", err, model.ErrNotFound)
+	_, err = f.repos.Decks.GetByID(f.ctx, nouns.ID)
+	expectErr(t, "GetByID(subdeck deleted on its own)", err, model.ErrNotFound)
+	f.deck(user.ID, &spanish.ID, "Nouns", 1)
+	expectErr(t, "Restore(taken name)", f.repos.Decks.Restore(f.ctx, nouns.ID), model.ErrDuplicateName)
+
+	if err := f.repos.Cards.Restore(f.ctx, adios.ID); err != nil {
+		t.Fatalf("Restore(card) error = %v", err)
+	}
+	expectErr(t, "Restore(live card)", f.repos.Cards.Restore(f.ctx, adios.ID), model.ErrNotFound)
+	if err := f.repos.Cards.Delete(f.ctx, hola.ID); err != nil {
+		t.Fatalf("Delete(card) error = %v", err)
+	}
+	deleted, _ := f.repos.Cards.GetDeleted(f.ctx, hola.ID)
+	purged, err := f.repos.Cards.Purge(f.ctx, deleted.DeletedAt.Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Errorf("Cards.Purge() = %d, %v", purged, err)
+	}
+	if page, _ := f.repos.Cards.GetByDeckID(f.ctx, spanish.ID, model.PageRequest{}); len(page.Items) != 1 || page.Items[0].ID != adios.ID {
+		t.Errorf("GetByDeckID() after purge = %+v", page.Items)
+	}
+}
+
+func testArchivedDecks(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	spanish := f.deck(user.ID, nil, "Spanish", 0)
+	verbs := f.deck(user.ID, &spanish.ID, "Verbs", 0)
+	f.schedule(f.card(verbs.ID, "hablar", 0).ID, user.ID, model.ScheduleStateReview, base.Add(-time.Hour))
+	f.schedule(f.card(verbs.ID, "comer", 1).ID, user.ID, model.ScheduleStateNew, base)
+
+	spanish.ArchivedAt = &base
+	if err := f.repos.Decks.Update(f.ctx, spanish); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	stored, err := f.repos.Decks.GetByID(f.ctx, spanish.ID)
+	if err != nil || stored.ArchivedAt == nil || !stored.ArchivedAt.Equal(base) {
+		t.Fatalf("GetByID() = %+v, %v", stored, err)
+	}
+	due, err := f.repos.Schedules.GetDueCards(f.ctx, user.ID, verbs.ID, base, 10)
+	if err != nil || len(due) != 0 {
+		t.Errorf("GetDueCards(archived subdeck) = %v, %v", due, err)
+	}
+	fresh, err := f.repos.Schedules.GetNewCards(f.ctx, user.ID, verbs.ID, 10)
+	if err != nil || len(fresh) != 0 {
+		t.Errorf("GetNewCards(archived subdeck) = %v, %v", fresh, err)
+	}
+	counts, err := f.repos.Decks.GetCardCounts(f.ctx, user.ID, base)
+	if err != nil || len(counts) != 0 {
+		t.Errorf("GetCardCounts() = %v, %v", counts, err)
+	}
+
+	stored.ArchivedAt = nil
+	if err := f.repos.Decks.Update(f.ctx, stored); err != nil {
+		t.Fatalf("Update(unarchive) error = %v", err)
+	}
+	due, _ = f.repos.Schedules.GetDueCards(f.ctx, user.ID, verbs.ID, base, 10)
+	fresh, _ = f.repos.Schedules.GetNewCards(f.ctx, user.ID, verbs.ID, 10)
+	if len(due) != 1 || len(fresh) != 1 {
+		t.Errorf("expected an unarchived deck back in the queues, got %d due and %d new", len(due), len(fresh))
+	}
+}
+
+func testCards(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	target := f.deck(user.ID, nil, "French", 1)
+
+	card := &model.Card{DeckID: deck.ID, Type: model.CardTypeCloze, Front: "{{c1::hola}}", Back: "hello", Extra: "greeting", Tags: []string{"greetings", "spanish", "a,b", `quote "q"`, `back\slash`, "NULL", "{braces}", " padded ", "日本語"}}
+	if err := f.repos.Cards.Create(f.ctx, card); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	card.Tags[0] = "mutated"
+
+	stored, err := f.repos.Cards.GetByID(f.ctx, card.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if stored.Type != model.CardTypeCloze || stored.Extra != "greeting" || strings.Join(stored.Tags, "|") != `greetings|spanish|a,b|quote "q"|back\slash|NULL|{braces}| padded |日本語` {
+		t.Errorf("unexpected card %+v", stored)
+	}
+
+	stored.DeckID = target.ID
+	stored.Tags = []string{}
+	stored.Suspended = true
+	if err := f.repos.Cards.Update(f.ctx, stored); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	updated, _ := f.repos.Cards.GetByID(f.ctx, card.ID)
+	if updated.DeckID != target.ID || !updated.Suspended || updated.Tags != nil {
+		t.Errorf("unexpected updated card %+v", updated)
+	}
+
+	expectErr(t, "Update(missing)", f.repos.Cards.Update(f.ctx, &model.Card{ID: 999_999, DeckID: deck.ID, Type: model.CardTypeBasic, Front: "x"}), model.ErrNotFound)
+	if err := f.repos.Cards.Delete(f.ctx, card.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	expectErr(t, "Delete(deleted)", f.repos.Cards.Delete(f.ctx, card.ID), model.ErrNotFound)
+	if err := f.repos.Cards.Create(f.ctx, &model.Card{DeckID: 999_999, Type: model.CardTypeBasic, Front: "orphan"}); err == nil {
+		t.Error("expected a card in a missing deck to be rejected")
+	}
+}
+
+func testVersions(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	card := f.card(deck.ID, "hola", 0)
+	if deck.Version != 1 || card.Version != 1 {
+		t.Fatalf("expected new rows at version 1, got deck %d and card %d", deck.Version, card.Version)
+	}
+
+	// Two clients read the deck; the second writer loses.
+	first, _ := f.repos.Decks.GetByID(f.ctx, deck.ID)
+	second, _ := f.repos.Decks.GetByID(f.ctx, deck.ID)
+	first.Description = "first"
+	if err := f.repos.Decks.Update(f.ctx, first); err != nil || first.Version != 2 {
+		t.Fatalf("Update() = %v, version %d", err, first.Version)
+	}
+	second.Description = "second"
+	expectErr(t, "Update(stale deck)", f.repos.Decks.Update(f.ctx, second), model.ErrConflict)
+
+	if err := f.repos.Decks.UpdateSRSConfig(f.ctx, deck.ID, model.DefaultSRSConfig()); err != nil {
+		t.Fatalf("UpdateSRSConfig() error = %v", err)
+	}
+	expectErr(t, "Update(deck before SRS config change)", f.repos.Decks.Update(f.ctx, first), model.ErrConflict)
+	stored, _ := f.repos.Decks.GetByID(f.ctx, deck.ID)
+	if stored.Version != 3 || stored.Description != "first" {
+		t.Errorf("unexpected deck %+v", stored)
+	}
+
+	staleCard, _ := f.repos.Cards.GetByID(f.ctx, card.ID)
+	card.Back = "hello"
+	if err := f.repos.Cards.Update(f.ctx, card); err != nil || card.Version != 2 {
+		t.Fatalf("Update() = %v, version %d", err, card.Version)
+	}
+	staleCard.Back = "hi"
+	expectErr(t, "Update(stale card)", f.repos.Cards.Update(f.ctx, staleCard), model.ErrConflict)
+	if storedCard, _ := f.repos.Cards.GetByID(f.ctx, card.ID); storedCard.Version != 2 || storedCard.Back != "hello" {
+		t.Errorf("unexpected card %+v", storedCard)
+	}
+}
+
+func testTags(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	subdeck := f.deck(user.ID, &deck.ID, "Verbs", 0)
+	foreign := f.deck(other.ID, nil, "Foreign", 0)
+
+	tagged := func(deckID int64, front string, tags ...string) *model.Card {
+		t.Helper()
+		card := &model.Card{DeckID: deckID, Type: model.CardTypeBasic, Front: front, Tags: tags}
+		if err := f.repos.Cards.Create(f.ctx, card); err != nil {
+			t.Fatalf("create card %q: %v", front, err)
+		}
+		return card
+	}
+	hablar := tagged(subdeck.ID, "hablar", "lang::es::verbs", "lang::es")
+	hola := tagged(deck.ID, "hola", "lang::es::greetings", "Lang")
+	bonjour := tagged(deck.ID, "bonjour", "lang::fr", "language")
+	tagged(deck.ID, "untagged")
+	tagged(foreign.ID, "foreign", "lang::es")
+
+	tags, err := f.repos.Tags.List(f.ctx, user.ID)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var listed []string
+	for _, tag := range tags {
+		listed = append(listed, fmt.Sprintf("%s=%d/%d", tag.Tag, tag.Cards, tag.Total))
+	}
+	want := "Lang=1/1,lang=0/3,lang::es=1/2,lang::es::greetings=1/1,lang::es::verbs=1/1,lang::fr=1/1,language=1/1"
+	if got := strings.Join(listed, ","); got != want {
+		t.Errorf("List() = %s, want %s", got, want)
+	}
+
+	var fronts []string
+	page := model.PageRequest{Limit: 1}
+	for i := 0; ; i++ {
+		if i > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		result, err := f.repos.Tags.Cards(f.ctx, user.ID, "lang::es", page)
+		if err != nil {
+			t.Fatalf("Cards() error = %v", err)
+		}
+		for _, card := range result.Items {
+			fronts = append(fronts, card.Front)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	// "language" shares a prefix with "lang" but is not below it.
+	if got := strings.Join(fronts, ","); got != "hablar,hola" {
+		t.Errorf("Cards(lang::es) = %s", got)
+	}
+
+	// Renaming moves the subtree; tags that collide are merged.
+	changed, err := f.repos.Tags.Replace(f.ctx, user.ID, []string{"lang::es"}, "spanish")
+	if err != nil || changed != 2 {
+		t.Fatalf("Replace() = %d, %v", changed, err)
+	}
+	stored, _ := f.repos.Cards.GetByID(f.ctx, hablar.ID)
+	if got := strings.Join(stored.Tags, "|"); got != "spanish::verbs|spanish" || stored.Version != 2 {
+		t.Errorf("unexpected renamed card %+v", stored)
+	}
+
+	changed, err = f.repos.Tags.Replace(f.ctx, user.ID, []string{"spanish::greetings", "spanish", "lang::fr"}, "romance")
+	if err != nil || changed != 3 {
+		t.Fatalf("Replace(merge) = %d, %v", changed, err)
+	}
+	stored, _ = f.repos.Cards.GetByID(f.ctx, hablar.ID)
+	if got := strings.Join(stored.Tags, "|"); got != "romance::verbs|romance" {
+		t.Errorf("unexpected merged tags %q", got)
+	}
+	stored, _ = f.repos.Cards.GetByID(f.ctx, hola.ID)
+	if got := strings.Join(stored.Tags, "|"); got != "romance|Lang" {
+		t.Errorf("unexpected merged tags %q", got)
+	}
+	stored, _ = f.repos.Cards.GetByID(f.ctx, bonjour.ID)
+	if got := strings.Join(stored.Tags, "|"); got != "romance|language" {
+		t.Errorf("unexpected merged tags %q", got)
+	}
+
+	changed, err = f.repos.Tags.Replace(f.ctx, user.ID, []string{"missing"}, "anything")
+	if err != nil || changed != 0 {
+		t.Errorf("Replace(missing) = %d, %v", changed, err)
+	}
+	foreignTags, _ := f.repos.Tags.List(f.ctx, other.ID)
+	if len(foreignTags) != 2 || foreignTags[1].Tag != "lang::es" {
+		t.Errorf("expected the other user's tags to be untouched, got %+v", foreignTags)
+	}
+}
+
+func testSearch(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	deck := f.deck(user.ID, nil, "Animals", 0)
+	subdeck := f.deck(user.ID, &deck.ID, "Pets", 0)
+	verbs := &model.Deck{UserID: user.ID, Name: "Verbs", Algorithm: model.AlgorithmSM2, Language: "spanish"}
+	if err := f.repos.Decks.Create(f.ctx, verbs); err != nil {
+		t.Fatalf("create deck: %v", err)
+	}
+	if stored, _ := f.repos.Decks.GetByID(f.ctx, verbs.ID); stored.Language != "spanish" || deck.Language != model.DefaultLanguage {
+		t.Errorf("unexpected languages %q and %q", stored.Language, deck.Language)
+	}
+	foreign := f.deck(other.ID, nil, "Foreign", 0)
+
+	card := func(deckID int64, cardType model.CardType, front, back, extra string, tags ...string) *model.Card {
+		t.Helper()
+		card := &model.Card{DeckID: deckID, Type: cardType, Front: front, Back: back, Extra: extra, Tags: tags}
+		if err := f.repos.Cards.Create(f.ctx, card); err != nil {
+			t.Fatalf("create card %q: %v", front, err)
+		}
+		return card
+	}
+	dog := card(deck.ID, model.CardTypeBasic, "dog", "a loyal animal", "", "animals::mammals")
+	perro := card(subdeck.ID, model.CardTypeReverse, "el perro", "the dog", "", "animals::mammals", "pets")
+	gato := card(subdeck.ID, model.CardTypeBasic, "el gato", "the cat", "chases the mouse", "animals::mammals")
+	hablar := card(verbs.ID, model.CardTypeCloze, "{{c1::hablar}}", "to speak", "")
+	card(foreign.ID, model.CardTypeBasic, "dog", "foreign", "")
+	f.schedule(gato.ID, user.ID, model.ScheduleStateReview, base)
+
+	search := func(q string, page model.PageRequest) model.Page[*model.CardSearchHit] {
+		t.Helper()
+		expr, err := query.Parse(q)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", q, err)
+		}
+		result, err := f.repos.Search.Search(f.ctx, user.ID, expr, page)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", q, err)
+		}
+		return result
+	}
+	ids := func(hits []*model.CardSearchHit) []int64 {
+		var ids []int64
+		for _, hit := range hits {
+			ids = append(ids, hit.Card.ID)
+		}
+		return ids
+	}
+	expectIDs := func(q string, want ...int64) {
+		t.Helper()
+		if got := ids(search(q, model.PageRequest{}).Items); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Search(%q) = %v, want %v", q, got, want)
+		}
+	}
+
+	// A match on the front outranks one on the back.
+	hits := search("dog", model.PageRequest{}).Items
+	if got := ids(hits); fmt.Sprint(got) != fmt.Sprint([]int64{dog.ID, perro.ID}) {
+		t.Errorf("Search(dog) = %v", got)
+	}
+	if len(hits) == 2 && (hits[0].Rank <= hits[1].Rank || hits[1].BackSnippet != "the <mark>dog</mark>") {
+		t.Errorf("unexpected hits %+v, %+v", hits[0], hits[1])
+	}
+	if hits := search("is:review", model.PageRequest{}).Items; len(hits) != 1 || hits[0].Rank != 0 || hits[0].FrontSnippet != "" {
+		t.Errorf("expected an unranked hit without snippets, got %+v", hits)
+	}
+
+	expectIDs(`"the cat" -dog`, gato.ID)
+	expectIDs(`"cat the"`)
+	expectIDs("mouse", gato.ID)
+	expectIDs("animal -loyal")
+	expectIDs("cat or speak", gato.ID, hablar.ID)
+	expectIDs("-(cat or speak) -dog")
+
+	expectIDs(fmt.Sprintf("did:%d", subdeck.ID), perro.ID, gato.ID)
+	expectIDs(fmt.Sprintf("did:%d", deck.ID), dog.ID, perro.ID, gato.ID)
+	expectIDs(fmt.Sprintf("did:%d", foreign.ID))
+	expectIDs("deck:animals", dog.ID, perro.ID, gato.ID)
+	expectIDs("deck:Animals::Pets", perro.ID, gato.ID)
+	expectIDs("deck:*pets", perro.ID, gato.ID)
+	expectIDs("deck:pets")
+	expectIDs("deck:foreign")
+	expectIDs("tag:animals tag:pets", perro.ID)
+	expectIDs("tag:ANIMALS::mammals -tag:pets", dog.ID, gato.ID)
+	expectIDs("tag:anim*", dog.ID, perro.ID, gato.ID)
+	expectIDs("tag:none", hablar.ID)
+	expectIDs("type:reverse or type:cloze", perro.ID, hablar.ID)
+	expectIDs("is:review", gato.ID)
+	expectIDs("el is:new", perro.ID)
+	expectIDs("is:due", gato.ID)
+	expectIDs("-is:due", dog.ID, perro.ID, hablar.ID)
+	expectIDs("is:suspended")
+	expectIDs("prop:ease>=2.5 prop:lapses=0 prop:due<0", gato.ID)
+	expectIDs("-prop:lapses>3", dog.ID, perro.ID, gato.ID, hablar.ID)
+	expectIDs("added:1", dog.ID, perro.ID, gato.ID, hablar.ID)
+	expectIDs("edited:1 -rated:1", dog.ID, perro.ID, gato.ID, hablar.ID)
+	expectIDs("front:el*", perro.ID, gato.ID)
+	expectIDs("front:*HABLAR*", hablar.ID)
+	expectIDs(`back:"the dog"`, perro.ID)
+	expectIDs("(cat or dog) type:basic", dog.ID, gato.ID)
+
+	var paged []int64
+	page := model.PageRequest{Limit: 1}
+	for i := 0; ; i++ {
+		if i > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		result := search("dog or cat", page)
+		paged = append(paged, ids(result.Items)...)
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	if fmt.Sprint(paged) != fmt.Sprint([]int64{dog.ID, perro.ID, gato.ID}) {
+		t.Errorf("Search(paginated) = %v", paged)
+	}
+}
+
+func testCardPagination(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	other := f.deck(user.ID, nil, "French", 1)
+	f.card(other.ID, "bonjour", 0)
+	for i, position := range []int{2, 0, 1, 0, 2} {
+		f.card(deck.ID, string(rune('a'+i)), position)
+	}
+
+	var fronts []string
+	page := model.PageRequest{Limit: 2}
+	for i := 0; ; i++ {
+		if i > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		result, err := f.repos.Cards.GetByDeckID(f.ctx, deck.ID, page)
+		if err != nil {
+			t.Fatalf("GetByDeckID() error = %v", err)
+		}
+		for _, card := range result.Items {
+			fronts = append(fronts, card.Front)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+
+	if got := strings.Join(fronts, ","); got != "b,d,c,a,e" {
+		t.Errorf("unexpected order %s", got)
+	}
+}
+
+func testSchedules(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	card := f.card(deck.ID, "hola", 0)
+	schedule := f.schedule(card.ID, user.ID, model.ScheduleStateNew, base)
+
+	err := f.repos.Schedules.Create(f.ctx, &model.CardSchedule{CardID: card.ID, UserID: user.ID, State: model.ScheduleStateNew, DueAt: base, EaseFactor: 2.5})
+	expectErr(t, "Create(duplicate card and user)", err, model.ErrDuplicateKey)
+
+	reviewedAt := base.Add(time.Hour)
+	schedule.State = model.ScheduleStateReview
+	schedule.DueAt = base.Add(72 * time.Hour)
+	schedule.Interval = 3
+	schedule.ReviewCount = 1
+	schedule.LastReviewedAt = &reviewedAt
+	if err := f.repos.Schedules.Update(f.ctx, schedule); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	stored, err := f.repos.Schedules.GetByCardAndUser(f.ctx, card.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetByCardAndUser() error = %v", err)
+	}
+	if stored.ID != schedule.ID || stored.State != model.ScheduleStateReview || stored.Interval != 3 ||
+		!stored.DueAt.Equal(schedule.DueAt) || stored.LastReviewedAt == nil || !stored.LastReviewedAt.Equal(reviewedAt) {
+		t.Errorf("unexpected schedule %+v", stored)
+	}
+	_, err = f.repos.Schedules.GetByCardAndUser(f.ctx, card.ID, user.ID+1000)
+	expectErr(t, "GetByCardAndUser(other user)", err, model.ErrNotFound)
+
+	unscheduled := f.card(deck.ID, "adios", 1)
+	later := f.card(deck.ID, "gracias", 2)
+	laterSchedule := f.schedule(later.ID, user.ID, model.ScheduleStateNew, base)
+	byCards, err := f.repos.Schedules.GetByCards(f.ctx, user.ID, []int64{later.ID, unscheduled.ID, card.ID})
+	if err != nil || len(byCards) != 2 || byCards[0].ID != schedule.ID || byCards[1].ID != laterSchedule.ID {
+		t.Errorf("GetByCards() = %v, %v", byCards, err)
+	}
+	if byCards, err := f.repos.Schedules.GetByCards(f.ctx, user.ID+1000, []int64{card.ID}); err != nil || len(byCards) != 0 {
+		t.Errorf("GetByCards(other user) = %v, %v", byCards, err)
+	}
+
+	intruder := f.user("mallory@example.com")
+	moved := base.Add(24 * time.Hour)
+	if err := f.repos.Schedules.UpdateDueDates(f.ctx, intruder.ID, []model.DueDateMove{{ScheduleID: schedule.ID, From: schedule.DueAt, To: base}}); err != nil {
+		t.Fatalf("UpdateDueDates() error = %v", err)
+	}
+	// A schedule whose due date changed since the move was planned stays.
+	if err := f.repos.Schedules.UpdateDueDates(f.ctx, user.ID, []model.DueDateMove{{ScheduleID: schedule.ID, From: base, To: base}}); err != nil {
+		t.Fatalf("UpdateDueDates() error = %v", err)
+	}
+	if stored, _ := f.repos.Schedules.GetByID(f.ctx, schedule.ID); !stored.DueAt.Equal(schedule.DueAt) {
+		t.Errorf("expected due date %v to stay, got %v", schedule.DueAt, stored.DueAt)
+	}
+	if err := f.repos.Schedules.UpdateDueDates(f.ctx, user.ID, []model.DueDateMove{{ScheduleID: schedule.ID, From: schedule.DueAt, To: moved}}); err != nil {
+		t.Fatalf("UpdateDueDates() error = %v", err)
+	}
+	if stored, _ := f.repos.Schedules.GetByID(f.ctx, schedule.ID); !stored.DueAt.Equal(moved) {
+		t.Errorf("expected due date %v, got %v", moved, stored.DueAt)
+	}
+
+	expectErr(t, "Update(missing)", f.repos.Schedules.Update(f.ctx, &model.CardSchedule{ID: 999_999, State: model.ScheduleStateNew}), model.ErrNotFound)
+	if err := f.repos.Schedules.Delete(f.ctx, schedule.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	expectErr(t, "Delete(deleted)", f.repos.Schedules.Delete(f.ctx, schedule.ID), model.ErrNotFound)
+}
+
+func testQueues(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	second := f.deck(user.ID, nil, "French", 1)
+	below := f.deck(user.ID, &second.ID, "Verbs", 0)
+
+	ids := map[string]int64{}
+	add := func(deckID int64, front string, position int, state model.ScheduleState, dueAt time.Time) {
+		card := f.card(deckID, front, position)
+		ids[front] = f.schedule(card.ID, user.ID, state, dueAt).ID
+	}
+	add(deck.ID, "due-late", 0, model.ScheduleStateReview, base.Add(-time.Hour))
+	add(deck.ID, "due-early", 1, model.ScheduleStateLearning, base.Add(-2*time.Hour))
+	add(deck.ID, "due-now", 2, model.ScheduleStateRelearning, base)
+	add(deck.ID, "future", 3, model.ScheduleStateReview, base.Add(time.Hour))
+	add(deck.ID, "mastered", 4, model.ScheduleStateMastered, base.Add(-3*time.Hour))
+	add(deck.ID, "new-second", 6, model.ScheduleStateNew, base)
+	add(deck.ID, "new-first", 5, model.ScheduleStateNew, base)
+	add(second.ID, "other-deck", 0, model.ScheduleStateReview, base.Add(-time.Hour))
+	add(below.ID, "subdeck", 0, model.ScheduleStateReview, base.Add(-time.Minute))
+	add(below.ID, "tomorrow", 1, model.ScheduleStateReview, base.Add(30*time.Hour))
+
+	suspended := f.card(deck.ID, "suspended", 7)
+	suspended.Suspended = true
+	if err := f.repos.Cards.Update(f.ctx, suspended); err != nil {
+		t.Fatalf("suspend card: %v", err)
+	}
+	f.schedule(suspended.ID, user.ID, model.ScheduleStateReview, base.Add(-time.Hour))
+	f.schedule(suspended.ID, other.ID, model.ScheduleStateReview, base.Add(-time.Hour))
+
+	names := func(schedules []*model.CardSchedule) string {
+		byID := map[int64]string{}
+		for name, id := range ids {
+			byID[id] = name
+		}
+		var got []string
+		for _, schedule := range schedules {
+			got = append(got, byID[schedule.ID])
+		}
+		return strings.Join(got, ",")
+	}
+
+	due, err := f.repos.Schedules.GetDueCards(f.ctx, user.ID, deck.ID, base, 10)
+	if err != nil {
+		t.Fatalf("GetDueCards() error = %v", err)
+	}
+	if got := names(due); got != "due-early,due-late,due-now" {
+		t.Errorf("GetDueCards() = %s", got)
+	}
+	if limited, _ := f.repos.Schedules.GetDueCards(f.ctx, user.ID, deck.ID, base, 2); len(limited) != 2 {
+		t.Errorf("expected the limit to apply, got %d", len(limited))
+	}
+
+	fresh, err := f.repos.Schedules.GetNewCards(f.ctx, user.ID, deck.ID, 10)
+	if err != nil {
+		t.Fatalf("GetNewCards() error = %v", err)
+	}
+	if got := names(fresh); got != "new-first,new-second" {
+		t.Errorf("GetNewCards() = %s", got)
+	}
+
+	overdue, err := f.repos.Schedules.GetOverdue(f.ctx, user.ID, 0, base)
+	if err != nil {
+		t.Fatalf("GetOverdue() error = %v", err)
+	}
+	if got := names(overdue); got != "mastered,due-early,due-late,other-deck,subdeck" {
+		t.Errorf("GetOverdue(all decks) = %s", got)
+	}
+	if overdue, _ := f.repos.Schedules.GetOverdue(f.ctx, user.ID, second.ID, base); names(overdue) != "other-deck,subdeck" {
+		t.Errorf("GetOverdue(deck with subdeck) = %s", names(overdue))
+	}
+	if overdue, _ := f.repos.Schedules.GetOverdue(f.ctx, user.ID, below.ID, base); names(overdue) != "subdeck" {
+		t.Errorf("GetOverdue(subdeck) = %s", names(overdue))
+	}
+
+	counts, err := f.repos.Schedules.CountDueByDay(f.ctx, user.ID, 0, base)
+	if err != nil {
+		t.Fatalf("CountDueByDay() error = %v", err)
+	}
+	if !reflect.DeepEqual(counts, map[int]int{0: 2, 1: 1}) {
+		t.Errorf("CountDueByDay(all decks) = %v", counts)
+	}
+	if counts, _ := f.repos.Schedules.CountDueByDay(f.ctx, user.ID, second.ID, base); !reflect.DeepEqual(counts, map[int]int{1: 1}) {
+		t.Errorf("CountDueByDay(deck with subdeck) = %v", counts)
+	}
+}
+
+func testCardCounts(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	f.deck(user.ID, nil, "Empty", 1)
+
+	f.card(deck.ID, "unscheduled", 0)
+	f.schedule(f.card(deck.ID, "new", 1).ID, user.ID, model.ScheduleStateNew, base)
+	f.schedule(f.card(deck.ID, "learning-due", 2).ID, user.ID, model.ScheduleStateLearning, base.Add(-time.Minute))
+	f.schedule(f.card(deck.ID, "relearning", 3).ID, user.ID, model.ScheduleStateRelearning, base.Add(time.Hour))
+	f.schedule(f.card(deck.ID, "review-due", 4).ID, user.ID, model.ScheduleStateReview, base)
+	f.schedule(f.card(deck.ID, "review-later", 5).ID, user.ID, model.ScheduleStateReview, base.Add(time.Hour))
+
+	counts, err := f.repos.Decks.GetCardCounts(f.ctx, user.ID, base)
+	if err != nil {
+		t.Fatalf("GetCardCounts() error = %v", err)
+	}
+	if len(counts) != 1 {
+		t.Errorf("expected counts only for decks with cards, got %v", counts)
+	}
+	if got, want := counts[deck.ID], (model.DeckCounts{New: 2, Learning: 2, Due: 2}); got != want {
+		t.Errorf("GetCardCounts() = %+v, want %+v", got, want)
+	}
+}
+
+func testDeckTree(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	spanish := f.deck(user.ID, nil, "Spanish", 1)
+	french := f.deck(user.ID, nil, "French", 1)
+	verbs := f.deck(user.ID, &spanish.ID, "Verbs", 0)
+	irregular := f.deck(user.ID, &verbs.ID, "Irregular", 0)
+	nouns := f.deck(user.ID, &spanish.ID, "Nouns", 5)
+	foreign := f.deck(other.ID, nil, "Foreign", 0)
+
+	f.card(spanish.ID, "hola", 0)
+	f.schedule(f.card(verbs.ID, "hablar", 0).ID, user.ID, model.ScheduleStateLearning, base.Add(-time.Minute))
+	f.card(irregular.ID, "ser", 0)
+	f.schedule(f.card(irregular.ID, "ir", 1).ID, user.ID, model.ScheduleStateReview, base.Add(time.Hour))
+
+	names := func(decks []*model.Deck) string {
+		var names []string
+		for _, deck := range decks {
+			names = append(names, fmt.Sprintf("%s:%d", deck.Name, deck.Position))
+		}
+		return strings.Join(names, ",")
+	}
+	roots, err := f.repos.Decks.Children(f.ctx, user.ID, nil)
+	if err != nil {
+		t.Fatalf("Children() error = %v", err)
+	}
+	if got := names(roots); got != "French:1,Spanish:1" {
+		t.Errorf("Children(nil) = %s", got)
+	}
+
+	if err := f.repos.Decks.Renumber(f.ctx, []int64{nouns.ID, verbs.ID}); err != nil {
+		t.Fatalf("Renumber() error = %v", err)
+	}
+	children, _ := f.repos.Decks.Children(f.ctx, user.ID, &spanish.ID)
+	if got := names(children); got != "Nouns:0,Verbs:1" {
+		t.Errorf("Children(spanish) = %s", got)
+	}
+	if children[0].Version != nouns.Version+1 || children[1].Version != verbs.Version+1 {
+		t.Errorf("expected renumbered decks to get new versions, got %d and %d", children[0].Version, children[1].Version)
+	}
+	if err := f.repos.Decks.Renumber(f.ctx, []int64{nouns.ID, verbs.ID}); err != nil {
+		t.Fatalf("Renumber() error = %v", err)
+	}
+	if again, _ := f.repos.Decks.Children(f.ctx, user.ID, &spanish.ID); again[0].Version != children[0].Version {
+		t.Error("expected decks already in place to keep their version")
+	}
+
+	tree, err := f.repos.Decks.Tree(f.ctx, user.ID, nil, base)
+	if err != nil {
+		t.Fatalf("Tree() error = %v", err)
+	}
+	var order []string
+	nodes := map[int64]*model.DeckNode{}
+	for _, node := range tree {
+		order = append(order, node.Name)
+		nodes[node.ID] = node
+	}
+	if got := strings.Join(order, ","); got != "French,Spanish,Nouns,Verbs,Irregular" {
+		t.Errorf("Tree() order = %s", got)
+	}
+	if got, want := nodes[spanish.ID].Counts, (model.DeckCounts{New: 1}); got != want {
+		t.Errorf("spanish counts = %+v, want %+v", got, want)
+	}
+	if got, want := nodes[spanish.ID].Totals, (model.DeckCounts{New: 2, Learning: 1, Due: 1}); got != want {
+		t.Errorf("spanish totals = %+v, want %+v", got, want)
+	}
+	if got, want := nodes[verbs.ID].Totals, (model.DeckCounts{New: 1, Learning: 1, Due: 1}); got != want {
+		t.Errorf("verbs totals = %+v, want %+v", got, want)
+	}
+	if got := nodes[french.ID].Totals; got != (model.DeckCounts{}) {
+		t.Errorf("french totals = %+v", got)
+	}
+
+	scoped, err := f.repos.Decks.Tree(f.ctx, user.ID, []int64{verbs.ID, foreign.ID}, base)
+	if err != nil {
+		t.Fatalf("Tree(verbs) error = %v", err)
+	}
+	order = nil
+	for _, node := range scoped {
+		order = append(order, node.Name)
+	}
+	if got := strings.Join(order, ","); got != "Verbs,Irregular" {
+		t.Errorf("Tree(verbs) order = %s", got)
+	}
+	if got, want := scoped[0].Totals, nodes[verbs.ID].Totals; got != want {
+		t.Errorf("Tree(verbs) totals = %+v, want %+v", got, want)
+	}
+	if empty, _ := f.repos.Decks.Tree(f.ctx, user.ID, []int64{}, base); len(empty) != 0 {
+		t.Errorf("Tree(no roots) = %d decks", len(empty))
+	}
+
+	stored, _ := f.repos.Decks.GetByID(f.ctx, spanish.ID)
+	stored.ParentID = &irregular.ID
+	expectErr(t, "Update(below a descendant)", f.repos.Decks.Update(f.ctx, stored), model.ErrDeckCycle)
+	stored.ParentID = &spanish.ID
+	expectErr(t, "Update(below itself)", f.repos.Decks.Update(f.ctx, stored), model.ErrDeckCycle)
+	stored.ParentID = &french.ID
+	if err := f.repos.Decks.Update(f.ctx, stored); err != nil {
+		t.Errorf("Update(below a sibling) error = %v", err)
+	}
+}
+
+func testReviewLogs(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	other := f.user("grace@example.com")
+	deck := f.deck(user.ID, nil, "Spanish", 0)
+	schedule := f.schedule(f.card(deck.ID, "hola", 0).ID, user.ID, model.ScheduleStateReview, base)
+	otherSchedule := f.schedule(f.card(deck.ID, "adios", 1).ID, other.ID, model.ScheduleStateReview, base)
+
+	create := func(userID, scheduleID int64, reviewedAt time.Time) *model.ReviewLog {
+		log := &model.ReviewLog{
+			CardScheduleID: scheduleID, UserID: userID, Rating: model.ReviewRatingCorrect,
+			PreviousState: model.ScheduleStateReview, NewState: model.ScheduleStateReview,
+			PreviousEase: 2.5, NewEase: 2.6, PreviousInterval: 1, NewInterval: 3, ReviewDuration: 1500,
+			ReviewedAt: reviewedAt,
+		}
+		if err := f.repos.ReviewLogs.Create(f.ctx, log); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		return log
+	}
+	first := create(user.ID, schedule.ID, base)
+	second := create(user.ID, schedule.ID, base.Add(time.Hour))
+	tied := create(user.ID, schedule.ID, base.Add(time.Hour))
+	create(other.ID, otherSchedule.ID, base.Add(30*time.Minute))
+
+	stored, err := f.repos.ReviewLogs.GetByID(f.ctx, first.ID)
+	if err != nil || stored.NewEase != 2.6 || stored.ReviewDuration != 1500 || !stored.ReviewedAt.Equal(base) {
+		t.Errorf("GetByID() = %+v, %v", stored, err)
+	}
+
+	var ids []int64
+	page := model.PageRequest{Limit: 2}
+	for i := 0; ; i++ {
+		if i > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		result, err := f.repos.ReviewLogs.GetByUserID(f.ctx, user.ID, page)
+		if err != nil {
+			t.Fatalf("GetByUserID() error = %v", err)
+		}
+		for _, log := range result.Items {
+			ids = append(ids, log.ID)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	if len(ids) != 3 || ids[0] != tied.ID || ids[1] != second.ID || ids[2] != first.ID {
+		t.Errorf("expected most recent first, got %v", ids)
+	}
+
+	ranged, err := f.repos.ReviewLogs.GetByDateRange(f.ctx, user.ID, base, base.Add(time.Hour))
+	if err != nil || len(ranged) != 1 || ranged[0].ID != first.ID {
+		t.Errorf("GetByDateRange() = %v, %v; the end must be exclusive", ranged, err)
+	}
+
+	bySchedules, err := f.repos.ReviewLogs.GetBySchedules(f.ctx, []int64{schedule.ID})
+	if err != nil || len(bySchedules) != 3 || bySchedules[0].ID != first.ID || bySchedules[1].ID != second.ID || bySchedules[2].ID != tied.ID {
+		t.Errorf("GetBySchedules() = %v, %v; want oldest first", bySchedules, err)
+	}
+
+	_, err = f.repos.ReviewLogs.GetByID(f.ctx, 999_999)
+	expectErr(t, "GetByID(missing)", err, model.ErrNotFound)
+}
+
+func testRefreshTokens(t *testing.T, f *fixture) {
+	user := f.user("ada@example.com")
+	hash := func(c string) string { return strings.Repeat(c, 64) }
+
+	token := &model.RefreshToken{UserID: user.ID, TokenHash: hash("a"), ExpiresAt: base.Add(time.Hour)}
+	if err := f.repos.RefreshTokens.Create(f.ctx, token); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	expectErr(t, "Create(duplicate hash)", f.repos.RefreshTokens.Create(f.ctx, &model.RefreshToken{UserID: user.ID, TokenHash: hash("a"), ExpiresAt: base}), model.ErrDuplicateKey)
+
+	replacement := &model.RefreshToken{UserID: user.ID, TokenHash: hash("b"), ExpiresAt: base.Add(time.Hour)}
+	if err := f.repos.RefreshTokens.Create(f.ctx, replacement); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := f.repos.RefreshTokens.Revoke(f.ctx, token.ID, &replacement.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	revoked, err := f.repos.RefreshTokens.GetByHash(f.ctx, hash("a"))
+	if err != nil || !revoked.IsRevoked() || revoked.ReplacedByID == nil || *revoked.ReplacedByID != replacement.ID {
+		t.Fatalf("GetByHash() = %+v, %v", revoked, err)
+	}
+
+	// Revoking again keeps the first revocation time and replacement.
+	if err := f.repos.RefreshTokens.Revoke(f.ctx, token.ID, nil); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	again, _ := f.repos.RefreshTokens.GetByHash(f.ctx, hash("a"))
+	if !again.RevokedAt.Equal(*revoked.RevokedAt) || again.ReplacedByID == nil {
+		t.Errorf("expected the revocation to be kept, got %+v", again)
+	}
+
+	if err := f.repos.RefreshTokens.RevokeAllForUser(f.ctx, user.ID); err != nil {
+		t.Fatalf("RevokeAllForUser() error = %v", err)
+	}
+	if stored, _ := f.repos.RefreshTokens.GetByHash(f.ctx, hash("b")); !stored.IsRevoked() {
+		t.Error("expected every token of the user to be revoked")
+	}
+
+	expectErr(t, "Revoke(missing)", f.repos.RefreshTokens.Revoke(f.ctx, 999_999, nil), model.ErrNotFound)
+	_, err = f.repos.RefreshTokens.GetByHash(f.ctx, hash("c"))
+	expectErr(t, "GetByHash(unknown)", err, model.ErrNotFound)
+}
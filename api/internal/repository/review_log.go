@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"memwright/api/internal/model"
@@ -12,8 +13,11 @@ import (
 type ReviewLogRepository interface {
 	Create(ctx context.Context, log *model.ReviewLog) error
 	GetByID(ctx context.Context, id int64) (*model.ReviewLog, error)
-	GetByUserID(ctx context.Context, userID int64, limit int) ([]*model.ReviewLog, error)
+	GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.ReviewLog], error)
 	GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*model.ReviewLog, error)
+	// GetBySchedules returns the reviews of the given schedules, oldest
+	// first.
+	GetBySchedules(ctx context.Context, scheduleIDs []int64) ([]*model.ReviewLog, error)
 }
 
 type reviewLogRepository struct {
@@ -81,21 +85,38 @@ func (r *reviewLogRepository) GetByID(ctx context.Context, id int64) (*model.Rev
 	return log, nil
 }
 
-func (r *reviewLogRepository) GetByUserID(ctx context.Context, userID int64, limit int) ([]*model.ReviewLog, error) {
+// GetByUserID lists a user's reviews, most recent first.
+func (r *reviewLogRepository) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.ReviewLog], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.ReviewLog]{}, err
+	}
+	if cursor != nil && cursor.ReviewedAt == nil {
+		return model.Page[*model.ReviewLog]{}, model.NewValidationError("cursor", "is malformed")
+	}
+
 	query := `
 		SELECT id, card_schedule_id, user_id, rating, previous_state, new_state, previous_ease, new_ease, previous_interval, new_interval, review_duration, reviewed_at
 		FROM review_logs
-		WHERE user_id = $1
-		ORDER BY reviewed_at DESC
-		LIMIT $2`
+		WHERE user_id = $1`
+	args := []interface{}{userID}
+	if cursor != nil {
+		query += ` AND (reviewed_at, id) < ($2, $3)`
+		args = append(args, *cursor.ReviewedAt, cursor.ID)
+	}
+	query += ` ORDER BY reviewed_at DESC, id DESC` + limitClause(page.Limit)
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return model.Page[*model.ReviewLog]{}, err
 	}
 	defer rows.Close()
 
-	return scanReviewLogs(rows)
+	logs, err := scanReviewLogs(rows)
+	if err != nil {
+		return model.Page[*model.ReviewLog]{}, err
+	}
+	return model.NewPage(logs, page.Limit, model.ReviewLogCursor), nil
 }
 
 func (r *reviewLogRepository) GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*model.ReviewLog, error) {
@@ -114,6 +135,30 @@ func (r *reviewLogRepository) GetByDateRange(ctx context.Context, userID int64,
 	return scanReviewLogs(rows)
 }
 
+func (r *reviewLogRepository) GetBySchedules(ctx context.Context, scheduleIDs []int64) ([]*model.ReviewLog, error) {
+	if len(scheduleIDs) == 0 {
+		return nil, nil
+	}
+	q := &sqlBuilder{}
+	ids := make([]string, len(scheduleIDs))
+	for i, id := range scheduleIDs {
+		ids[i] = q.arg(id)
+	}
+	query := `
+		SELECT id, card_schedule_id, user_id, rating, previous_state, new_state, previous_ease, new_ease, previous_interval, new_interval, review_duration, reviewed_at
+		FROM review_logs
+		WHERE card_schedule_id IN (` + strings.Join(ids, ", ") + `)
+		ORDER BY reviewed_at ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReviewLogs(rows)
+}
+
 func scanReviewLogs(rows *sql.Rows) ([]*model.ReviewLog, error) {
 	var logs []*model.ReviewLog
 	for rows.Next() {
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type SchemaRepository interface {
+	// Version reads the applied migration version. dirty is set when
+	// another migration tool failed halfway and the schema needs manual
+	// repair. A database without applied migrations reports version 0.
+	Version(ctx context.Context) (version int64, dirty bool, err error)
+}
+
+type schemaRepository struct {
+	db DB
+}
+
+func NewSchemaRepository(db DB) SchemaRepository {
+	return &schemaRepository{db: db}
+}
+
+func (r *schemaRepository) Version(ctx context.Context) (int64, bool, error) {
+	query := `SELECT version, dirty FROM schema_migrations ORDER BY version DESC LIMIT 1`
+
+	var version int64
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return version, dirty, nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/query"
+)
+
+// SearchRepository finds a user's cards matching a search query.
+type SearchRepository interface {
+	Search(ctx context.Context, userID int64, expr query.Node, page model.PageRequest) (model.Page[*model.CardSearchHit], error)
+}
+
+const headlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2"
+
+type searchRepository struct {
+	db DB
+}
+
+func NewSearchRepository(db DB) SearchRepository {
+	return &searchRepository{db: db}
+}
+
+// Search compiles the query to a condition and ranks hits with ts_rank
+// against the words and phrases it looks for, each parsed in the language
+// of the card's deck. Because a tsquery depends on the language, the index
+// is probed with every word or phrase the query requires parsed in every
+// language the user's decks use, and each hit is then checked against its
+// own deck's language.
+func (r *searchRepository) Search(ctx context.Context, userID int64, expr query.Node, page model.PageRequest) (model.Page[*model.CardSearchHit], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.CardSearchHit]{}, err
+	}
+
+	q := &sqlBuilder{}
+	userParam := q.arg(userID)
+	filters := []string{"d.user_id = " + userParam, "c.deleted_at IS NULL"}
+
+	if required := query.RequiredTerms(expr); len(required) > 0 {
+		languages, err := r.languages(ctx, userID)
+		if err != nil {
+			return model.Page[*model.CardSearchHit]{}, err
+		}
+		if len(languages) == 0 {
+			return model.NewPage([]*model.CardSearchHit(nil), page.Limit, model.SearchHitCursor), nil
+		}
+		for _, term := range required {
+			probes := make([]string, len(languages))
+			for i, language := range languages {
+				probes[i] = query.TSQuery(term, q.arg(language)+"::regconfig", q.arg)
+			}
+			filters = append(filters, "c.search_vector @@ ("+strings.Join(probes, " || ")+")")
+		}
+	}
+	filters = append(filters, query.SQL(expr, q.arg))
+
+	terms := query.Terms(expr)
+	hasTerms := len(terms) > 0
+	rank := "0::float8"
+	tsquery := "NULL::tsquery"
+	if hasTerms {
+		tsqueries := make([]string, len(terms))
+		for i, term := range terms {
+			tsqueries[i] = query.TSQuery(term, "d.language", q.arg)
+		}
+		tsquery = "(" + strings.Join(tsqueries, " || ") + ")"
+		rank = "ts_rank(c.search_vector, " + tsquery + ")::float8"
+	}
+
+	sqlQuery := `
+		WITH RECURSIVE deck_paths(id, path) AS (
+			SELECT id, name::text FROM decks WHERE user_id = ` + userParam + ` AND parent_id IS NULL
+			UNION ALL
+			SELECT child.id, deck_paths.path || '::' || child.name
+			FROM decks child INNER JOIN deck_paths ON child.parent_id = deck_paths.id
+		)
+		SELECT h.id, h.deck_id, h.type, h.front, h.back, h.extra, h.tags, h.position, h.suspended, h.version, h.created_at, h.updated_at,
+			h.rank, ` + headline(hasTerms, "h.front") + `, ` + headline(hasTerms, "h.back") + `
+		FROM (
+			SELECT c.*, d.language AS deck_language, ` + tsquery + ` AS query, ` + rank + ` AS rank
+			FROM cards c
+			INNER JOIN decks d ON c.deck_id = d.id
+			INNER JOIN deck_paths dp ON dp.id = d.id
+			LEFT JOIN card_schedules cs ON cs.card_id = c.id AND cs.user_id = d.user_id
+			WHERE ` + strings.Join(filters, "\n\t\t\t\tAND ") + `
+		) h`
+	if cursor != nil && cursor.Rank != nil {
+		rankParam, idParam := q.arg(*cursor.Rank), q.arg(cursor.ID)
+		sqlQuery += fmt.Sprintf(` WHERE h.rank < %s OR (h.rank = %s AND h.id > %s)`, rankParam, rankParam, idParam)
+	}
+	sqlQuery += ` ORDER BY h.rank DESC, h.id` + limitClause(page.Limit)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, q.args...)
+	if err != nil {
+		return model.Page[*model.CardSearchHit]{}, err
+	}
+	defer rows.Close()
+
+	var hits []*model.CardSearchHit
+	for rows.Next() {
+		card := &model.Card{}
+		hit := &model.CardSearchHit{Card: card}
+		var tags TextArray
+		var front, back sql.NullString
+		err := rows.Scan(
+			&card.ID,
+			&card.DeckID,
+			&card.Type,
+			&card.Front,
+			&card.Back,
+			&card.Extra,
+			&tags,
+			&card.Position,
+			&card.Suspended,
+			&card.Version,
+			&card.CreatedAt,
+			&card.UpdatedAt,
+			&hit.Rank,
+			&front,
+			&back,
+		)
+		if err != nil {
+			return model.Page[*model.CardSearchHit]{}, err
+		}
+		card.Tags = tagsFromArray(tags)
+		hit.FrontSnippet, hit.BackSnippet = front.String, back.String
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.CardSearchHit]{}, err
+	}
+	return model.NewPage(hits, page.Limit, model.SearchHitCursor), nil
+}
+
+func (r *searchRepository) languages(ctx context.Context, userID int64) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT language::text FROM decks WHERE user_id = $1 ORDER BY 1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var languages []string
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, err
+		}
+		languages = append(languages, language)
+	}
+	return languages, rows.Err()
+}
+
+func headline(hasTerms bool, column string) string {
+	if !hasTerms {
+		return "NULL::text"
+	}
+	return fmt.Sprintf("ts_headline(h.deck_language, %s, h.query, '%s')", column, headlineOptions)
+}
+
+// sqlBuilder numbers positional parameters as they are added.
+type sqlBuilder struct {
+	args []interface{}
+}
+
+func (b *sqlBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
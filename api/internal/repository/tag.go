@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+
+	"memwright/api/internal/model"
+)
+
+// TagRepository queries and rewrites the hierarchical tags of a user's
+// cards. Subtree matches go through tag_paths(tags), which has a GIN index.
+type TagRepository interface {
+	// List returns every tag and tag ancestor of the user's cards, ordered
+	// bytewise.
+	List(ctx context.Context, userID int64) ([]model.TagCount, error)
+	// Cards lists the user's cards tagged with tag or below it, ordered by
+	// ID.
+	Cards(ctx context.Context, userID int64, tag string, page model.PageRequest) (model.Page[*model.Card], error)
+	// Replace moves every tag in the subtree of one of sources to the same
+	// place below target and returns the number of cards changed. Tags that
+	// end up equal are merged.
+	Replace(ctx context.Context, userID int64, sources []string, target string) (int64, error)
+}
+
+type tagRepository struct {
+	db DB
+}
+
+func NewTagRepository(db DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) List(ctx context.Context, userID int64) ([]model.TagCount, error) {
+	query := `
+		SELECT p.tag, COUNT(*) FILTER (WHERE p.tag = ANY (c.tags)), COUNT(*)
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		CROSS JOIN LATERAL unnest(tag_paths(c.tags)) AS p(tag)
+		WHERE d.user_id = $1 AND c.deleted_at IS NULL
+		GROUP BY p.tag
+		ORDER BY p.tag COLLATE "C"`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []model.TagCount{}
+	for rows.Next() {
+		var tag model.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Cards, &tag.Total); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *tagRepository) Cards(ctx context.Context, userID int64, tag string, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
+	query := `
+		SELECT c.id, c.deck_id, c.type, c.front, c.back, c.extra, c.tags, c.position, c.suspended, c.version, c.created_at, c.updated_at
+		FROM cards c
+		INNER JOIN decks d ON c.deck_id = d.id
+		WHERE d.user_id = $1 AND c.deleted_at IS NULL AND tag_paths(c.tags) @> ARRAY[$2::text]`
+	args := []interface{}{userID, tag}
+	if cursor != nil {
+		query += ` AND c.id > $3`
+		args = append(args, cursor.ID)
+	}
+	query += ` ORDER BY c.id` + limitClause(page.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	defer rows.Close()
+
+	var cards []*model.Card
+	for rows.Next() {
+		card := &model.Card{}
+		var tags TextArray
+		err := rows.Scan(
+			&card.ID,
+			&card.DeckID,
+			&card.Type,
+			&card.Front,
+			&card.Back,
+			&card.Extra,
+			&tags,
+			&card.Position,
+			&card.Suspended,
+			&card.Version,
+			&card.CreatedAt,
+			&card.UpdatedAt,
+		)
+		if err != nil {
+			return model.Page[*model.Card]{}, err
+		}
+		card.Tags = tagsFromArray(tags)
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	return model.NewPage(cards, page.Limit, model.CardIDCursor), nil
+}
+
+// Replace rewrites each matching card in one statement. Every tag takes the
+// longest source it lies below, and duplicates keep their first position.
+func (r *tagRepository) Replace(ctx context.Context, userID int64, sources []string, target string) (int64, error) {
+	query := `
+		UPDATE cards c
+		SET tags = (
+				SELECT array_agg(renamed.tag ORDER BY renamed.ord)
+				FROM (
+					SELECT DISTINCT ON (moved.tag) moved.tag, moved.ord
+					FROM (
+						SELECT COALESCE((
+							SELECT $3 || substr(t.tag, length(s.source) + 1)
+							FROM unnest($2::text[]) AS s(source)
+							WHERE t.tag = s.source OR starts_with(t.tag, s.source || '::')
+							ORDER BY length(s.source) DESC
+							LIMIT 1
+						), t.tag) AS tag, t.ord
+						FROM unnest(c.tags) WITH ORDINALITY AS t(tag, ord)
+					) moved
+					ORDER BY moved.tag, moved.ord
+				) renamed
+			),
+			version = c.version + 1,
+			updated_at = NOW()
+		FROM decks d
+		WHERE c.deck_id = d.id AND d.user_id = $1 AND c.deleted_at IS NULL AND tag_paths(c.tags) && $2::text[]`
+
+	result, err := r.db.ExecContext(ctx, query, userID, TextArray(sources), target)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
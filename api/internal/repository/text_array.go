@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TextArray maps a PostgreSQL text[] column. It encodes and decodes the
+// array literal itself, so it needs no driver support: elements are always
+// written quoted, and reads handle quoting, backslash escapes, unquoted
+// NULLs and explicit bounds such as "[0:1]={a,b}".
+//
+// A NULL column scans as a nil slice and '{}' as an empty one. NULL
+// elements have no []string representation and are skipped.
+type TextArray []string
+
+// Value implements driver.Valuer.
+func (a TextArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, element := range a {
+		if strings.IndexByte(element, 0) >= 0 {
+			return nil, fmt.Errorf("text array element %d contains a NUL byte", i)
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('"')
+		for j := 0; j < len(element); j++ {
+			if element[j] == '"' || element[j] == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(element[j])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (a *TextArray) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return a.parse(string(src))
+	case string:
+		return a.parse(src)
+	default:
+		return fmt.Errorf("cannot scan %T into a text array", src)
+	}
+}
+
+var errMalformedArray = errors.New("malformed text array literal")
+
+func (a *TextArray) parse(literal string) error {
+	// Arrays whose lower bound is not 1 are prefixed with their dimensions.
+	if strings.HasPrefix(literal, "[") {
+		_, rest, found := strings.Cut(literal, "=")
+		if !found {
+			return fmt.Errorf("%w: %q", errMalformedArray, literal)
+		}
+		literal = rest
+	}
+
+	literal = strings.TrimSpace(literal)
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return fmt.Errorf("%w: %q", errMalformedArray, literal)
+	}
+	body := literal[1 : len(literal)-1]
+
+	elements := TextArray{}
+	if strings.TrimSpace(body) == "" {
+		*a = elements
+		return nil
+	}
+
+	for i := 0; ; {
+		element, quoted, next, err := parseElement(body, i)
+		if err != nil {
+			return fmt.Errorf("%w: %q: %v", errMalformedArray, literal, err)
+		}
+		if quoted || !strings.EqualFold(element, "NULL") {
+			elements = append(elements, element)
+		}
+		if next == len(body) {
+			break
+		}
+		i = next + 1 // skip the delimiter
+	}
+	*a = elements
+	return nil
+}
+
+// parseElement reads the element starting at body[start:] and returns it
+// with the index of the delimiter that ends it, or len(body) for the last.
+func parseElement(body string, start int) (element string, quoted bool, end int, err error) {
+	i := skipSpace(body, start)
+	if i < len(body) && body[i] == '{' {
+		return "", false, 0, errors.New("multidimensional arrays are not supported")
+	}
+
+	var b strings.Builder
+	if i < len(body) && body[i] == '"' {
+		quoted = true
+		i++
+		for {
+			if i == len(body) {
+				return "", false, 0, errors.New("unterminated quoted element")
+			}
+			ch := body[i]
+			if ch == '"' {
+				i++
+				break
+			}
+			if ch == '\\' {
+				i++
+				if i == len(body) {
+					return "", false, 0, errors.New("dangling escape")
+				}
+				ch = body[i]
+			}
+			b.WriteByte(ch)
+			i++
+		}
+		i = skipSpace(body, i)
+		if i < len(body) && body[i] != ',' {
+			return "", false, 0, fmt.Errorf("unexpected %q after quoted element", body[i])
+		}
+		return b.String(), quoted, i, nil
+	}
+
+	// Unquoted elements end at the next delimiter; surrounding whitespace
+	// is not part of them, but escaped characters are.
+	trailingSpace := 0
+	for ; i < len(body) && body[i] != ','; i++ {
+		ch := body[i]
+		switch {
+		case ch == '"' || ch == '{' || ch == '}':
+			return "", false, 0, fmt.Errorf("unexpected %q in unquoted element", ch)
+		case ch == '\\':
+			i++
+			if i == len(body) {
+				return "", false, 0, errors.New("dangling escape")
+			}
+			b.WriteByte(body[i])
+			trailingSpace = 0
+			quoted = true // an escaped element is never the NULL keyword
+		case isArraySpace(ch):
+			b.WriteByte(ch)
+			trailingSpace++
+		default:
+			b.WriteByte(ch)
+			trailingSpace = 0
+		}
+	}
+	element = b.String()
+	element = element[:len(element)-trailingSpace]
+	if element == "" && !quoted {
+		return "", false, 0, errors.New("empty unquoted element")
+	}
+	return element, quoted, i, nil
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && isArraySpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+// isArraySpace matches the whitespace PostgreSQL ignores around elements.
+func isArraySpace(ch byte) bool {
+	switch ch {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UnitOfWork runs a function against repositories that share one
+// transaction: everything fn writes is committed together, or not at all
+// if fn returns an error.
+//
+// fn may run more than once when the database asks for a retry, so it must
+// not have side effects outside the repositories it is given.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+	// Read runs fn once against repositories that see a single snapshot
+	// of the data and must not be written to, for reads that span many
+	// queries.
+	Read(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+const (
+	defaultTxAttempts = 3
+	txRetryBackoff    = 20 * time.Millisecond
+)
+
+// TxUnitOfWork runs each unit in a SERIALIZABLE PostgreSQL transaction and
+// retries it on serialization failures and deadlocks.
+type TxUnitOfWork struct {
+	db          TxBeginner
+	maxAttempts int
+}
+
+func NewUnitOfWork(db TxBeginner) *TxUnitOfWork {
+	return &TxUnitOfWork{db: db, maxAttempts: defaultTxAttempts}
+}
+
+// WithMaxAttempts bounds how often a unit runs before its last error is
+// returned.
+func (u *TxUnitOfWork) WithMaxAttempts(attempts int) *TxUnitOfWork {
+	if attempts > 0 {
+		u.maxAttempts = attempts
+	}
+	return u
+}
+
+func (u *TxUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	var err error
+	for attempt := 1; attempt <= u.maxAttempts; attempt++ {
+		err = u.run(ctx, fn)
+		if err == nil || !IsRetryable(err) || attempt == u.maxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * txRetryBackoff):
+		}
+	}
+	return err
+}
+
+func (u *TxUnitOfWork) run(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(ctx, NewRepositories(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Read runs fn in a read-only REPEATABLE READ transaction, whose queries
+// all see the data as it was at the first one. Such a transaction never
+// fails to serialize, so fn is not retried.
+func (u *TxUnitOfWork) Read(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(ctx, NewRepositories(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PostgreSQL error codes that mean the transaction lost a race and may
+// succeed when run again.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// IsRetryable reports whether err is a serialization failure or deadlock.
+func IsRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+}
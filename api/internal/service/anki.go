@@ -0,0 +1,376 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// Anki note types that cards are exported as.
+const (
+	AnkiNoteTypeBasic   = "Basic"
+	AnkiNoteTypeReverse = "Basic (and reversed card)"
+	AnkiNoteTypeCloze   = "Cloze"
+)
+
+// DefaultAnkiDeck receives the notes of a file that names no deck.
+const DefaultAnkiDeck = "Default"
+
+type AnkiImportOptions struct {
+	ImportOptions
+	// Deck names the deck, "::" separating subdecks, of notes that the
+	// file gives none; DefaultAnkiDeck when empty.
+	Deck string
+}
+
+var (
+	clozePattern     = regexp.MustCompile(`\{\{c\d+::`)
+	ankiBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|<(div|p)(\s[^>]*)?>`)
+	ankiTagPattern   = regexp.MustCompile(`<[^>]*>`)
+	ankiFieldPattern = regexp.MustCompile(`^(decks|cards)\[(\d+)\]`)
+
+	ankiEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "", "\n", "<br>")
+
+	ankiSeparators = map[string]rune{
+		"tab":       '\t',
+		"comma":     ',',
+		"semicolon": ';',
+		"space":     ' ',
+		"pipe":      '|',
+		"colon":     ':',
+	}
+)
+
+// ImportAnki imports notes exported from Anki as "Notes in Plain Text". The
+// notes become a document with a deck for every "Parent::Child" deck name,
+// imported like a native one, and problems are reported by line, such as
+// lines[12].front.
+func (s *TransferService) ImportAnki(ctx context.Context, userID int64, data []byte, options AnkiImportOptions) (*model.ImportResult, error) {
+	notes, err := parseAnkiNotes(data, options.Deck)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.importDocument(ctx, userID, notes.document, options.ImportOptions, true)
+	if err != nil {
+		return nil, notes.lineErrors(err)
+	}
+	return result, nil
+}
+
+// ankiFile holds the headers of a file, which say how to read its rows.
+type ankiFile struct {
+	separator rune
+	html      bool
+	tags      []string
+	deck      string
+	noteType  string
+	// Columns counted from 0, or -1 when the file has none.
+	deckColumn, noteTypeColumn, tagsColumn, guidColumn int
+}
+
+// parseAnkiHeaders reads the "#key:value" lines at the start of the data and
+// returns the rest. Unknown headers are ignored.
+func parseAnkiHeaders(data []byte) (*ankiFile, []byte, int, error) {
+	file := &ankiFile{deckColumn: -1, noteTypeColumn: -1, tagsColumn: -1, guidColumn: -1}
+	errs := &model.ValidationError{}
+	lines := 0
+	for bytes.HasPrefix(data, []byte("#")) {
+		line := data
+		if end := bytes.IndexByte(data, '\n'); end >= 0 {
+			line, data = data[:end], data[end+1:]
+		} else {
+			data = nil
+		}
+		lines++
+		key, value, ok := strings.Cut(strings.TrimRight(string(line[1:]), "\r"), ":")
+		if !ok {
+			continue
+		}
+		key, field := strings.ToLower(strings.TrimSpace(key)), fmt.Sprintf("lines[%d]", lines)
+		switch key {
+		case "separator":
+			separator, ok := ankiSeparators[strings.ToLower(value)]
+			if !ok {
+				r, size := utf8.DecodeRuneInString(value)
+				if size == 0 || size != len(value) || r == '"' || r == utf8.RuneError {
+					errs.Add(field, "separator must be a single character or one of Tab, Comma, Semicolon, Space, Pipe and Colon")
+					continue
+				}
+				separator = r
+			}
+			file.separator = separator
+		case "html":
+			html, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				errs.Add(field, "html must be true or false")
+				continue
+			}
+			file.html = html
+		case "tags":
+			file.tags = strings.Fields(value)
+		case "deck":
+			file.deck = strings.TrimSpace(value)
+		case "notetype":
+			file.noteType = strings.TrimSpace(value)
+		case "deck column", "notetype column", "tags column", "guid column":
+			column, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || column < 1 {
+				errs.Add(field, key+" must be a positive integer")
+				continue
+			}
+			switch key {
+			case "deck column":
+				file.deckColumn = column - 1
+			case "notetype column":
+				file.noteTypeColumn = column - 1
+			case "tags column":
+				file.tagsColumn = column - 1
+			default:
+				file.guidColumn = column - 1
+			}
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, nil, 0, err
+	}
+	if file.separator == 0 {
+		file.separator = detectDelimiter(data)
+	}
+	return file, data, lines, nil
+}
+
+// ankiNotes is a file of notes turned into a document, with the line each
+// of its decks and cards came from.
+type ankiNotes struct {
+	document  *model.ExportDocument
+	deckLines []int
+	cardLines []int
+	// deckIDs maps deck paths, joined by "::", to their document IDs.
+	deckIDs map[string]int64
+}
+
+func parseAnkiNotes(data []byte, defaultDeck string) (*ankiNotes, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	file, rows, headerLines, err := parseAnkiHeaders(data)
+	if err != nil {
+		return nil, err
+	}
+	if defaultDeck == "" {
+		defaultDeck = DefaultAnkiDeck
+	}
+	if file.deck != "" {
+		defaultDeck = file.deck
+	}
+
+	notes := &ankiNotes{
+		document: &model.ExportDocument{Format: model.ExportFormat, Version: model.ExportVersion, Decks: []model.ExportedDeck{}, Cards: []model.ExportedCard{}},
+		deckIDs:  map[string]int64{},
+	}
+	errs := &model.ValidationError{}
+	for _, record := range readCSV(rows, file.separator) {
+		line := record.line + headerLines
+		field := fmt.Sprintf("lines[%d]", line)
+		if record.err != "" {
+			errs.Add(field, record.err)
+			continue
+		}
+		if blankRecord(record.fields) {
+			continue
+		}
+
+		deckName, noteType, tags := defaultDeck, file.noteType, append([]string(nil), file.tags...)
+		var fields []string
+		for i, value := range record.fields {
+			switch i {
+			case file.deckColumn:
+				if strings.TrimSpace(value) != "" {
+					deckName = value
+				}
+			case file.noteTypeColumn:
+				noteType = value
+			case file.tagsColumn:
+				tags = append(tags, strings.Fields(value)...)
+			case file.guidColumn:
+			default:
+				fields = append(fields, ankiText(value, file.html))
+			}
+		}
+
+		path, err := splitDeckPath(deckName)
+		if err != nil {
+			errs.Add(field+".deck", err.Error())
+			continue
+		}
+		card := ankiCard(noteType, fields)
+		card.DeckID = notes.deck(path, line)
+		card.Tags = tags
+		notes.document.Cards = append(notes.document.Cards, card)
+		notes.cardLines = append(notes.cardLines, line)
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+	if len(notes.document.Cards) == 0 {
+		return nil, model.NewValidationError("notes", "are required")
+	}
+	return notes, nil
+}
+
+// ankiCard maps the fields of a note to a card. Cloze notes keep their text
+// and extra in their first two fields, other notes their front and back;
+// the next field fills in the remaining card field and any further ones
+// are appended to the extra.
+func ankiCard(noteType string, fields []string) model.ExportedCard {
+	fields = append(fields, "", "", "")
+	card := model.ExportedCard{Type: ankiCardType(noteType, fields[0]), Front: fields[0]}
+	if card.Type == model.CardTypeCloze {
+		card.Extra, card.Back = fields[1], fields[2]
+	} else {
+		card.Back, card.Extra = fields[1], fields[2]
+	}
+	for _, field := range fields[3:] {
+		if strings.TrimSpace(field) != "" {
+			card.Extra = strings.TrimLeft(card.Extra+"\n"+field, "\n")
+		}
+	}
+	return card
+}
+
+// ankiCardType takes the type from the note type's name, such as "Cloze"
+// or "Basic (and reversed card)", and without one spots cloze deletions.
+func ankiCardType(noteType, front string) model.CardType {
+	name := strings.ToLower(noteType)
+	switch {
+	case strings.Contains(name, "cloze"):
+		return model.CardTypeCloze
+	case strings.Contains(name, "reverse"):
+		return model.CardTypeReverse
+	case name == "" && clozePattern.MatchString(front):
+		return model.CardTypeCloze
+	}
+	return model.CardTypeBasic
+}
+
+// ankiText turns a field into plain text. Fields in HTML have their line
+// breaks and paragraphs turned into newlines and other markup dropped.
+func ankiText(value string, isHTML bool) string {
+	if !isHTML {
+		return value
+	}
+	value = ankiBreakPattern.ReplaceAllString(value, "\n")
+	value = ankiTagPattern.ReplaceAllString(value, "")
+	value = strings.ReplaceAll(html.UnescapeString(value), "\u00a0", " ")
+	return strings.Trim(value, "\n")
+}
+
+// splitDeckPath splits a hierarchical deck name such as "Spanish::Verbs".
+func splitDeckPath(name string) ([]string, error) {
+	path := strings.Split(name, "::")
+	for i, segment := range path {
+		path[i] = strings.TrimSpace(segment)
+		if path[i] == "" {
+			return nil, errors.New(`must not have empty names around "::"`)
+		}
+	}
+	return path, nil
+}
+
+// deck returns the document ID of the deck at path, adding it and any
+// missing ancestors.
+func (n *ankiNotes) deck(path []string, line int) int64 {
+	var parentID *int64
+	for i := range path {
+		key := strings.Join(path[:i+1], "::")
+		id, ok := n.deckIDs[key]
+		if !ok {
+			id = int64(len(n.document.Decks) + 1)
+			n.deckIDs[key] = id
+			n.document.Decks = append(n.document.Decks, model.ExportedDeck{ID: id, ParentID: parentID, Name: path[i], Position: len(n.document.Decks)})
+			n.deckLines = append(n.deckLines, line)
+		}
+		parentID = &id
+	}
+	return *parentID
+}
+
+// lineErrors renames the document's decks[i] and cards[i] fields in a
+// validation error after the lines they came from.
+func (n *ankiNotes) lineErrors(err error) error {
+	var validation *model.ValidationError
+	if !errors.As(err, &validation) {
+		return err
+	}
+	errs := &model.ValidationError{}
+	for _, field := range validation.Fields {
+		if match := ankiFieldPattern.FindStringSubmatch(field.Field); match != nil {
+			index, _ := strconv.Atoi(match[2])
+			lines := n.cardLines
+			if match[1] == "decks" {
+				lines = n.deckLines
+			}
+			if index < len(lines) {
+				field.Field = fmt.Sprintf("lines[%d]", lines[index]) + field.Field[len(match[0]):]
+			}
+		}
+		errs.Fields = append(errs.Fields, field)
+	}
+	return errs
+}
+
+// EncodeAnki writes the export as Anki "Notes in Plain Text", tab separated
+// with HTML fields, naming decks by their path from the exported deck.
+// Cards are written with three fields, the third holding whatever the note
+// type has no field for, and tags have their spaces replaced by "_".
+func (e *DeckExport) EncodeAnki(ctx context.Context, w io.Writer) error {
+	return e.service.uow.Read(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		return e.encodeAnki(ctx, repos, w)
+	})
+}
+
+func (e *DeckExport) encodeAnki(ctx context.Context, repos repository.Repositories, w io.Writer) error {
+	out := &errWriter{w: w}
+	out.write("#separator:tab\n#html:true\n#notetype column:1\n#deck column:2\n#tags column:6\n")
+	writer := csv.NewWriter(out)
+	writer.Comma = '\t'
+
+	paths := make(map[int64]string, len(e.decks))
+	for i, deck := range e.decks {
+		paths[deck.ID] = deck.Name
+		if i > 0 && deck.ParentID != nil {
+			paths[deck.ID] = paths[*deck.ParentID] + "::" + deck.Name
+		}
+	}
+	err := e.eachCardPage(ctx, repos, func(deck *model.Deck, cards []*model.Card) error {
+		for _, card := range cards {
+			noteType, fields := AnkiNoteTypeBasic, []string{card.Front, card.Back, card.Extra}
+			switch card.Type {
+			case model.CardTypeCloze:
+				noteType, fields = AnkiNoteTypeCloze, []string{card.Front, card.Extra, card.Back}
+			case model.CardTypeReverse:
+				noteType = AnkiNoteTypeReverse
+			}
+			tags := make([]string, len(card.Tags))
+			for i, tag := range card.Tags {
+				tags[i] = strings.Join(strings.Fields(tag), "_")
+			}
+			_ = writer.Write([]string{noteType, paths[deck.ID], ankiEscaper.Replace(fields[0]), ankiEscaper.Replace(fields[1]), ankiEscaper.Replace(fields[2]), strings.Join(tags, " ")})
+		}
+		writer.Flush()
+		return out.err
+	})
+	if err != nil {
+		return err
+	}
+	return out.err
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"memwright/api/internal/auth"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type RegisterInput struct {
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DisplayName string `json:"display_name"`
+}
+
+type AuthResult struct {
+	User                  *model.User `json:"user"`
+	TokenType             string      `json:"token_type"`
+	AccessToken           string      `json:"access_token"`
+	AccessTokenExpiresAt  time.Time   `json:"access_token_expires_at"`
+	RefreshToken          string      `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time   `json:"refresh_token_expires_at"`
+}
+
+// AuthService registers users and manages their access and refresh tokens.
+// Refresh tokens rotate on every use; presenting a token that was already
+// rotated is treated as theft and revokes every token of the user.
+type AuthService struct {
+	users      repository.UserRepository
+	tokens     repository.RefreshTokenRepository
+	uow        repository.UnitOfWork
+	issuer     *auth.TokenManager
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+func NewAuthService(users repository.UserRepository, tokens repository.RefreshTokenRepository, uow repository.UnitOfWork, issuer *auth.TokenManager, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		users:      users,
+		tokens:     tokens,
+		uow:        uow,
+		issuer:     issuer,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*AuthResult, error) {
+	email, err := normalizeEmail(input.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	displayName := strings.TrimSpace(input.DisplayName)
+	if displayName == "" {
+		displayName = strings.SplitN(email, "@", 2)[0]
+	}
+
+	user := &model.User{
+		Email:         email,
+		PasswordHash:  hash,
+		DisplayName:   displayName,
+		SRSAlgorithm:  model.SRSAlgorithmSM2,
+		DailyNewCards: model.DefaultDailyNewCards,
+		DailyReviews:  model.DefaultDailyReviews,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user)
+}
+
+func (s *AuthService) Login(ctx context.Context, email, password string) (*AuthResult, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, model.ErrUnauthorized
+	}
+
+	user, err := s.users.GetByEmail(ctx, email)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, auth.RejectPassword(password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := auth.CheckPassword(user.PasswordHash, password); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new pair. Looking up, rotating
+// and revoking the token happen in one transaction, so two concurrent
+// requests cannot both rotate the same token.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*AuthResult, error) {
+	var result *AuthResult
+	var reused bool
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		result, reused = nil, false
+
+		current, err := repos.RefreshTokens.GetByHash(ctx, auth.HashRefreshToken(refreshToken))
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+
+		// Reuse of a rotated token must revoke the family even though the
+		// request fails, so it commits and is reported afterwards.
+		if current.IsRevoked() {
+			reused = true
+			return repos.RefreshTokens.RevokeAllForUser(ctx, current.UserID)
+		}
+		if current.IsExpired(s.now()) {
+			return model.ErrUnauthorized
+		}
+
+		user, err := repos.Users.GetByID(ctx, current.UserID)
+		if errors.Is(err, model.ErrNotFound) {
+			return model.ErrUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+
+		var next *model.RefreshToken
+		result, next, err = s.issueWithToken(ctx, repos.RefreshTokens, user)
+		if err != nil {
+			return err
+		}
+		return repos.RefreshTokens.Revoke(ctx, current.ID, &next.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, model.ErrUnauthorized
+	}
+	return result, nil
+}
+
+// Logout revokes the given refresh token. Unknown tokens are ignored so the
+// endpoint does not reveal which tokens exist.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	current, err := s.tokens.GetByHash(ctx, auth.HashRefreshToken(refreshToken))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.tokens.Revoke(ctx, current.ID, nil)
+}
+
+func (s *AuthService) issue(ctx context.Context, user *model.User) (*AuthResult, error) {
+	result, _, err := s.issueWithToken(ctx, s.tokens, user)
+	return result, err
+}
+
+func (s *AuthService) issueWithToken(ctx context.Context, tokens repository.RefreshTokenRepository, user *model.User) (*AuthResult, *model.RefreshToken, error) {
+	accessToken, accessExpiresAt, err := s.issuer.IssueAccessToken(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := &model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: refreshHash,
+		ExpiresAt: s.now().Add(s.refreshTTL),
+	}
+	if err := tokens.Create(ctx, stored); err != nil {
+		return nil, nil, err
+	}
+
+	return &AuthResult{
+		User:                  user,
+		TokenType:             "Bearer",
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt,
+	}, stored, nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", model.NewValidationError("email", "must be a valid email address")
+	}
+	return email, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// Authorizer scopes every deck, card, schedule and review log lookup to the
+// requesting user. Resources that do not exist yield model.ErrNotFound and
+// resources owned by someone else yield model.ErrForbidden, whichever
+// repository method is used underneath.
+type Authorizer struct {
+	decks      repository.DeckRepository
+	cards      repository.CardRepository
+	schedules  repository.CardScheduleRepository
+	reviewLogs repository.ReviewLogRepository
+}
+
+func NewAuthorizer(
+	decks repository.DeckRepository,
+	cards repository.CardRepository,
+	schedules repository.CardScheduleRepository,
+	reviewLogs repository.ReviewLogRepository,
+) *Authorizer {
+	return &Authorizer{
+		decks:      decks,
+		cards:      cards,
+		schedules:  schedules,
+		reviewLogs: reviewLogs,
+	}
+}
+
+func (a *Authorizer) Deck(ctx context.Context, userID, deckID int64) (*model.Deck, error) {
+	deck, err := a.decks.GetByID(ctx, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if deck.UserID != userID {
+		return nil, model.ErrForbidden
+	}
+	return deck, nil
+}
+
+// Card authorizes access through the deck that contains the card.
+func (a *Authorizer) Card(ctx context.Context, userID, cardID int64) (*model.Card, error) {
+	card, err := a.cards.GetByID(ctx, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := a.Deck(ctx, userID, card.DeckID); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (a *Authorizer) Schedule(ctx context.Context, userID, scheduleID int64) (*model.CardSchedule, error) {
+	schedule, err := a.schedules.GetByID(ctx, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule.UserID != userID {
+		return nil, model.ErrForbidden
+	}
+	return schedule, nil
+}
+
+func (a *Authorizer) ReviewLog(ctx context.Context, userID, logID int64) (*model.ReviewLog, error) {
+	log, err := a.reviewLogs.GetByID(ctx, logID)
+	if err != nil {
+		return nil, err
+	}
+	if log.UserID != userID {
+		return nil, model.ErrForbidden
+	}
+	return log, nil
+}
+
+func (a *Authorizer) CardsInDeck(ctx context.Context, userID, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	if _, err := a.Deck(ctx, userID, deckID); err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+	return a.cards.GetByDeckID(ctx, deckID, page)
+}
+
+func (a *Authorizer) DueCards(ctx context.Context, userID, deckID int64, dueBy time.Time, limit int) ([]*model.CardSchedule, error) {
+	if _, err := a.Deck(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+	return a.schedules.GetDueCards(ctx, userID, deckID, dueBy, limit)
+}
+
+func (a *Authorizer) NewCards(ctx context.Context, userID, deckID int64, limit int) ([]*model.CardSchedule, error) {
+	if _, err := a.Deck(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+	return a.schedules.GetNewCards(ctx, userID, deckID, limit)
+}
+
+// ScheduleForCard returns the user's own schedule of a card they can access.
+func (a *Authorizer) ScheduleForCard(ctx context.Context, userID, cardID int64) (*model.CardSchedule, error) {
+	if _, err := a.Card(ctx, userID, cardID); err != nil {
+		return nil, err
+	}
+	return a.schedules.GetByCardAndUser(ctx, cardID, userID)
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/srs"
+)
+
+type BacklogPriority string
+
+const (
+	// BacklogPriorityRetrievability reviews the cards most likely to be
+	// forgotten first.
+	BacklogPriorityRetrievability BacklogPriority = "retrievability"
+	// BacklogPriorityOverdueness reviews the cards that have waited the
+	// longest past their due date first.
+	BacklogPriorityOverdueness BacklogPriority = "overdueness"
+)
+
+type BacklogOptions struct {
+	DeckID      int64           `json:"deck_id,omitempty"`
+	DailyTarget int             `json:"daily_target"`
+	Priority    BacklogPriority `json:"priority"`
+}
+
+type BacklogAssignment struct {
+	ScheduleID     int64     `json:"schedule_id"`
+	CardID         int64     `json:"card_id"`
+	Day            int       `json:"day"`
+	PreviousDueAt  time.Time `json:"previous_due_at"`
+	NewDueAt       time.Time `json:"new_due_at"`
+	DaysOverdue    float64   `json:"days_overdue"`
+	Retrievability float64   `json:"retrievability"`
+}
+
+type BacklogDay struct {
+	Day  int       `json:"day"`
+	Date time.Time `json:"date"`
+	// Count is the number of overdue cards placed on the day, Scheduled the
+	// number of reviews that were already due that day.
+	Count     int `json:"count"`
+	Scheduled int `json:"scheduled"`
+}
+
+type BacklogPlan struct {
+	DailyTarget  int                 `json:"daily_target"`
+	Priority     BacklogPriority     `json:"priority"`
+	TotalOverdue int                 `json:"total_overdue"`
+	Days         []BacklogDay        `json:"days"`
+	Assignments  []BacklogAssignment `json:"assignments"`
+}
+
+// BacklogService spreads a pile of overdue reviews over the coming days so
+// that a user returning from a break faces at most DailyTarget reviews a day,
+// counting the reviews already due on those days.
+type BacklogService struct {
+	authorizer *Authorizer
+	schedules  repository.CardScheduleRepository
+	users      repository.UserRepository
+	uow        repository.UnitOfWork
+	now        func() time.Time
+}
+
+func NewBacklogService(authorizer *Authorizer, schedules repository.CardScheduleRepository, users repository.UserRepository, uow repository.UnitOfWork) *BacklogService {
+	return &BacklogService{
+		authorizer: authorizer,
+		schedules:  schedules,
+		users:      users,
+		uow:        uow,
+		now:        time.Now,
+	}
+}
+
+// WithClock replaces the time source, which keeps plans reproducible in tests.
+func (s *BacklogService) WithClock(now func() time.Time) *BacklogService {
+	s.now = now
+	return s
+}
+
+// Preview computes the redistribution without changing any schedule.
+func (s *BacklogService) Preview(ctx context.Context, userID int64, opts BacklogOptions) (*BacklogPlan, error) {
+	return s.plan(ctx, s.authorizer, s.schedules, userID, opts, s.now())
+}
+
+// Apply recomputes the plan and moves the due dates of every card that does
+// not fit into today's quota, in one unit of work. A card reviewed since it
+// was planned keeps its new due date.
+func (s *BacklogService) Apply(ctx context.Context, userID int64, opts BacklogOptions) (*BacklogPlan, error) {
+	var plan *BacklogPlan
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		authorizer := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+		var err error
+		plan, err = s.plan(ctx, authorizer, repos.Schedules, userID, opts, s.now())
+		if err != nil {
+			return err
+		}
+
+		var moves []model.DueDateMove
+		for _, assignment := range plan.Assignments {
+			if assignment.Day > 0 {
+				moves = append(moves, model.DueDateMove{ScheduleID: assignment.ScheduleID, From: assignment.PreviousDueAt, To: assignment.NewDueAt})
+			}
+		}
+		return repos.Schedules.UpdateDueDates(ctx, userID, moves)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *BacklogService) plan(ctx context.Context, authorizer *Authorizer, schedules repository.CardScheduleRepository, userID int64, opts BacklogOptions, now time.Time) (*BacklogPlan, error) {
+	if opts.Priority == "" {
+		opts.Priority = BacklogPriorityRetrievability
+	}
+	if opts.Priority != BacklogPriorityRetrievability && opts.Priority != BacklogPriorityOverdueness {
+		return nil, model.NewValidationError("priority", fmt.Sprintf("must be %q or %q", BacklogPriorityRetrievability, BacklogPriorityOverdueness))
+	}
+	if opts.DailyTarget < 0 {
+		return nil, model.NewValidationError("daily_target", "must not be negative")
+	}
+	if opts.DeckID != 0 {
+		if _, err := authorizer.Deck(ctx, userID, opts.DeckID); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DailyTarget == 0 {
+		target, err := s.defaultDailyTarget(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		opts.DailyTarget = target
+	}
+
+	overdue, err := schedules.GetOverdue(ctx, userID, opts.DeckID, now)
+	if err != nil {
+		return nil, err
+	}
+	scheduled, err := schedules.CountDueByDay(ctx, userID, opts.DeckID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := make([]BacklogAssignment, 0, len(overdue))
+	for _, schedule := range overdue {
+		assignments = append(assignments, BacklogAssignment{
+			ScheduleID:     schedule.ID,
+			CardID:         schedule.CardID,
+			PreviousDueAt:  schedule.DueAt,
+			DaysOverdue:    now.Sub(schedule.DueAt).Hours() / 24,
+			Retrievability: retrievabilityAt(schedule, now),
+		})
+	}
+	sortAssignments(assignments, opts.Priority)
+
+	plan := &BacklogPlan{
+		DailyTarget:  opts.DailyTarget,
+		Priority:     opts.Priority,
+		TotalOverdue: len(assignments),
+		Days:         []BacklogDay{},
+		Assignments:  assignments,
+	}
+
+	// Each card goes on the first day with room left once the reviews
+	// already due that day are counted.
+	day := -1
+	for i := range assignments {
+		for day < 0 || plan.Days[day].Scheduled+plan.Days[day].Count >= opts.DailyTarget {
+			day++
+			plan.Days = append(plan.Days, BacklogDay{Day: day, Date: now.AddDate(0, 0, day), Scheduled: scheduled[day]})
+		}
+		assignments[i].Day = day
+		if day == 0 {
+			assignments[i].NewDueAt = assignments[i].PreviousDueAt
+		} else {
+			assignments[i].NewDueAt = now.AddDate(0, 0, day)
+		}
+		plan.Days[day].Count++
+	}
+
+	return plan, nil
+}
+
+func (s *BacklogService) defaultDailyTarget(ctx context.Context, userID int64) (int, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, model.ErrNotFound) {
+		return model.DefaultDailyReviews, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if user.DailyReviews > 0 {
+		return user.DailyReviews, nil
+	}
+	return model.DefaultDailyReviews, nil
+}
+
+func retrievabilityAt(schedule *model.CardSchedule, now time.Time) float64 {
+	lastReview := schedule.DueAt.AddDate(0, 0, -schedule.Interval)
+	if schedule.LastReviewedAt != nil {
+		lastReview = *schedule.LastReviewedAt
+	}
+	return srs.Retrievability(schedule.Interval, now.Sub(lastReview).Hours()/24)
+}
+
+func sortAssignments(assignments []BacklogAssignment, priority BacklogPriority) {
+	sort.SliceStable(assignments, func(i, j int) bool {
+		a, b := assignments[i], assignments[j]
+		switch priority {
+		case BacklogPriorityOverdueness:
+			if a.DaysOverdue != b.DaysOverdue {
+				return a.DaysOverdue > b.DaysOverdue
+			}
+		default:
+			if a.Retrievability != b.Retrievability {
+				return a.Retrievability < b.Retrievability
+			}
+		}
+		return a.ScheduleID < b.ScheduleID
+	})
+}
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+const MaxBulkCards = 1000
+
+type CardInput struct {
+	DeckID    *int64         `json:"deck_id,omitempty"`
+	Type      model.CardType `json:"type"`
+	Front     string         `json:"front"`
+	Back      string         `json:"back"`
+	Extra     string         `json:"extra"`
+	Tags      []string       `json:"tags"`
+	Position  int            `json:"position"`
+	Suspended bool           `json:"suspended"`
+	// Version, when set, is the version the client last read; Update fails
+	// with ErrConflict if the card changed since.
+	Version int `json:"version,omitempty"`
+}
+
+type BulkOperation string
+
+const (
+	BulkAddTags    BulkOperation = "add_tags"
+	BulkRemoveTags BulkOperation = "remove_tags"
+	BulkMove       BulkOperation = "move"
+	BulkChangeType BulkOperation = "change_type"
+	BulkSuspend    BulkOperation = "suspend"
+	BulkUnsuspend  BulkOperation = "unsuspend"
+	BulkDelete     BulkOperation = "delete"
+)
+
+type BulkCardRequest struct {
+	Operation BulkOperation  `json:"operation"`
+	CardIDs   []int64        `json:"card_ids"`
+	Tags      []string       `json:"tags,omitempty"`
+	DeckID    int64          `json:"deck_id,omitempty"`
+	Type      model.CardType `json:"type,omitempty"`
+}
+
+type BulkItemStatus string
+
+const (
+	BulkItemOK         BulkItemStatus = "ok"
+	BulkItemFailed     BulkItemStatus = "failed"
+	BulkItemRolledBack BulkItemStatus = "rolled_back"
+)
+
+type BulkItemResult struct {
+	CardID int64          `json:"card_id"`
+	Status BulkItemStatus `json:"status"`
+	Error  string         `json:"error,omitempty"`
+}
+
+type BulkCardResult struct {
+	Operation BulkOperation    `json:"operation"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+type CardService struct {
+	authorizer *Authorizer
+	cards      repository.CardRepository
+	uow        repository.UnitOfWork
+}
+
+func NewCardService(authorizer *Authorizer, cards repository.CardRepository, uow repository.UnitOfWork) *CardService {
+	return &CardService{
+		authorizer: authorizer,
+		cards:      cards,
+		uow:        uow,
+	}
+}
+
+func (s *CardService) List(ctx context.Context, userID, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	return s.authorizer.CardsInDeck(ctx, userID, deckID, page)
+}
+
+func (s *CardService) Get(ctx context.Context, userID, cardID int64) (*model.Card, error) {
+	return s.authorizer.Card(ctx, userID, cardID)
+}
+
+func (s *CardService) Create(ctx context.Context, userID, deckID int64, input CardInput) (*model.Card, error) {
+	if _, err := s.authorizer.Deck(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	card := &model.Card{DeckID: deckID}
+	if err := applyCardInput(card, input); err != nil {
+		return nil, err
+	}
+	if err := s.cards.Create(ctx, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// Update replaces the card's content. Setting deck_id moves the card to
+// another deck of the same user.
+func (s *CardService) Update(ctx context.Context, userID, cardID int64, input CardInput) (*model.Card, error) {
+	card, err := s.authorizer.Card(ctx, userID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Version != 0 && input.Version != card.Version {
+		return nil, model.ErrConflict
+	}
+	if input.DeckID != nil && *input.DeckID != card.DeckID {
+		if _, err := s.authorizer.Deck(ctx, userID, *input.DeckID); err != nil {
+			return nil, err
+		}
+		card.DeckID = *input.DeckID
+	}
+
+	if err := applyCardInput(card, input); err != nil {
+		return nil, err
+	}
+	if err := s.cards.Update(ctx, card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (s *CardService) Delete(ctx context.Context, userID, cardID int64) error {
+	if _, err := s.authorizer.Card(ctx, userID, cardID); err != nil {
+		return err
+	}
+	return s.cards.Delete(ctx, cardID)
+}
+
+// Bulk applies one operation to many cards inside a single transaction. The
+// operation is all or nothing: if any card cannot be changed, nothing is
+// committed and the result reports which items failed and why.
+func (s *CardService) Bulk(ctx context.Context, userID int64, request BulkCardRequest) (*BulkCardResult, error) {
+	if err := validateBulkRequest(&request); err != nil {
+		return nil, err
+	}
+
+	var result *BulkCardResult
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		authorizer := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+		if request.Operation == BulkMove {
+			if _, err := authorizer.Deck(ctx, userID, request.DeckID); err != nil {
+				return err
+			}
+		}
+
+		result = &BulkCardResult{
+			Operation: request.Operation,
+			Results:   make([]BulkItemResult, 0, len(request.CardIDs)),
+		}
+		for _, cardID := range request.CardIDs {
+			err := applyBulkOperation(ctx, authorizer, repos.Cards, userID, cardID, request)
+			if err != nil && !isItemError(err) {
+				return err
+			}
+
+			item := BulkItemResult{CardID: cardID, Status: BulkItemOK}
+			if err != nil {
+				item.Status = BulkItemFailed
+				item.Error = err.Error()
+				result.Failed++
+			} else {
+				result.Succeeded++
+			}
+			result.Results = append(result.Results, item)
+		}
+
+		if result.Failed > 0 {
+			return errBulkRolledBack
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBulkRolledBack) {
+		for i := range result.Results {
+			if result.Results[i].Status == BulkItemOK {
+				result.Results[i].Status = BulkItemRolledBack
+			}
+		}
+		result.Succeeded = 0
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// errBulkRolledBack aborts the unit of work when an item failed; the
+// caller still gets the per-item result.
+var errBulkRolledBack = errors.New("bulk operation rolled back")
+
+func applyBulkOperation(ctx context.Context, authorizer *Authorizer, cards repository.CardRepository, userID, cardID int64, request BulkCardRequest) error {
+	card, err := authorizer.Card(ctx, userID, cardID)
+	if err != nil {
+		return err
+	}
+
+	switch request.Operation {
+	case BulkDelete:
+		return cards.Delete(ctx, cardID)
+	case BulkAddTags:
+		card.AddTags(request.Tags...)
+	case BulkRemoveTags:
+		card.RemoveTags(request.Tags...)
+	case BulkMove:
+		card.DeckID = request.DeckID
+	case BulkChangeType:
+		card.Type = request.Type
+	case BulkSuspend:
+		card.Suspended = true
+	case BulkUnsuspend:
+		card.Suspended = false
+	}
+	return cards.Update(ctx, card)
+}
+
+// isItemError reports whether err concerns a single card rather than the
+// whole transaction.
+func isItemError(err error) bool {
+	return errors.Is(err, model.ErrNotFound) ||
+		errors.Is(err, model.ErrForbidden) ||
+		errors.Is(err, model.ErrInvalidInput)
+}
+
+func validateBulkRequest(request *BulkCardRequest) error {
+	errs := &model.ValidationError{}
+	switch {
+	case len(request.CardIDs) == 0:
+		errs.Add("card_ids", "is required")
+	case len(request.CardIDs) > MaxBulkCards:
+		errs.Add("card_ids", fmt.Sprintf("must contain at most %d cards", MaxBulkCards))
+	default:
+		seen := make(map[int64]bool, len(request.CardIDs))
+		for i, id := range request.CardIDs {
+			field := fmt.Sprintf("card_ids[%d]", i)
+			if id <= 0 {
+				errs.Add(field, "must be a positive integer")
+			} else if seen[id] {
+				errs.Add(field, fmt.Sprintf("duplicates card %d", id))
+			}
+			seen[id] = true
+		}
+	}
+
+	switch request.Operation {
+	case BulkAddTags, BulkRemoveTags:
+		validateTags(errs, request.Tags)
+		request.Tags = model.NormalizeTags(request.Tags)
+		if len(request.Tags) == 0 {
+			errs.Add("tags", "is required for "+string(request.Operation))
+		}
+	case BulkMove:
+		if request.DeckID <= 0 {
+			errs.Add("deck_id", "is required for move")
+		}
+	case BulkChangeType:
+		if !request.Type.IsValid() {
+			errs.Add("type", "must be a valid card type")
+		}
+	case BulkSuspend, BulkUnsuspend, BulkDelete:
+	default:
+		errs.Add("operation", "must be one of add_tags, remove_tags, move, change_type, suspend, unsuspend, delete")
+	}
+	return errs.OrNil()
+}
+
+func applyCardInput(card *model.Card, input CardInput) error {
+	errs := &model.ValidationError{}
+	cardType := input.Type
+	if cardType == "" {
+		cardType = model.CardTypeBasic
+	}
+	if !cardType.IsValid() {
+		errs.Add("type", "must be a valid card type")
+	}
+	if strings.TrimSpace(input.Front) == "" {
+		errs.Add("front", "is required")
+	}
+	if input.Position < 0 {
+		errs.Add("position", "must not be negative")
+	}
+	validateTags(errs, input.Tags)
+	if err := errs.OrNil(); err != nil {
+		return err
+	}
+
+	card.Type = cardType
+	card.Front = input.Front
+	card.Back = input.Back
+	card.Extra = input.Extra
+	card.Tags = model.NormalizeTags(input.Tags)
+	card.Position = input.Position
+	card.Suspended = input.Suspended
+	return nil
+}
+
+// validateTags rejects hierarchical tags with empty segments. Blank tags
+// are dropped by NormalizeTags instead.
+func validateTags(errs *model.ValidationError, tags []string) {
+	for i, tag := range tags {
+		if tag = model.NormalizeTag(tag); tag != "" && !model.ValidTag(tag) {
+			errs.Add(fmt.Sprintf("tags[%d]", i), `must not contain empty segments around "::"`)
+		}
+	}
+}
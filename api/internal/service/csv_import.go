@@ -0,0 +1,413 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// MaxCSVPreviewRows bounds how many rows a preview reads.
+const MaxCSVPreviewRows = 100
+
+// csvDelimiters are the delimiters tried when none is given, preferred in
+// this order on a tie.
+var csvDelimiters = []rune{'\t', ',', ';', '|'}
+
+// csvSniffRows is how many records delimiter detection looks at.
+const csvSniffRows = 10
+
+type CSVImportOptions struct {
+	// Delimiter is detected when zero.
+	Delimiter rune
+	// Header says whether the first row names the columns; nil detects it
+	// from the names.
+	Header *bool
+	// Columns maps each column to a card field, "" ignoring it. Without
+	// it, the header's names are used, or else front and back.
+	Columns []string
+	// Key, when set, is the field by which rows update existing cards of
+	// the deck instead of adding new ones.
+	Key string
+	// Preview, when positive, reads only that many rows and reports what
+	// importing them would do without changing anything.
+	Preview int
+}
+
+// csvRecord is a row of the file with the line it starts on, or the
+// syntax error that kept it from being read.
+type csvRecord struct {
+	line   int
+	fields []string
+	err    string
+}
+
+// errCSVPreview rolls back the unit of work of a preview.
+var errCSVPreview = errors.New("csv preview rolled back")
+
+// ImportCSV adds or updates cards of a deck from a spreadsheet. Rows that
+// fail validation are reported and the others imported all the same.
+func (s *TransferService) ImportCSV(ctx context.Context, userID, deckID int64, data []byte, options CSVImportOptions) (*model.CSVImportResult, error) {
+	if options.Preview < 0 || options.Preview > MaxCSVPreviewRows {
+		return nil, model.NewValidationError("preview", fmt.Sprintf("must be between 1 and %d", MaxCSVPreviewRows))
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if options.Delimiter == 0 {
+		options.Delimiter = detectDelimiter(data)
+	}
+	records := readCSV(data, options.Delimiter)
+
+	result := &model.CSVImportResult{Preview: options.Preview > 0, Delimiter: string(options.Delimiter), Rows: []model.CSVRow{}}
+	if len(records) > 0 && records[0].err == "" && isCSVHeader(records[0].fields, options.Header) {
+		result.Header = records[0].fields
+		records = records[1:]
+	}
+	columns, err := csvColumns(options.Columns, result.Header)
+	if err != nil {
+		return nil, err
+	}
+	result.Columns = columns
+	if options.Key != "" && (options.Key == model.CSVColumnTags || options.Key == model.CSVColumnType || !contains(columns, options.Key)) {
+		return nil, model.NewValidationError("key", "must be front, back or extra and mapped to a column")
+	}
+	if result.Preview && len(records) > options.Preview {
+		records = records[:options.Preview]
+	}
+
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs).Deck(ctx, userID, deckID); err != nil {
+			return err
+		}
+		*result = model.CSVImportResult{
+			Preview:   result.Preview,
+			Delimiter: result.Delimiter,
+			Header:    result.Header,
+			Columns:   result.Columns,
+			Rows:      []model.CSVRow{},
+		}
+		importer := &csvImporter{repos: repos, deckID: deckID, columns: columns, key: options.Key, result: result}
+		if err := importer.load(ctx); err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := importer.importRow(ctx, record); err != nil {
+				return err
+			}
+		}
+		if result.Preview {
+			return errCSVPreview
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errCSVPreview) {
+		return nil, err
+	}
+	return result, nil
+}
+
+type csvImporter struct {
+	repos   repository.Repositories
+	deckID  int64
+	columns []string
+	key     string
+	result  *model.CSVImportResult
+	// byKey indexes the deck's cards by their key field.
+	byKey map[string]*model.Card
+	next  int
+}
+
+// load indexes the deck's cards and finds the position after the last.
+func (im *csvImporter) load(ctx context.Context) error {
+	im.byKey = map[string]*model.Card{}
+	page := model.PageRequest{Limit: exportPageSize}
+	for {
+		cards, err := im.repos.Cards.GetByDeckID(ctx, im.deckID, page)
+		if err != nil {
+			return err
+		}
+		for _, card := range cards.Items {
+			if key := csvKey(card, im.key); key != "" {
+				if _, ok := im.byKey[key]; !ok {
+					im.byKey[key] = card
+				}
+			}
+			if card.Position >= im.next {
+				im.next = card.Position + 1
+			}
+		}
+		if cards.NextCursor == "" {
+			return nil
+		}
+		page.Cursor = cards.NextCursor
+	}
+}
+
+func (im *csvImporter) importRow(ctx context.Context, record csvRecord) error {
+	if record.err != "" {
+		im.fail(model.CSVRow{Line: record.line, Errors: []model.FieldError{{Field: "row", Message: record.err}}})
+		return nil
+	}
+	if blankRecord(record.fields) {
+		return nil
+	}
+	values := map[string]string{}
+	for i, field := range record.fields {
+		if i < len(im.columns) && im.columns[i] != "" {
+			values[im.columns[i]] = field
+		}
+	}
+
+	row := model.CSVRow{Line: record.line}
+	input := CardInput{
+		Type:  model.CardType(strings.ToLower(strings.TrimSpace(values[model.CSVColumnType]))),
+		Front: values[model.CSVColumnFront],
+		Back:  values[model.CSVColumnBack],
+		Extra: values[model.CSVColumnExtra],
+		Tags:  splitCSVTags(values[model.CSVColumnTags]),
+	}
+	existing := im.byKey[strings.TrimSpace(values[im.key])]
+	if im.key != "" && existing != nil {
+		input = mergeCSVInput(existing, input, im.key, values)
+	}
+
+	card := &model.Card{DeckID: im.deckID}
+	if existing != nil {
+		copied := *existing
+		card = &copied
+		input.Position, input.Suspended = existing.Position, existing.Suspended
+	}
+	if err := applyCardInput(card, input); err != nil {
+		var validation *model.ValidationError
+		if !errors.As(err, &validation) {
+			return err
+		}
+		row.Errors = validation.Fields
+		im.fail(row)
+		return nil
+	}
+
+	switch {
+	case existing != nil && len(cardDifferences(existing, card)) == 0 && existing.Type == card.Type && existing.Front == card.Front:
+		row.Status, row.CardID = model.CSVRowUnchanged, existing.ID
+		im.result.Unchanged++
+	case existing != nil:
+		if err := im.repos.Cards.Update(ctx, card); err != nil {
+			return err
+		}
+		*existing = *card
+		row.Status, row.CardID = model.CSVRowUpdated, card.ID
+		im.result.Updated++
+	default:
+		card.Position = im.next
+		if err := im.repos.Cards.Create(ctx, card); err != nil {
+			return err
+		}
+		im.next++
+		if key := csvKey(card, im.key); key != "" {
+			im.byKey[key] = card
+		}
+		row.Status = model.CSVRowCreated
+		if !im.result.Preview {
+			row.CardID = card.ID
+		}
+		im.result.Created++
+	}
+	if im.result.Preview {
+		row.Type, row.Front, row.Back, row.Extra, row.Tags = card.Type, card.Front, card.Back, card.Extra, card.Tags
+		im.result.Rows = append(im.result.Rows, row)
+	}
+	return nil
+}
+
+func (im *csvImporter) fail(row model.CSVRow) {
+	row.Status = model.CSVRowFailed
+	im.result.Failed++
+	im.result.Rows = append(im.result.Rows, row)
+}
+
+// mergeCSVInput keeps the fields of an existing card that no column sets,
+// and its key as it was rather than as the row spaces it.
+func mergeCSVInput(existing *model.Card, input CardInput, key string, values map[string]string) CardInput {
+	if _, ok := values[model.CSVColumnType]; !ok {
+		input.Type = existing.Type
+	}
+	if _, ok := values[model.CSVColumnBack]; !ok || key == model.CSVColumnBack {
+		input.Back = existing.Back
+	}
+	if _, ok := values[model.CSVColumnExtra]; !ok || key == model.CSVColumnExtra {
+		input.Extra = existing.Extra
+	}
+	if key == model.CSVColumnFront {
+		input.Front = existing.Front
+	}
+	if _, ok := values[model.CSVColumnTags]; !ok {
+		input.Tags = existing.Tags
+	}
+	return input
+}
+
+func csvKey(card *model.Card, key string) string {
+	switch key {
+	case model.CSVColumnFront:
+		return strings.TrimSpace(card.Front)
+	case model.CSVColumnBack:
+		return strings.TrimSpace(card.Back)
+	case model.CSVColumnExtra:
+		return strings.TrimSpace(card.Extra)
+	}
+	return ""
+}
+
+// splitCSVTags splits a tags cell on commas or semicolons when it has any,
+// and on whitespace otherwise, as Anki writes tags.
+func splitCSVTags(cell string) []string {
+	if strings.ContainsAny(cell, ",;") {
+		return strings.FieldsFunc(cell, func(r rune) bool { return r == ',' || r == ';' })
+	}
+	return strings.Fields(cell)
+}
+
+// readCSV reads the records of the data. Quoted fields may span lines and
+// rows may have any number of fields; a row with a syntax error is kept
+// with the error, and reading goes on after it.
+func readCSV(data []byte, delimiter rune) []csvRecord {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	var records []csvRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			records = append(records, csvRecord{line: parseErr.StartLine, err: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return append(records, csvRecord{err: err.Error()})
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, csvRecord{line: line, fields: fields})
+	}
+}
+
+func blankRecord(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// detectDelimiter picks the candidate that splits the first records into
+// the same number of fields, the most fields winning. Data that no
+// candidate splits has a single column and is read as comma separated.
+func detectDelimiter(data []byte) rune {
+	best, bestFields := ',', 1
+	for _, delimiter := range csvDelimiters {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+		fields := 0
+		for i := 0; i < csvSniffRows; i++ {
+			record, err := reader.Read()
+			if err != nil {
+				break
+			}
+			if fields != 0 && len(record) != fields {
+				fields = 0
+				break
+			}
+			fields = len(record)
+		}
+		if fields > bestFields {
+			best, bestFields = delimiter, fields
+		}
+	}
+	return best
+}
+
+// isCSVHeader reports whether the first row is a header: as told, or else
+// when it names a card field.
+func isCSVHeader(fields []string, header *bool) bool {
+	if header != nil {
+		return *header
+	}
+	for _, field := range fields {
+		if validCSVColumn(normalizeCSVColumn(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// csvColumns resolves the field of each column from the requested mapping
+// or the header.
+func csvColumns(requested, header []string) ([]string, error) {
+	var columns []string
+	switch {
+	case requested != nil:
+		columns = make([]string, len(requested))
+		for i, column := range requested {
+			columns[i] = normalizeCSVColumn(column)
+			if columns[i] == "-" || columns[i] == "skip" {
+				columns[i] = ""
+			}
+			if columns[i] != "" && !validCSVColumn(columns[i]) {
+				return nil, model.NewValidationError(fmt.Sprintf("columns[%d]", i), "must be front, back, extra, tags, type or empty")
+			}
+		}
+	case header != nil:
+		columns = make([]string, len(header))
+		for i, name := range header {
+			if name = normalizeCSVColumn(name); validCSVColumn(name) {
+				columns[i] = name
+			}
+		}
+	default:
+		columns = []string{model.CSVColumnFront, model.CSVColumnBack}
+	}
+
+	seen := map[string]bool{}
+	for i, column := range columns {
+		if column != "" && seen[column] {
+			return nil, model.NewValidationError(fmt.Sprintf("columns[%d]", i), fmt.Sprintf("maps %s a second time", column))
+		}
+		seen[column] = true
+	}
+	if !seen[model.CSVColumnFront] {
+		return nil, model.NewValidationError("columns", "must map a column to front")
+	}
+	return columns, nil
+}
+
+func normalizeCSVColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func validCSVColumn(name string) bool {
+	switch name {
+	case model.CSVColumnFront, model.CSVColumnBack, model.CSVColumnExtra, model.CSVColumnTags, model.CSVColumnType:
+		return true
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
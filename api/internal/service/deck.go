@@ -0,0 +1,470 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+type DeckInput struct {
+	ParentID    *int64           `json:"parent_id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Algorithm   string           `json:"algorithm"`
+	Language    string           `json:"language"`
+	SRSConfig   *model.SRSConfig `json:"srs_config"`
+	// Position places the deck among its siblings. Without it, a new deck
+	// or one given a new parent goes last and other decks stay put.
+	Position *int `json:"position"`
+	// Version, when set, is the version the client last read; Update fails
+	// with ErrConflict if the deck changed since.
+	Version int `json:"version,omitempty"`
+}
+
+// MoveDeckRequest places a deck below a new parent, or at the top level
+// when ParentID is nil, at Position among its new siblings or last.
+type MoveDeckRequest struct {
+	ParentID *int64 `json:"parent_id"`
+	Position *int   `json:"position"`
+	Version  int    `json:"version,omitempty"`
+}
+
+// ReorderDecksRequest lists every deck below ParentID in their new order.
+type ReorderDecksRequest struct {
+	ParentID *int64  `json:"parent_id"`
+	DeckIDs  []int64 `json:"deck_ids"`
+}
+
+// DeckService keeps the decks below each parent numbered 0..n-1: creating,
+// moving and deleting a deck renumber its siblings in the same unit of
+// work.
+type DeckService struct {
+	authorizer *Authorizer
+	decks      repository.DeckRepository
+	uow        repository.UnitOfWork
+	now        func() time.Time
+}
+
+func NewDeckService(authorizer *Authorizer, decks repository.DeckRepository, uow repository.UnitOfWork) *DeckService {
+	return &DeckService{
+		authorizer: authorizer,
+		decks:      decks,
+		uow:        uow,
+		now:        time.Now,
+	}
+}
+
+// Tree returns the user's top-level decks with their subdecks nested below.
+// Pagination applies to the top-level decks; each comes with its whole
+// subtree. The page and the subtrees are read from one snapshot.
+func (s *DeckService) Tree(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.DeckNode], error) {
+	var tree model.Page[*model.DeckNode]
+	err := s.uow.Read(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		roots, err := repos.Decks.GetByUserID(ctx, userID, page)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, len(roots.Items))
+		for i, root := range roots.Items {
+			ids[i] = root.ID
+		}
+		nodes, err := s.nodes(ctx, repos.Decks, userID, ids)
+		if err != nil {
+			return err
+		}
+
+		tree = model.Page[*model.DeckNode]{Items: []*model.DeckNode{}, NextCursor: roots.NextCursor}
+		for _, id := range ids {
+			if node, ok := nodes[id]; ok {
+				tree.Items = append(tree.Items, node)
+			}
+		}
+		return nil
+	})
+	return tree, err
+}
+
+// Get returns a single deck with its subtree.
+func (s *DeckService) Get(ctx context.Context, userID, deckID int64) (*model.DeckNode, error) {
+	if _, err := s.authorizer.Deck(ctx, userID, deckID); err != nil {
+		return nil, err
+	}
+
+	nodes, err := s.nodes(ctx, s.decks, userID, []int64{deckID})
+	if err != nil {
+		return nil, err
+	}
+	node, ok := nodes[deckID]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	return node, nil
+}
+
+// Subdecks returns the direct children of a deck, each with its own subtree.
+func (s *DeckService) Subdecks(ctx context.Context, userID, deckID int64) ([]*model.DeckNode, error) {
+	node, err := s.Get(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	return node.Children, nil
+}
+
+func (s *DeckService) Create(ctx context.Context, userID int64, input DeckInput) (*model.Deck, error) {
+	deck := &model.Deck{UserID: userID}
+	if err := s.apply(ctx, userID, deck, input); err != nil {
+		return nil, err
+	}
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		return place(ctx, repos.Decks, deck, nil, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+func (s *DeckService) Update(ctx context.Context, userID, deckID int64, input DeckInput) (*model.Deck, error) {
+	var deck *model.Deck
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		deck, err = NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs).Deck(ctx, userID, deckID)
+		if err != nil {
+			return err
+		}
+		if input.Version != 0 && input.Version != deck.Version {
+			return model.ErrConflict
+		}
+		from := deck.ParentID
+		if err := s.apply(ctx, userID, deck, input); err != nil {
+			return err
+		}
+		return place(ctx, repos.Decks, deck, from, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+// Move changes only the parent and position of a deck.
+func (s *DeckService) Move(ctx context.Context, userID, deckID int64, request MoveDeckRequest) (*model.Deck, error) {
+	if request.Position != nil && *request.Position < 0 {
+		return nil, model.NewValidationError("position", "must not be negative")
+	}
+
+	var deck *model.Deck
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		authorizer := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+		var err error
+		if deck, err = authorizer.Deck(ctx, userID, deckID); err != nil {
+			return err
+		}
+		if request.Version != 0 && request.Version != deck.Version {
+			return model.ErrConflict
+		}
+		if request.ParentID != nil {
+			if _, err := authorizer.Deck(ctx, userID, *request.ParentID); err != nil {
+				return err
+			}
+		}
+		from := deck.ParentID
+		deck.ParentID, deck.Position = request.ParentID, newPosition(request.Position)
+		return place(ctx, repos.Decks, deck, from, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+// Archive keeps a deck and its subdecks with their cards and history but
+// leaves them out of study queues and counts. version, when set, must be
+// the deck's current version.
+func (s *DeckService) Archive(ctx context.Context, userID, deckID int64, version int) (*model.Deck, error) {
+	return s.setArchived(ctx, userID, deckID, true, version)
+}
+
+func (s *DeckService) Unarchive(ctx context.Context, userID, deckID int64, version int) (*model.Deck, error) {
+	return s.setArchived(ctx, userID, deckID, false, version)
+}
+
+// setArchived leaves a deck that is already in the requested state as it is.
+func (s *DeckService) setArchived(ctx context.Context, userID, deckID int64, archived bool, version int) (*model.Deck, error) {
+	deck, err := s.authorizer.Deck(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != deck.Version {
+		return nil, model.ErrConflict
+	}
+	if archived == (deck.ArchivedAt != nil) {
+		return deck, nil
+	}
+
+	deck.ArchivedAt = nil
+	if archived {
+		archivedAt := s.now().UTC().Truncate(time.Microsecond)
+		deck.ArchivedAt = &archivedAt
+	}
+	if err := s.decks.Update(ctx, deck); err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+// Reorder renumbers the decks below a parent in the order given, which must
+// name each of them exactly once.
+func (s *DeckService) Reorder(ctx context.Context, userID int64, request ReorderDecksRequest) ([]*model.Deck, error) {
+	if len(request.DeckIDs) == 0 {
+		return nil, model.NewValidationError("deck_ids", "is required")
+	}
+	if request.ParentID != nil {
+		if _, err := s.authorizer.Deck(ctx, userID, *request.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	var decks []*model.Deck
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		siblings, err := repos.Decks.Children(ctx, userID, request.ParentID)
+		if err != nil {
+			return err
+		}
+		listed := make(map[int64]bool, len(request.DeckIDs))
+		for _, id := range request.DeckIDs {
+			listed[id] = true
+		}
+		if len(listed) != len(request.DeckIDs) || len(listed) != len(siblings) {
+			return model.NewValidationError("deck_ids", "must list every deck below the parent exactly once")
+		}
+		for _, sibling := range siblings {
+			if !listed[sibling.ID] {
+				return model.NewValidationError("deck_ids", "must list every deck below the parent exactly once")
+			}
+		}
+
+		if err := repos.Decks.Renumber(ctx, request.DeckIDs); err != nil {
+			return err
+		}
+		decks, err = repos.Decks.Children(ctx, userID, request.ParentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decks, nil
+}
+
+// Delete moves the deck, its subdecks and their cards to the trash.
+func (s *DeckService) Delete(ctx context.Context, userID, deckID int64) error {
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		deck, err := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs).Deck(ctx, userID, deckID)
+		if err != nil {
+			return err
+		}
+		if err := repos.Decks.Delete(ctx, deckID); err != nil {
+			return err
+		}
+		return renumber(ctx, repos.Decks, userID, deck.ParentID)
+	})
+}
+
+// place stores the deck at deck.Position below its parent, clamped to the
+// end, and renumbers its siblings around it. A deck that changed parent
+// also closes the gap it left behind.
+func place(ctx context.Context, decks repository.DeckRepository, deck *model.Deck, from *int64, create bool) error {
+	if !create {
+		if err := checkCycle(ctx, decks, deck.ID, deck.ParentID); err != nil {
+			return err
+		}
+	}
+	siblings, err := decks.Children(ctx, deck.UserID, deck.ParentID)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, 0, len(siblings)+1)
+	for _, sibling := range siblings {
+		if sibling.ID != deck.ID {
+			ids = append(ids, sibling.ID)
+		}
+	}
+	if deck.Position > len(ids) {
+		deck.Position = len(ids)
+	}
+
+	if create {
+		err = decks.Create(ctx, deck)
+	} else {
+		err = decks.Update(ctx, deck)
+	}
+	if err != nil {
+		return err
+	}
+
+	ids = append(ids[:deck.Position], append([]int64{deck.ID}, ids[deck.Position:]...)...)
+	if err := decks.Renumber(ctx, ids); err != nil {
+		return err
+	}
+	if !create && !sameDeck(from, deck.ParentID) {
+		return renumber(ctx, decks, deck.UserID, from)
+	}
+	return nil
+}
+
+// renumber closes gaps among the decks below a parent.
+func renumber(ctx context.Context, decks repository.DeckRepository, userID int64, parentID *int64) error {
+	siblings, err := decks.Children(ctx, userID, parentID)
+	if err != nil {
+		return err
+	}
+	ids := make([]int64, len(siblings))
+	for i, sibling := range siblings {
+		ids[i] = sibling.ID
+	}
+	return decks.Renumber(ctx, ids)
+}
+
+// checkCycle rejects a parent that is the deck itself or lies below it.
+func checkCycle(ctx context.Context, decks repository.DeckRepository, deckID int64, parentID *int64) error {
+	for id := parentID; id != nil; {
+		if *id == deckID {
+			return model.ErrDeckCycle
+		}
+		parent, err := decks.GetByID(ctx, *id)
+		if err != nil {
+			return err
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+// newPosition returns the requested position, or one past any sibling so
+// place puts the deck last.
+func newPosition(position *int) int {
+	if position == nil {
+		return math.MaxInt
+	}
+	return *position
+}
+
+func sameDeck(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func (s *DeckService) apply(ctx context.Context, userID int64, deck *model.Deck, input DeckInput) error {
+	if err := validateDeckInput(input); err != nil {
+		return err
+	}
+	if input.ParentID != nil {
+		if _, err := s.authorizer.Deck(ctx, userID, *input.ParentID); err != nil {
+			return err
+		}
+	}
+	applyDeckInput(deck, input)
+	return nil
+}
+
+func validateDeckInput(input DeckInput) error {
+	errs := &model.ValidationError{}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		errs.Add("name", "is required")
+	}
+	if len(name) > model.MaxDeckNameLength {
+		errs.Add("name", fmt.Sprintf("must be at most %d characters", model.MaxDeckNameLength))
+	}
+	if input.Position != nil && *input.Position < 0 {
+		errs.Add("position", "must not be negative")
+	}
+	if input.Language != "" && !model.ValidLanguage(input.Language) {
+		errs.Add("language", "must be a supported text search language such as english or simple")
+	}
+	if err := errs.OrNil(); err != nil {
+		return err
+	}
+
+	config := input.SRSConfig
+	if config == nil {
+		config = model.DefaultSRSConfig()
+	}
+	return config.Validate(deckAlgorithm(input))
+}
+
+// applyDeckInput copies a validated input onto the deck, filling in the
+// defaults.
+func applyDeckInput(deck *model.Deck, input DeckInput) {
+	if input.Position != nil || deck.ID == 0 || !sameDeck(deck.ParentID, input.ParentID) {
+		deck.Position = newPosition(input.Position)
+	}
+	deck.ParentID = input.ParentID
+	deck.Name = strings.TrimSpace(input.Name)
+	deck.Description = input.Description
+	deck.Algorithm = deckAlgorithm(input)
+	if input.Language != "" {
+		deck.Language = input.Language
+	} else if deck.Language == "" {
+		deck.Language = model.DefaultLanguage
+	}
+	deck.SRSConfig = input.SRSConfig
+	if deck.SRSConfig == nil {
+		deck.SRSConfig = model.DefaultSRSConfig()
+	}
+}
+
+func deckAlgorithm(input DeckInput) string {
+	if input.Algorithm == "" {
+		return model.AlgorithmSM2
+	}
+	return input.Algorithm
+}
+
+// nodes loads every deck of the user with counts and links the tree.
+func (s *DeckService) nodes(ctx context.Context, decks repository.DeckRepository, userID int64, rootIDs []int64) (map[int64]*model.DeckNode, error) {
+	tree, err := decks.Tree(ctx, userID, rootIDs, s.now())
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[int64]*model.DeckNode, len(tree))
+	for _, node := range tree {
+		nodes[node.ID] = node
+	}
+	for _, node := range tree {
+		if node.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	for _, node := range nodes {
+		sortDeckNodes(node.Children)
+	}
+	return nodes, nil
+}
+
+func sortDeckNodes(nodes []*model.DeckNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return deckLess(nodes[i].Deck, nodes[j].Deck)
+	})
+}
+
+// deckLess orders decks by position, name and ID, matching the order of
+// DeckRepository.GetByUserID and Children.
+func deckLess(a, b *model.Deck) bool {
+	if a.Position != b.Position {
+		return a.Position < b.Position
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
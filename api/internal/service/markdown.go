@@ -0,0 +1,810 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// Markdown deck files have a front matter, a heading for every deck and
+// cards below their deck's heading:
+//
+//	---
+//	algorithm: sm2
+//	tags: spanish
+//	---
+//
+//	# Spanish
+//	<!-- description: Everyday words -->
+//
+//	<!-- id: 12 -->
+//	hola
+//	---
+//	hello
+//	***
+//	adiós
+//	---
+//	goodbye
+//
+// "---" separates a card's front, back and extra, and "***" or a comment
+// with a card key starts the next card. The front matter's tags are added
+// to every card and its deck settings apply to decks that don't set their
+// own. Content lines that would read as structure are escaped with "\".
+const (
+	markdownFrontMatter = "---"
+	markdownSeparator   = "---"
+	markdownCardBreak   = "***"
+)
+
+var (
+	markdownHeadingPattern = regexp.MustCompile(`^(#+)\s+(.*?)(\s+#+)?$`)
+	markdownCommentPattern = regexp.MustCompile(`^<!--\s*([a-z_]+)\s*:(.*)-->$`)
+	markdownDeckKeys       = map[string]bool{"description": true, "algorithm": true, "language": true, "srs_config": true}
+	markdownCardKeys       = map[string]bool{"id": true, "type": true, "tags": true, "suspended": true}
+)
+
+type MarkdownImportOptions struct {
+	// ParentID places the top-level headings' decks below a deck instead
+	// of at the top level.
+	ParentID *int64
+}
+
+// markdownSettings holds the deck settings a file sets, nil where it sets
+// none.
+type markdownSettings struct {
+	description *string
+	algorithm   *string
+	language    *string
+	srsConfig   *model.SRSConfig
+}
+
+// over returns the settings with those of base filled in where s has none.
+func (s markdownSettings) over(base markdownSettings) markdownSettings {
+	if s.description == nil {
+		s.description = base.description
+	}
+	if s.algorithm == nil {
+		s.algorithm = base.algorithm
+	}
+	if s.language == nil {
+		s.language = base.language
+	}
+	if s.srsConfig == nil {
+		s.srsConfig = base.srsConfig
+	}
+	return s
+}
+
+type markdownDeck struct {
+	line     int
+	level    int
+	name     string
+	parent   *markdownDeck
+	settings markdownSettings
+	cards    []*markdownCard
+	// lines lists every heading of the deck, which may appear more than
+	// once.
+	lines []int
+}
+
+type markdownCard struct {
+	line      int
+	id        int64
+	cardType  model.CardType
+	tags      []string
+	suspended *bool
+	// parts holds the lines of the front, back and extra.
+	parts [][]string
+	keys  map[string]bool
+	card  *model.Card
+}
+
+func (c *markdownCard) hasContent() bool {
+	for _, part := range c.parts {
+		for _, line := range part {
+			if strings.TrimSpace(line) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markdownFile is a parsed file with its decks, parents first.
+type markdownFile struct {
+	defaults markdownSettings
+	tags     []string
+	decks    []*markdownDeck
+}
+
+// ImportMarkdown imports a Markdown deck file. Headings are matched to
+// existing decks by name below the same parent and cards by their ID
+// comment, or else by type and front within their deck; what the file sets
+// overwrites what is stored, and decks and cards it doesn't mention are
+// left alone. Problems are reported by line, such as lines[12].front, and
+// nothing is imported.
+func (s *TransferService) ImportMarkdown(ctx context.Context, userID int64, data []byte, options MarkdownImportOptions) (*model.MarkdownImportResult, error) {
+	file, err := parseMarkdown(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *model.MarkdownImportResult
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		authorizer := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+		if options.ParentID != nil {
+			if _, err := authorizer.Deck(ctx, userID, *options.ParentID); err != nil {
+				return err
+			}
+		}
+		importer := &markdownImporter{
+			importer:   &importer{repos: repos, userID: userID, children: map[int64][]*model.Deck{}},
+			authorizer: authorizer,
+			file:       file,
+			deckIDs:    map[*markdownDeck]int64{},
+			named:      map[int64]bool{},
+			claimed:    map[int64]bool{},
+			result:     &model.MarkdownImportResult{Decks: []model.MarkdownItem{}, Cards: []model.MarkdownItem{}},
+		}
+		for _, deck := range file.decks {
+			for _, card := range deck.cards {
+				if card.id != 0 {
+					importer.named[card.id] = true
+				}
+			}
+		}
+		errs := &model.ValidationError{}
+		for _, deck := range file.decks {
+			parentID := options.ParentID
+			if deck.parent != nil {
+				id, ok := importer.deckIDs[deck.parent]
+				if !ok {
+					// The parent is invalid and already reported.
+					continue
+				}
+				parentID = &id
+			}
+			if err := importer.importDeck(ctx, errs, deck, parentID); err != nil {
+				return err
+			}
+		}
+		if err := errs.OrNil(); err != nil {
+			return err
+		}
+		result = importer.result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type markdownImporter struct {
+	*importer
+	authorizer *Authorizer
+	file       *markdownFile
+	deckIDs    map[*markdownDeck]int64
+	// named holds the IDs the file gives; matching by type and front leaves
+	// those cards to the card that names them.
+	named map[int64]bool
+	// claimed holds the cards already matched to a card of the file.
+	claimed map[int64]bool
+	result  *model.MarkdownImportResult
+}
+
+func (im *markdownImporter) importDeck(ctx context.Context, errs *model.ValidationError, source *markdownDeck, parentID *int64) error {
+	existing, err := im.child(ctx, parentID, source.name)
+	if err != nil {
+		return err
+	}
+
+	input := DeckInput{ParentID: parentID, Name: source.name}
+	if existing != nil {
+		input.Description, input.Algorithm, input.Language, input.SRSConfig = existing.Description, existing.Algorithm, existing.Language, existing.SRSConfig
+	}
+	settings := source.settings.over(im.file.defaults)
+	if settings.description != nil {
+		input.Description = *settings.description
+	}
+	if settings.algorithm != nil {
+		input.Algorithm = *settings.algorithm
+	}
+	if settings.language != nil {
+		input.Language = *settings.language
+	}
+	if settings.srsConfig != nil {
+		input.SRSConfig = settings.srsConfig
+	}
+	if err := validateDeckInput(input); err != nil {
+		addNested(errs, fmt.Sprintf("lines[%d]", source.line), err)
+		return nil
+	}
+
+	deck := existing
+	status := model.MarkdownUnchanged
+	if deck == nil {
+		deck = &model.Deck{UserID: im.userID}
+		applyDeckInput(deck, input)
+		deck.Position = math.MaxInt
+		if err := place(ctx, im.repos.Decks, deck, nil, true); err != nil {
+			return err
+		}
+		key := parentKey(parentID)
+		im.children[key] = append(im.children[key], deck)
+		status = model.MarkdownCreated
+		im.result.DecksCreated++
+	} else {
+		updated := *deck
+		applyDeckInput(&updated, input)
+		if updated.Description != deck.Description || updated.Algorithm != deck.Algorithm || updated.Language != deck.Language || !reflect.DeepEqual(updated.SRSConfig, deck.SRSConfig) {
+			deck.Description, deck.Algorithm, deck.Language, deck.SRSConfig = updated.Description, updated.Algorithm, updated.Language, updated.SRSConfig
+			if err := im.repos.Decks.Update(ctx, deck); err != nil {
+				return err
+			}
+			status = model.MarkdownUpdated
+			im.result.DecksUpdated++
+		} else {
+			im.result.DecksUnchanged++
+		}
+	}
+	im.deckIDs[source] = deck.ID
+	for _, line := range source.lines {
+		im.result.Decks = append(im.result.Decks, model.MarkdownItem{Line: line, ID: deck.ID, Status: status})
+	}
+	if len(source.cards) == 0 {
+		return nil
+	}
+
+	byKey, next, err := im.cards(ctx, deck.ID)
+	if err != nil {
+		return err
+	}
+	for _, source := range source.cards {
+		var match *model.Card
+		if source.id != 0 {
+			match, err = im.authorizer.Card(ctx, im.userID, source.id)
+			if errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrForbidden) {
+				match, err = nil, nil
+			}
+			if err != nil {
+				return err
+			}
+			// A card created earlier in this import may have been given
+			// the ID; it keeps its content and this one becomes new.
+			if match != nil && im.claimed[match.ID] {
+				match = nil
+			}
+		} else if candidate := byKey[cardKey(source.card)]; candidate != nil && !im.claimed[candidate.ID] && !im.named[candidate.ID] {
+			match = candidate
+		}
+
+		card := source.card
+		status := model.MarkdownCreated
+		switch {
+		case match == nil:
+			card.DeckID, card.Position = deck.ID, next
+			if err := im.repos.Cards.Create(ctx, card); err != nil {
+				return err
+			}
+			next++
+			byKey[cardKey(card)] = card
+			im.result.CardsCreated++
+		default:
+			updated := *match
+			updated.Type, updated.Front, updated.Back, updated.Extra, updated.Tags = card.Type, card.Front, card.Back, card.Extra, card.Tags
+			if source.suspended != nil {
+				updated.Suspended = *source.suspended
+			}
+			if updated.DeckID != deck.ID {
+				updated.DeckID, updated.Position = deck.ID, next
+				next++
+			}
+			if updated.DeckID == match.DeckID && updated.Type == match.Type && updated.Front == match.Front && len(cardDifferences(match, &updated)) == 0 {
+				status = model.MarkdownUnchanged
+				im.result.CardsUnchanged++
+				break
+			}
+			if err := im.repos.Cards.Update(ctx, &updated); err != nil {
+				return err
+			}
+			card = &updated
+			status = model.MarkdownUpdated
+			im.result.CardsUpdated++
+		}
+		im.claimed[card.ID] = true
+		im.result.Cards = append(im.result.Cards, model.MarkdownItem{Line: source.line, ID: card.ID, Status: status})
+	}
+	return nil
+}
+
+// parseMarkdown reads a file and validates its cards.
+func parseMarkdown(data []byte) (*markdownFile, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	file := &markdownFile{}
+	errs := &model.ValidationError{}
+
+	start := 0
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == markdownFrontMatter {
+		end := 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != markdownFrontMatter {
+			end++
+		}
+		if end == len(lines) {
+			errs.Add("lines[1]", "front matter is not closed by a \"---\" line")
+			return nil, errs
+		}
+		for i := 1; i < end; i++ {
+			line := strings.TrimSpace(lines[i])
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				errs.Add(fmt.Sprintf("lines[%d]", i+1), `must be "key: value"`)
+				continue
+			}
+			field := fmt.Sprintf("lines[%d].%s", i+1, strings.TrimSpace(key))
+			switch key = strings.TrimSpace(key); {
+			case key == "tags":
+				tags, err := parseMarkdownTags(strings.TrimSpace(value))
+				if err != nil {
+					errs.Add(field, err.Error())
+				}
+				file.tags = tags
+			case markdownDeckKeys[key]:
+				if err := file.defaults.set(key, strings.TrimSpace(value)); err != nil {
+					errs.Add(field, err.Error())
+				}
+			}
+		}
+		start = end + 1
+	}
+
+	var (
+		deck   *markdownDeck
+		card   *markdownCard
+		fence  string
+		byPath = map[string]*markdownDeck{}
+		paths  = map[*markdownDeck]string{}
+	)
+	closeCard := func() {
+		if card != nil && (card.hasContent() || len(card.keys) > 0) {
+			deck.cards = append(deck.cards, card)
+		}
+		card = nil
+	}
+	for i := start; i < len(lines); i++ {
+		number, line := i+1, lines[i]
+		trimmed := strings.TrimSpace(line)
+		field := fmt.Sprintf("lines[%d]", number)
+
+		if fence == "" {
+			if heading := markdownHeadingPattern.FindStringSubmatch(trimmed); heading != nil {
+				closeCard()
+				level, name := len(heading[1]), strings.TrimSpace(heading[2])
+				if name == "" {
+					errs.Add(field, "heading must name a deck")
+					deck = nil
+					continue
+				}
+				parent := deck
+				for parent != nil && parent.level >= level {
+					parent = parent.parent
+				}
+				path := name
+				if parent != nil {
+					path = paths[parent] + "\x00" + name
+				}
+				if existing := byPath[path]; existing != nil {
+					deck = existing
+					deck.lines = append(deck.lines, number)
+					continue
+				}
+				deck = &markdownDeck{line: number, level: level, name: name, parent: parent, lines: []int{number}}
+				byPath[path], paths[deck] = deck, path
+				file.decks = append(file.decks, deck)
+				continue
+			}
+			if comment := markdownCommentPattern.FindStringSubmatch(trimmed); comment != nil && (markdownDeckKeys[comment[1]] || markdownCardKeys[comment[1]]) {
+				key, value := comment[1], strings.TrimSpace(comment[2])
+				if deck == nil {
+					errs.Add(field, "must follow a deck heading")
+					continue
+				}
+				if markdownDeckKeys[key] {
+					if err := deck.settings.set(key, value); err != nil {
+						errs.Add(field+"."+key, err.Error())
+					}
+					continue
+				}
+				if card == nil || card.hasContent() || card.keys[key] {
+					closeCard()
+					card = &markdownCard{line: number, parts: [][]string{nil}, keys: map[string]bool{}}
+				}
+				card.keys[key] = true
+				if err := card.set(key, value); err != nil {
+					errs.Add(field+"."+key, err.Error())
+				}
+				continue
+			}
+			if trimmed == markdownCardBreak {
+				closeCard()
+				continue
+			}
+		}
+
+		if card == nil {
+			if trimmed == "" {
+				continue
+			}
+			if deck == nil {
+				errs.Add(field, "must follow a deck heading")
+				continue
+			}
+			card = &markdownCard{line: number, parts: [][]string{nil}, keys: map[string]bool{}}
+		}
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case markdownFence(trimmed) != "":
+			fence = markdownFence(trimmed)
+		case trimmed == markdownSeparator && len(card.parts) < 3:
+			card.parts = append(card.parts, nil)
+			continue
+		default:
+			line = unescapeMarkdown(line)
+		}
+		last := len(card.parts) - 1
+		card.parts[last] = append(card.parts[last], line)
+	}
+	closeCard()
+
+	if len(file.decks) == 0 && len(errs.Fields) == 0 {
+		errs.Add("headings", "at least one is required")
+	}
+	for _, deck := range file.decks {
+		for _, source := range deck.cards {
+			parts := make([]string, 3)
+			for i, part := range source.parts {
+				for len(part) > 0 && strings.TrimSpace(part[0]) == "" {
+					part = part[1:]
+				}
+				for len(part) > 0 && strings.TrimSpace(part[len(part)-1]) == "" {
+					part = part[:len(part)-1]
+				}
+				parts[i] = strings.Join(part, "\n")
+			}
+			cardType := source.cardType
+			if cardType == "" {
+				cardType = model.CardTypeBasic
+				if clozePattern.MatchString(parts[0]) {
+					cardType = model.CardTypeCloze
+				}
+			}
+			source.card = &model.Card{}
+			err := applyCardInput(source.card, CardInput{
+				Type:      cardType,
+				Front:     parts[0],
+				Back:      parts[1],
+				Extra:     parts[2],
+				Tags:      append(append([]string(nil), file.tags...), source.tags...),
+				Suspended: source.suspended != nil && *source.suspended,
+			})
+			if err != nil {
+				addNested(errs, fmt.Sprintf("lines[%d]", source.line), err)
+			}
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+
+	ids := map[int64]int{}
+	for _, deck := range file.decks {
+		for _, source := range deck.cards {
+			if source.id == 0 {
+				continue
+			}
+			if line, ok := ids[source.id]; ok {
+				errs.Add(fmt.Sprintf("lines[%d].id", source.line), fmt.Sprintf("repeats the card on line %d", line))
+			}
+			ids[source.id] = source.line
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *markdownSettings) set(key, raw string) error {
+	switch key {
+	case "srs_config":
+		config := &model.SRSConfig{}
+		if err := json.Unmarshal([]byte(raw), config); err != nil {
+			return errors.New("must be a JSON object")
+		}
+		s.srsConfig = config
+		return nil
+	}
+	value, err := parseMarkdownValue(raw)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "description":
+		s.description = &value
+	case "algorithm":
+		s.algorithm = &value
+	case "language":
+		s.language = &value
+	}
+	return nil
+}
+
+func (c *markdownCard) set(key, raw string) error {
+	if key == "tags" {
+		tags, err := parseMarkdownTags(raw)
+		c.tags = tags
+		return err
+	}
+	value, err := parseMarkdownValue(raw)
+	if err != nil {
+		return err
+	}
+	switch key {
+	case "id":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			return errors.New("must be a positive integer")
+		}
+		c.id = id
+	case "type":
+		c.cardType = model.CardType(value)
+	case "suspended":
+		suspended, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		c.suspended = &suspended
+	}
+	return nil
+}
+
+// parseMarkdownValue reads a value as written, or as a JSON string when it
+// is quoted.
+func parseMarkdownValue(raw string) (string, error) {
+	if !strings.HasPrefix(raw, `"`) {
+		return raw, nil
+	}
+	var value string
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return "", errors.New("must be a valid JSON string when quoted")
+	}
+	return value, nil
+}
+
+// parseMarkdownTags reads comma separated tags, or a JSON array of them.
+func parseMarkdownTags(raw string) ([]string, error) {
+	if strings.HasPrefix(raw, "[") {
+		var tags []string
+		if err := json.Unmarshal([]byte(raw), &tags); err != nil {
+			return nil, errors.New("must be comma separated or a JSON array of strings")
+		}
+		return tags, nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// markdownStructure reports whether a line outside a code block would be
+// read as more than content.
+func markdownStructure(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == markdownSeparator || trimmed == markdownCardBreak || markdownHeadingPattern.MatchString(trimmed) || markdownFence(trimmed) != "" {
+		return true
+	}
+	comment := markdownCommentPattern.FindStringSubmatch(trimmed)
+	return comment != nil && (markdownDeckKeys[comment[1]] || markdownCardKeys[comment[1]])
+}
+
+// markdownFence returns the fence that a line opening a code block starts
+// with.
+func markdownFence(trimmed string) string {
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		return trimmed[:3]
+	}
+	return ""
+}
+
+// escapeMarkdown prefixes lines that would be read as structure, and lines
+// that would be unescaped, with a backslash.
+func escapeMarkdown(line string) string {
+	if markdownStructure(line) || unescapeMarkdown(line) != line {
+		return `\` + line
+	}
+	return line
+}
+
+func unescapeMarkdown(line string) string {
+	if rest, ok := strings.CutPrefix(line, `\`); ok && (markdownStructure(rest) || unescapeMarkdown(rest) != rest) {
+		return rest
+	}
+	return line
+}
+
+// markdownValue writes a value as it is unless reading it back would
+// change it, quoting it as a JSON string then. Quoting escapes ">", so a
+// value never ends its comment early.
+func markdownValue(value string) string {
+	if value != "" && value == strings.TrimSpace(value) && !strings.ContainsAny(value, "\n\r") && !strings.Contains(value, "--") && !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "[") {
+		return value
+	}
+	quoted, _ := json.Marshal(value)
+	return string(quoted)
+}
+
+func markdownTags(tags []string) string {
+	for _, tag := range tags {
+		if tag != strings.TrimSpace(tag) || strings.Contains(tag, ",") || strings.HasPrefix(tag, "[") || strings.Contains(tag, "--") {
+			quoted, _ := json.Marshal(tags)
+			return string(quoted)
+		}
+	}
+	return strings.Join(tags, ", ")
+}
+
+// markdownContent escapes the lines of a front, back or extra. Code blocks
+// are kept as they are when they are closed within the text, so that they
+// still render; the fences of an unclosed one are escaped instead.
+func markdownContent(text string) []string {
+	lines := strings.Split(text, "\n")
+	fence, open := "", -1
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			out[i] = line
+		case markdownFence(trimmed) != "":
+			fence, open = markdownFence(trimmed), i
+			out[i] = line
+		default:
+			out[i] = escapeMarkdown(line)
+		}
+	}
+	if fence != "" {
+		// Escape the unclosed fence and read the rest as ordinary lines.
+		for i := open; i < len(lines); i++ {
+			if i == open {
+				out[i] = `\` + lines[i]
+				continue
+			}
+			out[i] = escapeMarkdown(lines[i])
+		}
+	}
+	return out
+}
+
+// EncodeMarkdown writes the export as a Markdown deck file. The exported
+// deck's algorithm, language and SRS config go into the front matter, and
+// subdecks only note the settings they differ in.
+func (e *DeckExport) EncodeMarkdown(ctx context.Context, w io.Writer) error {
+	return e.service.uow.Read(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		return e.encodeMarkdown(ctx, repos, w)
+	})
+}
+
+func (e *DeckExport) encodeMarkdown(ctx context.Context, repos repository.Repositories, w io.Writer) error {
+	out := &errWriter{w: w}
+	root := e.Root()
+	out.write(markdownFrontMatter + "\n")
+	out.write("algorithm: " + markdownValue(root.Algorithm) + "\n")
+	out.write("language: " + markdownValue(root.Language) + "\n")
+	if root.SRSConfig != nil {
+		config, err := json.Marshal(root.SRSConfig)
+		if err != nil {
+			return err
+		}
+		out.write("srs_config: " + string(config) + "\n")
+	}
+	out.write(markdownFrontMatter + "\n")
+
+	children := map[int64][]*model.Deck{}
+	for _, deck := range e.decks[1:] {
+		children[*deck.ParentID] = append(children[*deck.ParentID], deck)
+	}
+	var write func(deck *model.Deck, level int) error
+	write = func(deck *model.Deck, level int) error {
+		out.write("\n" + strings.Repeat("#", level) + " " + deck.Name + "\n")
+		if deck.Description != "" {
+			out.write("<!-- description: " + markdownValue(deck.Description) + " -->\n")
+		}
+		if deck.Algorithm != root.Algorithm {
+			out.write("<!-- algorithm: " + markdownValue(deck.Algorithm) + " -->\n")
+		}
+		if deck.Language != root.Language {
+			out.write("<!-- language: " + markdownValue(deck.Language) + " -->\n")
+		}
+		if !reflect.DeepEqual(deck.SRSConfig, root.SRSConfig) && deck.SRSConfig != nil {
+			config, err := json.Marshal(deck.SRSConfig)
+			if err != nil {
+				return err
+			}
+			out.write("<!-- srs_config: " + string(config) + " -->\n")
+		}
+		err := e.deckCardPages(ctx, repos, deck, func(cards []*model.Card) error {
+			for _, card := range cards {
+				writeMarkdownCard(out, card)
+			}
+			return out.err
+		})
+		if err != nil {
+			return err
+		}
+		for _, child := range children[deck.ID] {
+			if err := write(child, level+1); err != nil {
+				return err
+			}
+		}
+		return out.err
+	}
+	if err := write(root, 1); err != nil {
+		return err
+	}
+	return out.err
+}
+
+func writeMarkdownCard(out *errWriter, card *model.Card) {
+	out.write("\n<!-- id: " + strconv.FormatInt(card.ID, 10) + " -->\n")
+	detected := model.CardTypeBasic
+	if clozePattern.MatchString(card.Front) {
+		detected = model.CardTypeCloze
+	}
+	if card.Type != detected {
+		out.write("<!-- type: " + markdownValue(string(card.Type)) + " -->\n")
+	}
+	if len(card.Tags) > 0 {
+		out.write("<!-- tags: " + markdownTags(card.Tags) + " -->\n")
+	}
+	if card.Suspended {
+		out.write("<!-- suspended: true -->\n")
+	}
+	parts := []string{card.Front}
+	if card.Back != "" || card.Extra != "" {
+		parts = append(parts, card.Back)
+	}
+	if card.Extra != "" {
+		parts = append(parts, card.Extra)
+	}
+	for i, part := range parts {
+		if i > 0 {
+			out.write(markdownSeparator + "\n")
+		}
+		if part != "" {
+			out.write(strings.Join(markdownContent(part), "\n") + "\n")
+		}
+	}
+}
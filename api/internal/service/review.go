@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// ReviewService reads the user's review history.
+type ReviewService struct {
+	logs repository.ReviewLogRepository
+}
+
+func NewReviewService(logs repository.ReviewLogRepository) *ReviewService {
+	return &ReviewService{logs: logs}
+}
+
+// List returns the user's reviews, most recent first.
+func (s *ReviewService) List(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.ReviewLog], error) {
+	return s.logs.GetByUserID(ctx, userID, page)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/query"
+	"memwright/api/internal/repository"
+)
+
+const MaxSearchQueryLength = 500
+
+type SearchService struct {
+	authorizer *Authorizer
+	search     repository.SearchRepository
+}
+
+func NewSearchService(authorizer *Authorizer, search repository.SearchRepository) *SearchService {
+	return &SearchService{
+		authorizer: authorizer,
+		search:     search,
+	}
+}
+
+// Search finds the user's cards matching the query and filters. A query
+// that does not parse is invalid input whose field error carries the
+// position of the problem.
+func (s *SearchService) Search(ctx context.Context, userID int64, search model.CardSearch, page model.PageRequest) (model.Page[*model.CardSearchHit], error) {
+	errs := &model.ValidationError{}
+	var expr query.Node
+	if len(search.Query) > MaxSearchQueryLength {
+		errs.Add("q", fmt.Sprintf("must be at most %d characters", MaxSearchQueryLength))
+	} else {
+		var err error
+		var syntaxErr *query.SyntaxError
+		if expr, err = query.Parse(search.Query); errors.As(err, &syntaxErr) {
+			errs.AddAt("q", syntaxErr.Pos, syntaxErr.Msg)
+		} else if err != nil {
+			return model.Page[*model.CardSearchHit]{}, err
+		}
+	}
+	tags := make([]string, 0, len(search.Tags))
+	for i, tag := range search.Tags {
+		if tag = validTagField(errs, fmt.Sprintf("tag[%d]", i), tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	search.Tags = model.NormalizeTags(tags)
+	for i, cardType := range search.Types {
+		if !cardType.IsValid() {
+			errs.Add(fmt.Sprintf("type[%d]", i), "must be a valid card type")
+		}
+	}
+	for i, state := range search.States {
+		if !state.IsValid() {
+			errs.Add(fmt.Sprintf("state[%d]", i), "must be one of new, learning, review, relearning, mastered")
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return model.Page[*model.CardSearchHit]{}, err
+	}
+
+	if search.DeckID != nil {
+		if _, err := s.authorizer.Deck(ctx, userID, *search.DeckID); err != nil {
+			return model.Page[*model.CardSearchHit]{}, err
+		}
+	}
+	return s.search.Search(ctx, userID, query.Conjoin(expr, searchFilters(search)), page)
+}
+
+// searchFilters expresses the filters in the query language: tags must all
+// match, types and states are alternatives.
+func searchFilters(search model.CardSearch) query.Node {
+	var filters []query.Node
+	if search.DeckID != nil {
+		filters = append(filters, query.DeckID{ID: *search.DeckID})
+	}
+	for _, tag := range search.Tags {
+		filters = append(filters, query.Tag{Pattern: tag})
+	}
+	types := make([]query.Node, len(search.Types))
+	for i, cardType := range search.Types {
+		types[i] = query.Type{Type: cardType}
+	}
+	states := make([]query.Node, len(search.States))
+	for i, state := range search.States {
+		states[i] = query.State{State: state}
+	}
+	return query.Conjoin(append(filters, query.Disjoin(types...), query.Disjoin(states...))...)
+}
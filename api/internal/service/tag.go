@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+const MaxMergeTags = 100
+
+type RenameTagRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type MergeTagsRequest struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+type TagChangeResult struct {
+	Updated int64 `json:"updated"`
+}
+
+// TagService manages the hierarchical tags of a user's cards. Every
+// operation works on whole subtrees: "lang" covers "lang::es" as well.
+type TagService struct {
+	tags repository.TagRepository
+}
+
+func NewTagService(tags repository.TagRepository) *TagService {
+	return &TagService{tags: tags}
+}
+
+func (s *TagService) List(ctx context.Context, userID int64) ([]model.TagCount, error) {
+	return s.tags.List(ctx, userID)
+}
+
+// Cards lists the cards tagged with tag or any tag below it.
+func (s *TagService) Cards(ctx context.Context, userID int64, tag string, page model.PageRequest) (model.Page[*model.Card], error) {
+	tag = model.NormalizeTag(tag)
+	if !model.ValidTag(tag) {
+		return model.Page[*model.Card]{}, model.NewValidationError("tag", "must be a tag such as lang::spanish")
+	}
+	return s.tags.Cards(ctx, userID, tag, page)
+}
+
+// Rename renames a tag and its subtree. Renaming onto an existing tag
+// merges the two.
+func (s *TagService) Rename(ctx context.Context, userID int64, request RenameTagRequest) (*TagChangeResult, error) {
+	errs := &model.ValidationError{}
+	from := validTagField(errs, "from", request.From)
+	to := validTagField(errs, "to", request.To)
+	if from != "" && from == to {
+		errs.Add("to", "must differ from from")
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+	return s.replace(ctx, userID, []string{from}, to)
+}
+
+// Merge moves the sources and their subtrees below target.
+func (s *TagService) Merge(ctx context.Context, userID int64, request MergeTagsRequest) (*TagChangeResult, error) {
+	errs := &model.ValidationError{}
+	target := validTagField(errs, "target", request.Target)
+	switch {
+	case len(request.Sources) == 0:
+		errs.Add("sources", "is required")
+	case len(request.Sources) > MaxMergeTags:
+		errs.Add("sources", fmt.Sprintf("must contain at most %d tags", MaxMergeTags))
+	}
+
+	var sources []string
+	for i, source := range request.Sources {
+		source = validTagField(errs, fmt.Sprintf("sources[%d]", i), source)
+		if source != "" && source != target {
+			sources = append(sources, source)
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return &TagChangeResult{}, nil
+	}
+	return s.replace(ctx, userID, model.NormalizeTags(sources), target)
+}
+
+// replace reports ErrNotFound when no card carries any of the sources.
+func (s *TagService) replace(ctx context.Context, userID int64, sources []string, target string) (*TagChangeResult, error) {
+	updated, err := s.tags.Replace(ctx, userID, sources, target)
+	if err != nil {
+		return nil, err
+	}
+	if updated == 0 {
+		return nil, model.ErrNotFound
+	}
+	return &TagChangeResult{Updated: updated}, nil
+}
+
+// validTagField normalizes a tag and records a validation error if it is
+// malformed, returning "" in that case.
+func validTagField(errs *model.ValidationError, field, tag string) string {
+	tag = model.NormalizeTag(tag)
+	switch {
+	case tag == "":
+		errs.Add(field, "is required")
+	case !model.ValidTag(tag):
+		errs.Add(field, `must not contain empty segments around "::"`)
+	default:
+		return tag
+	}
+	return ""
+}
@@ -0,0 +1,755 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// exportPageSize is how many cards an export holds in memory at a time.
+const exportPageSize = 500
+
+type ExportOptions struct {
+	// IncludeSchedules adds the user's progress on each card.
+	IncludeSchedules bool `json:"include_schedules"`
+	// IncludeReviews adds the review history too and implies
+	// IncludeSchedules.
+	IncludeReviews bool `json:"include_reviews"`
+}
+
+// ConflictMode decides what an import does with an item that matches an
+// existing one but differs from it.
+type ConflictMode string
+
+const (
+	ConflictKeep      ConflictMode = "keep"
+	ConflictOverwrite ConflictMode = "overwrite"
+)
+
+type ImportOptions struct {
+	// ParentID places the imported top-level decks below a deck instead of
+	// at the top level.
+	ParentID   *int64
+	OnConflict ConflictMode
+}
+
+// TransferService exports deck subtrees in the native JSON format and
+// imports them again. An import merges decks into existing ones of the same
+// name below the same parent and skips cards whose type and front already
+// exist in the target deck.
+type TransferService struct {
+	authorizer *Authorizer
+	decks      repository.DeckRepository
+	cards      repository.CardRepository
+	schedules  repository.CardScheduleRepository
+	reviewLogs repository.ReviewLogRepository
+	uow        repository.UnitOfWork
+	now        func() time.Time
+}
+
+func NewTransferService(authorizer *Authorizer, decks repository.DeckRepository, cards repository.CardRepository, schedules repository.CardScheduleRepository, reviewLogs repository.ReviewLogRepository, uow repository.UnitOfWork) *TransferService {
+	return &TransferService{
+		authorizer: authorizer,
+		decks:      decks,
+		cards:      cards,
+		schedules:  schedules,
+		reviewLogs: reviewLogs,
+		uow:        uow,
+		now:        time.Now,
+	}
+}
+
+// DeckExport is a deck subtree ready to be encoded.
+type DeckExport struct {
+	service    *TransferService
+	userID     int64
+	decks      []*model.Deck
+	options    ExportOptions
+	exportedAt time.Time
+}
+
+// Export checks access to the deck and collects its subtree. The cards are
+// only read while the export is encoded.
+func (s *TransferService) Export(ctx context.Context, userID, deckID int64, options ExportOptions) (*DeckExport, error) {
+	root, err := s.authorizer.Deck(ctx, userID, deckID)
+	if err != nil {
+		return nil, err
+	}
+	decks := []*model.Deck{root}
+	for i := 0; i < len(decks); i++ {
+		children, err := s.decks.Children(ctx, userID, &decks[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, children...)
+	}
+	options.IncludeSchedules = options.IncludeSchedules || options.IncludeReviews
+	return &DeckExport{
+		service:    s,
+		userID:     userID,
+		decks:      decks,
+		options:    options,
+		exportedAt: s.now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// Root returns the exported deck.
+func (e *DeckExport) Root() *model.Deck {
+	return e.decks[0]
+}
+
+// Encode writes the document to w one card at a time, reading the cards a
+// page at a time, so large decks never sit in memory as a whole.
+func (e *DeckExport) Encode(ctx context.Context, w io.Writer) error {
+	return e.service.uow.Read(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		return e.encode(ctx, repos, w)
+	})
+}
+
+func (e *DeckExport) encode(ctx context.Context, repos repository.Repositories, w io.Writer) error {
+	out := &errWriter{w: w}
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+
+	out.write(`{"format":` + strconv.Quote(model.ExportFormat) + `,"version":` + strconv.Itoa(model.ExportVersion) + `,"exported_at":`)
+	_ = encoder.Encode(e.exportedAt)
+	out.write(`,"decks":`)
+	decks := make([]model.ExportedDeck, len(e.decks))
+	for i, deck := range e.decks {
+		decks[i] = exportedDeck(deck, i == 0)
+	}
+	_ = encoder.Encode(decks)
+	out.write(`,"cards":[`)
+
+	first := true
+	err := e.eachCardPage(ctx, repos, func(_ *model.Deck, cards []*model.Card) error {
+		exported, err := e.exportCards(ctx, repos, cards)
+		if err != nil {
+			return err
+		}
+		for _, card := range exported {
+			if !first {
+				out.write(",")
+			}
+			first = false
+			_ = encoder.Encode(card)
+		}
+		return out.err
+	})
+	if err != nil {
+		return err
+	}
+	out.write("]}\n")
+	return out.err
+}
+
+// eachCardPage calls fn with the cards of every exported deck, a page at a
+// time, and stops at the first error. The encoders read the pages from one
+// snapshot, so a card that moves during the export is written once.
+func (e *DeckExport) eachCardPage(ctx context.Context, repos repository.Repositories, fn func(deck *model.Deck, cards []*model.Card) error) error {
+	for _, deck := range e.decks {
+		if err := e.deckCardPages(ctx, repos, deck, func(cards []*model.Card) error { return fn(deck, cards) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deckCardPages calls fn with the cards of one deck a page at a time.
+func (e *DeckExport) deckCardPages(ctx context.Context, repos repository.Repositories, deck *model.Deck, fn func(cards []*model.Card) error) error {
+	page := model.PageRequest{Limit: exportPageSize}
+	for {
+		cards, err := repos.Cards.GetByDeckID(ctx, deck.ID, page)
+		if err != nil {
+			return err
+		}
+		if err := fn(cards.Items); err != nil {
+			return err
+		}
+		if cards.NextCursor == "" {
+			return nil
+		}
+		page.Cursor = cards.NextCursor
+	}
+}
+
+func (e *DeckExport) exportCards(ctx context.Context, repos repository.Repositories, cards []*model.Card) ([]model.ExportedCard, error) {
+	exported := make([]model.ExportedCard, len(cards))
+	byCard := make(map[int64]*model.ExportedCard, len(cards))
+	ids := make([]int64, len(cards))
+	for i, card := range cards {
+		exported[i] = model.ExportedCard{
+			ID:        card.ID,
+			DeckID:    card.DeckID,
+			Type:      card.Type,
+			Front:     card.Front,
+			Back:      card.Back,
+			Extra:     card.Extra,
+			Tags:      card.Tags,
+			Position:  card.Position,
+			Suspended: card.Suspended,
+		}
+		byCard[card.ID] = &exported[i]
+		ids[i] = card.ID
+	}
+	if !e.options.IncludeSchedules {
+		return exported, nil
+	}
+
+	schedules, err := repos.Schedules.GetByCards(ctx, e.userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	bySchedule := make(map[int64]*model.ExportedSchedule, len(schedules))
+	scheduleIDs := make([]int64, len(schedules))
+	for i, schedule := range schedules {
+		exportedSchedule := &model.ExportedSchedule{
+			State:          schedule.State,
+			DueAt:          schedule.DueAt,
+			Interval:       schedule.Interval,
+			EaseFactor:     schedule.EaseFactor,
+			ReviewCount:    schedule.ReviewCount,
+			LapseCount:     schedule.LapseCount,
+			LastReviewedAt: schedule.LastReviewedAt,
+		}
+		byCard[schedule.CardID].Schedule = exportedSchedule
+		bySchedule[schedule.ID] = exportedSchedule
+		scheduleIDs[i] = schedule.ID
+	}
+	if !e.options.IncludeReviews {
+		return exported, nil
+	}
+
+	logs, err := repos.ReviewLogs.GetBySchedules(ctx, scheduleIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, log := range logs {
+		schedule := bySchedule[log.CardScheduleID]
+		schedule.Reviews = append(schedule.Reviews, model.ExportedReview{
+			Rating:           log.Rating,
+			PreviousState:    log.PreviousState,
+			NewState:         log.NewState,
+			PreviousEase:     log.PreviousEase,
+			NewEase:          log.NewEase,
+			PreviousInterval: log.PreviousInterval,
+			NewInterval:      log.NewInterval,
+			ReviewDuration:   log.ReviewDuration,
+			ReviewedAt:       log.ReviewedAt,
+		})
+	}
+	return exported, nil
+}
+
+func exportedDeck(deck *model.Deck, root bool) model.ExportedDeck {
+	exported := model.ExportedDeck{
+		ID:          deck.ID,
+		ParentID:    deck.ParentID,
+		Name:        deck.Name,
+		Description: deck.Description,
+		Algorithm:   deck.Algorithm,
+		Language:    deck.Language,
+		SRSConfig:   deck.SRSConfig,
+		Position:    deck.Position,
+		Archived:    deck.ArchivedAt != nil,
+	}
+	if root {
+		exported.ParentID = nil
+	}
+	return exported
+}
+
+// errWriter remembers the first write error and skips later writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	var n int
+	n, w.err = w.w.Write(p)
+	return n, w.err
+}
+
+func (w *errWriter) write(s string) {
+	_, _ = io.WriteString(w, s)
+}
+
+// importDeck is a validated deck of a document with the cards that go into
+// it, in document order.
+type importDeck struct {
+	source model.ExportedDeck
+	deck   *model.Deck
+	cards  []importCard
+}
+
+type importCard struct {
+	source model.ExportedCard
+	card   *model.Card
+}
+
+// Import adds a document to the user's decks in a single unit of work.
+// Schedules and reviews are only imported with new cards; a card that is
+// already there keeps its own.
+func (s *TransferService) Import(ctx context.Context, userID int64, document *model.ExportDocument, options ImportOptions) (*model.ImportResult, error) {
+	return s.importDocument(ctx, userID, document, options, false)
+}
+
+// importDocument imports a document. contentOnly is set for documents read
+// from formats that only carry deck names and card contents, so that the
+// settings of existing decks and the suspension of existing cards are
+// neither compared nor overwritten.
+func (s *TransferService) importDocument(ctx context.Context, userID int64, document *model.ExportDocument, options ImportOptions, contentOnly bool) (*model.ImportResult, error) {
+	if options.OnConflict == "" {
+		options.OnConflict = ConflictKeep
+	}
+	if options.OnConflict != ConflictKeep && options.OnConflict != ConflictOverwrite {
+		return nil, model.NewValidationError("on_conflict", fmt.Sprintf("must be %q or %q", ConflictKeep, ConflictOverwrite))
+	}
+	decks, err := validateDocument(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *model.ImportResult
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if options.ParentID != nil {
+			authorizer := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+			if _, err := authorizer.Deck(ctx, userID, *options.ParentID); err != nil {
+				return err
+			}
+		}
+		importer := &importer{
+			repos:       repos,
+			userID:      userID,
+			onConflict:  options.OnConflict,
+			contentOnly: contentOnly,
+			now:         s.now().UTC().Truncate(time.Microsecond),
+			children:    map[int64][]*model.Deck{},
+			result:      &model.ImportResult{DeckIDs: map[int64]int64{}, Conflicts: []model.ImportConflict{}},
+		}
+		for _, deck := range decks {
+			parentID := options.ParentID
+			if deck.source.ParentID != nil {
+				id := importer.result.DeckIDs[*deck.source.ParentID]
+				parentID = &id
+			}
+			if err := importer.importDeck(ctx, deck, parentID); err != nil {
+				return err
+			}
+		}
+		result = importer.result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type importer struct {
+	repos       repository.Repositories
+	userID      int64
+	onConflict  ConflictMode
+	contentOnly bool
+	now         time.Time
+	// children caches the decks below each parent, 0 standing for the top
+	// level.
+	children map[int64][]*model.Deck
+	result   *model.ImportResult
+}
+
+func (im *importer) importDeck(ctx context.Context, source importDeck, parentID *int64) error {
+	existing, err := im.child(ctx, parentID, source.deck.Name)
+	if err != nil {
+		return err
+	}
+
+	deck := existing
+	if deck == nil {
+		deck = source.deck
+		deck.UserID, deck.ParentID, deck.Position = im.userID, parentID, math.MaxInt
+		if source.source.Archived {
+			deck.ArchivedAt = &im.now
+		}
+		if err := place(ctx, im.repos.Decks, deck, nil, true); err != nil {
+			return err
+		}
+		key := parentKey(parentID)
+		im.children[key] = append(im.children[key], deck)
+		im.result.DecksCreated++
+	} else {
+		im.result.DecksMerged++
+		if fields := deckDifferences(deck, source); len(fields) > 0 && !im.contentOnly {
+			if err := im.conflict(ctx, "deck", source.source.ID, deck.ID, fields, func() error {
+				deck.Description = source.deck.Description
+				deck.Algorithm = source.deck.Algorithm
+				deck.Language = source.deck.Language
+				deck.SRSConfig = source.deck.SRSConfig
+				deck.ArchivedAt = nil
+				if source.source.Archived {
+					deck.ArchivedAt = &im.now
+				}
+				return im.repos.Decks.Update(ctx, deck)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	im.result.DeckIDs[source.source.ID] = deck.ID
+
+	if len(source.cards) == 0 {
+		return nil
+	}
+	existingCards, next, err := im.cards(ctx, deck.ID)
+	if err != nil {
+		return err
+	}
+	for _, item := range source.cards {
+		key := cardKey(item.card)
+		if match, ok := existingCards[key]; ok {
+			if im.contentOnly {
+				item.card.Suspended = match.Suspended
+			}
+			fields := cardDifferences(match, item.card)
+			if len(fields) == 0 {
+				im.result.CardsSkipped++
+				continue
+			}
+			if err := im.conflict(ctx, "card", item.source.ID, match.ID, fields, func() error {
+				match.Back, match.Extra, match.Tags, match.Suspended = item.card.Back, item.card.Extra, item.card.Tags, item.card.Suspended
+				return im.repos.Cards.Update(ctx, match)
+			}); err != nil {
+				return err
+			}
+			if im.onConflict == ConflictOverwrite {
+				im.result.CardsUpdated++
+			} else {
+				im.result.CardsSkipped++
+			}
+			continue
+		}
+
+		card := item.card
+		card.DeckID, card.Position = deck.ID, next
+		if err := im.repos.Cards.Create(ctx, card); err != nil {
+			return err
+		}
+		next++
+		existingCards[key] = card
+		im.result.CardsCreated++
+		if err := im.importSchedule(ctx, card.ID, item.source.Schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) importSchedule(ctx context.Context, cardID int64, source *model.ExportedSchedule) error {
+	if source == nil {
+		return nil
+	}
+	schedule := &model.CardSchedule{
+		CardID:         cardID,
+		UserID:         im.userID,
+		State:          source.State,
+		DueAt:          source.DueAt,
+		Interval:       source.Interval,
+		EaseFactor:     source.EaseFactor,
+		ReviewCount:    source.ReviewCount,
+		LapseCount:     source.LapseCount,
+		LastReviewedAt: source.LastReviewedAt,
+	}
+	if err := im.repos.Schedules.Create(ctx, schedule); err != nil {
+		return err
+	}
+	im.result.Schedules++
+	for _, review := range source.Reviews {
+		log := &model.ReviewLog{
+			CardScheduleID:   schedule.ID,
+			UserID:           im.userID,
+			Rating:           review.Rating,
+			PreviousState:    review.PreviousState,
+			NewState:         review.NewState,
+			PreviousEase:     review.PreviousEase,
+			NewEase:          review.NewEase,
+			PreviousInterval: review.PreviousInterval,
+			NewInterval:      review.NewInterval,
+			ReviewDuration:   review.ReviewDuration,
+			ReviewedAt:       review.ReviewedAt,
+		}
+		if err := im.repos.ReviewLogs.Create(ctx, log); err != nil {
+			return err
+		}
+		im.result.Reviews++
+	}
+	return nil
+}
+
+// conflict records a conflict and, when overwriting, resolves it with
+// overwrite.
+func (im *importer) conflict(ctx context.Context, kind string, sourceID, existingID int64, fields []string, overwrite func() error) error {
+	resolution := "kept"
+	if im.onConflict == ConflictOverwrite {
+		if err := overwrite(); err != nil {
+			return err
+		}
+		resolution = "overwritten"
+	}
+	im.result.Conflicts = append(im.result.Conflicts, model.ImportConflict{
+		Kind:       kind,
+		SourceID:   sourceID,
+		ExistingID: existingID,
+		Fields:     fields,
+		Resolution: resolution,
+	})
+	return nil
+}
+
+// child returns the live deck of the given name below parentID, if any.
+func (im *importer) child(ctx context.Context, parentID *int64, name string) (*model.Deck, error) {
+	key := parentKey(parentID)
+	children, ok := im.children[key]
+	if !ok {
+		var err error
+		if children, err = im.repos.Decks.Children(ctx, im.userID, parentID); err != nil {
+			return nil, err
+		}
+		im.children[key] = children
+	}
+	for _, child := range children {
+		if child.Name == name {
+			return child, nil
+		}
+	}
+	return nil, nil
+}
+
+// cards indexes a deck's cards by cardKey and returns the position after
+// the last of them.
+func (im *importer) cards(ctx context.Context, deckID int64) (map[string]*model.Card, int, error) {
+	cards := map[string]*model.Card{}
+	next := 0
+	page := model.PageRequest{Limit: exportPageSize}
+	for {
+		result, err := im.repos.Cards.GetByDeckID(ctx, deckID, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, card := range result.Items {
+			if _, ok := cards[cardKey(card)]; !ok {
+				cards[cardKey(card)] = card
+			}
+			if card.Position >= next {
+				next = card.Position + 1
+			}
+		}
+		if result.NextCursor == "" {
+			return cards, next, nil
+		}
+		page.Cursor = result.NextCursor
+	}
+}
+
+func parentKey(parentID *int64) int64 {
+	if parentID == nil {
+		return 0
+	}
+	return *parentID
+}
+
+// cardKey identifies a card within its deck for de-duplication.
+func cardKey(card *model.Card) string {
+	return string(card.Type) + "\x00" + strings.TrimSpace(card.Front)
+}
+
+func deckDifferences(deck *model.Deck, source importDeck) []string {
+	var fields []string
+	if deck.Description != source.deck.Description {
+		fields = append(fields, "description")
+	}
+	if deck.Algorithm != source.deck.Algorithm {
+		fields = append(fields, "algorithm")
+	}
+	if deck.Language != source.deck.Language {
+		fields = append(fields, "language")
+	}
+	if !reflect.DeepEqual(deck.SRSConfig, source.deck.SRSConfig) {
+		fields = append(fields, "srs_config")
+	}
+	if (deck.ArchivedAt != nil) != source.source.Archived {
+		fields = append(fields, "archived")
+	}
+	return fields
+}
+
+func cardDifferences(existing, card *model.Card) []string {
+	var fields []string
+	if existing.Back != card.Back {
+		fields = append(fields, "back")
+	}
+	if existing.Extra != card.Extra {
+		fields = append(fields, "extra")
+	}
+	if strings.Join(existing.Tags, "\x00") != strings.Join(card.Tags, "\x00") {
+		fields = append(fields, "tags")
+	}
+	if existing.Suspended != card.Suspended {
+		fields = append(fields, "suspended")
+	}
+	return fields
+}
+
+// validateDocument checks a whole document before anything is written and
+// returns its decks with parents before their subdecks.
+func validateDocument(document *model.ExportDocument) ([]importDeck, error) {
+	errs := &model.ValidationError{}
+	if document.Format != model.ExportFormat {
+		errs.Add("format", fmt.Sprintf("must be %q", model.ExportFormat))
+	}
+	if document.Version != model.ExportVersion {
+		errs.Add("version", fmt.Sprintf("must be %d", model.ExportVersion))
+	}
+	if len(document.Decks) == 0 {
+		errs.Add("decks", "is required")
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+
+	decks := make(map[int64]*importDeck, len(document.Decks))
+	// known holds every deck ID, valid or not, so that references to an
+	// invalid deck are not reported twice.
+	known := make(map[int64]bool, len(document.Decks))
+	for i, source := range document.Decks {
+		field := fmt.Sprintf("decks[%d]", i)
+		if known[source.ID] {
+			errs.Add(field+".id", fmt.Sprintf("duplicates deck %d", source.ID))
+			continue
+		}
+		known[source.ID] = true
+		input := DeckInput{
+			Name:        source.Name,
+			Description: source.Description,
+			Algorithm:   source.Algorithm,
+			Language:    source.Language,
+			SRSConfig:   source.SRSConfig,
+		}
+		if err := validateDeckInput(input); err != nil {
+			addNested(errs, field, err)
+			continue
+		}
+		deck := &model.Deck{}
+		applyDeckInput(deck, input)
+		decks[source.ID] = &importDeck{source: source, deck: deck}
+	}
+	for i, source := range document.Decks {
+		if source.ParentID != nil && !known[*source.ParentID] {
+			errs.Add(fmt.Sprintf("decks[%d].parent_id", i), fmt.Sprintf("refers to deck %d, which is not in the document", *source.ParentID))
+		}
+	}
+
+	for i, source := range document.Cards {
+		field := fmt.Sprintf("cards[%d]", i)
+		if !known[source.DeckID] {
+			errs.Add(field+".deck_id", fmt.Sprintf("refers to deck %d, which is not in the document", source.DeckID))
+			continue
+		}
+		card := &model.Card{}
+		err := applyCardInput(card, CardInput{
+			Type:      source.Type,
+			Front:     source.Front,
+			Back:      source.Back,
+			Extra:     source.Extra,
+			Tags:      source.Tags,
+			Position:  source.Position,
+			Suspended: source.Suspended,
+		})
+		if err != nil {
+			addNested(errs, field, err)
+			continue
+		}
+		validateSchedule(errs, field+".schedule", source.Schedule)
+		if deck := decks[source.DeckID]; deck != nil {
+			deck.cards = append(deck.cards, importCard{source: source, card: card})
+		}
+	}
+	if err := errs.OrNil(); err != nil {
+		return nil, err
+	}
+
+	// Take decks in document order once their parent is placed; whatever
+	// is left over lies on a cycle.
+	ordered := make([]importDeck, 0, len(decks))
+	placed := make(map[int64]bool, len(decks))
+	for len(ordered) < len(decks) {
+		progress := false
+		for _, source := range document.Decks {
+			if placed[source.ID] || (source.ParentID != nil && !placed[*source.ParentID]) {
+				continue
+			}
+			placed[source.ID] = true
+			ordered = append(ordered, *decks[source.ID])
+			progress = true
+		}
+		if !progress {
+			for i, source := range document.Decks {
+				if !placed[source.ID] {
+					errs.Add(fmt.Sprintf("decks[%d].parent_id", i), "forms a cycle")
+				}
+			}
+			return nil, errs
+		}
+	}
+	return ordered, nil
+}
+
+func validateSchedule(errs *model.ValidationError, field string, schedule *model.ExportedSchedule) {
+	if schedule == nil {
+		return
+	}
+	if !schedule.State.IsValid() {
+		errs.Add(field+".state", "must be a valid schedule state")
+	}
+	if schedule.EaseFactor <= 0 {
+		errs.Add(field+".ease_factor", "must be positive")
+	}
+	if schedule.Interval < 0 || schedule.ReviewCount < 0 || schedule.LapseCount < 0 {
+		errs.Add(field, "interval, review_count and lapse_count must not be negative")
+	}
+	for i, review := range schedule.Reviews {
+		reviewField := fmt.Sprintf("%s.reviews[%d]", field, i)
+		if review.Rating < model.ReviewRatingWrong || review.Rating > model.ReviewRatingEasy {
+			errs.Add(reviewField+".rating", fmt.Sprintf("must be between %d and %d", model.ReviewRatingWrong, model.ReviewRatingEasy))
+		}
+		if !review.PreviousState.IsValid() || !review.NewState.IsValid() {
+			errs.Add(reviewField, "previous_state and new_state must be valid schedule states")
+		}
+	}
+}
+
+// addNested adds the field errors of err below prefix.
+func addNested(errs *model.ValidationError, prefix string, err error) {
+	var validation *model.ValidationError
+	if !errors.As(err, &validation) {
+		errs.Add(prefix, err.Error())
+		return
+	}
+	for _, field := range validation.Fields {
+		field.Field = prefix + "." + field.Field
+		errs.Fields = append(errs.Fields, field)
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+)
+
+// DefaultTrashRetention is how long deleted decks and cards stay
+// restorable unless configured otherwise.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashService lists and restores deleted decks and cards, and purges them
+// for good once they have been in the trash longer than the retention
+// period. Items past it are gone for the user even before the purge runs.
+type TrashService struct {
+	decks     repository.DeckRepository
+	cards     repository.CardRepository
+	uow       repository.UnitOfWork
+	retention time.Duration
+	now       func() time.Time
+}
+
+func NewTrashService(decks repository.DeckRepository, cards repository.CardRepository, uow repository.UnitOfWork, retention time.Duration) *TrashService {
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	return &TrashService{
+		decks:     decks,
+		cards:     cards,
+		uow:       uow,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// WithClock replaces the time source, which lets tests move past the
+// retention period.
+func (s *TrashService) WithClock(now func() time.Time) *TrashService {
+	s.now = now
+	return s
+}
+
+// Decks lists the decks deleted on their own, not along with a parent.
+func (s *TrashService) Decks(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.TrashedDeck], error) {
+	decks, err := s.decks.Trash(ctx, userID, s.cutoff(), page)
+	if err != nil {
+		return model.Page[*model.TrashedDeck]{}, err
+	}
+	items := make([]*model.TrashedDeck, len(decks.Items))
+	for i, deck := range decks.Items {
+		items[i] = &model.TrashedDeck{Deck: deck, PurgeAt: deck.DeletedAt.Add(s.retention)}
+	}
+	return model.Page[*model.TrashedDeck]{Items: items, NextCursor: decks.NextCursor}, nil
+}
+
+// Cards lists the cards deleted on their own, not along with their deck.
+func (s *TrashService) Cards(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.TrashedCard], error) {
+	cards, err := s.cards.Trash(ctx, userID, s.cutoff(), page)
+	if err != nil {
+		return model.Page[*model.TrashedCard]{}, err
+	}
+	items := make([]*model.TrashedCard, len(cards.Items))
+	for i, card := range cards.Items {
+		items[i] = &model.TrashedCard{Card: card, PurgeAt: card.DeletedAt.Add(s.retention)}
+	}
+	return model.Page[*model.TrashedCard]{Items: items, NextCursor: cards.NextCursor}, nil
+}
+
+// RestoreDeck brings a deck back with the subdecks and cards deleted along
+// with it, last among its siblings. A deck whose parent is in the trash
+// too comes back with the parent only.
+func (s *TrashService) RestoreDeck(ctx context.Context, userID, deckID int64) (*model.Deck, error) {
+	var deck *model.Deck
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		trashed, err := repos.Decks.GetDeleted(ctx, deckID)
+		if err != nil {
+			return err
+		}
+		if trashed.UserID != userID {
+			return model.ErrForbidden
+		}
+		if !trashed.DeletedAt.After(s.cutoff()) {
+			return model.ErrNotFound
+		}
+		if trashed.ParentID != nil {
+			if _, err := repos.Decks.GetByID(ctx, *trashed.ParentID); err != nil {
+				return err
+			}
+		}
+
+		if err := repos.Decks.Restore(ctx, deckID); err != nil {
+			return err
+		}
+		siblings, err := repos.Decks.Children(ctx, userID, trashed.ParentID)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, 0, len(siblings))
+		for _, sibling := range siblings {
+			if sibling.ID != deckID {
+				ids = append(ids, sibling.ID)
+			}
+		}
+		if err := repos.Decks.Renumber(ctx, append(ids, deckID)); err != nil {
+			return err
+		}
+		deck, err = repos.Decks.GetByID(ctx, deckID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deck, nil
+}
+
+// RestoreCard brings back a card deleted on its own. A card whose deck is
+// in the trash comes back with the deck only.
+func (s *TrashService) RestoreCard(ctx context.Context, userID, cardID int64) (*model.Card, error) {
+	var card *model.Card
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		trashed, err := repos.Cards.GetDeleted(ctx, cardID)
+		if err != nil {
+			return err
+		}
+		if _, err := NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs).Deck(ctx, userID, trashed.DeckID); err != nil {
+			return err
+		}
+		if !trashed.DeletedAt.After(s.cutoff()) {
+			return model.ErrNotFound
+		}
+
+		if err := repos.Cards.Restore(ctx, cardID); err != nil {
+			return err
+		}
+		card, err = repos.Cards.GetByID(ctx, cardID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// Purge deletes every user's decks and cards that have been in the trash
+// longer than the retention period, with their schedules and review logs.
+func (s *TrashService) Purge(ctx context.Context) (model.PurgeResult, error) {
+	cutoff := s.cutoff()
+	var result model.PurgeResult
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		if result.Decks, err = repos.Decks.Purge(ctx, cutoff); err != nil {
+			return err
+		}
+		result.Cards, err = repos.Cards.Purge(ctx, cutoff)
+		return err
+	})
+	if err != nil {
+		return model.PurgeResult{}, err
+	}
+	return result, nil
+}
+
+// cutoff is the deletion time before which items are past retention.
+func (s *TrashService) cutoff() time.Time {
+	return s.now().Add(-s.retention)
+}
@@ -0,0 +1,20 @@
+package srs
+
+import "math"
+
+// TargetRetention is the recall probability a card is assumed to have on its
+// due date. SM-2 has no memory model of its own, so the interval is treated
+// as the stability at which recall drops to this value.
+const TargetRetention = 0.9
+
+// Retrievability estimates the probability of recalling a card elapsedDays
+// after its last review, using the power forgetting curve from FSRS with the
+// scheduled interval as stability.
+func Retrievability(interval int, elapsedDays float64) float64 {
+	if elapsedDays <= 0 {
+		return 1
+	}
+	stability := math.Max(float64(interval), 1)
+	factor := 1/TargetRetention - 1
+	return math.Pow(1+factor*elapsedDays/stability, -1)
+}
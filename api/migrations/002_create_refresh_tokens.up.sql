@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    replaced_by_id BIGINT REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'SHA-256 hex digest of the opaque refresh token; the token itself is never stored';
+COMMENT ON COLUMN refresh_tokens.replaced_by_id IS 'Token issued when this one was rotated; presenting a rotated token revokes the whole family';
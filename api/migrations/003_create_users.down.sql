@@ -0,0 +1,3 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_id_fkey;
+ALTER TABLE decks DROP CONSTRAINT IF EXISTS decks_user_id_fkey;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    srs_algorithm VARCHAR(50) NOT NULL DEFAULT 'sm2',
+    daily_new_cards INTEGER NOT NULL DEFAULT 20,
+    daily_reviews INTEGER NOT NULL DEFAULT 200,
+    timezone_offset INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE decks
+    ADD CONSTRAINT decks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+COMMENT ON COLUMN users.email IS 'Stored lower-cased; uniqueness is enforced by users_email_key';
+COMMENT ON COLUMN users.timezone_offset IS 'Offset from UTC in minutes, used to roll over daily limits';
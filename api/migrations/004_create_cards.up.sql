@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS cards (
+    id BIGSERIAL PRIMARY KEY,
+    deck_id BIGINT NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL DEFAULT 'basic'
+        CHECK (type IN ('basic', 'cloze', 'mcq', 'image_occlusion', 'audio', 'reverse')),
+    front TEXT NOT NULL,
+    back TEXT NOT NULL DEFAULT '',
+    extra TEXT NOT NULL DEFAULT '',
+    tags TEXT[],
+    position INTEGER NOT NULL DEFAULT 0,
+    suspended BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_cards_deck_id_position ON cards(deck_id, position, id);
+CREATE INDEX idx_cards_tags ON cards USING GIN (tags);
+
+COMMENT ON COLUMN cards.suspended IS 'Suspended cards keep their schedule but are left out of study queues';
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS card_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    card_id BIGINT NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    state VARCHAR(20) NOT NULL DEFAULT 'new'
+        CHECK (state IN ('new', 'learning', 'review', 'relearning', 'mastered')),
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    interval INTEGER NOT NULL DEFAULT 0,
+    ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    review_count INTEGER NOT NULL DEFAULT 0,
+    lapse_count INTEGER NOT NULL DEFAULT 0,
+    last_reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE(card_id, user_id)
+);
+
+CREATE INDEX idx_card_schedules_user_state_due ON card_schedules(user_id, state, due_at);
+
+COMMENT ON COLUMN card_schedules.interval IS 'Current review interval in days';
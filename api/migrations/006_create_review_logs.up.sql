@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS review_logs (
+    id BIGSERIAL PRIMARY KEY,
+    card_schedule_id BIGINT NOT NULL REFERENCES card_schedules(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 3),
+    previous_state VARCHAR(20) NOT NULL,
+    new_state VARCHAR(20) NOT NULL,
+    previous_ease DOUBLE PRECISION NOT NULL,
+    new_ease DOUBLE PRECISION NOT NULL,
+    previous_interval INTEGER NOT NULL,
+    new_interval INTEGER NOT NULL,
+    review_duration INTEGER NOT NULL DEFAULT 0,
+    reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_review_logs_user_reviewed_at ON review_logs(user_id, reviewed_at DESC, id DESC);
+CREATE INDEX idx_review_logs_card_schedule_id ON review_logs(card_schedule_id);
+
+COMMENT ON COLUMN review_logs.rating IS '1 = wrong, 2 = correct, 3 = easy';
+COMMENT ON COLUMN review_logs.review_duration IS 'Time spent answering, in milliseconds';
@@ -0,0 +1,2 @@
+ALTER TABLE cards DROP COLUMN IF EXISTS version;
+ALTER TABLE decks DROP COLUMN IF EXISTS version;
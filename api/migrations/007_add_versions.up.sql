@@ -0,0 +1,5 @@
+ALTER TABLE decks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE cards ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN decks.version IS 'Incremented on every update; updates that expect another version fail';
+COMMENT ON COLUMN cards.version IS 'Incremented on every update; updates that expect another version fail';
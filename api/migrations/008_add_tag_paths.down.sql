@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_cards_tag_paths;
+DROP FUNCTION IF EXISTS tag_paths(TEXT[]);
@@ -0,0 +1,13 @@
+-- tag_paths expands hierarchical tags into themselves and all their
+-- ancestors: {lang::es::verbs} becomes {lang, lang::es, lang::es::verbs}.
+-- Indexing it lets subtree queries use the GIN index instead of scanning
+-- every tag. array_to_string is only STABLE for arbitrary element types,
+-- but it is immutable on text.
+CREATE FUNCTION tag_paths(tags TEXT[]) RETURNS TEXT[]
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT COALESCE(array_agg(DISTINCT array_to_string((string_to_array(t.tag, '::'))[1:n], '::')), '{}')
+    FROM unnest(tags) AS t(tag)
+    CROSS JOIN LATERAL generate_series(1, cardinality(string_to_array(t.tag, '::'))) AS n
+$$;
+
+CREATE INDEX idx_cards_tag_paths ON cards USING GIN (tag_paths(tags));
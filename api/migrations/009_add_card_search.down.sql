@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_cards_search_vector;
+DROP TRIGGER IF EXISTS decks_search_language ON decks;
+DROP FUNCTION IF EXISTS decks_reindex_cards();
+DROP TRIGGER IF EXISTS cards_search_vector ON cards;
+DROP FUNCTION IF EXISTS cards_set_search_vector();
+DROP FUNCTION IF EXISTS card_search_vector(REGCONFIG, TEXT, TEXT, TEXT);
+ALTER TABLE cards DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE decks DROP COLUMN IF EXISTS language;
@@ -0,0 +1,48 @@
+ALTER TABLE decks ADD COLUMN language REGCONFIG NOT NULL DEFAULT 'simple';
+ALTER TABLE cards ADD COLUMN search_vector TSVECTOR;
+
+COMMENT ON COLUMN decks.language IS 'Text search configuration used to index and search the cards of the deck';
+COMMENT ON COLUMN cards.search_vector IS 'Front (weight A), back (B) and extra (C), maintained by triggers in the language of the deck';
+
+CREATE FUNCTION card_search_vector(language REGCONFIG, front TEXT, back TEXT, extra TEXT) RETURNS TSVECTOR
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT setweight(to_tsvector(language, front), 'A')
+        || setweight(to_tsvector(language, back), 'B')
+        || setweight(to_tsvector(language, extra), 'C')
+$$;
+
+CREATE FUNCTION cards_set_search_vector() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.search_vector := card_search_vector(
+        (SELECT language FROM decks WHERE id = NEW.deck_id), NEW.front, NEW.back, NEW.extra);
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER cards_search_vector
+    BEFORE INSERT OR UPDATE OF deck_id, front, back, extra ON cards
+    FOR EACH ROW EXECUTE FUNCTION cards_set_search_vector();
+
+-- Changing the language of a deck re-indexes its cards.
+CREATE FUNCTION decks_reindex_cards() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE cards
+    SET search_vector = card_search_vector(NEW.language, front, back, extra)
+    WHERE deck_id = NEW.id;
+    RETURN NULL;
+END
+$$;
+
+CREATE TRIGGER decks_search_language
+    AFTER UPDATE OF language ON decks
+    FOR EACH ROW WHEN (OLD.language IS DISTINCT FROM NEW.language)
+    EXECUTE FUNCTION decks_reindex_cards();
+
+UPDATE cards c
+SET search_vector = card_search_vector(d.language, c.front, c.back, c.extra)
+FROM decks d
+WHERE d.id = c.deck_id;
+
+CREATE INDEX idx_cards_search_vector ON cards USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS decks_no_cycles ON decks;
+DROP FUNCTION IF EXISTS decks_prevent_cycles();
@@ -0,0 +1,34 @@
+-- A deck may not become its own ancestor. The walk uses UNION so it stops
+-- even if a cycle slipped in before this trigger existed.
+CREATE FUNCTION decks_prevent_cycles() RETURNS TRIGGER
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NEW.parent_id IS NOT NULL AND EXISTS (
+        WITH RECURSIVE ancestors(id) AS (
+            SELECT NEW.parent_id
+            UNION
+            SELECT d.parent_id FROM decks d INNER JOIN ancestors a ON d.id = a.id WHERE d.parent_id IS NOT NULL
+        )
+        SELECT 1 FROM ancestors WHERE id = NEW.id
+    ) THEN
+        RAISE EXCEPTION 'decks_no_cycles: deck % cannot be moved below itself', NEW.id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER decks_no_cycles
+    BEFORE UPDATE OF parent_id ON decks
+    FOR EACH ROW WHEN (NEW.parent_id IS DISTINCT FROM OLD.parent_id)
+    EXECUTE FUNCTION decks_prevent_cycles();
+
+-- Siblings are numbered 0..n-1 from now on; close the gaps and ties left by
+-- earlier updates in their current order.
+UPDATE decks
+SET position = ordered.rank
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id, parent_id ORDER BY position, name COLLATE "C", id) - 1 AS rank
+    FROM decks
+) ordered
+WHERE decks.id = ordered.id AND decks.position <> ordered.rank;
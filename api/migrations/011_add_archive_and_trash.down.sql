@@ -0,0 +1,12 @@
+-- The trash is emptied first: restored names could clash with live decks.
+DELETE FROM decks WHERE deleted_at IS NOT NULL;
+DELETE FROM cards WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_cards_deleted_at;
+DROP INDEX IF EXISTS idx_decks_deleted_at;
+DROP INDEX IF EXISTS decks_user_id_parent_id_name_key;
+ALTER TABLE decks ADD CONSTRAINT decks_user_id_parent_id_name_key UNIQUE (user_id, parent_id, name);
+
+ALTER TABLE cards DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE decks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE decks DROP COLUMN IF EXISTS archived_at;
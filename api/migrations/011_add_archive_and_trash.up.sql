@@ -0,0 +1,14 @@
+ALTER TABLE decks ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE decks ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE cards ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN decks.archived_at IS 'Archived decks and their subdecks are kept but left out of study queues';
+COMMENT ON COLUMN decks.deleted_at IS 'Set while the deck is in the trash; the subdecks and cards deleted with it share the timestamp';
+COMMENT ON COLUMN cards.deleted_at IS 'Set while the card is in the trash';
+
+-- Decks in the trash must not block their name.
+ALTER TABLE decks DROP CONSTRAINT decks_user_id_parent_id_name_key;
+CREATE UNIQUE INDEX decks_user_id_parent_id_name_key ON decks(user_id, parent_id, name) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_decks_deleted_at ON decks(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_cards_deleted_at ON cards(deleted_at) WHERE deleted_at IS NOT NULL;
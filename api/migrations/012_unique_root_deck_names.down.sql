@@ -0,0 +1,4 @@
+-- Only the index is restored; decks renamed by the up migration keep their
+-- new names.
+DROP INDEX IF EXISTS decks_user_id_parent_id_name_key;
+CREATE UNIQUE INDEX decks_user_id_parent_id_name_key ON decks(user_id, parent_id, name) WHERE deleted_at IS NULL;
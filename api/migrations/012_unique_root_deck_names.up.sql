@@ -0,0 +1,39 @@
+-- NULL parents never conflict in a plain unique index, so top-level decks
+-- could share a name. Existing clashes are renamed "Spanish (2)" and so on
+-- before the index is rebuilt over COALESCE(parent_id, 0). A suffix that
+-- another top-level deck of the user already has is skipped, so a user with
+-- "Spanish", "Spanish" and "Spanish (2)" ends up with "Spanish (3)".
+DO $$
+DECLARE
+    clash RECORD;
+    suffix INTEGER;
+BEGIN
+    FOR clash IN
+        SELECT id, user_id, name
+        FROM (
+            SELECT id, user_id, name, position,
+                ROW_NUMBER() OVER (PARTITION BY user_id, name ORDER BY position, id) AS rank
+            FROM decks
+            WHERE parent_id IS NULL AND deleted_at IS NULL
+        ) ranked
+        WHERE rank > 1
+        ORDER BY user_id, name COLLATE "C", position, id
+    LOOP
+        suffix := 2;
+        WHILE EXISTS (
+            SELECT 1 FROM decks
+            WHERE user_id = clash.user_id AND parent_id IS NULL AND deleted_at IS NULL
+                AND name = clash.name || ' (' || suffix || ')'
+        ) LOOP
+            suffix := suffix + 1;
+        END LOOP;
+
+        UPDATE decks
+        SET name = clash.name || ' (' || suffix || ')', version = version + 1, updated_at = NOW()
+        WHERE id = clash.id;
+    END LOOP;
+END
+$$;
+
+DROP INDEX decks_user_id_parent_id_name_key;
+CREATE UNIQUE INDEX decks_user_id_parent_id_name_key ON decks(user_id, COALESCE(parent_id, 0), name) WHERE deleted_at IS NULL;
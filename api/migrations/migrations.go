@@ -0,0 +1,49 @@
+// Package migrations embeds the SQL schema migrations so the binary knows
+// which schema version it expects.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the highest migration version embedded in the
+// binary.
+func LatestVersion() (int64, error) {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		version, err := parseVersion(entry.Name())
+		if err != nil {
+			return 0, err
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
+
+// parseVersion extracts the numeric prefix of a file such as
+// 001_create_decks.up.sql.
+func parseVersion(name string) (int64, error) {
+	prefix, _, found := strings.Cut(name, "_")
+	if !found {
+		return 0, fmt.Errorf("migration %q: missing version prefix", name)
+	}
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("migration %q: invalid version %q", name, prefix)
+	}
+	return version, nil
+}
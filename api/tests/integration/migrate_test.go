@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"sync"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"memwright/api/internal/migrate"
+	"memwright/api/migrations"
+	"memwright/api/pkg/logger"
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL. The schema is
+// dropped and recreated by the tests, so never point it at real data.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	return db
+}
+
+func newTestRunner(t *testing.T, db *sql.DB) *migrate.Runner {
+	t.Helper()
+	runner, err := migrate.NewRunner(db, migrations.FS, logger.New(&bytes.Buffer{}, logger.LevelError))
+	if err != nil {
+		t.Fatalf("NewRunner() error = %v", err)
+	}
+	return runner
+}
+
+func TestMigrateUpDownIntegration(t *testing.T) {
+	db := openTestDB(t)
+	runner := newTestRunner(t, db)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if _, err := runner.Down(ctx, int(runner.Latest())); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if version, _, _ := runner.Version(ctx); version != 0 {
+		t.Fatalf("expected version 0 after rolling everything back, got %d", version)
+	}
+
+	// Concurrent runners must serialize on the advisory lock and apply each
+	// migration once.
+	var wg sync.WaitGroup
+	applied := make([]int, 3)
+	errs := make([]error, 3)
+	for i := range applied {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied[i], errs[i] = newTestRunner(t, db).Up(ctx)
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := range applied {
+		if errs[i] != nil {
+			t.Fatalf("concurrent Up() error = %v", errs[i])
+		}
+		total += applied[i]
+	}
+	if total != int(runner.Latest()) {
+		t.Errorf("expected %d migrations applied in total, got %d", runner.Latest(), total)
+	}
+
+	version, dirty, err := runner.Version(ctx)
+	if err != nil || dirty || version != runner.Latest() {
+		t.Errorf("Version() = %d, %t, %v, want %d, false, nil", version, dirty, err, runner.Latest())
+	}
+}
+
+func TestMigrateUniqueRootDeckNamesIntegration(t *testing.T) {
+	db := openTestDB(t)
+	runner := newTestRunner(t, db)
+	ctx := context.Background()
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if _, err := runner.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+
+	var userID int64
+	if err := db.QueryRowContext(ctx, `INSERT INTO users (email, password_hash) VALUES ('renames@example.com', 'x') RETURNING id`).Scan(&userID); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.ExecContext(context.Background(), `DELETE FROM users WHERE id = $1`, userID) })
+	var ids []int64
+	for position, name := range []string{"Spanish", "Spanish", "Spanish (2)"} {
+		var id int64
+		if err := db.QueryRowContext(ctx, `INSERT INTO decks (user_id, name, position) VALUES ($1, $2, $3) RETURNING id`, userID, name, position).Scan(&id); err != nil {
+			t.Fatalf("insert deck: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := runner.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	for i, want := range []string{"Spanish", "Spanish (3)", "Spanish (2)"} {
+		var name string
+		if err := db.QueryRowContext(ctx, `SELECT name FROM decks WHERE id = $1`, ids[i]).Scan(&name); err != nil || name != want {
+			t.Errorf("deck %d name = %q, %v, want %q", i, name, err, want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"memwright/api/internal/repository"
+	"memwright/api/internal/repository/repositorytest"
+)
+
+func TestPostgresRepositories_Contract(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := newTestRunner(t, db).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repository.Repositories {
+		_, err := db.Exec(`TRUNCATE users, decks, cards, card_schedules, review_logs, refresh_tokens RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return repository.NewRepositories(db)
+	})
+}
@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+func (api *etagAPI) importAnki(query, data string) *model.ImportResult {
+	api.t.Helper()
+	response := api.doRaw(http.MethodPost, "/api/v1/decks/import/anki"+query, "text/plain", data)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("import anki: status %d: %s", response.Code, response.Body)
+	}
+	var result model.ImportResult
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	return &result
+}
+
+func TestAnkiAPI_Import(t *testing.T) {
+	api := newETagAPI(t)
+	data := "#separator:tab\n" +
+		"#html:true\n" +
+		"#tags:anki\n" +
+		"#guid column:1\n" +
+		"#notetype column:2\n" +
+		"#deck column:3\n" +
+		"#tags column:6\n" +
+		"a1\tBasic\tSpanish::Verbs\thablar\tto <b>speak</b><br>regular\tverbs lang::es\n" +
+		"a2\tCloze\tSpanish\t{{c1::Hola}}, amigo\t<div>greeting&nbsp;&amp; more</div>\t\n" +
+		"a3\tBasic (and reversed card)\tSpanish\tperro\t\"dog\tcanine\"\t\n" +
+		"\n" +
+		"a4\tBasic\tSpanish :: Verbs\tcomer\tto eat\t\n"
+	result := api.importAnki("", data)
+	if result.DecksCreated != 2 || result.CardsCreated != 4 {
+		t.Fatalf("result = %+v", result)
+	}
+	spanish, verbs := result.DeckIDs[1], result.DeckIDs[2]
+	if api.order(nil) != "Spanish:0" || api.order(&spanish) != "Verbs:0" {
+		t.Fatalf("decks = %s, %s", api.order(nil), api.order(&spanish))
+	}
+
+	cards := api.deckCards(verbs)
+	if len(cards) != 2 || cards[0].Front != "hablar" || cards[0].Back != "to speak\nregular" || cards[0].Type != model.CardTypeBasic {
+		t.Fatalf("verbs = %+v", cards)
+	}
+	if !reflect.DeepEqual(cards[0].Tags, []string{"anki", "verbs", "lang::es"}) || !reflect.DeepEqual(cards[1].Tags, []string{"anki"}) {
+		t.Fatalf("tags = %v, %v", cards[0].Tags, cards[1].Tags)
+	}
+	cards = api.deckCards(spanish)
+	if len(cards) != 2 || cards[0].Type != model.CardTypeCloze || cards[0].Extra != "greeting & more" || cards[0].Back != "" {
+		t.Fatalf("cloze = %+v", cards[0])
+	}
+	if cards[1].Type != model.CardTypeReverse || cards[1].Back != "dog\tcanine" {
+		t.Fatalf("reverse = %+v", cards[1])
+	}
+
+	// Importing again merges the decks and skips the cards.
+	again := api.importAnki("", data)
+	if again.DecksMerged != 2 || again.CardsSkipped != 4 || again.CardsCreated != 0 || len(again.Conflicts) != 0 {
+		t.Fatalf("second import = %+v", again)
+	}
+}
+
+func TestAnkiAPI_ImportWithoutHeaders(t *testing.T) {
+	api := newETagAPI(t)
+	parent := api.createDeck("Languages", nil)
+
+	data := "perro;dog\n\"{{c1::gato}} is cat\";\n"
+	result := api.importAnki("?deck=French::Animals&parent_id="+strconv.FormatInt(parent.ID, 10), data)
+	if result.DecksCreated != 2 || result.CardsCreated != 2 {
+		t.Fatalf("result = %+v", result)
+	}
+	if api.order(&parent.ID) != "French:0" {
+		t.Fatalf("decks below parent = %s", api.order(&parent.ID))
+	}
+	cards := api.deckCards(result.DeckIDs[2])
+	if len(cards) != 2 || cards[0].Type != model.CardTypeBasic || cards[0].Back != "dog" || cards[1].Type != model.CardTypeCloze {
+		t.Fatalf("cards = %+v", cards)
+	}
+
+	result = api.importAnki("", "#deck:Italian\ncane,dog\n")
+	if cards := api.deckCards(result.DeckIDs[1]); len(cards) != 1 || cards[0].Front != "cane" || cards[0].Back != "dog" {
+		t.Fatalf("fixed deck cards = %+v", cards)
+	}
+	result = api.importAnki("", "hund,dog\n")
+	if api.order(nil) != "Languages:0 Italian:1 Default:2" || result.CardsCreated != 1 {
+		t.Fatalf("top level = %s", api.order(nil))
+	}
+}
+
+func TestAnkiAPI_ImportValidation(t *testing.T) {
+	api := newETagAPI(t)
+	for name, test := range map[string]struct {
+		data  string
+		field string
+	}{
+		"separator":   {"#separator:Banana\na\tb\n", "lines[1]"},
+		"deck column": {"#deck column:0\na\tb\n", "lines[1]"},
+		"deck path":   {"#separator:tab\n#deck column:1\nSpanish::::Verbs\thola\thello\n", "lines[3].deck"},
+		"front":       {"#separator:tab\nhola\thello\n\thello\n", "lines[3].front"},
+		"tags":        {"#separator:tab\n#tags column:3\nhola\thello\tlang::\n", "lines[3].tags[0]"},
+		"quote":       {"#separator:tab\nhola\thello\n\"hola\"x\thello\n", "lines[3]"},
+		"empty":       {"#separator:tab\n\n", "notes"},
+	} {
+		response := api.doRaw(http.MethodPost, "/api/v1/decks/import/anki", "text/plain", test.data)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d: %s", name, response.Code, response.Body)
+			continue
+		}
+		var problem struct {
+			Errors []model.FieldError `json:"errors"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&problem)
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != test.field {
+			t.Errorf("%s: errors = %+v", name, problem.Errors)
+		}
+	}
+	if api.order(nil) != "" {
+		t.Fatalf("failed imports created decks: %s", api.order(nil))
+	}
+}
+
+func TestAnkiAPI_ExportRoundTrip(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, verbs, _ := transferFixture(t, api)
+	api.createCard(verbs.ID, service.CardInput{Type: model.CardTypeReverse, Front: "<ir>", Back: "to go\nirregular", Tags: []string{"false friends"}})
+
+	response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10)+"/export/anki", "", nil)
+	if response.Code != http.StatusOK || !strings.HasPrefix(response.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("export: status %d, type %s", response.Code, response.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSuffix(response.Body.String(), "\n"), "\n")
+	expected := []string{
+		"#separator:tab",
+		"#html:true",
+		"#notetype column:1",
+		"#deck column:2",
+		"#tags column:6",
+		"Basic\tSpanish\thola\thello\t\tgreetings",
+		"Basic\tSpanish::Verbs\thablar\tto speak\tregular\t",
+		"Cloze\tSpanish::Verbs\t{{c1::ser}} o no {{c1::ser}}\t\t\t",
+		"Basic (and reversed card)\tSpanish::Verbs\t&lt;ir&gt;\tto go<br>irregular\t\tfalse_friends",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("export =\n%s", strings.Join(lines, "\n"))
+	}
+
+	target := api.createDeck("Copy", nil)
+	result := api.importAnki("?parent_id="+strconv.FormatInt(target.ID, 10), response.Body.String())
+	if result.DecksCreated != 2 || result.CardsCreated != 4 {
+		t.Fatalf("result = %+v", result)
+	}
+	cards := api.deckCards(result.DeckIDs[2])
+	if len(cards) != 3 || cards[1].Type != model.CardTypeCloze || cards[2].Front != "<ir>" || cards[2].Back != "to go\nirregular" || cards[2].Type != model.CardTypeReverse {
+		t.Fatalf("cards = %+v", cards)
+	}
+	if !reflect.DeepEqual(cards[2].Tags, []string{"false_friends"}) || cards[0].Extra != "regular" {
+		t.Fatalf("cards = %+v", cards)
+	}
+}
@@ -0,0 +1,351 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/auth"
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+)
+
+const testJWTSecret = "test-secret-that-is-at-least-32-characters"
+
+type authUserRepo struct {
+	users map[int64]*model.User
+}
+
+func newAuthUserRepo() *authUserRepo {
+	return &authUserRepo{users: map[int64]*model.User{}}
+}
+
+func (r *authUserRepo) Create(ctx context.Context, user *model.User) error {
+	for _, existing := range r.users {
+		if existing.Email == user.Email {
+			return model.ErrDuplicateEmail
+		}
+	}
+	user.ID = int64(len(r.users) + 1)
+	r.users[user.ID] = user
+	return nil
+}
+func (r *authUserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authUserRepo) Update(ctx context.Context, user *model.User) error {
+	return nil
+}
+func (r *authUserRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+type authTokenRepo struct {
+	tokens map[int64]*model.RefreshToken
+}
+
+func newAuthTokenRepo() *authTokenRepo {
+	return &authTokenRepo{tokens: map[int64]*model.RefreshToken{}}
+}
+
+func (r *authTokenRepo) Create(ctx context.Context, token *model.RefreshToken) error {
+	token.ID = int64(len(r.tokens) + 1)
+	r.tokens[token.ID] = token
+	return nil
+}
+func (r *authTokenRepo) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authTokenRepo) Revoke(ctx context.Context, id int64, replacedByID *int64) error {
+	token, ok := r.tokens[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	if token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+	}
+	if replacedByID != nil {
+		token.ReplacedByID = replacedByID
+	}
+	return nil
+}
+func (r *authTokenRepo) RevokeAllForUser(ctx context.Context, userID int64) error {
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// directUnitOfWork runs units straight against the fakes, without rollback.
+type directUnitOfWork struct {
+	repos repository.Repositories
+}
+
+func (u directUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	return fn(ctx, u.repos)
+}
+
+func (u directUnitOfWork) Read(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	return fn(ctx, u.repos)
+}
+
+func newTestTokenManager(t *testing.T) *auth.TokenManager {
+	t.Helper()
+	tokens, err := auth.NewTokenManager(testJWTSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokenManager() error = %v", err)
+	}
+	return tokens
+}
+
+func newTestAuthService(t *testing.T) (*service.AuthService, *authTokenRepo, *auth.TokenManager) {
+	t.Helper()
+	tokens := newTestTokenManager(t)
+	users := newAuthUserRepo()
+	refreshTokens := newAuthTokenRepo()
+	uow := directUnitOfWork{repos: repository.Repositories{Users: users, RefreshTokens: refreshTokens}}
+	return service.NewAuthService(users, refreshTokens, uow, tokens, 24*time.Hour), refreshTokens, tokens
+}
+
+func TestNewTokenManager_RejectsShortSecret(t *testing.T) {
+	if _, err := auth.NewTokenManager("too-short", time.Hour); err == nil {
+		t.Error("expected error for short secret")
+	}
+}
+
+func TestTokenManager_RoundTrip(t *testing.T) {
+	tokens := newTestTokenManager(t)
+
+	token, expiresAt, err := tokens.IssueAccessToken(42)
+	if err != nil {
+		t.Fatalf("IssueAccessToken() error = %v", err)
+	}
+	if !expiresAt.After(time.Now()) {
+		t.Errorf("expected expiry in the future, got %v", expiresAt)
+	}
+
+	claims, err := tokens.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken() error = %v", err)
+	}
+	userID, err := claims.UserID()
+	if err != nil || userID != 42 {
+		t.Errorf("expected user ID 42, got %d (%v)", userID, err)
+	}
+}
+
+func TestTokenManager_RejectsTamperedAndForeignTokens(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	token, _, _ := tokens.IssueAccessToken(1)
+
+	other, _ := auth.NewTokenManager(strings.Repeat("x", auth.MinSecretLength), time.Hour)
+	foreign, _, _ := other.IssueAccessToken(1)
+
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+
+	for name, candidate := range map[string]string{"tampered": tampered, "foreign": foreign, "garbage": "abc"} {
+		if _, err := tokens.ParseAccessToken(candidate); !errors.Is(err, model.ErrUnauthorized) {
+			t.Errorf("%s: ParseAccessToken() error = %v, want ErrUnauthorized", name, err)
+		}
+	}
+}
+
+func TestTokenManager_RejectsExpiredToken(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	issuedAt := time.Now().Add(-2 * time.Hour)
+	token, _, _ := tokens.WithClock(func() time.Time { return issuedAt }).IssueAccessToken(1)
+
+	tokens.WithClock(time.Now)
+	if _, err := tokens.ParseAccessToken(token); !errors.Is(err, auth.ErrExpiredToken) {
+		t.Errorf("ParseAccessToken() error = %v, want ErrExpiredToken", err)
+	}
+}
+
+func TestAuthService_LoginTakesAsLongForUnknownEmails(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+	if _, err := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "correct horse"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	elapsed := func(email string) time.Duration {
+		start := time.Now()
+		if _, err := authService.Login(ctx, email, "wrong password"); !errors.Is(err, model.ErrUnauthorized) {
+			t.Fatalf("Login(%s) error = %v, want ErrUnauthorized", email, err)
+		}
+		return time.Since(start)
+	}
+
+	// Both run bcrypt; without the dummy comparison an unknown email
+	// returns orders of magnitude sooner.
+	known, unknown := elapsed("ada@example.com"), elapsed("nobody@example.com")
+	if unknown < known/4 {
+		t.Errorf("unknown email took %v, a known one %v", unknown, known)
+	}
+}
+
+func TestAuthService_RegisterAndLogin(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	registered, err := authService.Register(ctx, service.RegisterInput{Email: " Ada@Example.com ", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if registered.User.Email != "ada@example.com" {
+		t.Errorf("expected normalized email, got %q", registered.User.Email)
+	}
+	if registered.User.PasswordHash == "correct horse" {
+		t.Error("password must be hashed")
+	}
+	if registered.AccessToken == "" || registered.RefreshToken == "" {
+		t.Error("expected access and refresh tokens")
+	}
+
+	if _, err := authService.Login(ctx, "ada@example.com", "correct horse"); err != nil {
+		t.Errorf("Login() error = %v", err)
+	}
+	if _, err := authService.Login(ctx, "ada@example.com", "wrong password"); !errors.Is(err, model.ErrUnauthorized) {
+		t.Errorf("Login() with wrong password error = %v, want ErrUnauthorized", err)
+	}
+	if _, err := authService.Login(ctx, "nobody@example.com", "correct horse"); !errors.Is(err, model.ErrUnauthorized) {
+		t.Errorf("Login() with unknown email error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestAuthService_Register_Validation(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	if _, err := authService.Register(ctx, service.RegisterInput{Email: "not-an-email", Password: "long enough"}); !errors.Is(err, model.ErrInvalidInput) {
+		t.Errorf("invalid email: error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "short"}); !errors.Is(err, model.ErrInvalidInput) {
+		t.Errorf("short password: error = %v, want ErrInvalidInput", err)
+	}
+
+	_, _ = authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "long enough"})
+	if _, err := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "long enough"}); !errors.Is(err, model.ErrDuplicateEmail) {
+		t.Errorf("duplicate email: error = %v, want ErrDuplicateEmail", err)
+	}
+}
+
+func TestAuthService_Refresh_RotatesToken(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	registered, _ := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "long enough"})
+
+	refreshed, err := authService.Refresh(ctx, registered.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.RefreshToken == registered.RefreshToken {
+		t.Error("expected a new refresh token")
+	}
+
+	if _, err := authService.Refresh(ctx, refreshed.RefreshToken); err != nil {
+		t.Errorf("Refresh() with rotated token error = %v", err)
+	}
+}
+
+func TestAuthService_Refresh_ReuseRevokesAllTokens(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	registered, _ := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "long enough"})
+	refreshed, _ := authService.Refresh(ctx, registered.RefreshToken)
+
+	if _, err := authService.Refresh(ctx, registered.RefreshToken); !errors.Is(err, model.ErrUnauthorized) {
+		t.Fatalf("reused token: error = %v, want ErrUnauthorized", err)
+	}
+	if _, err := authService.Refresh(ctx, refreshed.RefreshToken); !errors.Is(err, model.ErrUnauthorized) {
+		t.Errorf("token family must be revoked after reuse, error = %v", err)
+	}
+}
+
+func TestAuthService_Logout_RevokesRefreshToken(t *testing.T) {
+	authService, _, _ := newTestAuthService(t)
+	ctx := context.Background()
+
+	registered, _ := authService.Register(ctx, service.RegisterInput{Email: "ada@example.com", Password: "long enough"})
+
+	if err := authService.Logout(ctx, registered.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := authService.Refresh(ctx, registered.RefreshToken); !errors.Is(err, model.ErrUnauthorized) {
+		t.Errorf("Refresh() after logout error = %v, want ErrUnauthorized", err)
+	}
+	if err := authService.Logout(ctx, "unknown-token"); err != nil {
+		t.Errorf("Logout() with unknown token error = %v, want nil", err)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	token, _, _ := tokens.IssueAccessToken(7)
+
+	var gotUserID int64
+	protected := handler.RequireAuth(tokens)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		gotUserID, _ = auth.UserIDFromContext(request.Context())
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", header: "Bearer " + token, wantStatus: http.StatusOK},
+		{name: "missing header", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic " + token, wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", header: "Bearer invalid", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+			if tt.header != "" {
+				request.Header.Set("Authorization", tt.header)
+			}
+			recorder := httptest.NewRecorder()
+
+			protected.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, recorder.Code)
+			}
+		})
+	}
+
+	if gotUserID != 7 {
+		t.Errorf("expected user ID 7 in context, got %d", gotUserID)
+	}
+}
@@ -0,0 +1,230 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+type authzDeckRepo struct {
+	deckRepoMock
+	decks map[int64]*model.Deck
+}
+
+func (r *authzDeckRepo) GetByID(ctx context.Context, id int64) (*model.Deck, error) {
+	if deck, ok := r.decks[id]; ok {
+		return deck, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+type authzCardRepo struct {
+	cards map[int64]*model.Card
+}
+
+func (r *authzCardRepo) Create(ctx context.Context, card *model.Card) error {
+	return nil
+}
+func (r *authzCardRepo) GetByID(ctx context.Context, id int64) (*model.Card, error) {
+	if card, ok := r.cards[id]; ok {
+		return card, nil
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authzCardRepo) GetByDeckID(ctx context.Context, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	var cards []*model.Card
+	for _, card := range r.cards {
+		if card.DeckID == deckID {
+			cards = append(cards, card)
+		}
+	}
+	return model.NewPage(cards, 0, model.CardCursor), nil
+}
+func (r *authzCardRepo) Update(ctx context.Context, card *model.Card) error {
+	return nil
+}
+func (r *authzCardRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+func (r *authzCardRepo) GetDeleted(ctx context.Context, id int64) (*model.Card, error) {
+	return nil, model.ErrNotFound
+}
+func (r *authzCardRepo) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Card], error) {
+	return model.NewPage([]*model.Card(nil), 0, model.DeletedCardCursor), nil
+}
+func (r *authzCardRepo) Restore(ctx context.Context, id int64) error {
+	return model.ErrNotFound
+}
+func (r *authzCardRepo) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+type authzScheduleRepo struct {
+	backlogScheduleRepo
+	schedules map[int64]*model.CardSchedule
+}
+
+func (r *authzScheduleRepo) GetByID(ctx context.Context, id int64) (*model.CardSchedule, error) {
+	if schedule, ok := r.schedules[id]; ok {
+		return schedule, nil
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authzScheduleRepo) GetByCardAndUser(ctx context.Context, cardID, userID int64) (*model.CardSchedule, error) {
+	for _, schedule := range r.schedules {
+		if schedule.CardID == cardID && schedule.UserID == userID {
+			return schedule, nil
+		}
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authzScheduleRepo) GetByCards(ctx context.Context, userID int64, cardIDs []int64) ([]*model.CardSchedule, error) {
+	return nil, nil
+}
+
+type authzReviewLogRepo struct {
+	logs map[int64]*model.ReviewLog
+}
+
+func (r *authzReviewLogRepo) Create(ctx context.Context, log *model.ReviewLog) error {
+	return nil
+}
+func (r *authzReviewLogRepo) GetByID(ctx context.Context, id int64) (*model.ReviewLog, error) {
+	if log, ok := r.logs[id]; ok {
+		return log, nil
+	}
+	return nil, model.ErrNotFound
+}
+func (r *authzReviewLogRepo) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.ReviewLog], error) {
+	return model.Page[*model.ReviewLog]{}, nil
+}
+func (r *authzReviewLogRepo) GetByDateRange(ctx context.Context, userID int64, start, end time.Time) ([]*model.ReviewLog, error) {
+	return nil, nil
+}
+func (r *authzReviewLogRepo) GetBySchedules(ctx context.Context, scheduleIDs []int64) ([]*model.ReviewLog, error) {
+	return nil, nil
+}
+
+const (
+	ownerID    int64 = 1
+	intruderID int64 = 2
+)
+
+func newTestAuthorizer() *service.Authorizer {
+	decks := &authzDeckRepo{decks: map[int64]*model.Deck{
+		10: {ID: 10, UserID: ownerID, Name: "Spanish"},
+	}}
+	cards := &authzCardRepo{cards: map[int64]*model.Card{
+		100: {ID: 100, DeckID: 10, Front: "hola", Back: "hello"},
+	}}
+	schedules := &authzScheduleRepo{schedules: map[int64]*model.CardSchedule{
+		1000: {ID: 1000, CardID: 100, UserID: ownerID},
+	}}
+	logs := &authzReviewLogRepo{logs: map[int64]*model.ReviewLog{
+		5000: {ID: 5000, CardScheduleID: 1000, UserID: ownerID},
+	}}
+	return service.NewAuthorizer(decks, cards, schedules, logs)
+}
+
+func TestAuthorizer_OwnerHasAccess(t *testing.T) {
+	authorizer := newTestAuthorizer()
+	ctx := context.Background()
+
+	if _, err := authorizer.Deck(ctx, ownerID, 10); err != nil {
+		t.Errorf("Deck() error = %v", err)
+	}
+	if _, err := authorizer.Card(ctx, ownerID, 100); err != nil {
+		t.Errorf("Card() error = %v", err)
+	}
+	if _, err := authorizer.Schedule(ctx, ownerID, 1000); err != nil {
+		t.Errorf("Schedule() error = %v", err)
+	}
+	if _, err := authorizer.ReviewLog(ctx, ownerID, 5000); err != nil {
+		t.Errorf("ReviewLog() error = %v", err)
+	}
+	if cards, err := authorizer.CardsInDeck(ctx, ownerID, 10, model.PageRequest{}); err != nil || len(cards.Items) != 1 {
+		t.Errorf("CardsInDeck() = %d cards, error = %v", len(cards.Items), err)
+	}
+	if _, err := authorizer.ScheduleForCard(ctx, ownerID, 100); err != nil {
+		t.Errorf("ScheduleForCard() error = %v", err)
+	}
+}
+
+func TestAuthorizer_CrossUserAccessIsForbidden(t *testing.T) {
+	authorizer := newTestAuthorizer()
+	ctx := context.Background()
+
+	checks := map[string]func() error{
+		"deck": func() error {
+			_, err := authorizer.Deck(ctx, intruderID, 10)
+			return err
+		},
+		"card": func() error {
+			_, err := authorizer.Card(ctx, intruderID, 100)
+			return err
+		},
+		"schedule": func() error {
+			_, err := authorizer.Schedule(ctx, intruderID, 1000)
+			return err
+		},
+		"review log": func() error {
+			_, err := authorizer.ReviewLog(ctx, intruderID, 5000)
+			return err
+		},
+		"cards in deck": func() error {
+			_, err := authorizer.CardsInDeck(ctx, intruderID, 10, model.PageRequest{})
+			return err
+		},
+		"due cards": func() error {
+			_, err := authorizer.DueCards(ctx, intruderID, 10, time.Now(), 10)
+			return err
+		},
+		"new cards": func() error {
+			_, err := authorizer.NewCards(ctx, intruderID, 10, 10)
+			return err
+		},
+		"schedule for card": func() error {
+			_, err := authorizer.ScheduleForCard(ctx, intruderID, 100)
+			return err
+		},
+	}
+
+	for name, check := range checks {
+		t.Run(name, func(t *testing.T) {
+			if err := check(); !errors.Is(err, model.ErrForbidden) {
+				t.Errorf("error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_MissingResourcesAreNotFound(t *testing.T) {
+	authorizer := newTestAuthorizer()
+	ctx := context.Background()
+
+	if _, err := authorizer.Deck(ctx, ownerID, 99); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("Deck() error = %v, want ErrNotFound", err)
+	}
+	if _, err := authorizer.Card(ctx, ownerID, 99); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("Card() error = %v, want ErrNotFound", err)
+	}
+	if _, err := authorizer.Schedule(ctx, ownerID, 99); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("Schedule() error = %v, want ErrNotFound", err)
+	}
+	if _, err := authorizer.ReviewLog(ctx, ownerID, 99); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("ReviewLog() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBacklogService_ForeignDeckIsForbidden(t *testing.T) {
+	backlog := service.NewBacklogService(newTestAuthorizer(), &backlogScheduleRepo{}, &backlogUserRepo{}, nil)
+
+	_, err := backlog.Preview(context.Background(), intruderID, service.BacklogOptions{DeckID: 10, DailyTarget: 10})
+	if !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("Preview() error = %v, want ErrForbidden", err)
+	}
+}
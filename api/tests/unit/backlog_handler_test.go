@@ -0,0 +1,104 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+func (api *etagAPI) schedule(cardID int64, dueAt time.Time) *model.CardSchedule {
+	api.t.Helper()
+	lastReviewed := dueAt.AddDate(0, 0, -5)
+	schedule := &model.CardSchedule{CardID: cardID, UserID: 1, State: model.ScheduleStateReview, DueAt: dueAt, Interval: 5, EaseFactor: model.DefaultEaseFactor, LastReviewedAt: &lastReviewed}
+	if err := api.repos.Schedules.Create(context.Background(), schedule); err != nil {
+		api.t.Fatal(err)
+	}
+	return schedule
+}
+
+func (api *etagAPI) backlog(action string, opts service.BacklogOptions) *service.BacklogPlan {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/backlog/"+action, "", opts)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("%s: status %d: %s", action, response.Code, response.Body)
+	}
+	var plan service.BacklogPlan
+	_ = json.NewDecoder(response.Body).Decode(&plan)
+	return &plan
+}
+
+func TestBacklogAPI_PreviewAndApply(t *testing.T) {
+	api := newETagAPI(t)
+	spanish := api.createDeck("Spanish", nil)
+	verbs := api.createDeck("Verbs", &spanish.ID)
+	other := api.createDeck("French", nil)
+
+	now := time.Now()
+	overdue := []*model.CardSchedule{
+		api.schedule(api.createCard(spanish.ID, service.CardInput{Front: "hola", Back: "hello"}).ID, now.AddDate(0, 0, -3)),
+		api.schedule(api.createCard(spanish.ID, service.CardInput{Front: "adiós", Back: "goodbye"}).ID, now.AddDate(0, 0, -2)),
+		api.schedule(api.createCard(verbs.ID, service.CardInput{Front: "hablar", Back: "to speak"}).ID, now.AddDate(0, 0, -1)),
+	}
+	// Tomorrow already has one review, and another deck's backlog is not
+	// part of the plan.
+	api.schedule(api.createCard(verbs.ID, service.CardInput{Front: "comer", Back: "to eat"}).ID, now.Add(30*time.Hour))
+	api.schedule(api.createCard(other.ID, service.CardInput{Front: "bonjour", Back: "hello"}).ID, now.AddDate(0, 0, -3))
+
+	opts := service.BacklogOptions{DeckID: spanish.ID, DailyTarget: 2, Priority: service.BacklogPriorityOverdueness}
+	plan := api.backlog("preview", opts)
+	if plan.TotalOverdue != 3 || len(plan.Days) != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if plan.Days[0].Count != 2 || plan.Days[1].Count != 1 || plan.Days[1].Scheduled != 1 {
+		t.Fatalf("days = %+v", plan.Days)
+	}
+	if plan.Assignments[2].ScheduleID != overdue[2].ID || plan.Assignments[2].Day != 1 {
+		t.Fatalf("assignments = %+v", plan.Assignments)
+	}
+	if stored, _ := api.repos.Schedules.GetByID(context.Background(), overdue[2].ID); !stored.DueAt.Equal(overdue[2].DueAt) {
+		t.Fatalf("preview moved schedule to %v", stored.DueAt)
+	}
+
+	applied := api.backlog("apply", opts)
+	if len(applied.Assignments) != 3 {
+		t.Fatalf("applied = %+v", applied)
+	}
+	for i, schedule := range overdue {
+		stored, _ := api.repos.Schedules.GetByID(context.Background(), schedule.ID)
+		if want := applied.Assignments[i].NewDueAt; !stored.DueAt.Equal(want) {
+			t.Errorf("schedule %d: due %v, want %v", schedule.ID, stored.DueAt, want)
+		}
+	}
+	if stored, _ := api.repos.Schedules.GetByID(context.Background(), overdue[2].ID); !stored.DueAt.After(now) {
+		t.Errorf("deferred schedule still due %v", stored.DueAt)
+	}
+
+	// Once applied, only the cards kept for today are overdue.
+	if again := api.backlog("preview", opts); again.TotalOverdue != 2 {
+		t.Fatalf("preview after apply = %+v", again)
+	}
+}
+
+func TestBacklogAPI_Errors(t *testing.T) {
+	api := newETagAPI(t)
+	if response := api.do(http.MethodPost, "/api/v1/backlog/preview", "", service.BacklogOptions{Priority: "random"}); response.Code != http.StatusBadRequest {
+		t.Errorf("unknown priority: status %d", response.Code)
+	}
+	if response := api.do(http.MethodPost, "/api/v1/backlog/apply", "", service.BacklogOptions{DeckID: 999}); response.Code != http.StatusNotFound {
+		t.Errorf("missing deck: status %d", response.Code)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/backlog/preview", strings.NewReader("{}"))
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("without token: status %d", recorder.Code)
+	}
+}
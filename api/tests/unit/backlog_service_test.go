@@ -0,0 +1,270 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+)
+
+type backlogScheduleRepo struct {
+	overdue   []*model.CardSchedule
+	scheduled map[int]int
+	moved     []model.DueDateMove
+}
+
+func (r *backlogScheduleRepo) Create(ctx context.Context, schedule *model.CardSchedule) error {
+	return nil
+}
+func (r *backlogScheduleRepo) GetByID(ctx context.Context, id int64) (*model.CardSchedule, error) {
+	return nil, model.ErrNotFound
+}
+func (r *backlogScheduleRepo) GetByCardAndUser(ctx context.Context, cardID, userID int64) (*model.CardSchedule, error) {
+	return nil, model.ErrNotFound
+}
+func (r *backlogScheduleRepo) GetByCards(ctx context.Context, userID int64, cardIDs []int64) ([]*model.CardSchedule, error) {
+	return nil, nil
+}
+func (r *backlogScheduleRepo) GetDueCards(ctx context.Context, userID int64, deckID int64, dueBy time.Time, limit int) ([]*model.CardSchedule, error) {
+	return nil, nil
+}
+func (r *backlogScheduleRepo) GetNewCards(ctx context.Context, userID int64, deckID int64, limit int) ([]*model.CardSchedule, error) {
+	return nil, nil
+}
+func (r *backlogScheduleRepo) GetOverdue(ctx context.Context, userID int64, deckID int64, dueBefore time.Time) ([]*model.CardSchedule, error) {
+	return r.overdue, nil
+}
+func (r *backlogScheduleRepo) CountDueByDay(ctx context.Context, userID int64, deckID int64, from time.Time) (map[int]int, error) {
+	return r.scheduled, nil
+}
+func (r *backlogScheduleRepo) UpdateDueDates(ctx context.Context, userID int64, moves []model.DueDateMove) error {
+	r.moved = moves
+	return nil
+}
+func (r *backlogScheduleRepo) Update(ctx context.Context, schedule *model.CardSchedule) error {
+	return nil
+}
+func (r *backlogScheduleRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+type backlogUserRepo struct {
+	user *model.User
+}
+
+func (r *backlogUserRepo) Create(ctx context.Context, user *model.User) error {
+	return nil
+}
+func (r *backlogUserRepo) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if r.user == nil {
+		return nil, model.ErrNotFound
+	}
+	return r.user, nil
+}
+func (r *backlogUserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return nil, model.ErrNotFound
+}
+func (r *backlogUserRepo) Update(ctx context.Context, user *model.User) error {
+	return nil
+}
+func (r *backlogUserRepo) Delete(ctx context.Context, id int64) error {
+	return nil
+}
+
+var backlogNow = time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC)
+
+func overdueSchedule(id int64, interval int, daysOverdue int) *model.CardSchedule {
+	due := backlogNow.AddDate(0, 0, -daysOverdue)
+	lastReviewed := due.AddDate(0, 0, -interval)
+	return &model.CardSchedule{
+		ID:             id,
+		CardID:         id * 10,
+		UserID:         1,
+		State:          model.ScheduleStateReview,
+		DueAt:          due,
+		Interval:       interval,
+		LastReviewedAt: &lastReviewed,
+	}
+}
+
+func newBacklogService(schedules *backlogScheduleRepo, user *model.User) *service.BacklogService {
+	return service.NewBacklogService(nil, schedules, &backlogUserRepo{user: user}, directUnitOfWork{repos: repository.Repositories{Schedules: schedules}}).
+		WithClock(func() time.Time { return backlogNow })
+}
+
+func TestBacklogService_Preview_SpreadsAcrossDays(t *testing.T) {
+	schedules := &backlogScheduleRepo{}
+	for i := int64(1); i <= 5; i++ {
+		schedules.overdue = append(schedules.overdue, overdueSchedule(i, 10, 14))
+	}
+
+	plan, err := newBacklogService(schedules, nil).Preview(context.Background(), 1, service.BacklogOptions{DailyTarget: 2})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	if plan.TotalOverdue != 5 {
+		t.Errorf("expected 5 overdue cards, got %d", plan.TotalOverdue)
+	}
+
+	wantCounts := []int{2, 2, 1}
+	if len(plan.Days) != len(wantCounts) {
+		t.Fatalf("expected %d days, got %d", len(wantCounts), len(plan.Days))
+	}
+	for i, want := range wantCounts {
+		if plan.Days[i].Count != want {
+			t.Errorf("day %d: expected %d cards, got %d", i, want, plan.Days[i].Count)
+		}
+	}
+
+	for _, assignment := range plan.Assignments {
+		if assignment.Day == 0 && !assignment.NewDueAt.Equal(assignment.PreviousDueAt) {
+			t.Errorf("schedule %d: cards kept for today must keep their due date", assignment.ScheduleID)
+		}
+		if assignment.Day > 0 && !assignment.NewDueAt.Equal(backlogNow.AddDate(0, 0, assignment.Day)) {
+			t.Errorf("schedule %d: expected due date on day %d, got %v", assignment.ScheduleID, assignment.Day, assignment.NewDueAt)
+		}
+	}
+
+	if schedules.moved != nil {
+		t.Error("Preview() must not update schedules")
+	}
+}
+
+func TestBacklogService_Preview_RetrievabilityPriority(t *testing.T) {
+	schedules := &backlogScheduleRepo{overdue: []*model.CardSchedule{
+		overdueSchedule(1, 60, 14),
+		overdueSchedule(2, 2, 14),
+		overdueSchedule(3, 20, 14),
+	}}
+
+	plan, err := newBacklogService(schedules, nil).Preview(context.Background(), 1, service.BacklogOptions{
+		DailyTarget: 1,
+		Priority:    service.BacklogPriorityRetrievability,
+	})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	wantOrder := []int64{2, 3, 1}
+	for i, want := range wantOrder {
+		if plan.Assignments[i].ScheduleID != want {
+			t.Errorf("position %d: expected schedule %d, got %d", i, want, plan.Assignments[i].ScheduleID)
+		}
+		if plan.Assignments[i].Day != i {
+			t.Errorf("position %d: expected day %d, got %d", i, i, plan.Assignments[i].Day)
+		}
+	}
+}
+
+func TestBacklogService_Preview_OverduenessPriority(t *testing.T) {
+	schedules := &backlogScheduleRepo{overdue: []*model.CardSchedule{
+		overdueSchedule(1, 5, 3),
+		overdueSchedule(2, 5, 10),
+		overdueSchedule(3, 5, 7),
+	}}
+
+	plan, err := newBacklogService(schedules, nil).Preview(context.Background(), 1, service.BacklogOptions{
+		DailyTarget: 5,
+		Priority:    service.BacklogPriorityOverdueness,
+	})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	wantOrder := []int64{2, 3, 1}
+	for i, want := range wantOrder {
+		if plan.Assignments[i].ScheduleID != want {
+			t.Errorf("position %d: expected schedule %d, got %d", i, want, plan.Assignments[i].ScheduleID)
+		}
+	}
+}
+
+func TestBacklogService_Preview_DefaultsToUserDailyReviews(t *testing.T) {
+	schedules := &backlogScheduleRepo{overdue: []*model.CardSchedule{
+		overdueSchedule(1, 5, 3),
+		overdueSchedule(2, 5, 3),
+		overdueSchedule(3, 5, 3),
+	}}
+
+	plan, err := newBacklogService(schedules, &model.User{ID: 1, DailyReviews: 1}).
+		Preview(context.Background(), 1, service.BacklogOptions{})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	if plan.DailyTarget != 1 {
+		t.Errorf("expected daily target 1, got %d", plan.DailyTarget)
+	}
+	if len(plan.Days) != 3 {
+		t.Errorf("expected 3 days, got %d", len(plan.Days))
+	}
+}
+
+func TestBacklogService_Preview_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts service.BacklogOptions
+	}{
+		{name: "negative target", opts: service.BacklogOptions{DailyTarget: -1}},
+		{name: "unknown priority", opts: service.BacklogOptions{DailyTarget: 10, Priority: "random"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newBacklogService(&backlogScheduleRepo{}, nil).Preview(context.Background(), 1, tt.opts)
+			if !errors.Is(err, model.ErrInvalidInput) {
+				t.Errorf("Preview() error = %v, want ErrInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestBacklogService_Preview_CountsReviewsAlreadyDue(t *testing.T) {
+	schedules := &backlogScheduleRepo{scheduled: map[int]int{0: 1, 1: 2, 2: 3, 4: 1}}
+	for i := int64(1); i <= 4; i++ {
+		schedules.overdue = append(schedules.overdue, overdueSchedule(i, 10, 14))
+	}
+
+	plan, err := newBacklogService(schedules, nil).Preview(context.Background(), 1, service.BacklogOptions{DailyTarget: 2})
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+
+	// Days 1 and 2 are already full, so the cards fill days 0, 3 and 4.
+	wantCounts := []int{1, 0, 0, 2, 1}
+	wantScheduled := []int{1, 2, 3, 0, 1}
+	if len(plan.Days) != len(wantCounts) {
+		t.Fatalf("expected %d days, got %+v", len(wantCounts), plan.Days)
+	}
+	for i, day := range plan.Days {
+		if day.Day != i || day.Count != wantCounts[i] || day.Scheduled != wantScheduled[i] {
+			t.Errorf("day %d = %+v, want count %d and scheduled %d", i, day, wantCounts[i], wantScheduled[i])
+		}
+	}
+}
+
+func TestBacklogService_Apply_MovesOnlyDeferredCards(t *testing.T) {
+	schedules := &backlogScheduleRepo{overdue: []*model.CardSchedule{
+		overdueSchedule(1, 5, 3),
+		overdueSchedule(2, 5, 3),
+		overdueSchedule(3, 5, 3),
+	}}
+
+	_, err := newBacklogService(schedules, nil).Apply(context.Background(), 1, service.BacklogOptions{DailyTarget: 2})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	if len(schedules.moved) != 1 {
+		t.Fatalf("expected 1 rescheduled card, got %d", len(schedules.moved))
+	}
+	move := schedules.moved[0]
+	if move.ScheduleID != 3 || !move.From.Equal(backlogNow.AddDate(0, 0, -3)) || !move.To.Equal(backlogNow.AddDate(0, 0, 1)) {
+		t.Errorf("expected schedule 3 to move from its due date to tomorrow, got %+v", move)
+	}
+}
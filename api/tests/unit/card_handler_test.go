@@ -0,0 +1,250 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type cardStore struct {
+	cards  map[int64]*model.Card
+	nextID int64
+}
+
+func (s *cardStore) Create(ctx context.Context, card *model.Card) error {
+	s.nextID++
+	card.ID = s.nextID
+	card.CreatedAt = time.Now()
+	card.UpdatedAt = card.CreatedAt
+	stored := *card
+	s.cards[card.ID] = &stored
+	return nil
+}
+func (s *cardStore) GetByID(ctx context.Context, id int64) (*model.Card, error) {
+	card, ok := s.cards[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	copied := *card
+	return &copied, nil
+}
+func (s *cardStore) GetByDeckID(ctx context.Context, deckID int64, page model.PageRequest) (model.Page[*model.Card], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Card]{}, err
+	}
+
+	var cards []*model.Card
+	for _, card := range s.cards {
+		if card.DeckID != deckID {
+			continue
+		}
+		if cursor != nil && (card.Position < cursor.Position || card.Position == cursor.Position && card.ID <= cursor.ID) {
+			continue
+		}
+		copied := *card
+		cards = append(cards, &copied)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Position != cards[j].Position {
+			return cards[i].Position < cards[j].Position
+		}
+		return cards[i].ID < cards[j].ID
+	})
+	if page.Limit > 0 && len(cards) > page.Limit+1 {
+		cards = cards[:page.Limit+1]
+	}
+	return model.NewPage(cards, page.Limit, model.CardCursor), nil
+}
+func (s *cardStore) Update(ctx context.Context, card *model.Card) error {
+	if _, ok := s.cards[card.ID]; !ok {
+		return model.ErrNotFound
+	}
+	stored := *card
+	s.cards[card.ID] = &stored
+	return nil
+}
+func (s *cardStore) Delete(ctx context.Context, id int64) error {
+	if _, ok := s.cards[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(s.cards, id)
+	return nil
+}
+func (s *cardStore) GetDeleted(ctx context.Context, id int64) (*model.Card, error) {
+	return nil, model.ErrNotFound
+}
+func (s *cardStore) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Card], error) {
+	return model.NewPage([]*model.Card(nil), 0, model.DeletedCardCursor), nil
+}
+func (s *cardStore) Restore(ctx context.Context, id int64) error {
+	return model.ErrNotFound
+}
+func (s *cardStore) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newCardAPI(t *testing.T) (*deckAPI, *cardStore) {
+	t.Helper()
+	api := newDeckAPI(t)
+	cards := &cardStore{cards: map[int64]*model.Card{}}
+	authorizer := service.NewAuthorizer(api.store, cards, &authzScheduleRepo{}, &authzReviewLogRepo{})
+
+	tokens := newTestTokenManager(t)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens: tokens,
+		Decks:  service.NewDeckService(authorizer, api.store, directUnitOfWork{repos: repository.Repositories{Decks: api.store}}),
+		Cards:  service.NewCardService(authorizer, cards, nil),
+	})
+	api.server = mux
+	for userID := range api.tokens {
+		api.tokens[userID], _, _ = tokens.IssueAccessToken(userID)
+	}
+	return api, cards
+}
+
+func (api *deckAPI) createCard(userID, deckID int64, input service.CardInput) *model.Card {
+	api.t.Helper()
+	recorder := api.do(userID, http.MethodPost, "/api/v1/decks/"+itoa(deckID)+"/cards", input)
+	if recorder.Code != http.StatusCreated {
+		api.t.Fatalf("create card: expected status %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body)
+	}
+	card := &model.Card{}
+	_ = json.NewDecoder(recorder.Body).Decode(card)
+	return card
+}
+
+func TestCardAPI_CreateAndList(t *testing.T) {
+	api, _ := newCardAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+
+	card := api.createCard(ownerID, deck.ID, service.CardInput{Front: "hola", Back: "hello", Tags: []string{" verbs ", "verbs", ""}})
+	if card.Type != model.CardTypeBasic {
+		t.Errorf("expected default type basic, got %q", card.Type)
+	}
+	if !reflect.DeepEqual(card.Tags, []string{"verbs"}) {
+		t.Errorf("expected normalized tags, got %v", card.Tags)
+	}
+
+	recorder := api.do(ownerID, http.MethodGet, "/api/v1/decks/"+itoa(deck.ID)+"/cards", nil)
+	var cards handler.PageResponse[*model.Card]
+	_ = json.NewDecoder(recorder.Body).Decode(&cards)
+	if recorder.Code != http.StatusOK || len(cards.Items) != 1 || cards.NextCursor != "" {
+		t.Errorf("expected 1 card on a single page, got status %d and %+v", recorder.Code, cards)
+	}
+}
+
+func TestCardAPI_CreateValidation(t *testing.T) {
+	api, _ := newCardAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	path := "/api/v1/decks/" + itoa(deck.ID) + "/cards"
+
+	if recorder := api.do(ownerID, http.MethodPost, path, service.CardInput{Back: "missing front"}); recorder.Code != http.StatusBadRequest {
+		t.Errorf("missing front: expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if recorder := api.do(ownerID, http.MethodPost, path, service.CardInput{Front: "x", Type: "flashy"}); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid type: expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+}
+
+func TestCardAPI_UpdateMovesBetweenOwnDecksOnly(t *testing.T) {
+	api, cards := newCardAPI(t)
+	source := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	target := api.createDeck(ownerID, service.DeckInput{Name: "French"})
+	foreign := api.createDeck(intruderID, service.DeckInput{Name: "Foreign"})
+	card := api.createCard(ownerID, source.ID, service.CardInput{Front: "hola"})
+	path := "/api/v1/cards/" + itoa(card.ID)
+
+	recorder := api.do(ownerID, http.MethodPut, path, service.CardInput{DeckID: &foreign.ID, Front: "hola"})
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("move to foreign deck: expected status %d, got %d", http.StatusForbidden, recorder.Code)
+	}
+
+	recorder = api.do(ownerID, http.MethodPut, path, service.CardInput{DeckID: &target.ID, Front: "bonjour", Suspended: true})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("update: expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if stored := cards.cards[card.ID]; stored.DeckID != target.ID || stored.Front != "bonjour" || !stored.Suspended {
+		t.Errorf("expected card to be moved and updated, got %+v", stored)
+	}
+}
+
+func TestCardAPI_CrossUserAccessDenied(t *testing.T) {
+	api, _ := newCardAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	card := api.createCard(ownerID, deck.ID, service.CardInput{Front: "hola"})
+	cardPath := "/api/v1/cards/" + itoa(card.ID)
+	deckPath := "/api/v1/decks/" + itoa(deck.ID) + "/cards"
+
+	requests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodGet, cardPath, nil},
+		{http.MethodPut, cardPath, service.CardInput{Front: "stolen"}},
+		{http.MethodDelete, cardPath, nil},
+		{http.MethodGet, deckPath, nil},
+		{http.MethodPost, deckPath, service.CardInput{Front: "injected"}},
+	}
+
+	for _, req := range requests {
+		recorder := api.do(intruderID, req.method, req.path, req.body)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected status %d, got %d", req.method, req.path, http.StatusForbidden, recorder.Code)
+		}
+	}
+}
+
+func TestCardAPI_BulkValidation(t *testing.T) {
+	api, _ := newCardAPI(t)
+
+	tests := []struct {
+		name    string
+		request service.BulkCardRequest
+	}{
+		{name: "no cards", request: service.BulkCardRequest{Operation: service.BulkDelete}},
+		{name: "unknown operation", request: service.BulkCardRequest{Operation: "archive", CardIDs: []int64{1}}},
+		{name: "duplicate ids", request: service.BulkCardRequest{Operation: service.BulkDelete, CardIDs: []int64{1, 1}}},
+		{name: "tags missing", request: service.BulkCardRequest{Operation: service.BulkAddTags, CardIDs: []int64{1}, Tags: []string{" "}}},
+		{name: "move without deck", request: service.BulkCardRequest{Operation: service.BulkMove, CardIDs: []int64{1}}},
+		{name: "invalid type", request: service.BulkCardRequest{Operation: service.BulkChangeType, CardIDs: []int64{1}, Type: "flashy"}},
+		{name: "too many cards", request: service.BulkCardRequest{Operation: service.BulkSuspend, CardIDs: make([]int64, service.MaxBulkCards+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := api.do(ownerID, http.MethodPost, "/api/v1/cards/bulk", tt.request)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestCard_AddAndRemoveTags(t *testing.T) {
+	card := &model.Card{Tags: []string{"verbs", "spanish"}}
+
+	card.AddTags("spanish", " irregular ", "")
+	if want := []string{"verbs", "spanish", "irregular"}; !reflect.DeepEqual(card.Tags, want) {
+		t.Errorf("AddTags() = %v, want %v", card.Tags, want)
+	}
+
+	card.RemoveTags("verbs", "unknown")
+	if want := []string{"spanish", "irregular"}; !reflect.DeepEqual(card.Tags, want) {
+		t.Errorf("RemoveTags() = %v, want %v", card.Tags, want)
+	}
+}
@@ -137,6 +137,32 @@ func TestInvalidPortFallsBackToDefault(t *testing.T) {
 	}
 }
 
+func TestLoadMiddlewareSettings(t *testing.T) {
+	clearEnvVars()
+	os.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://admin.example.com,")
+	os.Setenv("ACCESS_LOG_ENABLED", "false")
+	os.Setenv("HSTS_MAX_AGE_SECONDS", "31536000")
+	defer clearEnvVars()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfg.CORSAllowedOrigins) != 2 || cfg.CORSAllowedOrigins[1] != "https://admin.example.com" {
+		t.Errorf("unexpected CORS origins %v", cfg.CORSAllowedOrigins)
+	}
+	if cfg.AccessLogEnabled {
+		t.Error("expected access log to be disabled")
+	}
+	if !cfg.SecurityHeadersEnabled {
+		t.Error("expected security headers to be enabled by default")
+	}
+	if cfg.HSTSMaxAgeSeconds != 31536000 {
+		t.Errorf("expected HSTS max age 31536000, got %d", cfg.HSTSMaxAgeSeconds)
+	}
+}
+
 func clearEnvVars() {
 	os.Unsetenv("PORT")
 	os.Unsetenv("ENVIRONMENT")
@@ -147,6 +173,25 @@ func clearEnvVars() {
 	os.Unsetenv("POSTGRES_PASSWORD")
 	os.Unsetenv("POSTGRES_DB")
 	os.Unsetenv("POSTGRES_SSLMODE")
+	os.Unsetenv("DATABASE_MAX_OPEN_CONNS")
+	os.Unsetenv("DATABASE_MAX_IDLE_CONNS")
+	os.Unsetenv("DATABASE_CONN_MAX_LIFETIME_MINUTES")
+	os.Unsetenv("DATABASE_CONN_MAX_IDLE_TIME_MINUTES")
+	os.Unsetenv("DATABASE_STATEMENT_TIMEOUT_MS")
+	os.Unsetenv("DATABASE_CONNECT_ATTEMPTS")
 	os.Unsetenv("JWT_SECRET")
 	os.Unsetenv("JWT_EXPIRATION_HOURS")
+	os.Unsetenv("JWT_REFRESH_EXPIRATION_HOURS")
+	os.Unsetenv("ACCESS_LOG_ENABLED")
+	os.Unsetenv("CORS_ALLOWED_ORIGINS")
+	os.Unsetenv("CORS_MAX_AGE_SECONDS")
+	os.Unsetenv("SECURITY_HEADERS_ENABLED")
+	os.Unsetenv("HSTS_MAX_AGE_SECONDS")
+	os.Unsetenv("RATE_LIMIT_ENABLED")
+	os.Unsetenv("RATE_LIMIT_AUTH_PER_MINUTE")
+	os.Unsetenv("RATE_LIMIT_AUTH_BURST")
+	os.Unsetenv("RATE_LIMIT_API_PER_MINUTE")
+	os.Unsetenv("RATE_LIMIT_API_BURST")
+	os.Unsetenv("TRUST_PROXY_HEADERS")
+	os.Unsetenv("HEALTH_CHECK_TIMEOUT_SECONDS")
 }
@@ -0,0 +1,200 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+// doRaw sends a body that is not JSON.
+func (api *etagAPI) doRaw(method, path, contentType, body string) *httptest.ResponseRecorder {
+	api.t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+api.token)
+	request.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (api *etagAPI) importCSV(deckID int64, query, data string) *httptest.ResponseRecorder {
+	api.t.Helper()
+	return api.doRaw(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deckID, 10)+"/import/csv"+query, "text/csv", data)
+}
+
+func (api *etagAPI) importCSVResult(deckID int64, query, data string) *model.CSVImportResult {
+	api.t.Helper()
+	response := api.importCSV(deckID, query, data)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("import csv: status %d: %s", response.Code, response.Body)
+	}
+	var result model.CSVImportResult
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	return &result
+}
+
+func (api *etagAPI) deckCards(deckID int64) []*model.Card {
+	api.t.Helper()
+	page, err := api.repos.Cards.GetByDeckID(context.Background(), deckID, model.PageRequest{Limit: 100})
+	if err != nil {
+		api.t.Fatal(err)
+	}
+	return page.Items
+}
+
+func TestCSVImportAPI_DetectsDelimiterAndHeader(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+
+	data := "\ufeffFront\tBack\tTags\n" +
+		"hola\thello\tgreetings, basics\n" +
+		"\"adiós\nchao\"\t\"goodbye, \"\"bye\"\"\"\tgreetings\n" +
+		"\t\t\n" +
+		"gato\tcat\t\n"
+	result := api.importCSVResult(deck.ID, "", data)
+	if result.Delimiter != "\t" || !reflect.DeepEqual(result.Columns, []string{"front", "back", "tags"}) {
+		t.Fatalf("read as delimiter %q columns %v", result.Delimiter, result.Columns)
+	}
+	if result.Created != 3 || result.Failed != 0 || len(result.Rows) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	cards := api.deckCards(deck.ID)
+	if len(cards) != 3 {
+		t.Fatalf("got %d cards", len(cards))
+	}
+	if cards[1].Front != "adiós\nchao" || cards[1].Back != `goodbye, "bye"` {
+		t.Fatalf("multiline card = %q / %q", cards[1].Front, cards[1].Back)
+	}
+	if !reflect.DeepEqual(cards[0].Tags, []string{"greetings", "basics"}) || len(cards[2].Tags) != 0 {
+		t.Fatalf("tags = %v, %v", cards[0].Tags, cards[2].Tags)
+	}
+	for i, card := range cards {
+		if card.Position != i || card.Type != model.CardTypeBasic {
+			t.Fatalf("card %d at position %d of type %s", i, card.Position, card.Type)
+		}
+	}
+
+	headless := api.createDeck("French", nil)
+	result = api.importCSVResult(headless.ID, "", "chat;cat\nchien;dog\n")
+	if result.Delimiter != ";" || result.Header != nil || result.Created != 2 {
+		t.Fatalf("headless result = %+v", result)
+	}
+}
+
+func TestCSVImportAPI_ColumnsAndRowErrors(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+
+	data := "1,hola,hello,basic\n" +
+		"2,,missing front,basic\n" +
+		"3,{{c1::ser}},,cloze\n" +
+		"4,perro,dog,picture\n" +
+		"5,\"unterminated,x\n"
+	result := api.importCSVResult(deck.ID, "?columns=skip,front,back,type&header=false", data)
+	if result.Created != 2 || result.Failed != 3 {
+		t.Fatalf("result = %+v", result)
+	}
+	var failed []string
+	for _, row := range result.Rows {
+		if row.Status != model.CSVRowFailed || len(row.Errors) == 0 {
+			t.Fatalf("row = %+v", row)
+		}
+		failed = append(failed, strconv.Itoa(row.Line)+":"+row.Errors[0].Field)
+	}
+	if !reflect.DeepEqual(failed, []string{"2:front", "4:type", "5:row"}) {
+		t.Fatalf("failed rows = %v", failed)
+	}
+	if cards := api.deckCards(deck.ID); len(cards) != 2 || cards[1].Type != model.CardTypeCloze {
+		t.Fatalf("cards = %+v", cards)
+	}
+
+	for name, query := range map[string]string{
+		"no front":        "?columns=back,extra",
+		"twice":           "?columns=front,front",
+		"unknown":         "?columns=front,notes",
+		"unmapped key":    "?key=extra",
+		"tags key":        "?columns=front,tags&key=tags",
+		"delimiter":       "?delimiter=ab",
+		"header":          "?header=maybe",
+		"preview":         "?preview=0",
+		"preview too big": "?preview=101",
+	} {
+		if response := api.importCSV(deck.ID, query, "a,b\n"); response.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d: %s", name, response.Code, response.Body)
+		}
+	}
+	if response := api.importCSV(deck.ID, "", ""); response.Code != http.StatusBadRequest {
+		t.Errorf("empty body: status %d", response.Code)
+	}
+	if response := api.importCSV(9999, "", "a,b\n"); response.Code != http.StatusNotFound {
+		t.Errorf("missing deck: status %d", response.Code)
+	}
+}
+
+func TestCSVImportAPI_UpdatesByKey(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+	hola := api.createCard(deck.ID, service.CardInput{Front: "hola", Back: "hi", Extra: "informal", Tags: []string{"greetings"}, Suspended: true})
+	gato := api.createCard(deck.ID, service.CardInput{Front: "gato", Back: "cat", Position: 1})
+
+	data := "front,back\n" +
+		"hola,hello\n" +
+		" gato ,cat\n" +
+		"perro,dog\n" +
+		"perro,hound\n"
+	result := api.importCSVResult(deck.ID, "?key=front", data)
+	if result.Created != 1 || result.Updated != 2 || result.Unchanged != 1 || result.Failed != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	cards := api.deckCards(deck.ID)
+	if len(cards) != 3 {
+		t.Fatalf("got %d cards", len(cards))
+	}
+	updated := cards[0]
+	if updated.ID != hola.ID || updated.Back != "hello" || updated.Extra != "informal" || !updated.Suspended || !reflect.DeepEqual(updated.Tags, []string{"greetings"}) {
+		t.Fatalf("updated card = %+v", updated)
+	}
+	if cards[1].ID != gato.ID || cards[1].Version != gato.Version {
+		t.Fatalf("unchanged card = %+v", cards[1])
+	}
+	if cards[2].Front != "perro" || cards[2].Back != "hound" || cards[2].Position != 2 {
+		t.Fatalf("created card = %+v", cards[2])
+	}
+}
+
+func TestCSVImportAPI_Preview(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+	api.createCard(deck.ID, service.CardInput{Front: "hola", Back: "hi"})
+
+	data := "front|back|extra\nhola|hello|\nperro|dog|noun\n|nothing|\ngato|cat|\n"
+	result := api.importCSVResult(deck.ID, "?preview=3&key=front", data)
+	if !result.Preview || result.Delimiter != "|" || result.Updated != 1 || result.Created != 1 || result.Failed != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+	var rows []string
+	for _, row := range result.Rows {
+		rows = append(rows, string(row.Status)+":"+row.Front+":"+row.Back)
+	}
+	if !reflect.DeepEqual(rows, []string{"updated:hola:hello", "created:perro:dog", "failed::"}) {
+		t.Fatalf("rows = %v", rows)
+	}
+	if result.Rows[1].CardID != 0 || result.Rows[1].Extra != "noun" {
+		t.Fatalf("preview row = %+v", result.Rows[1])
+	}
+
+	cards := api.deckCards(deck.ID)
+	if len(cards) != 1 || cards[0].Back != "hi" {
+		t.Fatalf("preview changed the deck: %+v", cards)
+	}
+}
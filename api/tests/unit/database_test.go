@@ -0,0 +1,97 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/config"
+	"memwright/api/internal/database"
+	"memwright/api/pkg/logger"
+)
+
+type flakyPinger struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyPinger) PingContext(ctx context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func retryOptions(attempts int) database.Options {
+	return database.Options{
+		ConnectAttempts: attempts,
+		InitialBackoff:  time.Millisecond,
+		MaxBackoff:      2 * time.Millisecond,
+	}
+}
+
+func TestWaitForConnection_RetriesUntilReady(t *testing.T) {
+	var logs bytes.Buffer
+	pinger := &flakyPinger{failures: 2}
+
+	err := database.WaitForConnection(context.Background(), pinger, retryOptions(5), logger.New(&logs, logger.LevelDebug))
+	if err != nil {
+		t.Fatalf("WaitForConnection() error = %v", err)
+	}
+	if pinger.calls != 3 {
+		t.Errorf("expected 3 pings, got %d", pinger.calls)
+	}
+	if strings.Count(logs.String(), "database not ready") != 2 {
+		t.Errorf("expected a warning per failed attempt, got %q", logs.String())
+	}
+}
+
+func TestWaitForConnection_GivesUp(t *testing.T) {
+	pinger := &flakyPinger{failures: 10}
+
+	err := database.WaitForConnection(context.Background(), pinger, retryOptions(3), logger.New(&bytes.Buffer{}, logger.LevelDebug))
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("expected an error after 3 attempts, got %v", err)
+	}
+	if pinger.calls != 3 {
+		t.Errorf("expected 3 pings, got %d", pinger.calls)
+	}
+}
+
+func TestWaitForConnection_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	options := retryOptions(5)
+	options.InitialBackoff = time.Hour
+
+	err := database.WaitForConnection(ctx, &flakyPinger{failures: 10}, options, logger.New(&bytes.Buffer{}, logger.LevelDebug))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDatabaseOptions_FromConfig(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	options := database.OptionsFromConfig(cfg)
+	if options.MaxOpenConns != 25 || options.MaxIdleConns != 5 || options.ConnMaxLifetime != 30*time.Minute {
+		t.Errorf("unexpected pool options %+v", options)
+	}
+
+	dsn, err := options.DSN()
+	if err != nil {
+		t.Fatalf("DSN() error = %v", err)
+	}
+	if !strings.Contains(dsn, "statement_timeout=30000") || !strings.Contains(dsn, "sslmode=disable") {
+		t.Errorf("unexpected DSN %q", dsn)
+	}
+}
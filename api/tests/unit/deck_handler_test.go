@@ -0,0 +1,370 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type deckStore struct {
+	decks  map[int64]*model.Deck
+	counts map[int64]model.DeckCounts
+	nextID int64
+}
+
+func newDeckStore() *deckStore {
+	return &deckStore{decks: map[int64]*model.Deck{}, counts: map[int64]model.DeckCounts{}}
+}
+
+func (s *deckStore) duplicate(deck *model.Deck) bool {
+	for _, existing := range s.decks {
+		sameParent := (existing.ParentID == nil && deck.ParentID == nil) ||
+			(existing.ParentID != nil && deck.ParentID != nil && *existing.ParentID == *deck.ParentID)
+		if existing.ID != deck.ID && existing.UserID == deck.UserID && sameParent && existing.Name == deck.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *deckStore) Create(ctx context.Context, deck *model.Deck) error {
+	if s.duplicate(deck) {
+		return model.ErrDuplicateName
+	}
+	s.nextID++
+	deck.ID = s.nextID
+	deck.CreatedAt = time.Now()
+	deck.UpdatedAt = deck.CreatedAt
+	stored := *deck
+	s.decks[deck.ID] = &stored
+	return nil
+}
+func (s *deckStore) GetByID(ctx context.Context, id int64) (*model.Deck, error) {
+	deck, ok := s.decks[id]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+	copied := *deck
+	return &copied, nil
+}
+func (s *deckStore) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.Deck], error) {
+	cursor, err := model.DecodeCursor(page.Cursor)
+	if err != nil {
+		return model.Page[*model.Deck]{}, err
+	}
+	roots, _ := s.Children(ctx, userID, nil)
+	var decks []*model.Deck
+	for _, deck := range roots {
+		if cursor == nil || deck.Position > cursor.Position || (deck.Position == cursor.Position && deck.ID > cursor.ID) {
+			decks = append(decks, deck)
+		}
+	}
+	if page.Limit > 0 && len(decks) > page.Limit+1 {
+		decks = decks[:page.Limit+1]
+	}
+	return model.NewPage(decks, page.Limit, model.DeckCursor), nil
+}
+func (s *deckStore) Update(ctx context.Context, deck *model.Deck) error {
+	if _, ok := s.decks[deck.ID]; !ok {
+		return model.ErrNotFound
+	}
+	if s.duplicate(deck) {
+		return model.ErrDuplicateName
+	}
+	stored := *deck
+	s.decks[deck.ID] = &stored
+	return nil
+}
+func (s *deckStore) UpdateSRSConfig(ctx context.Context, id int64, config *model.SRSConfig) error {
+	return nil
+}
+func (s *deckStore) Delete(ctx context.Context, id int64) error {
+	if _, ok := s.decks[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(s.decks, id)
+	return nil
+}
+func (s *deckStore) GetCardCounts(ctx context.Context, userID int64, now time.Time) (map[int64]model.DeckCounts, error) {
+	return s.counts, nil
+}
+func (s *deckStore) Children(ctx context.Context, userID int64, parentID *int64) ([]*model.Deck, error) {
+	var children []*model.Deck
+	for _, deck := range s.decks {
+		sameParent := (deck.ParentID == nil && parentID == nil) ||
+			(deck.ParentID != nil && parentID != nil && *deck.ParentID == *parentID)
+		if deck.UserID == userID && sameParent {
+			copied := *deck
+			children = append(children, &copied)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Position != children[j].Position {
+			return children[i].Position < children[j].Position
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children, nil
+}
+func (s *deckStore) Renumber(ctx context.Context, ids []int64) error {
+	for position, id := range ids {
+		if deck, ok := s.decks[id]; ok {
+			deck.Position = position
+		}
+	}
+	return nil
+}
+func (s *deckStore) Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error) {
+	var nodes []*model.DeckNode
+	for _, deck := range s.decks {
+		if deck.UserID == userID && s.inScope(deck, rootIDs) {
+			copied := *deck
+			nodes = append(nodes, &model.DeckNode{Deck: &copied, Counts: s.counts[deck.ID], Totals: s.counts[deck.ID], Children: []*model.DeckNode{}})
+		}
+	}
+	return nodes, nil
+}
+func (s *deckStore) inScope(deck *model.Deck, rootIDs []int64) bool {
+	if rootIDs == nil {
+		return true
+	}
+	for ; deck != nil; deck = s.parent(deck) {
+		for _, id := range rootIDs {
+			if deck.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+func (s *deckStore) parent(deck *model.Deck) *model.Deck {
+	if deck.ParentID == nil {
+		return nil
+	}
+	return s.decks[*deck.ParentID]
+}
+func (s *deckStore) GetDeleted(ctx context.Context, id int64) (*model.Deck, error) {
+	return nil, model.ErrNotFound
+}
+func (s *deckStore) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Deck], error) {
+	return model.NewPage([]*model.Deck(nil), 0, model.DeletedDeckCursor), nil
+}
+func (s *deckStore) Restore(ctx context.Context, id int64) error {
+	return model.ErrNotFound
+}
+func (s *deckStore) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
+
+type deckAPI struct {
+	t      *testing.T
+	server http.Handler
+	store  *deckStore
+	tokens map[int64]string
+}
+
+func newDeckAPI(t *testing.T) *deckAPI {
+	t.Helper()
+	tokens := newTestTokenManager(t)
+	store := newDeckStore()
+	authorizer := service.NewAuthorizer(store, &authzCardRepo{}, &authzScheduleRepo{}, &authzReviewLogRepo{})
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens: tokens,
+		Decks:  service.NewDeckService(authorizer, store, directUnitOfWork{repos: repository.Repositories{Decks: store}}),
+	})
+
+	api := &deckAPI{t: t, server: mux, store: store, tokens: map[int64]string{}}
+	for _, userID := range []int64{ownerID, intruderID} {
+		token, _, _ := tokens.IssueAccessToken(userID)
+		api.tokens[userID] = token
+	}
+	return api
+}
+
+func (api *deckAPI) do(userID int64, method, path string, body interface{}) *httptest.ResponseRecorder {
+	api.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	request := httptest.NewRequest(method, path, &payload)
+	request.Header.Set("Authorization", "Bearer "+api.tokens[userID])
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func (api *deckAPI) createDeck(userID int64, input service.DeckInput) *model.Deck {
+	api.t.Helper()
+	recorder := api.do(userID, http.MethodPost, "/api/v1/decks", input)
+	if recorder.Code != http.StatusCreated {
+		api.t.Fatalf("create deck: expected status %d, got %d: %s", http.StatusCreated, recorder.Code, recorder.Body)
+	}
+	deck := &model.Deck{}
+	_ = json.NewDecoder(recorder.Body).Decode(deck)
+	return deck
+}
+
+func TestDeckAPI_CreateAppliesDefaults(t *testing.T) {
+	api := newDeckAPI(t)
+
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "  Spanish  "})
+
+	if deck.Name != "Spanish" {
+		t.Errorf("expected trimmed name, got %q", deck.Name)
+	}
+	if deck.UserID != ownerID {
+		t.Errorf("expected deck owned by %d, got %d", ownerID, deck.UserID)
+	}
+	if deck.Algorithm != model.AlgorithmSM2 {
+		t.Errorf("expected default algorithm sm2, got %q", deck.Algorithm)
+	}
+	if deck.SRSConfig == nil || deck.SRSConfig.SM2 == nil || deck.SRSConfig.SM2.MasteredThreshold != 21 {
+		t.Errorf("expected default SM-2 config, got %+v", deck.SRSConfig)
+	}
+}
+
+func TestDeckAPI_ListReturnsTreeWithCounts(t *testing.T) {
+	api := newDeckAPI(t)
+
+	parent := api.createDeck(ownerID, service.DeckInput{Name: "Languages"})
+	child := api.createDeck(ownerID, service.DeckInput{Name: "Spanish", ParentID: &parent.ID})
+	api.createDeck(intruderID, service.DeckInput{Name: "Other user's deck"})
+	api.store.counts[child.ID] = model.DeckCounts{New: 3, Learning: 1, Due: 2}
+
+	recorder := api.do(ownerID, http.MethodGet, "/api/v1/decks", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var page handler.PageResponse[*model.DeckNode]
+	if err := json.NewDecoder(recorder.Body).Decode(&page); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	tree := page.Items
+	if len(tree) != 1 || tree[0].Name != "Languages" {
+		t.Fatalf("expected a single root deck 'Languages', got %+v", tree)
+	}
+	if len(tree[0].Children) != 1 || tree[0].Children[0].Counts.Due != 2 || tree[0].Children[0].Counts.New != 3 {
+		t.Errorf("expected subdeck with counts, got %+v", tree[0].Children)
+	}
+
+	recorder = api.do(ownerID, http.MethodGet, "/api/v1/decks/"+itoa(parent.ID)+"/subdecks", nil)
+	var subdecks []*model.DeckNode
+	_ = json.NewDecoder(recorder.Body).Decode(&subdecks)
+	if recorder.Code != http.StatusOK || len(subdecks) != 1 || subdecks[0].ID != child.ID {
+		t.Errorf("expected subdeck %d, got status %d and %+v", child.ID, recorder.Code, subdecks)
+	}
+}
+
+func TestDeckAPI_DuplicateNameConflicts(t *testing.T) {
+	api := newDeckAPI(t)
+	api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+
+	recorder := api.do(ownerID, http.MethodPost, "/api/v1/decks", service.DeckInput{Name: "Spanish"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, recorder.Code)
+	}
+}
+
+func TestDeckAPI_InvalidInput(t *testing.T) {
+	api := newDeckAPI(t)
+	invalidConfig := model.DefaultSRSConfig()
+	invalidConfig.SM2.MinEaseFactor = 3.5
+
+	tests := []struct {
+		name  string
+		input service.DeckInput
+	}{
+		{name: "missing name", input: service.DeckInput{}},
+		{name: "unknown algorithm", input: service.DeckInput{Name: "Deck", Algorithm: "leitner"}},
+		{name: "invalid srs config", input: service.DeckInput{Name: "Deck", SRSConfig: invalidConfig}},
+		{name: "missing sm2 config", input: service.DeckInput{Name: "Deck", SRSConfig: &model.SRSConfig{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := api.do(ownerID, http.MethodPost, "/api/v1/decks", tt.input)
+			if recorder.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestDeckAPI_UpdateAndDelete(t *testing.T) {
+	api := newDeckAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	path := "/api/v1/decks/" + itoa(deck.ID)
+
+	recorder := api.do(ownerID, http.MethodPut, path, service.DeckInput{Name: "Español", Description: "Vocabulary"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("update: expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if stored := api.store.decks[deck.ID]; stored.Name != "Español" || stored.Description != "Vocabulary" {
+		t.Errorf("expected deck to be updated, got %+v", stored)
+	}
+
+	if recorder := api.do(ownerID, http.MethodDelete, path, nil); recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected status %d, got %d", http.StatusNoContent, recorder.Code)
+	}
+	if recorder := api.do(ownerID, http.MethodGet, path, nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("get after delete: expected status %d, got %d", http.StatusNotFound, recorder.Code)
+	}
+}
+
+func TestDeckAPI_CrossUserAccessDenied(t *testing.T) {
+	api := newDeckAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	path := "/api/v1/decks/" + itoa(deck.ID)
+
+	requests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodGet, path, nil},
+		{http.MethodPut, path, service.DeckInput{Name: "Stolen"}},
+		{http.MethodDelete, path, nil},
+		{http.MethodGet, path + "/subdecks", nil},
+		{http.MethodPost, "/api/v1/decks", service.DeckInput{Name: "Child", ParentID: &deck.ID}},
+	}
+
+	for _, req := range requests {
+		recorder := api.do(intruderID, req.method, req.path, req.body)
+		if recorder.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected status %d, got %d", req.method, req.path, http.StatusForbidden, recorder.Code)
+		}
+	}
+}
+
+func TestDeckAPI_RequiresAuthentication(t *testing.T) {
+	api := newDeckAPI(t)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+}
+
+func itoa(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -178,8 +178,8 @@ func (m *deckRepoMock) GetByID(ctx context.Context, id int64) (*model.Deck, erro
 		UpdatedAt: time.Now(),
 	}, nil
 }
-func (m *deckRepoMock) GetByUserID(ctx context.Context, userID int64) ([]*model.Deck, error) {
-	return []*model.Deck{}, nil
+func (m *deckRepoMock) GetByUserID(ctx context.Context, userID int64, page model.PageRequest) (model.Page[*model.Deck], error) {
+	return model.Page[*model.Deck]{Items: []*model.Deck{}}, nil
 }
 func (m *deckRepoMock) Update(ctx context.Context, deck *model.Deck) error {
 	return nil
@@ -190,5 +190,29 @@ func (m *deckRepoMock) UpdateSRSConfig(ctx context.Context, id int64, config *mo
 func (m *deckRepoMock) Delete(ctx context.Context, id int64) error {
 	return nil
 }
+func (m *deckRepoMock) GetCardCounts(ctx context.Context, userID int64, now time.Time) (map[int64]model.DeckCounts, error) {
+	return map[int64]model.DeckCounts{}, nil
+}
+func (m *deckRepoMock) Children(ctx context.Context, userID int64, parentID *int64) ([]*model.Deck, error) {
+	return nil, nil
+}
+func (m *deckRepoMock) Renumber(ctx context.Context, ids []int64) error {
+	return nil
+}
+func (m *deckRepoMock) Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error) {
+	return nil, nil
+}
+func (m *deckRepoMock) GetDeleted(ctx context.Context, id int64) (*model.Deck, error) {
+	return nil, model.ErrNotFound
+}
+func (m *deckRepoMock) Trash(ctx context.Context, userID int64, deletedAfter time.Time, page model.PageRequest) (model.Page[*model.Deck], error) {
+	return model.Page[*model.Deck]{Items: []*model.Deck{}}, nil
+}
+func (m *deckRepoMock) Restore(ctx context.Context, id int64) error {
+	return model.ErrNotFound
+}
+func (m *deckRepoMock) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return 0, nil
+}
 
 var _ driver.Result = (*mockResult)(nil)
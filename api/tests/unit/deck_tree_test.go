@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+func (api *etagAPI) createDeck(name string, parentID *int64) *model.Deck {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/decks", "", service.DeckInput{Name: name, ParentID: parentID})
+	if response.Code != http.StatusCreated {
+		api.t.Fatalf("create %s: status %d: %s", name, response.Code, response.Body)
+	}
+	var deck model.Deck
+	_ = json.NewDecoder(response.Body).Decode(&deck)
+	return &deck
+}
+
+// order lists the names of a deck's children, or of the top-level decks,
+// with their positions.
+func (api *etagAPI) order(parentID *int64) string {
+	api.t.Helper()
+	var nodes []*model.DeckNode
+	if parentID == nil {
+		var page model.Page[*model.DeckNode]
+		_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/decks", "", nil).Body).Decode(&page)
+		nodes = page.Items
+	} else {
+		response := api.do(http.MethodGet, "/api/v1/decks/"+strconv.FormatInt(*parentID, 10)+"/subdecks", "", nil)
+		_ = json.NewDecoder(response.Body).Decode(&nodes)
+	}
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name + ":" + strconv.Itoa(node.Position)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestDeckAPI_CreateAppendsOrInserts(t *testing.T) {
+	api := newETagAPI(t)
+	api.createDeck("A", nil)
+	api.createDeck("B", nil)
+	position := 1
+	if response := api.do(http.MethodPost, "/api/v1/decks", "", service.DeckInput{Name: "C", Position: &position}); response.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", response.Code, response.Body)
+	}
+	if got := api.order(nil); got != "A:0 C:1 B:2" {
+		t.Errorf("order = %s", got)
+	}
+}
+
+func TestDeckAPI_TopLevelNamesAreUnique(t *testing.T) {
+	api := newETagAPI(t)
+	spanish := api.createDeck("Spanish", nil)
+	if response := api.do(http.MethodPost, "/api/v1/decks", "", service.DeckInput{Name: "Spanish"}); response.Code != http.StatusConflict {
+		t.Fatalf("create: status %d: %s", response.Code, response.Body)
+	}
+
+	verbs := api.createDeck("Verbs", &spanish.ID)
+	api.createDeck("Verbs", nil)
+	if response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(verbs.ID, 10)+"/move", "", service.MoveDeckRequest{}); response.Code != http.StatusConflict {
+		t.Fatalf("move: status %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestDeckAPI_Move(t *testing.T) {
+	api := newETagAPI(t)
+	spanish := api.createDeck("Spanish", nil)
+	french := api.createDeck("French", nil)
+	german := api.createDeck("German", nil)
+	verbs := api.createDeck("Verbs", &spanish.ID)
+	api.createDeck("Nouns", &spanish.ID)
+	irregular := api.createDeck("Irregular", &verbs.ID)
+
+	path := "/api/v1/decks/" + strconv.FormatInt(french.ID, 10) + "/move"
+	position := 99
+	moved := api.do(http.MethodPost, path, `"1"`, service.MoveDeckRequest{ParentID: &spanish.ID, Position: &position})
+	if moved.Code != http.StatusOK || moved.Header().Get("ETag") != `"2"` {
+		t.Fatalf("move: status %d, ETag %q: %s", moved.Code, moved.Header().Get("ETag"), moved.Body)
+	}
+	if got := api.order(nil); got != "Spanish:0 German:1" {
+		t.Errorf("top-level order = %s", got)
+	}
+	if got := api.order(&spanish.ID); got != "Verbs:0 Nouns:1 French:2" {
+		t.Errorf("Spanish order = %s", got)
+	}
+
+	position = 0
+	if moved := api.do(http.MethodPost, path, `"1"`, service.MoveDeckRequest{Position: &position}); moved.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale move: status %d", moved.Code)
+	}
+	if moved := api.do(http.MethodPost, path, "", service.MoveDeckRequest{Position: &position}); moved.Code != http.StatusOK {
+		t.Fatalf("move to the top: status %d: %s", moved.Code, moved.Body)
+	}
+	if got := api.order(nil); got != "French:0 Spanish:1 German:2" {
+		t.Errorf("top-level order = %s", got)
+	}
+	if got := api.order(&spanish.ID); got != "Verbs:0 Nouns:1" {
+		t.Errorf("Spanish order = %s", got)
+	}
+
+	for _, parentID := range []int64{spanish.ID, irregular.ID} {
+		response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10)+"/move", "", service.MoveDeckRequest{ParentID: &parentID})
+		if response.Code != http.StatusConflict || !strings.Contains(response.Body.String(), `"deck_cycle"`) {
+			t.Errorf("move below %d: status %d: %s", parentID, response.Code, response.Body)
+		}
+	}
+	update := api.do(http.MethodPut, "/api/v1/decks/"+strconv.FormatInt(verbs.ID, 10), "", service.DeckInput{Name: "Verbs", ParentID: &irregular.ID})
+	if update.Code != http.StatusConflict {
+		t.Errorf("update below a subdeck: status %d", update.Code)
+	}
+
+	position = -1
+	if response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(german.ID, 10)+"/move", "", service.MoveDeckRequest{Position: &position}); response.Code != http.StatusBadRequest {
+		t.Errorf("negative position: status %d", response.Code)
+	}
+}
+
+func TestDeckAPI_Reorder(t *testing.T) {
+	api := newETagAPI(t)
+	a := api.createDeck("A", nil)
+	b := api.createDeck("B", nil)
+	c := api.createDeck("C", nil)
+	child := api.createDeck("Child", &a.ID)
+
+	for _, ids := range [][]int64{nil, {a.ID, b.ID}, {a.ID, b.ID, b.ID}, {a.ID, b.ID, child.ID}, {a.ID, b.ID, c.ID, child.ID}} {
+		response := api.do(http.MethodPost, "/api/v1/decks/reorder", "", service.ReorderDecksRequest{DeckIDs: ids})
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("reorder %v: status %d", ids, response.Code)
+		}
+	}
+
+	response := api.do(http.MethodPost, "/api/v1/decks/reorder", "", service.ReorderDecksRequest{DeckIDs: []int64{c.ID, a.ID, b.ID}})
+	if response.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", response.Code, response.Body)
+	}
+	var decks []*model.Deck
+	_ = json.NewDecoder(response.Body).Decode(&decks)
+	if len(decks) != 3 || decks[0].ID != c.ID || decks[0].Position != 0 || decks[2].ID != b.ID {
+		t.Errorf("unexpected decks %+v", decks)
+	}
+	if got := api.order(nil); got != "C:0 A:1 B:2" {
+		t.Errorf("order = %s", got)
+	}
+
+	if response := api.do(http.MethodDelete, "/api/v1/decks/"+strconv.FormatInt(a.ID, 10), "", nil); response.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", response.Code)
+	}
+	if got := api.order(nil); got != "C:0 B:1" {
+		t.Errorf("order after delete = %s", got)
+	}
+}
@@ -0,0 +1,186 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+func decodeProblem(t *testing.T, recorder *httptest.ResponseRecorder) handler.Problem {
+	t.Helper()
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Fatalf("expected Content-Type application/problem+json, got %q", contentType)
+	}
+	var problem handler.Problem
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+		t.Fatalf("failed to decode problem: %v", err)
+	}
+	return problem
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: model.ErrInvalidInput, want: http.StatusBadRequest},
+		{err: model.NewValidationError("name", "is required"), want: http.StatusBadRequest},
+		{err: fmt.Errorf("load deck: %w", model.ErrNotFound), want: http.StatusNotFound},
+		{err: model.ErrUnauthorized, want: http.StatusUnauthorized},
+		{err: model.ErrForbidden, want: http.StatusForbidden},
+		{err: model.ErrDuplicateEmail, want: http.StatusConflict},
+		{err: model.ErrDuplicateName, want: http.StatusConflict},
+		{err: model.ErrDuplicateKey, want: http.StatusConflict},
+		{err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := handler.StatusForError(tt.err); got != tt.want {
+			t.Errorf("StatusForError(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorResponse_ValidationListsFields(t *testing.T) {
+	api := newDeckAPI(t)
+
+	position := -1
+	recorder := api.do(ownerID, http.MethodPost, "/api/v1/decks", service.DeckInput{Position: &position})
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+
+	problem := decodeProblem(t, recorder)
+	if problem.Status != http.StatusBadRequest || problem.Code != "validation_failed" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	if problem.Instance != "/api/v1/decks" {
+		t.Errorf("expected instance /api/v1/decks, got %q", problem.Instance)
+	}
+
+	fields := map[string]bool{}
+	for _, fieldErr := range problem.Errors {
+		fields[fieldErr.Field] = true
+	}
+	if !fields["name"] || !fields["position"] {
+		t.Errorf("expected errors for name and position, got %+v", problem.Errors)
+	}
+}
+
+func TestErrorResponse_EchoesRequestID(t *testing.T) {
+	api := newDeckAPI(t)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/decks/99", nil)
+	request.Header.Set("Authorization", "Bearer "+api.tokens[ownerID])
+	request.Header.Set(handler.RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, recorder.Code)
+	}
+	if got := recorder.Header().Get(handler.RequestIDHeader); got != "req-123" {
+		t.Errorf("expected %s header req-123, got %q", handler.RequestIDHeader, got)
+	}
+	if problem := decodeProblem(t, recorder); problem.RequestID != "req-123" || problem.Code != "not_found" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+}
+
+func TestErrorResponse_GeneratesRequestID(t *testing.T) {
+	api := newDeckAPI(t)
+
+	recorder := api.do(0, http.MethodGet, "/api/v1/decks", nil)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, recorder.Code)
+	}
+	problem := decodeProblem(t, recorder)
+	if problem.RequestID == "" {
+		t.Error("expected a generated request ID")
+	}
+	if recorder.Header().Get(handler.RequestIDHeader) != problem.RequestID {
+		t.Error("expected the header and body request IDs to match")
+	}
+}
+
+type failingDeckRepo struct {
+	deckRepoMock
+}
+
+func (r *failingDeckRepo) Tree(ctx context.Context, userID int64, rootIDs []int64, now time.Time) ([]*model.DeckNode, error) {
+	return nil, errors.New("pq: password authentication failed for user memwright")
+}
+
+func TestErrorResponse_InternalErrorHidesDetails(t *testing.T) {
+	var logs bytes.Buffer
+	tokens := newTestTokenManager(t)
+	decks := &failingDeckRepo{}
+	authorizer := service.NewAuthorizer(decks, &authzCardRepo{}, &authzScheduleRepo{}, &authzReviewLogRepo{})
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&logs, logger.LevelError),
+		Tokens: tokens,
+		Decks:  service.NewDeckService(authorizer, decks, directUnitOfWork{repos: repository.Repositories{Decks: decks}}),
+	})
+
+	token, _, _ := tokens.IssueAccessToken(ownerID)
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+	problem := decodeProblem(t, recorder)
+	if problem.Code != "internal_error" || problem.Detail != "" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	if !strings.Contains(logs.String(), "password authentication failed") {
+		t.Error("expected the underlying error to be logged")
+	}
+	if !strings.Contains(logs.String(), problem.RequestID) {
+		t.Error("expected the log entry to carry the request ID")
+	}
+}
+
+func TestRoutes_UnmatchedRequestsGetProblems(t *testing.T) {
+	api := newETagAPI(t)
+
+	recorder := api.do(http.MethodGet, "/api/v1/nope", "", nil)
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("unknown path: status %d", recorder.Code)
+	}
+	if problem := decodeProblem(t, recorder); problem.Code != "not_found" || problem.Instance != "/api/v1/nope" {
+		t.Errorf("unknown path: problem %+v", problem)
+	}
+
+	recorder = api.do(http.MethodDelete, "/api/v1/decks", "", nil)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("wrong method: status %d", recorder.Code)
+	}
+	if allow := recorder.Header().Get("Allow"); allow != "GET, HEAD, POST" {
+		t.Errorf("wrong method: Allow %q", allow)
+	}
+	if problem := decodeProblem(t, recorder); problem.Code != "method_not_allowed" {
+		t.Errorf("wrong method: problem %+v", problem)
+	}
+
+	if recorder := api.do(http.MethodGet, "/api/v1/decks", "", nil); recorder.Code != http.StatusOK {
+		t.Errorf("known route: status %d", recorder.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+type etagAPI struct {
+	t      *testing.T
+	server http.Handler
+	token  string
+	repos  repository.Repositories
+	// skew moves the trash service's clock, to age items in the trash.
+	skew time.Duration
+}
+
+func newETagAPI(t *testing.T) *etagAPI {
+	t.Helper()
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	if err := repos.Users.Create(context.Background(), &model.User{Email: "owner@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	authorizer := service.NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+	tokens := newTestTokenManager(t)
+
+	api := &etagAPI{t: t, repos: repos}
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens: tokens,
+		Decks:  service.NewDeckService(authorizer, repos.Decks, memory.NewUnitOfWork(store)),
+		Cards:  service.NewCardService(authorizer, repos.Cards, memory.NewUnitOfWork(store)),
+		Trash: service.NewTrashService(repos.Decks, repos.Cards, memory.NewUnitOfWork(store), service.DefaultTrashRetention).
+			WithClock(func() time.Time { return time.Now().Add(api.skew) }),
+		Transfer: service.NewTransferService(authorizer, repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs, memory.NewUnitOfWork(store)),
+		Backlog:  service.NewBacklogService(authorizer, repos.Schedules, repos.Users, memory.NewUnitOfWork(store)),
+		Reviews:  service.NewReviewService(repos.ReviewLogs),
+	})
+	api.server = mux
+	api.token, _, _ = tokens.IssueAccessToken(1)
+	return api
+}
+
+func (api *etagAPI) do(method, path, ifMatch string, body interface{}) *httptest.ResponseRecorder {
+	api.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	request := httptest.NewRequest(method, path, &payload)
+	request.Header.Set("Authorization", "Bearer "+api.token)
+	if ifMatch != "" {
+		request.Header.Set("If-Match", ifMatch)
+	}
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestDeckETag_IfMatch(t *testing.T) {
+	api := newETagAPI(t)
+	created := api.do(http.MethodPost, "/api/v1/decks", "", service.DeckInput{Name: "Spanish"})
+	if created.Code != http.StatusCreated || created.Header().Get("ETag") != `"1"` {
+		t.Fatalf("create: status %d, ETag %q", created.Code, created.Header().Get("ETag"))
+	}
+	var deck model.Deck
+	_ = json.NewDecoder(created.Body).Decode(&deck)
+	path := "/api/v1/decks/" + strconv.FormatInt(deck.ID, 10)
+
+	if etag := api.do(http.MethodGet, path, "", nil).Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("get: ETag %q", etag)
+	}
+
+	updated := api.do(http.MethodPut, path, `"1"`, service.DeckInput{Name: "Spanish", Description: "first tab"})
+	if updated.Code != http.StatusOK || updated.Header().Get("ETag") != `"2"` {
+		t.Fatalf("update: status %d, ETag %q: %s", updated.Code, updated.Header().Get("ETag"), updated.Body)
+	}
+
+	// The second tab still holds version 1.
+	stale := api.do(http.MethodPut, path, `"1"`, service.DeckInput{Name: "Spanish", Description: "second tab"})
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update: expected status %d, got %d: %s", http.StatusPreconditionFailed, stale.Code, stale.Body)
+	}
+	var problem handler.Problem
+	if err := json.NewDecoder(stale.Body).Decode(&problem); err != nil || problem.Code != "version_conflict" {
+		t.Errorf("unexpected problem %+v, %v", problem, err)
+	}
+
+	// A version in the body is honoured as well.
+	if recorder := api.do(http.MethodPut, path, "", service.DeckInput{Name: "Spanish", Version: 1}); recorder.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale body version: expected status %d, got %d", http.StatusPreconditionFailed, recorder.Code)
+	}
+	if recorder := api.do(http.MethodPut, path, "*", service.DeckInput{Name: "Spanish", Description: "forced"}); recorder.Code != http.StatusOK {
+		t.Errorf("If-Match *: expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+}
+
+func TestCardETag_IfMatch(t *testing.T) {
+	api := newETagAPI(t)
+	deckRecorder := api.do(http.MethodPost, "/api/v1/decks", "", service.DeckInput{Name: "Spanish"})
+	var deck model.Deck
+	_ = json.NewDecoder(deckRecorder.Body).Decode(&deck)
+
+	created := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deck.ID, 10)+"/cards", "", service.CardInput{Front: "hola"})
+	var card model.Card
+	_ = json.NewDecoder(created.Body).Decode(&card)
+	path := "/api/v1/cards/" + strconv.FormatInt(card.ID, 10)
+
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+		wantETag   string
+	}{
+		{name: "current version", ifMatch: `"1"`, wantStatus: http.StatusOK, wantETag: `"2"`},
+		{name: "stale version", ifMatch: `"1"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "weak tag", ifMatch: `W/"2"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "unknown tag", ifMatch: `"abc"`, wantStatus: http.StatusPreconditionFailed},
+		{name: "tag list", ifMatch: `"1", "2"`, wantStatus: http.StatusBadRequest},
+		{name: "no precondition", wantStatus: http.StatusOK, wantETag: `"3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := api.do(http.MethodPut, path, tt.ifMatch, service.CardInput{Front: "hola", Back: tt.name})
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, recorder.Code, recorder.Body)
+			}
+			if tt.wantETag != "" && recorder.Header().Get("ETag") != tt.wantETag {
+				t.Errorf("expected ETag %s, got %q", tt.wantETag, recorder.Header().Get("ETag"))
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+func (api *etagAPI) importMarkdown(query, data string) *model.MarkdownImportResult {
+	api.t.Helper()
+	response := api.doRaw(http.MethodPost, "/api/v1/decks/import/markdown"+query, "text/markdown", data)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("import markdown: status %d: %s", response.Code, response.Body)
+	}
+	var result model.MarkdownImportResult
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	return &result
+}
+
+func (api *etagAPI) exportMarkdown(deckID int64) string {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deckID, 10)+"/export/markdown", "", nil)
+	if response.Code != http.StatusOK || !strings.HasPrefix(response.Header().Get("Content-Type"), "text/markdown") {
+		api.t.Fatalf("export markdown: status %d, type %s: %s", response.Code, response.Header().Get("Content-Type"), response.Body)
+	}
+	return response.Body.String()
+}
+
+func (api *etagAPI) getDeck(id int64) *model.Deck {
+	api.t.Helper()
+	var deck model.Deck
+	_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/decks/"+strconv.FormatInt(id, 10), "", nil).Body).Decode(&deck)
+	return &deck
+}
+
+const markdownDeckFile = "---\n" +
+	"title: Spanish notes\n" +
+	"tags: spanish\n" +
+	"language: spanish\n" +
+	"---\n" +
+	"\n" +
+	"# Spanish\n" +
+	"<!-- description: Everyday words -->\n" +
+	"\n" +
+	"hola\n" +
+	"---\n" +
+	"hello\n" +
+	"***\n" +
+	"<!-- tags: greetings, lang::es -->\n" +
+	"{{c1::adiós}} means goodbye\n" +
+	"---\n" +
+	"---\n" +
+	"said when leaving\n" +
+	"\n" +
+	"## Verbs\n" +
+	"<!-- algorithm: fsrs -->\n" +
+	"\n" +
+	"<!-- type: reverse -->\n" +
+	"hablar\n" +
+	"---\n" +
+	"to speak\n" +
+	"\n" +
+	"```go\n" +
+	"x := \"---\"\n" +
+	"```\n" +
+	"<!-- suspended: true -->\n" +
+	"comer\n" +
+	"---\n" +
+	"to eat\n"
+
+func TestMarkdownAPI_Import(t *testing.T) {
+	api := newETagAPI(t)
+	result := api.importMarkdown("", markdownDeckFile)
+	if result.DecksCreated != 2 || result.CardsCreated != 4 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(result.Decks) != 2 || result.Decks[0].Line != 7 || result.Decks[1].Line != 20 {
+		t.Fatalf("decks = %+v", result.Decks)
+	}
+	var lines []int
+	for _, card := range result.Cards {
+		lines = append(lines, card.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{10, 14, 23, 31}) {
+		t.Fatalf("card lines = %v", lines)
+	}
+
+	spanish, verbs := api.getDeck(result.Decks[0].ID), api.getDeck(result.Decks[1].ID)
+	if spanish.Description != "Everyday words" || spanish.Language != "spanish" || spanish.Algorithm != model.AlgorithmSM2 {
+		t.Fatalf("spanish = %+v", spanish)
+	}
+	if verbs.ParentID == nil || *verbs.ParentID != spanish.ID || verbs.Algorithm != model.AlgorithmFSRS || verbs.Language != "spanish" || verbs.Description != "" {
+		t.Fatalf("verbs = %+v", verbs)
+	}
+
+	cards := api.deckCards(spanish.ID)
+	if len(cards) != 2 || cards[0].Back != "hello" || !reflect.DeepEqual(cards[0].Tags, []string{"spanish"}) {
+		t.Fatalf("spanish cards = %+v", cards)
+	}
+	cloze := cards[1]
+	if cloze.Type != model.CardTypeCloze || cloze.Back != "" || cloze.Extra != "said when leaving" || !reflect.DeepEqual(cloze.Tags, []string{"spanish", "greetings", "lang::es"}) {
+		t.Fatalf("cloze = %+v", cloze)
+	}
+	cards = api.deckCards(verbs.ID)
+	if len(cards) != 2 || cards[0].Type != model.CardTypeReverse || cards[0].Back != "to speak\n\n```go\nx := \"---\"\n```" || cards[0].Suspended {
+		t.Fatalf("verbs cards = %+v", cards)
+	}
+	if !cards[1].Suspended || cards[1].Front != "comer" {
+		t.Fatalf("suspended card = %+v", cards[1])
+	}
+
+	// Without IDs, cards are matched by type and front.
+	again := api.importMarkdown("", markdownDeckFile)
+	if again.DecksUnchanged != 2 || again.CardsUnchanged != 4 || again.CardsCreated != 0 {
+		t.Fatalf("second import = %+v", again)
+	}
+
+	// IDs match cards anywhere, so cards can be renamed and moved; unknown
+	// IDs make new cards.
+	hola := api.deckCards(spanish.ID)[0]
+	edited := "# Spanish\n## Verbs\n<!-- id: " + strconv.FormatInt(hola.ID, 10) + " -->\nhola!\n---\nhi\n<!-- id: 99999 -->\nvivir\n"
+	result = api.importMarkdown("", edited)
+	if result.DecksUnchanged != 2 || result.CardsUpdated != 1 || result.CardsCreated != 1 {
+		t.Fatalf("edit result = %+v", result)
+	}
+	cards = api.deckCards(verbs.ID)
+	if len(cards) != 4 || cards[2].ID != hola.ID || cards[2].Front != "hola!" || cards[2].Back != "hi" || cards[3].Front != "vivir" || cards[3].ID == 99999 {
+		t.Fatalf("verbs cards after edit = %+v", cards)
+	}
+	if len(api.deckCards(spanish.ID)) != 1 {
+		t.Fatal("card was not moved")
+	}
+}
+
+func TestMarkdownAPI_ImportKeepsEveryCard(t *testing.T) {
+	api := newETagAPI(t)
+	spanish := api.createDeck("Spanish", nil)
+	hola := api.createCard(spanish.ID, service.CardInput{Front: "hola", Back: "hello"})
+
+	// The card that names hola by ID gets it, even after a card with the
+	// same front.
+	id := strconv.FormatInt(hola.ID, 10)
+	result := api.importMarkdown("", "# Spanish\nhola\n---\nhi\n<!-- id: "+id+" -->\nhola!\n---\nhello!\n")
+	if result.CardsCreated != 1 || result.CardsUpdated != 1 || result.Cards[0].ID == hola.ID || result.Cards[1].ID != hola.ID {
+		t.Fatalf("result = %+v", result)
+	}
+	cards := api.deckCards(spanish.ID)
+	if len(cards) != 2 || cards[0].Front != "hola!" || cards[0].Back != "hello!" || cards[1].Front != "hola" || cards[1].Back != "hi" {
+		t.Fatalf("cards = %+v", cards)
+	}
+
+	// An unknown ID that a card created earlier in the import receives
+	// makes another card instead of overwriting it.
+	next := strconv.FormatInt(cards[1].ID+1, 10)
+	result = api.importMarkdown("", "# Spanish\nadiós\n***\n<!-- id: "+next+" -->\nchao\n")
+	if result.CardsCreated != 2 || result.Cards[0].ID == result.Cards[1].ID {
+		t.Fatalf("result = %+v", result)
+	}
+	if cards := api.deckCards(spanish.ID); len(cards) != 4 || cards[2].Front != "adiós" || cards[3].Front != "chao" {
+		t.Fatalf("cards = %+v", cards)
+	}
+}
+
+func TestMarkdownAPI_ExportRoundTrip(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, verbs, _ := transferFixture(t, api)
+	description := "Spanish words\n-- from class -->"
+	api.do(http.MethodPut, "/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10), "", service.DeckInput{Name: "Spanish", Description: description, Language: "spanish"})
+	api.do(http.MethodPut, "/api/v1/decks/"+strconv.FormatInt(verbs.ID, 10), "", service.DeckInput{Name: "Verbs", ParentID: &spanish.ID, Algorithm: model.AlgorithmFSRS})
+	tricky := api.createCard(verbs.ID, service.CardInput{
+		Front: "# not a heading\n---\n***\n\\---",
+		Back:  "```\nunclosed\n---",
+		Extra: "<!-- id: 3 -->\n```sh\necho ---\n```",
+		Tags:  []string{"a,b", "false friends"},
+	})
+	api.do(http.MethodPut, "/api/v1/cards/"+strconv.FormatInt(tricky.ID, 10), "", service.CardInput{Front: tricky.Front, Back: tricky.Back, Extra: tricky.Extra, Tags: tricky.Tags, Suspended: true})
+	before := api.deckCards(verbs.ID)
+
+	exported := api.exportMarkdown(spanish.ID)
+	for _, expected := range []string{
+		"---\nalgorithm: sm2\nlanguage: spanish\nsrs_config: {\"sm2\":",
+		"\n# Spanish\n<!-- description: \"Spanish words\\n-- from class --\\u003e\" -->\n",
+		"\n<!-- id: " + strconv.FormatInt(before[0].ID, 10) + " -->\nhablar\n---\nto speak\n---\nregular\n",
+		"\n## Verbs\n<!-- algorithm: fsrs -->\n<!-- language: simple -->\n",
+		"<!-- tags: [\"a,b\",\"false friends\"] -->\n<!-- suspended: true -->\n\\# not a heading\n\\---\n\\***\n\\\\---\n---\n\\```\nunclosed\n\\---\n---\n\\<!-- id: 3 -->\n```sh\necho ---\n```\n",
+	} {
+		if !strings.Contains(exported, expected) {
+			t.Fatalf("export lacks %q:\n%s", expected, exported)
+		}
+	}
+
+	result := api.importMarkdown("", exported)
+	if result.DecksUnchanged != 2 || result.CardsUnchanged != 4 || result.CardsCreated+result.CardsUpdated+result.DecksCreated+result.DecksUpdated != 0 {
+		t.Fatalf("re-import = %+v", result)
+	}
+	if after := api.deckCards(verbs.ID); !reflect.DeepEqual(after, before) {
+		t.Fatalf("cards changed:\n%+v\n%+v", before, after)
+	}
+	if again := api.exportMarkdown(spanish.ID); again != exported {
+		t.Fatalf("second export differs:\n%s\n---\n%s", exported, again)
+	}
+
+	// With its IDs replaced by card breaks, the file copies the decks and
+	// cards.
+	copyParent := api.createDeck("Copy", nil)
+	withoutIDs := regexp.MustCompile(`(?m)^<!-- id: \d+ -->$`).ReplaceAllString(exported, "***")
+	result = api.importMarkdown("?parent_id="+strconv.FormatInt(copyParent.ID, 10), withoutIDs)
+	if result.DecksCreated != 2 || result.CardsCreated != 4 {
+		t.Fatalf("copy = %+v", result)
+	}
+	copied := api.deckCards(result.Decks[1].ID)
+	if len(copied) != 3 || copied[2].Front != tricky.Front || copied[2].Back != tricky.Back || copied[2].Extra != tricky.Extra || !reflect.DeepEqual(copied[2].Tags, tricky.Tags) || !copied[2].Suspended {
+		t.Fatalf("copied = %+v", copied)
+	}
+	if deck := api.getDeck(result.Decks[0].ID); deck.Description != description || deck.Language != "spanish" {
+		t.Fatalf("copied deck = %+v", deck)
+	}
+}
+
+func TestMarkdownAPI_ImportValidation(t *testing.T) {
+	api := newETagAPI(t)
+	for name, test := range map[string]struct {
+		data  string
+		field string
+	}{
+		"before heading": {"hola\n# Spanish\n", "lines[1]"},
+		"front":          {"# Spanish\n---\nhello\n", "lines[2].front"},
+		"id":             {"# Spanish\n<!-- id: x -->\nhola\n", "lines[2].id"},
+		"repeated id":    {"# Spanish\n<!-- id: 5 -->\nhola\n<!-- id: 5 -->\nadiós\n", "lines[4].id"},
+		"type":           {"# Spanish\n<!-- type: essay -->\nhola\n", "lines[2].type"},
+		"tags":           {"# Spanish\n<!-- tags: [1] -->\nhola\n", "lines[2].tags"},
+		"front matter":   {"---\nsrs_config: nope\n---\n# Spanish\n", "lines[2].srs_config"},
+		"unclosed":       {"---\ntags: a\n# Spanish\n", "lines[1]"},
+		"algorithm":      {"# Spanish\n<!-- algorithm: bogus -->\n## Verbs\nhablar\n", "lines[1].algorithm"},
+		"no headings":    {"\n\n", "headings"},
+	} {
+		response := api.doRaw(http.MethodPost, "/api/v1/decks/import/markdown", "text/markdown", test.data)
+		if response.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d: %s", name, response.Code, response.Body)
+			continue
+		}
+		var problem struct {
+			Errors []model.FieldError `json:"errors"`
+		}
+		_ = json.NewDecoder(response.Body).Decode(&problem)
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != test.field {
+			t.Errorf("%s: errors = %+v", name, problem.Errors)
+		}
+	}
+	if api.order(nil) != "" {
+		t.Fatalf("failed imports created decks: %s", api.order(nil))
+	}
+}
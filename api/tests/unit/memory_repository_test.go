@@ -0,0 +1,15 @@
+package unit
+
+import (
+	"testing"
+
+	"memwright/api/internal/repository"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/repository/repositorytest"
+)
+
+func TestMemoryRepositories_Contract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repositories {
+		return memory.NewRepositories(memory.NewStore())
+	})
+}
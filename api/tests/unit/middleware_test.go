@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/pkg/logger"
+)
+
+func newMiddlewareTestHandler(logs *bytes.Buffer, next http.Handler) http.Handler {
+	return handler.Wrap(next, handler.MiddlewareOptions{
+		Logger:    logger.New(logs, logger.LevelDebug),
+		AccessLog: true,
+		CORS: handler.CORSOptions{
+			AllowedOrigins: []string{"http://localhost:5173"},
+			MaxAge:         10 * time.Minute,
+		},
+		SecurityHeaders: true,
+		HSTSMaxAge:      time.Hour,
+	})
+}
+
+func TestChain_AppliesMiddlewaresInOrder(t *testing.T) {
+	var order []string
+	tag := func(name string) handler.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(writer, request)
+			})
+		}
+	}
+
+	final := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		order = append(order, "handler")
+	})
+	handler.Chain(final, tag("first"), tag("second")).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if strings.Join(order, ",") != "first,second,handler" {
+		t.Errorf("unexpected order %v", order)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		seen = handler.RequestIDFromContext(request.Context())
+	})
+	server := newMiddlewareTestHandler(&bytes.Buffer{}, next)
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(handler.RequestIDHeader, "client-id-1")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	if seen != "client-id-1" || recorder.Header().Get(handler.RequestIDHeader) != "client-id-1" {
+		t.Errorf("expected client request ID to propagate, got context %q header %q", seen, recorder.Header().Get(handler.RequestIDHeader))
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(handler.RequestIDHeader, "bad id\n")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	if seen == "" || seen == "bad id\n" {
+		t.Errorf("expected a generated request ID, got %q", seen)
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusCreated)
+		_, _ = writer.Write([]byte("hello"))
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/decks", nil)
+	newMiddlewareTestHandler(&logs, next).ServeHTTP(httptest.NewRecorder(), request)
+
+	line := logs.String()
+	for _, want := range []string{"method=POST", "path=/api/v1/decks", "status=201", "bytes=5", "duration=", "request_id="} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected access log to contain %q, got %q", want, line)
+		}
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		panic("nil map write")
+	})
+
+	recorder := httptest.NewRecorder()
+	newMiddlewareTestHandler(&logs, next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+	if problem := decodeProblem(t, recorder); problem.Code != "internal_error" || problem.Detail != "" {
+		t.Errorf("unexpected problem %+v", problem)
+	}
+	if !strings.Contains(logs.String(), "nil map write") {
+		t.Error("expected the panic to be logged")
+	}
+	if !strings.Contains(logs.String(), "status=500") {
+		t.Error("expected the access log to record status 500")
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		called = true
+	})
+	server := newMiddlewareTestHandler(&bytes.Buffer{}, next)
+
+	t.Run("preflight from allowed origin", func(t *testing.T) {
+		called = false
+		request := httptest.NewRequest(http.MethodOptions, "/api/v1/decks", nil)
+		request.Header.Set("Origin", "http://localhost:5173")
+		request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusNoContent || called {
+			t.Errorf("expected preflight to be answered with 204, got %d (handler called: %v)", recorder.Code, called)
+		}
+		if recorder.Header().Get("Access-Control-Allow-Origin") != "http://localhost:5173" {
+			t.Errorf("unexpected Allow-Origin %q", recorder.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if recorder.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("unexpected Max-Age %q", recorder.Header().Get("Access-Control-Max-Age"))
+		}
+	})
+
+	t.Run("request from unknown origin", func(t *testing.T) {
+		called = false
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+		request.Header.Set("Origin", "https://evil.example.com")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+
+		if !called {
+			t.Error("expected the request to reach the handler")
+		}
+		if recorder.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Error("expected no Allow-Origin header for an unknown origin")
+		}
+	})
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	next := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {})
+	newMiddlewareTestHandler(&bytes.Buffer{}, next).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	headers := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Strict-Transport-Security": "max-age=3600; includeSubDomains",
+	}
+	for name, want := range headers {
+		if got := recorder.Header().Get(name); got != want {
+			t.Errorf("expected %s %q, got %q", name, want, got)
+		}
+	}
+}
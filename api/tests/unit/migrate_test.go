@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"memwright/api/internal/migrate"
+	"memwright/api/migrations"
+)
+
+func TestMigrate_LoadOrdersPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t(a);")},
+		"010_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"002_create_t.up.sql":    {Data: []byte("CREATE TABLE t (a INT);")},
+		"002_create_t.down.sql":  {Data: []byte("DROP TABLE t;")},
+		"README.md":              {Data: []byte("ignored")},
+	}
+
+	loaded, err := migrate.Load(fsys)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Version != 2 || loaded[1].Version != 10 {
+		t.Fatalf("unexpected migrations %+v", loaded)
+	}
+	if loaded[0].Name != "create_t" || loaded[0].Down != "DROP TABLE t;" {
+		t.Errorf("unexpected first migration %+v", loaded[0])
+	}
+}
+
+func TestMigrate_LoadRejectsInvalidSets(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{"001_a.up.sql": {Data: []byte("SELECT 1;")}},
+			want: "needs non-empty up and down",
+		},
+		{
+			name: "bad file name",
+			fsys: fstest.MapFS{"create_a.up.sql": {Data: []byte("SELECT 1;")}},
+			want: "expected NNN_name",
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"001_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"001_b.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			want: "conflicting names",
+		},
+		{
+			name: "zero version",
+			fsys: fstest.MapFS{
+				"000_a.up.sql":   {Data: []byte("SELECT 1;")},
+				"000_a.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			want: "invalid version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.Load(tt.fsys); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMigrate_EmbeddedMigrationsAreComplete(t *testing.T) {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	latest, _ := migrations.LatestVersion()
+	if len(loaded) == 0 || loaded[len(loaded)-1].Version != latest {
+		t.Errorf("expected migrations up to version %d, got %d", latest, len(loaded))
+	}
+	for i, migration := range loaded {
+		if migration.Version != int64(i+1) {
+			t.Errorf("expected contiguous versions, found %d at position %d", migration.Version, i)
+		}
+	}
+}
+
+func TestMigrate_CreateNumbersAfterLatest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"001_a.up.sql", "001_a.down.sql", "007_b.up.sql", "007_b.down.sql"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up, down, err := migrate.Create(dir, "Add_Tags")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if filepath.Base(up) != "008_add_tags.up.sql" || filepath.Base(down) != "008_add_tags.down.sql" {
+		t.Errorf("unexpected files %s, %s", up, down)
+	}
+
+	if _, _, err := migrate.Create(dir, "drop table"); err == nil {
+		t.Error("expected an invalid name to be rejected")
+	}
+}
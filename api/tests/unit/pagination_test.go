@@ -0,0 +1,222 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	reviewedAt := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	cursor := model.Cursor{Position: 4, Name: "Verbs", ReviewedAt: &reviewedAt, ID: 17}
+
+	decoded, err := model.DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if decoded.Position != 4 || decoded.Name != "Verbs" || decoded.ID != 17 || !decoded.ReviewedAt.Equal(reviewedAt) {
+		t.Errorf("DecodeCursor() = %+v, want %+v", decoded, cursor)
+	}
+
+	if decoded, err := model.DecodeCursor(""); decoded != nil || err != nil {
+		t.Errorf("DecodeCursor(\"\") = %v, %v, want nil, nil", decoded, err)
+	}
+	for _, malformed := range []string{"not base64!", "e30", "bnVsbA"} {
+		if _, err := model.DecodeCursor(malformed); !errors.Is(err, model.ErrInvalidInput) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidInput", malformed, err)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cards := []*model.Card{{ID: 1}, {ID: 2}, {ID: 3}}
+
+	page := model.NewPage(cards, 2, model.CardCursor)
+	if len(page.Items) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 items and a next cursor, got %+v", page)
+	}
+	if cursor, _ := model.DecodeCursor(page.NextCursor); cursor.ID != 2 {
+		t.Errorf("expected next cursor after card 2, got %+v", cursor)
+	}
+
+	if page := model.NewPage(cards, 3, model.CardCursor); page.NextCursor != "" {
+		t.Error("expected no next cursor on the last page")
+	}
+	if page := model.NewPage[*model.Card](nil, 10, model.CardCursor); page.Items == nil {
+		t.Error("expected an empty, non-nil item list")
+	}
+}
+
+func TestCardRepository_GetByDeckID_Keyset(t *testing.T) {
+	var gotQuery string
+	var gotArgs []interface{}
+	db := &mockDB{
+		queryFunc: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery, gotArgs = query, args
+			return nil, errors.New("stop")
+		},
+	}
+	repo := repository.NewCardRepository(db)
+
+	cursor := model.Cursor{Position: 3, ID: 42}.Encode()
+	_, _ = repo.GetByDeckID(context.Background(), 7, model.PageRequest{Cursor: cursor, Limit: 50})
+
+	if !strings.Contains(gotQuery, "(position, id) > ($2, $3)") || !strings.Contains(gotQuery, "LIMIT 51") {
+		t.Errorf("unexpected query %s", gotQuery)
+	}
+	if len(gotArgs) != 3 || gotArgs[1] != 3 || gotArgs[2] != int64(42) {
+		t.Errorf("unexpected args %v", gotArgs)
+	}
+
+	gotQuery = ""
+	_, err := repo.GetByDeckID(context.Background(), 7, model.PageRequest{Cursor: "%%%"})
+	if !errors.Is(err, model.ErrInvalidInput) || gotQuery != "" {
+		t.Errorf("expected a malformed cursor to be rejected before querying, got %v", err)
+	}
+}
+
+func TestReviewLogRepository_GetByUserID_Keyset(t *testing.T) {
+	var gotQuery string
+	db := &mockDB{
+		queryFunc: func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+			gotQuery = query
+			return nil, errors.New("stop")
+		},
+	}
+	repo := repository.NewReviewLogRepository(db)
+
+	reviewedAt := time.Now()
+	cursor := model.Cursor{ReviewedAt: &reviewedAt, ID: 9}.Encode()
+	_, _ = repo.GetByUserID(context.Background(), 1, model.PageRequest{Cursor: cursor, Limit: 20})
+
+	if !strings.Contains(gotQuery, "(reviewed_at, id) < ($2, $3)") || !strings.Contains(gotQuery, "ORDER BY reviewed_at DESC, id DESC") {
+		t.Errorf("unexpected query %s", gotQuery)
+	}
+
+	_, err := repo.GetByUserID(context.Background(), 1, model.PageRequest{Cursor: model.Cursor{ID: 9}.Encode()})
+	if !errors.Is(err, model.ErrInvalidInput) {
+		t.Errorf("expected a cursor without reviewed_at to be rejected, got %v", err)
+	}
+}
+
+func TestCardAPI_PaginatesWithCursor(t *testing.T) {
+	api, _ := newCardAPI(t)
+	deck := api.createDeck(ownerID, service.DeckInput{Name: "Spanish"})
+	for i := 0; i < 5; i++ {
+		api.createCard(ownerID, deck.ID, service.CardInput{Front: "card " + itoa(int64(i)), Position: 4 - i})
+	}
+
+	var fronts []string
+	path := "/api/v1/decks/" + itoa(deck.ID) + "/cards?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		recorder := api.do(ownerID, http.MethodGet, path, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+		}
+
+		var page handler.PageResponse[*model.Card]
+		_ = json.NewDecoder(recorder.Body).Decode(&page)
+		for _, card := range page.Items {
+			fronts = append(fronts, card.Front)
+		}
+		if page.Next != "" && recorder.Header().Get("Link") != `<`+page.Next+`>; rel="next"` {
+			t.Errorf("unexpected Link header %q", recorder.Header().Get("Link"))
+		}
+		path = page.Next
+	}
+
+	if strings.Join(fronts, ",") != "card 4,card 3,card 2,card 1,card 0" {
+		t.Errorf("unexpected order %v", fronts)
+	}
+}
+
+func TestDeckAPI_PaginatesRootDecks(t *testing.T) {
+	api := newDeckAPI(t)
+	for _, name := range []string{"C", "A", "B"} {
+		api.createDeck(ownerID, service.DeckInput{Name: name})
+	}
+
+	recorder := api.do(ownerID, http.MethodGet, "/api/v1/decks?limit=2", nil)
+	var first handler.PageResponse[*model.DeckNode]
+	_ = json.NewDecoder(recorder.Body).Decode(&first)
+	if len(first.Items) != 2 || first.Items[0].Name != "C" || first.Items[1].Name != "A" || first.NextCursor == "" {
+		t.Fatalf("unexpected first page %+v", first)
+	}
+
+	recorder = api.do(ownerID, http.MethodGet, "/api/v1/decks?limit=2&cursor="+url.QueryEscape(first.NextCursor), nil)
+	var second handler.PageResponse[*model.DeckNode]
+	_ = json.NewDecoder(recorder.Body).Decode(&second)
+	if len(second.Items) != 1 || second.Items[0].Name != "B" || second.NextCursor != "" {
+		t.Errorf("unexpected second page %+v", second)
+	}
+}
+
+func TestListAPI_RejectsInvalidPageParameters(t *testing.T) {
+	api := newDeckAPI(t)
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=100000", "cursor=not-a-cursor"} {
+		recorder := api.do(ownerID, http.MethodGet, "/api/v1/decks?"+query, nil)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", query, http.StatusBadRequest, recorder.Code)
+		}
+	}
+}
+
+func TestReviewAPI_PaginatesWithCursor(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+	schedule := api.schedule(api.createCard(deck.ID, service.CardInput{Front: "hola"}).ID, time.Now())
+	reviewedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		log := &model.ReviewLog{CardScheduleID: schedule.ID, UserID: ownerID, Rating: model.ReviewRatingCorrect, ReviewDuration: i, ReviewedAt: reviewedAt.Add(time.Duration(i) * time.Hour)}
+		if err := api.repos.ReviewLogs.Create(context.Background(), log); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := api.repos.Users.Create(context.Background(), &model.User{Email: "intruder@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	foreign := &model.ReviewLog{CardScheduleID: schedule.ID, UserID: intruderID, Rating: model.ReviewRatingCorrect, ReviewDuration: 9, ReviewedAt: reviewedAt.Add(time.Minute)}
+	if err := api.repos.ReviewLogs.Create(context.Background(), foreign); err != nil {
+		t.Fatal(err)
+	}
+
+	var durations []string
+	path := "/api/v1/reviews?limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		recorder := api.do(http.MethodGet, path, "", nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body)
+		}
+		var page handler.PageResponse[*model.ReviewLog]
+		_ = json.NewDecoder(recorder.Body).Decode(&page)
+		for _, log := range page.Items {
+			durations = append(durations, itoa(int64(log.ReviewDuration)))
+		}
+		path = page.Next
+	}
+
+	if got := strings.Join(durations, ","); got != "4,3,2,1,0" {
+		t.Errorf("unexpected order %s", got)
+	}
+	if recorder := api.do(http.MethodGet, "/api/v1/reviews?cursor="+url.QueryEscape(model.Cursor{ID: 1}.Encode()), "", nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a deck cursor to be rejected, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/query"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: "<nil>"},
+		{input: "  ", want: "<nil>"},
+		{input: "dog", want: "dog"},
+		{input: `deck:Spanish tag:verbs is:due -is:suspended prop:lapses>3 added:7 "exact phrase"`,
+			want: `deck:Spanish tag:verbs is:due -is:suspended prop:lapses>3 added:7 "exact phrase"`},
+		{input: "a b or c d", want: "a b or c d"},
+		{input: "a (b or c) and d", want: "a (b or c) d"},
+		{input: "-(a b) --c", want: "-(a b) c"},
+		{input: "(a or b) or c", want: "a or b or c"},
+		{input: `deck:"Spanish Verbs" Deck:A::B front:*x* IS:NEW type:Cloze`, want: `deck:"Spanish Verbs" deck:A::B front:*x* is:new type:cloze`},
+		{input: `tag:NONE did:42 prop:ease<=2.5 prop:due!=-1 rated:3 edited:2`, want: `tag:none did:42 prop:ease<=2.5 prop:due!=-1 rated:3 edited:2`},
+		{input: `10:30 "or" "say \"hi\""`, want: `"10:30" "or" "say \"hi\""`},
+		{input: "back:", want: `back:""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := query.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := fmt.Sprint(node); got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Nodes(t *testing.T) {
+	node, err := query.Parse(`deck:Spanish (is:due or prop:lapses>3) -"el perro"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := query.And{Nodes: []query.Node{
+		query.Deck{Pattern: "Spanish"},
+		query.Or{Nodes: []query.Node{query.Due{}, query.Prop{Name: query.PropLapses, Op: ">", Value: 3}}},
+		query.Not{Node: query.Text{Text: "el perro", Phrase: true}},
+	}}
+	if !reflect.DeepEqual(node, want) {
+		t.Errorf("Parse() = %#v", node)
+	}
+	if terms := query.Terms(node); len(terms) != 0 {
+		t.Errorf("expected negated text to be left out of the terms, got %v", terms)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantPos int
+		wantMsg string
+	}{
+		{input: "(dog", wantPos: 0, wantMsg: `missing ")"`},
+		{input: "dog)", wantPos: 3, wantMsg: `unexpected ")"`},
+		{input: "()", wantPos: 1, wantMsg: "empty group"},
+		{input: "dog or", wantPos: 6, wantMsg: "found end of query"},
+		{input: "and dog", wantPos: 0, wantMsg: `found "and"`},
+		{input: "dog -", wantPos: 5, wantMsg: "found end of query"},
+		{input: `cat "dog`, wantPos: 4, wantMsg: "unterminated quote"},
+		{input: `deck:"Spanish`, wantPos: 5, wantMsg: "unterminated quote"},
+		{input: `""`, wantPos: 0, wantMsg: "empty phrase"},
+		{input: "color:red", wantPos: 0, wantMsg: `unknown field "color"`},
+		{input: "is:later", wantPos: 3, wantMsg: "unknown is:later"},
+		{input: "type:essay", wantPos: 5, wantMsg: `unknown card type "essay"`},
+		{input: "deck:", wantPos: 5, wantMsg: "needs a deck name"},
+		{input: "did:x", wantPos: 4, wantMsg: "needs a deck id"},
+		{input: "prop:lapses", wantPos: 11, wantMsg: "needs a comparison"},
+		{input: "prop:lapses>x", wantPos: 12, wantMsg: "needs a number"},
+		{input: "prop:>3", wantPos: 5, wantMsg: "needs a property"},
+		{input: "añadir prop:lapse>3", wantPos: 12, wantMsg: `unknown property "lapse"`},
+		{input: "added:0", wantPos: 6, wantMsg: "between 1 and"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := query.Parse(tt.input)
+			var syntaxErr *query.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Pos != tt.wantPos || !strings.Contains(syntaxErr.Msg, tt.wantMsg) {
+				t.Errorf("got %v, want position %d: %s", err, tt.wantPos, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestSQL(t *testing.T) {
+	node, err := query.Parse(`tag:verbs* -prop:lapses>3 (dog or "the cat") type:basic added:7`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	got := query.SQL(node, arg)
+	want := "(EXISTS (SELECT 1 FROM unnest(c.tags) AS t(tag) WHERE lower(t.tag) LIKE $1 OR lower(t.tag) LIKE ($1 || '::%'))" +
+		" AND NOT (COALESCE(cs.lapse_count > $2::float8, FALSE))" +
+		" AND (c.search_vector @@ plainto_tsquery(d.language, $3) OR c.search_vector @@ phraseto_tsquery(d.language, $4))" +
+		" AND c.type = $5" +
+		" AND c.created_at >= NOW() - make_interval(days => $6::int))"
+	if got != want {
+		t.Errorf("SQL() =\n%s\nwant\n%s", got, want)
+	}
+	if fmt.Sprint(args) != "[verbs% 3 dog the cat basic 7]" {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	args = nil
+	if got := query.SQL(query.Field{Name: "front", Pattern: "100%_*"}, arg); got != "lower(c.front) LIKE $1" || args[0] != `100\%\_%` {
+		t.Errorf("SQL(front) = %s with %v", got, args)
+	}
+	if got := query.SQL(nil, arg); got != "TRUE" {
+		t.Errorf("SQL(nil) = %s", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	card := &model.Card{
+		Type:      model.CardTypeBasic,
+		Front:     "El Perro",
+		Tags:      []string{"animals::mammals"},
+		CreatedAt: now.AddDate(0, 0, -3),
+		UpdatedAt: now.AddDate(0, 0, -3),
+	}
+	candidate := query.Candidate{
+		Card:     card,
+		DeckPath: "Spanish::Nouns",
+		DeckIDs:  []int64{7, 3},
+		Text:     func(text query.Text) bool { return text.Text == "perro" },
+	}
+	review := &model.CardSchedule{State: model.ScheduleStateReview, DueAt: now.Add(-time.Hour), LapseCount: 4, EaseFactor: 2.5}
+
+	tests := []struct {
+		input    string
+		schedule *model.CardSchedule
+		want     bool
+	}{
+		{input: "perro deck:spanish did:3 tag:animals type:basic front:el*", want: true},
+		{input: "deck:nouns or deck:span or deck:span*::verbs"},
+		{input: "tag:ANIMALS::mammals::cats or tag:mammals"},
+		{input: "is:new -is:due -is:suspended tag:anim* added:3 -added:2", want: true},
+		{input: "prop:lapses>3"},
+		{input: "-prop:lapses>3", want: true},
+		{input: "is:due is:review prop:lapses>3 prop:due<0 prop:ease=2.5", schedule: review, want: true},
+		{input: "is:new or -prop:lapses>3 or rated:1", schedule: review},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := query.Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidate.Schedule = tt.schedule
+			if got := query.Match(node, candidate, now); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParse_RoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		`deck:Spanish tag:verbs is:due -is:suspended prop:lapses>3 added:7 "exact phrase"`,
+		`a (b or -c) and "d \" e"`,
+		`front:"x y" -(deck:A::B or did:3)`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		node, err := query.Parse(input)
+		if err != nil || node == nil {
+			return
+		}
+		reparsed, err := query.Parse(node.String())
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", node.String(), err)
+		}
+		if reparsed.String() != node.String() {
+			t.Errorf("round trip of %q: %q != %q", input, reparsed.String(), node.String())
+		}
+	})
+}
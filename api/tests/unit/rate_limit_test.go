@@ -0,0 +1,200 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/ratelimit"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.PerMinute(60, 3)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		decision, err := store.Take(ctx, "k", policy, now)
+		if err != nil || !decision.Allowed {
+			t.Fatalf("request %d: expected allowed, got %+v (err %v)", i, decision, err)
+		}
+		if decision.Remaining != 2-i {
+			t.Errorf("request %d: expected %d remaining, got %d", i, 2-i, decision.Remaining)
+		}
+	}
+
+	decision, _ := store.Take(ctx, "k", policy, now)
+	if decision.Allowed {
+		t.Fatal("expected the burst to be exhausted")
+	}
+	if decision.RetryAfter != time.Second {
+		t.Errorf("expected retry after 1s, got %s", decision.RetryAfter)
+	}
+
+	if decision, _ := store.Take(ctx, "other", policy, now); !decision.Allowed {
+		t.Error("expected buckets to be independent per key")
+	}
+
+	if decision, _ := store.Take(ctx, "k", policy, now.Add(time.Second)); !decision.Allowed {
+		t.Error("expected one token to be refilled after a second")
+	}
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.PerMinute(60, 5)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	_, _ = store.Take(ctx, "a", policy, now)
+	_, _ = store.Take(ctx, "b", policy, now)
+	if store.Len() != 2 {
+		t.Fatalf("expected 2 buckets, got %d", store.Len())
+	}
+
+	_, _ = store.Take(ctx, "c", policy, now.Add(2*time.Minute))
+	if store.Len() != 1 {
+		t.Errorf("expected refilled buckets to be swept, got %d buckets", store.Len())
+	}
+}
+
+func TestLimiter_NamespacesKeys(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	auth := ratelimit.NewLimiter("auth", store, ratelimit.PerMinute(1, 1))
+	api := ratelimit.NewLimiter("api", store, ratelimit.PerMinute(1, 1))
+	ctx := context.Background()
+
+	if decision, _ := auth.Allow(ctx, "ip:1.2.3.4"); !decision.Allowed {
+		t.Fatal("expected first auth request to be allowed")
+	}
+	if decision, _ := api.Allow(ctx, "ip:1.2.3.4"); !decision.Allowed {
+		t.Error("expected the api limiter not to share the auth bucket")
+	}
+	if decision, _ := auth.Allow(ctx, "ip:1.2.3.4"); decision.Allowed {
+		t.Error("expected second auth request to be limited")
+	}
+}
+
+func newRateLimitedAPI(t *testing.T, authPolicy, apiPolicy ratelimit.Policy) http.Handler {
+	t.Helper()
+	authService, _, tokens := newTestAuthService(t)
+	store := newDeckStore()
+	authorizer := service.NewAuthorizer(store, &authzCardRepo{}, &authzScheduleRepo{}, &authzReviewLogRepo{})
+	limits := ratelimit.NewMemoryStore()
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger:      logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens:      tokens,
+		Auth:        authService,
+		Decks:       service.NewDeckService(authorizer, store, directUnitOfWork{repos: repository.Repositories{Decks: store}}),
+		AuthLimiter: ratelimit.NewLimiter("auth", limits, authPolicy),
+		APILimiter:  ratelimit.NewLimiter("api", limits, apiPolicy),
+		TrustProxy:  true,
+	})
+	return mux
+}
+
+func TestRateLimit_LoginIsLimitedPerIP(t *testing.T) {
+	server := newRateLimitedAPI(t, ratelimit.PerMinute(2, 2), ratelimit.PerMinute(100, 100))
+
+	login := func(ip string) *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"email":"nobody@example.com","password":"wrong-password"}`)
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", body)
+		request.Header.Set("X-Forwarded-For", ip+", 10.0.0.1")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		if recorder := login("203.0.113.7"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i, http.StatusUnauthorized, recorder.Code)
+		}
+	}
+
+	recorder := login("203.0.113.7")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected Retry-After 30, got %q", recorder.Header().Get("Retry-After"))
+	}
+	if problem := decodeProblem(t, recorder); problem.Code != "rate_limited" {
+		t.Errorf("expected code rate_limited, got %q", problem.Code)
+	}
+
+	if recorder := login("198.51.100.1"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected another IP to be unaffected, got %d", recorder.Code)
+	}
+}
+
+func TestRateLimit_APIIsLimitedPerUser(t *testing.T) {
+	tokens := newTestTokenManager(t)
+	server := newRateLimitedAPI(t, ratelimit.PerMinute(10, 10), ratelimit.PerMinute(2, 2))
+
+	// Both users come from the same IP.
+	list := func(userID int64) *httptest.ResponseRecorder {
+		token, _, _ := tokens.IssueAccessToken(userID)
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i, want := range []string{"1", "0"} {
+		recorder := list(ownerID)
+		if recorder.Code != http.StatusOK || recorder.Header().Get("X-RateLimit-Remaining") != want {
+			t.Fatalf("request %d: expected status %d with %s remaining, got %d with %q", i, http.StatusOK, want, recorder.Code, recorder.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	if recorder := list(ownerID); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, recorder.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if recorder := list(intruderID); recorder.Code != http.StatusOK {
+			t.Errorf("request %d: expected another user on the same IP to keep their quota, got %d", i, recorder.Code)
+		}
+	}
+}
+
+func TestRateLimit_UnauthenticatedRequestsAreLimitedPerIP(t *testing.T) {
+	server := newRateLimitedAPI(t, ratelimit.PerMinute(10, 10), ratelimit.PerMinute(2, 2))
+
+	list := func(ip, authorization string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/decks", nil)
+		request.Header.Set("X-Forwarded-For", ip)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i, authorization := range []string{"", "Bearer not-a-token"} {
+		if recorder := list("203.0.113.7", authorization); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i, http.StatusUnauthorized, recorder.Code)
+		}
+	}
+	for _, authorization := range []string{"", "Bearer not-a-token"} {
+		recorder := list("203.0.113.7", authorization)
+		if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") == "" {
+			t.Errorf("%q: expected status %d with Retry-After, got %d", authorization, http.StatusTooManyRequests, recorder.Code)
+		}
+	}
+	if recorder := list("198.51.100.1", ""); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("expected another IP to be unaffected, got %d", recorder.Code)
+	}
+}
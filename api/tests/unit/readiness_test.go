@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/health"
+	"memwright/api/migrations"
+)
+
+type fakePinger struct {
+	err   error
+	delay time.Duration
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	select {
+	case <-time.After(p.delay):
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type fakeSchemaRepo struct {
+	version int64
+	dirty   bool
+	err     error
+}
+
+func (r *fakeSchemaRepo) Version(ctx context.Context) (int64, bool, error) {
+	return r.version, r.dirty, r.err
+}
+
+func serveReadiness(checker *health.Checker) (*httptest.ResponseRecorder, health.Report) {
+	recorder := httptest.NewRecorder()
+	handler.NewReadinessHandler(checker).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+	var report health.Report
+	_ = json.NewDecoder(recorder.Body).Decode(&report)
+	return recorder, report
+}
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.Database(&fakePinger{}),
+		health.Migrations(&fakeSchemaRepo{version: 2}, 2),
+	)
+
+	recorder, report := serveReadiness(checker)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if report.Status != health.StatusOK || len(report.Checks) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	for name, result := range report.Checks {
+		if result.Status != health.StatusOK || result.Error != "" {
+			t.Errorf("%s: unexpected result %+v", name, result)
+		}
+	}
+}
+
+func TestReadiness_DatabaseDown(t *testing.T) {
+	checker := health.NewChecker(time.Second,
+		health.Database(&fakePinger{err: errors.New("connection refused")}),
+		health.Migrations(&fakeSchemaRepo{version: 2}, 2),
+	)
+
+	recorder, report := serveReadiness(checker)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+	}
+	if report.Status != health.StatusUnavailable {
+		t.Errorf("expected overall status unavailable, got %q", report.Status)
+	}
+	if result := report.Checks["database"]; result.Status != health.StatusUnavailable || result.Error != "connection refused" {
+		t.Errorf("unexpected database result %+v", result)
+	}
+	if result := report.Checks["migrations"]; result.Status != health.StatusOK {
+		t.Errorf("expected migrations to pass, got %+v", result)
+	}
+}
+
+func TestReadiness_PingTimesOut(t *testing.T) {
+	checker := health.NewChecker(20*time.Millisecond, health.Database(&fakePinger{delay: time.Second}))
+
+	start := time.Now()
+	recorder, report := serveReadiness(checker)
+	if time.Since(start) > 500*time.Millisecond {
+		t.Error("expected the check to be cut off by the timeout")
+	}
+	if recorder.Code != http.StatusServiceUnavailable || report.Checks["database"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("unexpected response %d %+v", recorder.Code, report)
+	}
+}
+
+func TestReadiness_MigrationMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *fakeSchemaRepo
+		want   string
+	}{
+		{name: "behind", schema: &fakeSchemaRepo{version: 1}, want: "schema version 1, expected 2"},
+		{name: "dirty", schema: &fakeSchemaRepo{version: 2, dirty: true}, want: "migration 2 is dirty"},
+		{name: "query error", schema: &fakeSchemaRepo{err: errors.New("relation \"schema_migrations\" does not exist")}, want: "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, report := serveReadiness(health.NewChecker(time.Second, health.Migrations(tt.schema, 2)))
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+			}
+			if result := report.Checks["migrations"]; !strings.Contains(result.Error, tt.want) {
+				t.Errorf("expected error containing %q, got %+v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestLiveness_DoesNotCheckDependencies(t *testing.T) {
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Environment: "test",
+		Readiness:   health.NewChecker(time.Second, health.Database(&fakePinger{err: errors.New("down")})),
+	})
+
+	for path, want := range map[string]int{"/health/live": http.StatusOK, "/health": http.StatusOK, "/health/ready": http.StatusServiceUnavailable} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != want {
+			t.Errorf("%s: expected status %d, got %d", path, want, recorder.Code)
+		}
+	}
+}
+
+func TestMigrations_LatestVersion(t *testing.T) {
+	version, err := migrations.LatestVersion()
+	if err != nil {
+		t.Fatalf("LatestVersion() error = %v", err)
+	}
+	if version < 2 {
+		t.Errorf("expected at least version 2, got %d", version)
+	}
+}
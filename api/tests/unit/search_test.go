@@ -0,0 +1,109 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+func TestSearchAPI(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	for _, email := range []string{"owner@example.com", "intruder@example.com"} {
+		if err := repos.Users.Create(ctx, &model.User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	authorizer := service.NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+	decks := service.NewDeckService(authorizer, repos.Decks, memory.NewUnitOfWork(store))
+	cards := service.NewCardService(authorizer, repos.Cards, nil)
+
+	deck, err := decks.Create(ctx, ownerID, service.DeckInput{Name: "Spanish", Language: "spanish"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := decks.Create(ctx, intruderID, service.DeckInput{Name: "Foreign"})
+	for _, input := range []service.CardInput{
+		{Front: "el perro", Back: "the dog", Tags: []string{"nouns::animals"}},
+		{Front: "hablar", Back: "to speak"},
+	} {
+		if _, err := cards.Create(ctx, ownerID, deck.ID, input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokens := newTestTokenManager(t)
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens: tokens,
+		Search: service.NewSearchService(authorizer, repos.Search),
+	})
+	token, _, _ := tokens.IssueAccessToken(ownerID)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/cards/search?"+query, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get("q=dog&tag=nouns&type=basic&state=new")
+	var page handler.PageResponse[model.CardSearchHit]
+	if err := json.NewDecoder(recorder.Body).Decode(&page); err != nil || recorder.Code != http.StatusOK {
+		t.Fatalf("search: status %d, %v", recorder.Code, err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Card.Front != "el perro" || page.Items[0].BackSnippet != "the <mark>dog</mark>" {
+		t.Errorf("unexpected hits %+v", page.Items)
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "filters only", query: "deck_id=1", wantStatus: http.StatusOK},
+		{name: "bad deck id", query: "deck_id=abc", wantStatus: http.StatusBadRequest},
+		{name: "foreign deck", query: "deck_id=" + strconv.FormatInt(foreign.ID, 10), wantStatus: http.StatusForbidden},
+		{name: "bad type", query: "type=essay", wantStatus: http.StatusBadRequest},
+		{name: "bad state", query: "state=due", wantStatus: http.StatusBadRequest},
+		{name: "bad tag", query: "tag=a::", wantStatus: http.StatusBadRequest},
+		{name: "query language", query: "q=deck:spanish+-tag:none+is:new", wantStatus: http.StatusOK},
+		{name: "bad query", query: "q=is:new+(dog", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recorder := get(tt.query); recorder.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, recorder.Code, recorder.Body)
+			}
+		})
+	}
+
+	var problem handler.Problem
+	recorder = get("q=tag:verbs+prop:lapse>3")
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil || len(problem.Errors) != 1 {
+		t.Fatalf("bad query: status %d, %v", recorder.Code, err)
+	}
+	if field := problem.Errors[0]; field.Field != "q" || field.Position == nil || *field.Position != 15 {
+		t.Errorf("expected an error on q at position 15, got %+v", field)
+	}
+
+	_, err = decks.Create(ctx, ownerID, service.DeckInput{Name: "Klingon", Language: "klingon"})
+	var validationErr *model.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "language" {
+		t.Errorf("expected a validation error on language, got %v", err)
+	}
+}
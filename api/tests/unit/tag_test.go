@@ -0,0 +1,200 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag   string
+		want  string
+		valid bool
+	}{
+		{tag: "verbs", want: "verbs", valid: true},
+		{tag: " lang :: es ::verbs ", want: "lang::es::verbs", valid: true},
+		{tag: "lang::", want: "lang::", valid: false},
+		{tag: "a:: ::b", want: "a::::b", valid: false},
+		{tag: "  ", want: "", valid: false},
+		{tag: "a:b", want: "a:b", valid: true},
+	}
+
+	for _, tt := range tests {
+		got := model.NormalizeTag(tt.tag)
+		if got != tt.want || model.ValidTag(got) != tt.valid {
+			t.Errorf("NormalizeTag(%q) = %q (valid %v), want %q (valid %v)", tt.tag, got, model.ValidTag(got), tt.want, tt.valid)
+		}
+	}
+}
+
+func TestTagPaths(t *testing.T) {
+	got := model.TagPaths([]string{"lang::es::verbs", "lang::fr", "misc"})
+	want := []string{"lang", "lang::es", "lang::es::verbs", "lang::fr", "misc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TagPaths() = %v, want %v", got, want)
+	}
+}
+
+func TestReplaceTagPrefix(t *testing.T) {
+	sources := []string{"lang", "lang::es"}
+	tests := []struct {
+		tag     string
+		want    string
+		matched bool
+	}{
+		{tag: "lang", want: "target", matched: true},
+		{tag: "lang::fr", want: "target::fr", matched: true},
+		{tag: "lang::es::verbs", want: "target::verbs", matched: true},
+		{tag: "language", want: "language", matched: false},
+	}
+
+	for _, tt := range tests {
+		got, matched := model.ReplaceTagPrefix(tt.tag, sources, "target")
+		if got != tt.want || matched != tt.matched {
+			t.Errorf("ReplaceTagPrefix(%q) = %q, %v, want %q, %v", tt.tag, got, matched, tt.want, tt.matched)
+		}
+	}
+}
+
+type tagAPI struct {
+	t      *testing.T
+	server http.Handler
+	token  string
+	cards  *service.CardService
+	deckID int64
+}
+
+func newTagAPI(t *testing.T) *tagAPI {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	if err := repos.Users.Create(ctx, &model.User{Email: "owner@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	deck := &model.Deck{UserID: 1, Name: "Spanish", Algorithm: model.AlgorithmSM2}
+	if err := repos.Decks.Create(ctx, deck); err != nil {
+		t.Fatal(err)
+	}
+	authorizer := service.NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+	tokens := newTestTokenManager(t)
+	cards := service.NewCardService(authorizer, repos.Cards, memory.NewUnitOfWork(store))
+
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux, handler.Dependencies{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+		Tokens: tokens,
+		Cards:  cards,
+		Tags:   service.NewTagService(repos.Tags),
+	})
+	token, _, _ := tokens.IssueAccessToken(1)
+	return &tagAPI{t: t, server: mux, token: token, cards: cards, deckID: deck.ID}
+}
+
+func (api *tagAPI) card(front string, tags ...string) {
+	api.t.Helper()
+	if _, err := api.cards.Create(context.Background(), 1, api.deckID, service.CardInput{Front: front, Tags: tags}); err != nil {
+		api.t.Fatal(err)
+	}
+}
+
+func (api *tagAPI) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	api.t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&payload).Encode(body)
+	}
+	request := httptest.NewRequest(method, path, &payload)
+	request.Header.Set("Authorization", "Bearer "+api.token)
+	recorder := httptest.NewRecorder()
+	api.server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestTagAPI(t *testing.T) {
+	api := newTagAPI(t)
+	api.card("hablar", " lang :: es :: verbs ")
+	api.card("hola", "lang::es", "greetings")
+	api.card("bonjour", "lang::fr")
+
+	recorder := api.do(http.MethodGet, "/api/v1/tags", nil)
+	var tags []model.TagCount
+	_ = json.NewDecoder(recorder.Body).Decode(&tags)
+	if recorder.Code != http.StatusOK || len(tags) != 5 || tags[1] != (model.TagCount{Tag: "lang", Cards: 0, Total: 3}) {
+		t.Fatalf("list tags: status %d, %+v", recorder.Code, tags)
+	}
+
+	recorder = api.do(http.MethodGet, "/api/v1/tags/cards?tag=lang::es", nil)
+	var page handler.PageResponse[model.Card]
+	_ = json.NewDecoder(recorder.Body).Decode(&page)
+	if recorder.Code != http.StatusOK || len(page.Items) != 2 {
+		t.Fatalf("tagged cards: status %d, %+v", recorder.Code, page)
+	}
+
+	recorder = api.do(http.MethodPost, "/api/v1/tags/rename", service.RenameTagRequest{From: "lang::es", To: "spanish"})
+	var result service.TagChangeResult
+	_ = json.NewDecoder(recorder.Body).Decode(&result)
+	if recorder.Code != http.StatusOK || result.Updated != 2 {
+		t.Fatalf("rename: status %d, %+v", recorder.Code, result)
+	}
+
+	recorder = api.do(http.MethodPost, "/api/v1/tags/merge", service.MergeTagsRequest{Sources: []string{"spanish", "lang::fr"}, Target: "romance"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("merge: status %d: %s", recorder.Code, recorder.Body)
+	}
+	recorder = api.do(http.MethodGet, "/api/v1/tags", nil)
+	tags = nil
+	_ = json.NewDecoder(recorder.Body).Decode(&tags)
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+	if got := strings.Join(names, ","); got != "greetings,romance,romance::verbs" {
+		t.Errorf("tags after merge = %s", got)
+	}
+}
+
+func TestTagAPI_Errors(t *testing.T) {
+	api := newTagAPI(t)
+	api.card("hola", "lang::es")
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       interface{}
+		wantStatus int
+	}{
+		{name: "cards without tag", method: http.MethodGet, path: "/api/v1/tags/cards", wantStatus: http.StatusBadRequest},
+		{name: "rename onto itself", method: http.MethodPost, path: "/api/v1/tags/rename", body: service.RenameTagRequest{From: "lang", To: " lang "}, wantStatus: http.StatusBadRequest},
+		{name: "rename empty segment", method: http.MethodPost, path: "/api/v1/tags/rename", body: service.RenameTagRequest{From: "lang", To: "a::"}, wantStatus: http.StatusBadRequest},
+		{name: "rename missing tag", method: http.MethodPost, path: "/api/v1/tags/rename", body: service.RenameTagRequest{From: "missing", To: "other"}, wantStatus: http.StatusNotFound},
+		{name: "merge without sources", method: http.MethodPost, path: "/api/v1/tags/merge", body: service.MergeTagsRequest{Target: "x"}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if recorder := api.do(tt.method, tt.path, tt.body); recorder.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, recorder.Code, recorder.Body)
+			}
+		})
+	}
+
+	_, err := api.cards.Create(context.Background(), 1, api.deckID, service.CardInput{Front: "x", Tags: []string{"ok", "::bad"}})
+	var validationErr *model.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "tags[1]" {
+		t.Errorf("expected a validation error on tags[1], got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package unit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"memwright/api/internal/repository"
+)
+
+func TestTextArray_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    repository.TextArray
+		wantErr bool
+	}{
+		{name: "NULL column", src: nil, want: nil},
+		{name: "empty", src: "{}", want: repository.TextArray{}},
+		{name: "unquoted", src: []byte("{a,b,c}"), want: repository.TextArray{"a", "b", "c"}},
+		{name: "quoted delimiter", src: `{"a,b",c}`, want: repository.TextArray{"a,b", "c"}},
+		{name: "escaped quote and backslash", src: `{"quote \"q\"","back\\slash"}`, want: repository.TextArray{`quote "q"`, `back\slash`}},
+		{name: "trailing empty element", src: `{a,""}`, want: repository.TextArray{"a", ""}},
+		{name: "NULL elements are skipped", src: `{a,NULL,null,b}`, want: repository.TextArray{"a", "b"}},
+		{name: "quoted NULL is text", src: `{"NULL"}`, want: repository.TextArray{"NULL"}},
+		{name: "escaped NULL is text", src: `{\NULL}`, want: repository.TextArray{"NULL"}},
+		{name: "whitespace around elements", src: "{ a b , \"c\" }", want: repository.TextArray{"a b", "c"}},
+		{name: "unicode", src: `{日本語,"ça va"}`, want: repository.TextArray{"日本語", "ça va"}},
+		{name: "explicit bounds", src: "[0:1]={a,b}", want: repository.TextArray{"a", "b"}},
+		{name: "multidimensional", src: "{{a},{b}}", wantErr: true},
+		{name: "unterminated quote", src: `{"a}`, wantErr: true},
+		{name: "missing braces", src: "a,b", wantErr: true},
+		{name: "empty unquoted element", src: "{a,,b}", wantErr: true},
+		{name: "unsupported type", src: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got repository.TextArray
+			err := got.Scan(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextArray_Value(t *testing.T) {
+	tests := []struct {
+		name    string
+		array   repository.TextArray
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "nil", array: nil, want: nil},
+		{name: "empty", array: repository.TextArray{}, want: "{}"},
+		{name: "quotes every element", array: repository.TextArray{"a", "NULL", ""}, want: `{"a","NULL",""}`},
+		{name: "escapes", array: repository.TextArray{`quote "q"`, `back\slash`, "a,b"}, want: `{"quote \"q\"","back\\slash","a,b"}`},
+		{name: "NUL byte", array: repository.TextArray{"a\x00b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.array.Value()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Value() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzTextArray_RoundTrip(f *testing.F) {
+	for _, seed := range []string{"", "a", "a,b", `quote "q"`, `back\slash`, "NULL", "{}", " padded ", "日本語", "\\\""} {
+		f.Add(seed, "second")
+	}
+
+	f.Fuzz(func(t *testing.T, first, second string) {
+		// PostgreSQL text is valid UTF-8 without NUL bytes.
+		for _, s := range []string{first, second} {
+			if !utf8.ValidString(s) || strings.ContainsRune(s, 0) {
+				t.Skip()
+			}
+		}
+
+		array := repository.TextArray{first, second}
+		value, err := array.Value()
+		if err != nil {
+			t.Fatalf("Value() error = %v", err)
+		}
+		var scanned repository.TextArray
+		if err := scanned.Scan(value); err != nil {
+			t.Fatalf("Scan(%q) error = %v", value, err)
+		}
+		if !reflect.DeepEqual(scanned, array) {
+			t.Errorf("round trip of %q = %#v, want %#v", value, scanned, array)
+		}
+	})
+}
+
+func FuzzTextArray_Scan(f *testing.F) {
+	for _, seed := range []string{"{}", "{a,b}", `{"a,b",NULL}`, `{\"}`, "[1:2]={x,y}", "{{a}}", `{"a`, "{a\\"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, literal string) {
+		var scanned repository.TextArray
+		if err := scanned.Scan(literal); err != nil {
+			return
+		}
+		// Whatever parses must survive being written and read again.
+		value, err := scanned.Value()
+		if err != nil {
+			return
+		}
+		var again repository.TextArray
+		if err := again.Scan(value); err != nil {
+			t.Fatalf("Scan(%q) error = %v", value, err)
+		}
+		if !reflect.DeepEqual(again, scanned) {
+			t.Errorf("re-scan of %q = %#v, want %#v", value, again, scanned)
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package unit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"memwright/api/internal/handler"
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+	"memwright/api/pkg/logger"
+)
+
+func (api *etagAPI) createCard(deckID int64, input service.CardInput) *model.Card {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deckID, 10)+"/cards", "", input)
+	if response.Code != http.StatusCreated {
+		api.t.Fatalf("create card %s: status %d: %s", input.Front, response.Code, response.Body)
+	}
+	var card model.Card
+	_ = json.NewDecoder(response.Body).Decode(&card)
+	return &card
+}
+
+func (api *etagAPI) export(deckID int64, options *service.ExportOptions) *model.ExportDocument {
+	api.t.Helper()
+	var body interface{}
+	if options != nil {
+		body = options
+	}
+	response := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deckID, 10)+"/export", "", body)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("export: status %d: %s", response.Code, response.Body)
+	}
+	var document model.ExportDocument
+	if err := json.NewDecoder(response.Body).Decode(&document); err != nil {
+		api.t.Fatalf("decode export: %v", err)
+	}
+	return &document
+}
+
+func (api *etagAPI) importDocument(query string, document *model.ExportDocument) *model.ImportResult {
+	api.t.Helper()
+	response := api.do(http.MethodPost, "/api/v1/decks/import"+query, "", document)
+	if response.Code != http.StatusOK {
+		api.t.Fatalf("import: status %d: %s", response.Code, response.Body)
+	}
+	var result model.ImportResult
+	_ = json.NewDecoder(response.Body).Decode(&result)
+	return &result
+}
+
+// transferFixture builds Spanish > Verbs with three cards, one of them
+// studied twice.
+func transferFixture(t *testing.T, api *etagAPI) (spanish, verbs *model.Deck, studied *model.Card) {
+	t.Helper()
+	spanish = api.createDeck("Spanish", nil)
+	verbs = api.createDeck("Verbs", &spanish.ID)
+	api.createCard(spanish.ID, service.CardInput{Front: "hola", Back: "hello", Tags: []string{"greetings"}})
+	studied = api.createCard(verbs.ID, service.CardInput{Front: "hablar", Back: "to speak", Extra: "regular"})
+	api.createCard(verbs.ID, service.CardInput{Type: model.CardTypeCloze, Front: "{{c1::ser}} o no {{c1::ser}}"})
+
+	ctx := context.Background()
+	reviewedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	schedule := &model.CardSchedule{CardID: studied.ID, UserID: ownerID, State: model.ScheduleStateReview, DueAt: reviewedAt.AddDate(0, 0, 3), Interval: 3, EaseFactor: 2.6, ReviewCount: 2, LastReviewedAt: &reviewedAt}
+	if err := api.repos.Schedules.Create(ctx, schedule); err != nil {
+		t.Fatal(err)
+	}
+	for i, rating := range []model.ReviewRating{model.ReviewRatingWrong, model.ReviewRatingCorrect} {
+		log := &model.ReviewLog{CardScheduleID: schedule.ID, UserID: ownerID, Rating: rating, PreviousState: model.ScheduleStateLearning, NewState: model.ScheduleStateReview, ReviewedAt: reviewedAt.Add(time.Duration(i-1) * time.Hour)}
+		if err := api.repos.ReviewLogs.Create(ctx, log); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return spanish, verbs, studied
+}
+
+func TestTransferAPI_Export(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, verbs, studied := transferFixture(t, api)
+
+	plain := api.export(spanish.ID, nil)
+	if plain.Format != model.ExportFormat || plain.Version != model.ExportVersion || plain.ExportedAt.IsZero() {
+		t.Errorf("unexpected header %+v", plain)
+	}
+	if len(plain.Decks) != 2 || plain.Decks[0].ID != spanish.ID || plain.Decks[0].ParentID != nil ||
+		plain.Decks[1].ID != verbs.ID || *plain.Decks[1].ParentID != spanish.ID || plain.Decks[1].SRSConfig == nil {
+		t.Errorf("unexpected decks %+v", plain.Decks)
+	}
+	if len(plain.Cards) != 3 || plain.Cards[0].Tags[0] != "greetings" || plain.Cards[1].Extra != "regular" || plain.Cards[1].Schedule != nil {
+		t.Errorf("unexpected cards %+v", plain.Cards)
+	}
+
+	full := api.export(spanish.ID, &service.ExportOptions{IncludeReviews: true})
+	schedule := full.Cards[1].Schedule
+	if full.Cards[1].ID != studied.ID || schedule == nil || schedule.Interval != 3 || len(schedule.Reviews) != 2 ||
+		schedule.Reviews[0].Rating != model.ReviewRatingWrong || schedule.Reviews[1].Rating != model.ReviewRatingCorrect {
+		t.Errorf("unexpected schedule %+v", schedule)
+	}
+	if full.Cards[0].Schedule != nil {
+		t.Errorf("expected no schedule on a card never studied, got %+v", full.Cards[0].Schedule)
+	}
+
+	subtree := api.export(verbs.ID, &service.ExportOptions{IncludeSchedules: true})
+	if len(subtree.Decks) != 1 || subtree.Decks[0].ParentID != nil || len(subtree.Cards) != 2 || subtree.Cards[0].Schedule.Reviews != nil {
+		t.Errorf("unexpected subdeck export %+v", subtree)
+	}
+
+	if response := api.do(http.MethodPost, "/api/v1/decks/999/export", "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("export a missing deck: status %d", response.Code)
+	}
+}
+
+// slowWriter sleeps before every write, like a client reading slowly.
+type slowWriter struct {
+	http.ResponseWriter
+	delay time.Duration
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *slowWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestTransferAPI_ExportOutlivesWriteTimeout(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, _, _ := transferFixture(t, api)
+	for i := 0; i < 20; i++ {
+		api.createCard(spanish.ID, service.CardInput{Front: "card " + strconv.Itoa(i), Back: "back"})
+	}
+
+	slow := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		api.server.ServeHTTP(&slowWriter{ResponseWriter: writer, delay: 5 * time.Millisecond}, request)
+	})
+	server := httptest.NewUnstartedServer(handler.Wrap(slow, handler.MiddlewareOptions{
+		Logger:    logger.New(&bytes.Buffer{}, logger.LevelError),
+		AccessLog: true,
+	}))
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	for _, format := range []string{"", "/anki", "/markdown"} {
+		request, _ := http.NewRequest(http.MethodPost, server.URL+"/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10)+"/export"+format, nil)
+		request.Header.Set("Authorization", "Bearer "+api.token)
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatalf("export%s: %v", format, err)
+		}
+		body, err := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if err != nil || response.StatusCode != http.StatusOK {
+			t.Fatalf("export%s: status %d after %d bytes: %v", format, response.StatusCode, len(body), err)
+		}
+		if !strings.Contains(string(body), "card 19") {
+			t.Errorf("export%s was cut off:\n%s", format, body)
+		}
+	}
+}
+
+// slowBody sends data in chunks with a pause before each, like a client on
+// a slow uplink.
+func slowBody(data string, chunks int, delay time.Duration) io.Reader {
+	reader, writer := io.Pipe()
+	go func() {
+		size := (len(data) + chunks - 1) / chunks
+		for start := 0; start < len(data); start += size {
+			time.Sleep(delay)
+			if _, err := writer.Write([]byte(data[start:min(start+size, len(data))])); err != nil {
+				return
+			}
+		}
+		_ = writer.Close()
+	}()
+	return reader
+}
+
+func TestTransferAPI_ImportOutlivesReadTimeout(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, _, _ := transferFixture(t, api)
+	document, _ := json.Marshal(api.export(spanish.ID, nil))
+	anki := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10)+"/export/anki", "", nil).Body.String()
+	markdown := api.exportMarkdown(spanish.ID)
+
+	server := httptest.NewUnstartedServer(handler.Wrap(api.server, handler.MiddlewareOptions{
+		Logger: logger.New(&bytes.Buffer{}, logger.LevelError),
+	}))
+	server.Config.ReadTimeout = 100 * time.Millisecond
+	server.Config.WriteTimeout = 100 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	for _, upload := range []struct{ path, data string }{
+		{"/api/v1/decks/import", string(document)},
+		{"/api/v1/decks/import/anki", anki},
+		{"/api/v1/decks/import/markdown?parent_id=" + strconv.FormatInt(spanish.ID, 10), markdown},
+		{"/api/v1/decks/" + strconv.FormatInt(spanish.ID, 10) + "/import/csv", "front,back\nslow,upload\n"},
+	} {
+		request, _ := http.NewRequest(http.MethodPost, server.URL+upload.path, slowBody(upload.data, 6, 40*time.Millisecond))
+		request.Header.Set("Authorization", "Bearer "+api.token)
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatalf("%s: %v", upload.path, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d: %s", upload.path, response.StatusCode, body)
+		}
+	}
+}
+
+func TestTransferAPI_ImportRoundTrip(t *testing.T) {
+	source := newETagAPI(t)
+	spanish, _, _ := transferFixture(t, source)
+	document := source.export(spanish.ID, &service.ExportOptions{IncludeReviews: true})
+
+	target := newETagAPI(t)
+	target.createDeck("French", nil)
+	result := target.importDocument("", document)
+	if result.DecksCreated != 2 || result.CardsCreated != 3 || result.Schedules != 1 || result.Reviews != 2 || len(result.Conflicts) != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if got := target.order(nil); got != "French:0 Spanish:1" {
+		t.Errorf("order = %s", got)
+	}
+
+	imported := target.export(result.DeckIDs[spanish.ID], &service.ExportOptions{IncludeReviews: true})
+	if len(imported.Decks) != 2 || imported.Decks[1].Name != "Verbs" || *imported.Decks[1].ParentID != result.DeckIDs[spanish.ID] {
+		t.Errorf("unexpected decks %+v", imported.Decks)
+	}
+	for i, card := range imported.Cards {
+		want := document.Cards[i]
+		if card.Front != want.Front || card.Back != want.Back || card.Type != want.Type || card.DeckID != result.DeckIDs[want.DeckID] {
+			t.Errorf("card %d = %+v, want %+v", i, card, want)
+		}
+	}
+	schedule := imported.Cards[1].Schedule
+	if schedule == nil || !schedule.DueAt.Equal(document.Cards[1].Schedule.DueAt) || len(schedule.Reviews) != 2 {
+		t.Errorf("unexpected schedule %+v", schedule)
+	}
+
+	again := target.importDocument("", document)
+	if again.DecksCreated != 0 || again.DecksMerged != 2 || again.CardsCreated != 0 || again.CardsSkipped != 3 || again.Schedules != 0 || len(again.Conflicts) != 0 {
+		t.Errorf("expected a second import to change nothing, got %+v", again)
+	}
+}
+
+func TestTransferAPI_ImportConflicts(t *testing.T) {
+	api := newETagAPI(t)
+	spanish, verbs, studied := transferFixture(t, api)
+	document := api.export(spanish.ID, nil)
+	document.Decks[1].Description = "irregular too"
+	document.Cards[1].Back = "to talk"
+	document.Cards = append(document.Cards, model.ExportedCard{ID: 99, DeckID: verbs.ID, Type: model.CardTypeBasic, Front: "comer", Back: "to eat"})
+
+	kept := api.importDocument("", document)
+	if kept.DecksMerged != 2 || kept.CardsCreated != 1 || kept.CardsSkipped != 3 || len(kept.Conflicts) != 2 {
+		t.Fatalf("unexpected result %+v", kept)
+	}
+	want := []model.ImportConflict{
+		{Kind: "deck", SourceID: verbs.ID, ExistingID: verbs.ID, Fields: []string{"description"}, Resolution: "kept"},
+		{Kind: "card", SourceID: studied.ID, ExistingID: studied.ID, Fields: []string{"back"}, Resolution: "kept"},
+	}
+	for i, conflict := range kept.Conflicts {
+		if conflict.Kind != want[i].Kind || conflict.ExistingID != want[i].ExistingID || strings.Join(conflict.Fields, ",") != strings.Join(want[i].Fields, ",") || conflict.Resolution != want[i].Resolution {
+			t.Errorf("conflict %d = %+v, want %+v", i, conflict, want[i])
+		}
+	}
+
+	overwritten := api.importDocument("?on_conflict=overwrite", document)
+	if overwritten.CardsUpdated != 1 || overwritten.CardsSkipped != 3 || len(overwritten.Conflicts) != 2 || overwritten.Conflicts[1].Resolution != "overwritten" {
+		t.Fatalf("unexpected result %+v", overwritten)
+	}
+	card, _ := api.repos.Cards.GetByID(context.Background(), studied.ID)
+	deck, _ := api.repos.Decks.GetByID(context.Background(), verbs.ID)
+	if card.Back != "to talk" || deck.Description != "irregular too" {
+		t.Errorf("expected the document to win, got %q and %q", card.Back, deck.Description)
+	}
+
+	nested := api.importDocument("?parent_id="+strconv.FormatInt(spanish.ID, 10), document)
+	if nested.DecksCreated != 2 || nested.CardsCreated != 4 {
+		t.Errorf("unexpected result below a parent %+v", nested)
+	}
+	if got := api.order(&spanish.ID); got != "Verbs:0 Spanish:1" {
+		t.Errorf("Spanish order = %s", got)
+	}
+}
+
+func TestTransferAPI_ImportValidation(t *testing.T) {
+	api := newETagAPI(t)
+	parent := func(id int64) *int64 { return &id }
+	tests := []struct {
+		name     string
+		query    string
+		document model.ExportDocument
+		status   int
+		want     string
+	}{
+		{name: "format", document: model.ExportDocument{Format: "anki", Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: "A"}}}, status: http.StatusBadRequest, want: `"format"`},
+		{name: "newer version", document: model.ExportDocument{Format: model.ExportFormat, Version: 2, Decks: []model.ExportedDeck{{ID: 1, Name: "A"}}}, status: http.StatusBadRequest, want: `"version"`},
+		{name: "no decks", document: model.ExportDocument{Format: model.ExportFormat, Version: 1}, status: http.StatusBadRequest, want: `"decks"`},
+		{name: "invalid deck", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: " ", Algorithm: "sm2"}}, Cards: []model.ExportedCard{{DeckID: 1, Front: "x"}}},
+			status: http.StatusBadRequest, want: `"decks[0].name"`},
+		{name: "dangling parent", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, ParentID: parent(7), Name: "A"}}}, status: http.StatusBadRequest, want: `"decks[0].parent_id"`},
+		{name: "cycle", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: "Root"}, {ID: 2, ParentID: parent(3), Name: "A"}, {ID: 3, ParentID: parent(2), Name: "B"}}},
+			status: http.StatusBadRequest, want: "forms a cycle"},
+		{name: "card", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: "A"}}, Cards: []model.ExportedCard{
+			{DeckID: 2, Front: "x"},
+			{DeckID: 1, Front: "y", Schedule: &model.ExportedSchedule{State: "done", EaseFactor: 2.5, Reviews: []model.ExportedReview{{Rating: 4, PreviousState: "new", NewState: "review"}}}},
+		}}, status: http.StatusBadRequest, want: `"cards[1].schedule.reviews[0].rating"`},
+		{name: "conflict mode", query: "?on_conflict=merge", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: "A"}}}, status: http.StatusBadRequest, want: `"on_conflict"`},
+		{name: "missing parent", query: "?parent_id=999", document: model.ExportDocument{Format: model.ExportFormat, Version: 1, Decks: []model.ExportedDeck{{ID: 1, Name: "A"}}}, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := api.do(http.MethodPost, "/api/v1/decks/import"+tt.query, "", tt.document)
+			if response.Code != tt.status || !strings.Contains(response.Body.String(), tt.want) {
+				t.Errorf("status %d: %s", response.Code, response.Body)
+			}
+		})
+	}
+	if got := api.order(nil); got != "" {
+		t.Errorf("expected failed imports to leave no decks, got %s", got)
+	}
+}
@@ -0,0 +1,124 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/service"
+)
+
+func TestDeckAPI_Archive(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+	path := "/api/v1/decks/" + strconv.FormatInt(deck.ID, 10)
+
+	archived := api.do(http.MethodPost, path+"/archive", `"1"`, nil)
+	if archived.Code != http.StatusOK || archived.Header().Get("ETag") != `"2"` {
+		t.Fatalf("archive: status %d, ETag %q: %s", archived.Code, archived.Header().Get("ETag"), archived.Body)
+	}
+	var stored model.Deck
+	_ = json.NewDecoder(archived.Body).Decode(&stored)
+	if stored.ArchivedAt == nil {
+		t.Errorf("expected archived_at to be set, got %+v", stored)
+	}
+	if again := api.do(http.MethodPost, path+"/archive", "", nil); again.Code != http.StatusOK || again.Header().Get("ETag") != `"2"` {
+		t.Errorf("archive twice: status %d, ETag %q", again.Code, again.Header().Get("ETag"))
+	}
+
+	if stale := api.do(http.MethodPost, path+"/unarchive", `"1"`, nil); stale.Code != http.StatusPreconditionFailed {
+		t.Errorf("stale unarchive: status %d", stale.Code)
+	}
+	unarchived := api.do(http.MethodPost, path+"/unarchive", `"2"`, nil)
+	stored = model.Deck{}
+	_ = json.NewDecoder(unarchived.Body).Decode(&stored)
+	if unarchived.Code != http.StatusOK || stored.ArchivedAt != nil {
+		t.Errorf("unarchive: status %d: %+v", unarchived.Code, stored)
+	}
+}
+
+func TestTrashAPI_RestoreDeck(t *testing.T) {
+	api := newETagAPI(t)
+	spanish := api.createDeck("Spanish", nil)
+	api.createDeck("French", nil)
+	verbs := api.createDeck("Verbs", &spanish.ID)
+	created := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(verbs.ID, 10)+"/cards", "", service.CardInput{Type: model.CardTypeBasic, Front: "hablar", Back: "to speak"})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create card: status %d: %s", created.Code, created.Body)
+	}
+
+	if response := api.do(http.MethodDelete, "/api/v1/decks/"+strconv.FormatInt(spanish.ID, 10), "", nil); response.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", response.Code)
+	}
+	var trash model.Page[*model.TrashedDeck]
+	_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/trash/decks", "", nil).Body).Decode(&trash)
+	if len(trash.Items) != 1 || trash.Items[0].ID != spanish.ID || trash.Items[0].DeletedAt == nil {
+		t.Fatalf("unexpected trash %+v", trash.Items)
+	}
+	if purgeIn := trash.Items[0].PurgeAt.Sub(*trash.Items[0].DeletedAt); purgeIn != service.DefaultTrashRetention {
+		t.Errorf("purge_at is %v after deleted_at", purgeIn)
+	}
+
+	restore := "/api/v1/trash/decks/" + strconv.FormatInt(spanish.ID, 10) + "/restore"
+	if response := api.do(http.MethodPost, "/api/v1/trash/decks/"+strconv.FormatInt(verbs.ID, 10)+"/restore", "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("restore a subdeck of a trashed deck: status %d: %s", response.Code, response.Body)
+	}
+	restored := api.do(http.MethodPost, restore, "", nil)
+	if restored.Code != http.StatusOK {
+		t.Fatalf("restore: status %d: %s", restored.Code, restored.Body)
+	}
+	if got := api.order(nil); got != "French:0 Spanish:1" {
+		t.Errorf("order after restore = %s", got)
+	}
+	var cards model.Page[*model.Card]
+	_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/decks/"+strconv.FormatInt(verbs.ID, 10)+"/cards", "", nil).Body).Decode(&cards)
+	if len(cards.Items) != 1 {
+		t.Errorf("expected the card to come back with its deck, got %+v", cards.Items)
+	}
+	if again := api.do(http.MethodPost, restore, "", nil); again.Code != http.StatusNotFound {
+		t.Errorf("restore twice: status %d", again.Code)
+	}
+}
+
+func TestTrashAPI_RestoreCard(t *testing.T) {
+	api := newETagAPI(t)
+	deck := api.createDeck("Spanish", nil)
+	created := api.do(http.MethodPost, "/api/v1/decks/"+strconv.FormatInt(deck.ID, 10)+"/cards", "", service.CardInput{Type: model.CardTypeBasic, Front: "hola", Back: "hello"})
+	var card model.Card
+	_ = json.NewDecoder(created.Body).Decode(&card)
+	cardPath := "/api/v1/cards/" + strconv.FormatInt(card.ID, 10)
+
+	if response := api.do(http.MethodDelete, cardPath, "", nil); response.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", response.Code)
+	}
+	if response := api.do(http.MethodGet, cardPath, "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("get a trashed card: status %d", response.Code)
+	}
+	var trash model.Page[*model.TrashedCard]
+	_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/trash/cards", "", nil).Body).Decode(&trash)
+	if len(trash.Items) != 1 || trash.Items[0].ID != card.ID {
+		t.Fatalf("unexpected trash %+v", trash.Items)
+	}
+
+	restore := "/api/v1/trash/cards/" + strconv.FormatInt(card.ID, 10) + "/restore"
+	api.skew = service.DefaultTrashRetention + time.Minute
+	if response := api.do(http.MethodPost, restore, "", nil); response.Code != http.StatusNotFound {
+		t.Errorf("restore after the retention period: status %d", response.Code)
+	}
+	_ = json.NewDecoder(api.do(http.MethodGet, "/api/v1/trash/cards", "", nil).Body).Decode(&trash)
+	if len(trash.Items) != 0 {
+		t.Errorf("expected expired cards to leave the trash, got %+v", trash.Items)
+	}
+
+	api.skew = 0
+	restored := api.do(http.MethodPost, restore, "", nil)
+	if restored.Code != http.StatusOK || restored.Header().Get("ETag") != `"2"` {
+		t.Fatalf("restore: status %d, ETag %q: %s", restored.Code, restored.Header().Get("ETag"), restored.Body)
+	}
+	if response := api.do(http.MethodGet, cardPath, "", nil); response.Code != http.StatusOK {
+		t.Errorf("get a restored card: status %d", response.Code)
+	}
+}
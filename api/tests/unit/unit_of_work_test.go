@@ -0,0 +1,207 @@
+package unit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"memwright/api/internal/model"
+	"memwright/api/internal/repository"
+	"memwright/api/internal/repository/memory"
+	"memwright/api/internal/service"
+)
+
+// txDriver is a database/sql driver that only counts transactions.
+type txDriver struct {
+	mu        sync.Mutex
+	begins    int
+	commits   int
+	rollbacks int
+	isolation driver.IsolationLevel
+}
+
+func (d *txDriver) Open(name string) (driver.Conn, error) { return &txConn{driver: d}, nil }
+
+type txConn struct{ driver *txDriver }
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *txConn) Close() error                              { return nil }
+func (c *txConn) Begin() (driver.Tx, error)                 { return nil, errors.New("use BeginTx") }
+func (c *txConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.begins++
+	c.driver.isolation = opts.Isolation
+	return &txTx{driver: c.driver}, nil
+}
+
+type txTx struct{ driver *txDriver }
+
+func (t *txTx) Commit() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.commits++
+	return nil
+}
+func (t *txTx) Rollback() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.rollbacks++
+	return nil
+}
+
+func newTxDB(t *testing.T) (*sql.DB, *txDriver) {
+	t.Helper()
+	d := &txDriver{}
+	db := sql.OpenDB(connectorFunc(func() (driver.Conn, error) { return d.Open("") }))
+	t.Cleanup(func() { _ = db.Close() })
+	return db, d
+}
+
+type connectorFunc func() (driver.Conn, error)
+
+func (f connectorFunc) Connect(ctx context.Context) (driver.Conn, error) { return f() }
+func (f connectorFunc) Driver() driver.Driver                            { return nil }
+
+func TestUnitOfWork_RetriesSerializationFailures(t *testing.T) {
+	db, d := newTxDB(t)
+	uow := repository.NewUnitOfWork(db)
+
+	calls := 0
+	err := uow.Do(context.Background(), func(ctx context.Context, repos repository.Repositories) error {
+		calls++
+		if repos.Cards == nil || repos.Decks == nil {
+			t.Error("expected every repository to be bound to the transaction")
+		}
+		if calls < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if calls != 3 || d.begins != 3 || d.commits != 1 || d.rollbacks != 2 {
+		t.Errorf("calls=%d begins=%d commits=%d rollbacks=%d", calls, d.begins, d.commits, d.rollbacks)
+	}
+	if sql.IsolationLevel(d.isolation) != sql.LevelSerializable {
+		t.Errorf("expected serializable isolation, got %v", sql.IsolationLevel(d.isolation))
+	}
+}
+
+func TestUnitOfWork_GivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "deadlock until attempts run out", err: &pq.Error{Code: "40P01"}, wantCalls: 2},
+		{name: "not retryable", err: &pq.Error{Code: "23505"}, wantCalls: 1},
+		{name: "domain error", err: model.ErrNotFound, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, d := newTxDB(t)
+			calls := 0
+			err := repository.NewUnitOfWork(db).WithMaxAttempts(2).Do(context.Background(), func(ctx context.Context, repos repository.Repositories) error {
+				calls++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) || calls != tt.wantCalls || d.commits != 0 {
+				t.Errorf("Do() = %v after %d calls and %d commits", err, calls, d.commits)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	wrapped := errors.Join(errors.New("update schedule"), &pq.Error{Code: "40001"})
+	if !repository.IsRetryable(wrapped) {
+		t.Error("expected a wrapped serialization failure to be retryable")
+	}
+	if repository.IsRetryable(errors.New("40001")) || repository.IsRetryable(nil) {
+		t.Error("expected only PostgreSQL errors to be retryable")
+	}
+}
+
+func TestMemoryUnitOfWork_RollsBackOnError(t *testing.T) {
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	ctx := context.Background()
+	user := &model.User{Email: "ada@example.com"}
+	_ = repos.Users.Create(ctx, user)
+
+	failure := errors.New("boom")
+	err := memory.NewUnitOfWork(store).Do(ctx, func(ctx context.Context, tx repository.Repositories) error {
+		if err := tx.Decks.Create(ctx, &model.Deck{UserID: user.ID, Name: "Spanish"}); err != nil {
+			return err
+		}
+		user.DisplayName = "changed"
+		if err := tx.Users.Update(ctx, user); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	decks, _ := repos.Decks.GetByUserID(ctx, user.ID, model.PageRequest{})
+	stored, _ := repos.Users.GetByID(ctx, user.ID)
+	if len(decks.Items) != 0 || stored.DisplayName != "" {
+		t.Errorf("expected the unit to be rolled back, got %d decks and user %+v", len(decks.Items), stored)
+	}
+}
+
+func newMemoryCardService(t *testing.T) (*service.CardService, repository.Repositories, int64) {
+	t.Helper()
+	store := memory.NewStore()
+	repos := memory.NewRepositories(store)
+	ctx := context.Background()
+	for _, email := range []string{"owner@example.com", "intruder@example.com"} {
+		if err := repos.Users.Create(ctx, &model.User{Email: email}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	authorizer := service.NewAuthorizer(repos.Decks, repos.Cards, repos.Schedules, repos.ReviewLogs)
+	return service.NewCardService(authorizer, repos.Cards, memory.NewUnitOfWork(store)), repos, 1
+}
+
+func TestCardService_Bulk_IsAtomic(t *testing.T) {
+	cards, repos, ownerID := newMemoryCardService(t)
+	ctx := context.Background()
+
+	deck := &model.Deck{UserID: ownerID, Name: "Spanish"}
+	foreignDeck := &model.Deck{UserID: 2, Name: "Foreign"}
+	_ = repos.Decks.Create(ctx, deck)
+	_ = repos.Decks.Create(ctx, foreignDeck)
+	own := &model.Card{DeckID: deck.ID, Type: model.CardTypeBasic, Front: "hola"}
+	foreign := &model.Card{DeckID: foreignDeck.ID, Type: model.CardTypeBasic, Front: "bonjour"}
+	_ = repos.Cards.Create(ctx, own)
+	_ = repos.Cards.Create(ctx, foreign)
+
+	result, err := cards.Bulk(ctx, ownerID, service.BulkCardRequest{Operation: service.BulkSuspend, CardIDs: []int64{own.ID, foreign.ID}})
+	if err != nil {
+		t.Fatalf("Bulk() error = %v", err)
+	}
+	if result.Committed || result.Failed != 1 || result.Results[0].Status != service.BulkItemRolledBack {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if stored, _ := repos.Cards.GetByID(ctx, own.ID); stored.Suspended {
+		t.Error("expected the suspension of the own card to be rolled back")
+	}
+
+	result, err = cards.Bulk(ctx, ownerID, service.BulkCardRequest{Operation: service.BulkSuspend, CardIDs: []int64{own.ID}})
+	if err != nil || !result.Committed || result.Succeeded != 1 {
+		t.Fatalf("Bulk() = %+v, %v", result, err)
+	}
+	if stored, _ := repos.Cards.GetByID(ctx, own.ID); !stored.Suspended {
+		t.Error("expected the card to be suspended")
+	}
+}